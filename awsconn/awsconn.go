@@ -0,0 +1,279 @@
+// Package awsconn creates AWS sessions shared by every subsystem of kube-aws, so that retries, credentials, CA bundles
+// and endpoints are configured in one place
+package awsconn
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/coreos/kube-aws/model"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRetries is the number of times a throttled or failed AWS API call is retried by default.
+// It is higher than the SDK default because validating a cluster in a big account easily hits rate limits
+const DefaultMaxRetries = 8
+
+// Options configures how sessions are created
+type Options struct {
+	// Profile is the name of the profile in the shared credentials and config files. Defaults to AWS_PROFILE
+	Profile string
+	// AssumeRoleARN is the ARN of the IAM role assumed by every AWS API call
+	AssumeRoleARN string
+	// ExternalID, SessionName and MFASerial are passed to STS AssumeRole along with AssumeRoleARN
+	ExternalID  string
+	SessionName string
+	MFASerial   string
+	// CredentialsCacheDir is the directory to cache credentials of the assumed role in, so that they are reused among
+	// kube-aws commands until they expire. Credentials aren't cached on disk if empty
+	CredentialsCacheDir string
+	// CABundle is the path to a PEM file containing CA certificates trusted in addition to the system ones.
+	// Defaults to AWS_CA_BUNDLE
+	CABundle string
+	// Endpoints maps service IDs e.g. cloudformation, s3 and ec2 to URLs used instead of the default endpoints
+	Endpoints map[string]string
+	// MaxRetries is the number of retries for a failed AWS API call. Defaults to DefaultMaxRetries
+	MaxRetries int
+}
+
+// Config is what a session is created for
+type Config struct {
+	Region model.Region
+	// Debug enables debug logging of the SDK
+	Debug bool
+	// Options are specific to the cluster e.g. the role to assume from cluster.yaml. Global options override them
+	Options Options
+}
+
+var (
+	globalOptions = Options{}
+
+	sessionsMutex sync.Mutex
+	sessions      = map[string]*session.Session{}
+	// assumedRoles holds credentials of assumed roles shared among sessions for different regions, so that a role is
+	// assumed and an MFA token code is prompted only once
+	assumedRoles = map[string]*credentials.Credentials{}
+	// sessionHook is called on every new session
+	sessionHook func(*session.Session)
+)
+
+// SetGlobalOptions sets options shared by every session e.g. from command-line flags
+func SetGlobalOptions(o Options) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	globalOptions = o
+	sessions = map[string]*session.Session{}
+	assumedRoles = map[string]*credentials.Credentials{}
+}
+
+// SetSessionHook registers a function called on every session created by NewSession before any client is created from it
+// e.g. to serve AWS API calls from an in-memory fake backend in tests. nil removes the hook
+func SetSessionHook(h func(*session.Session)) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+	sessionHook = h
+	sessions = map[string]*session.Session{}
+	assumedRoles = map[string]*credentials.Credentials{}
+}
+
+// NewSession returns a session for the config. Sessions are cached and shared among calls with the same config so that
+// e.g. credentials of an assumed role are refreshed only once for all the clients
+func NewSession(c Config) (*session.Session, error) {
+	sessionsMutex.Lock()
+	defer sessionsMutex.Unlock()
+
+	o := c.Options.merge(globalOptions)
+	key := fmt.Sprintf("%s|%v|%s", c.Region.String(), c.Debug, o.key())
+	if s, ok := sessions[key]; ok {
+		return s, nil
+	}
+
+	s, err := newSession(c.Region, c.Debug, o)
+	if err != nil {
+		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+	}
+	sessions[key] = s
+	return s, nil
+}
+
+func newSession(region model.Region, debug bool, o Options) (*session.Session, error) {
+	awsConfig := aws.NewConfig().
+		WithRegion(region.String()).
+		WithCredentialsChainVerboseErrors(true)
+
+	if debug {
+		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
+	}
+
+	maxRetries := o.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	awsConfig = request.WithRetryer(awsConfig, newRetryer(maxRetries))
+
+	caBundle := o.CABundle
+	if caBundle == "" {
+		caBundle = os.Getenv("AWS_CA_BUNDLE")
+	}
+	if caBundle != "" {
+		client, err := httpClientTrusting(caBundle)
+		if err != nil {
+			return nil, err
+		}
+		awsConfig = awsConfig.WithHTTPClient(client)
+	}
+
+	if len(o.Endpoints) > 0 {
+		awsConfig = awsConfig.WithEndpointResolver(endpointResolver(o.Endpoints))
+		if _, ok := o.Endpoints["s3"]; ok {
+			// Custom S3 endpoints e.g. VPC endpoints and emulators usually don't support virtual hosted-style buckets
+			awsConfig = awsConfig.WithS3ForcePathStyle(true)
+		}
+	}
+
+	sessOpts := session.Options{Config: *awsConfig}
+	if o.Profile != "" {
+		sessOpts.Profile = o.Profile
+		// Allows profiles in ~/.aws/config e.g. ones assuming roles via `role_arn` and `source_profile`
+		sessOpts.SharedConfigState = session.SharedConfigEnable
+	}
+
+	s, err := session.NewSessionWithOptions(sessOpts)
+	if err != nil {
+		return nil, err
+	}
+	if sessionHook != nil {
+		sessionHook(s)
+	}
+
+	if o.AssumeRoleARN != "" {
+		roleKey := o.key()
+		creds, ok := assumedRoles[roleKey]
+		if !ok {
+			creds = newAssumeRoleCredentials(sts.New(s), s.Config.Credentials, o)
+			assumedRoles[roleKey] = creds
+		}
+		s = s.Copy(&aws.Config{Credentials: creds})
+	}
+
+	return s, nil
+}
+
+func (o Options) merge(overrides Options) Options {
+	if overrides.Profile != "" {
+		o.Profile = overrides.Profile
+	}
+	if overrides.AssumeRoleARN != "" {
+		// The role is overridden as a whole so that e.g. an external ID for one role is never sent for another
+		o.AssumeRoleARN = overrides.AssumeRoleARN
+		o.ExternalID = overrides.ExternalID
+		o.SessionName = overrides.SessionName
+		o.MFASerial = overrides.MFASerial
+	}
+	if overrides.CredentialsCacheDir != "" {
+		o.CredentialsCacheDir = overrides.CredentialsCacheDir
+	}
+	if overrides.CABundle != "" {
+		o.CABundle = overrides.CABundle
+	}
+	if overrides.MaxRetries > 0 {
+		o.MaxRetries = overrides.MaxRetries
+	}
+	if len(overrides.Endpoints) > 0 {
+		endpoints := map[string]string{}
+		for k, v := range o.Endpoints {
+			endpoints[k] = v
+		}
+		for k, v := range overrides.Endpoints {
+			endpoints[k] = v
+		}
+		o.Endpoints = endpoints
+	}
+	return o
+}
+
+// key returns a string identifying the options, in which endpoints are sorted by service ID
+func (o Options) key() string {
+	services := []string{}
+	for k := range o.Endpoints {
+		services = append(services, k)
+	}
+	sort.Strings(services)
+	endpoints := []string{}
+	for _, s := range services {
+		endpoints = append(endpoints, s+"="+o.Endpoints[s])
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s|%d|%s", o.Profile, o.AssumeRoleARN, o.ExternalID, o.SessionName, o.MFASerial, o.CredentialsCacheDir, o.CABundle, o.MaxRetries, strings.Join(endpoints, ","))
+}
+
+// ParseEndpoints parses endpoint overrides in the form of <service id>=<url>
+func ParseEndpoints(pairs []string) (map[string]string, error) {
+	m := map[string]string{}
+	for _, p := range pairs {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid endpoint override %q: must be in the form of <service id>=<url> e.g. s3=https://s3.example.com", p)
+		}
+		m[kv[0]] = kv[1]
+	}
+	return m, nil
+}
+
+// endpointResolver resolves endpoints of the services from the overrides, and the others with the default resolver
+func endpointResolver(overrides map[string]string) endpoints.Resolver {
+	return endpoints.ResolverFunc(func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		resolved, err := endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+		url, ok := overrides[service]
+		if !ok {
+			return resolved, err
+		}
+		// Signing names and methods of the default endpoint are kept, while the default endpoint may not exist
+		// e.g. for a region unknown to the SDK
+		resolved.URL = url
+		if resolved.SigningRegion == "" {
+			resolved.SigningRegion = region
+		}
+		return resolved, nil
+	})
+}
+
+func httpClientTrusting(caBundle string) (*http.Client, error) {
+	pem, err := ioutil.ReadFile(caBundle)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s: %v", caBundle, err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in CA bundle %s", caBundle)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			TLSClientConfig:       &tls.Config{RootCAs: pool},
+		},
+	}, nil
+}
@@ -0,0 +1,142 @@
+package awsconn
+
+import (
+	"github.com/coreos/kube-aws/model"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseEndpoints(t *testing.T) {
+	endpoints, err := ParseEndpoints([]string{"s3=http://localhost:4572", "cloudformation=http://localhost:4581"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"s3": "http://localhost:4572", "cloudformation": "http://localhost:4581"}
+	if !reflect.DeepEqual(endpoints, expected) {
+		t.Errorf("unexpected endpoints: expected=%v, actual=%v", expected, endpoints)
+	}
+
+	for _, invalid := range []string{"s3", "=http://localhost", "s3="} {
+		if _, err := ParseEndpoints([]string{invalid}); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestEndpointResolver(t *testing.T) {
+	r := endpointResolver(map[string]string{"s3": "http://localhost:4572"})
+
+	s3, err := r.EndpointFor("s3", "us-west-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s3.URL != "http://localhost:4572" || s3.SigningRegion != "us-west-1" {
+		t.Errorf("unexpected endpoint for s3: %+v", s3)
+	}
+
+	ec2, err := r.EndpointFor("ec2", "us-west-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ec2.URL != "https://ec2.us-west-1.amazonaws.com" {
+		t.Errorf("expected the default endpoint for ec2 but got: %+v", ec2)
+	}
+}
+
+func TestOptionsMerge(t *testing.T) {
+	global := Options{Profile: "ci", MaxRetries: 5, Endpoints: map[string]string{"s3": "http://a", "ec2": "http://b"}}
+	merged := global.merge(Options{AssumeRoleARN: "arn:aws:iam::123456789012:role/admin", Endpoints: map[string]string{"s3": "http://c"}})
+
+	expected := Options{
+		Profile:       "ci",
+		AssumeRoleARN: "arn:aws:iam::123456789012:role/admin",
+		MaxRetries:    5,
+		Endpoints:     map[string]string{"s3": "http://c", "ec2": "http://b"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("unexpected options: expected=%+v, actual=%+v", expected, merged)
+	}
+	if global.Endpoints["s3"] != "http://a" {
+		t.Errorf("global options should not be modified: %+v", global)
+	}
+}
+
+func TestOptionsMergeRole(t *testing.T) {
+	cluster := Options{AssumeRoleARN: "arn:aws:iam::123456789012:role/a", ExternalID: "a", MFASerial: "arn:aws:iam::123456789012:mfa/a"}
+
+	merged := cluster.merge(Options{Profile: "ci"})
+	if merged.AssumeRoleARN != cluster.AssumeRoleARN || merged.ExternalID != "a" || merged.Profile != "ci" {
+		t.Errorf("the role should be kept when not overridden: %+v", merged)
+	}
+
+	merged = cluster.merge(Options{AssumeRoleARN: "arn:aws:iam::123456789012:role/b"})
+	if merged.AssumeRoleARN != "arn:aws:iam::123456789012:role/b" || merged.ExternalID != "" || merged.MFASerial != "" {
+		t.Errorf("the role should be overridden as a whole: %+v", merged)
+	}
+}
+
+func TestNewSessionIsShared(t *testing.T) {
+	SetGlobalOptions(Options{})
+	defer SetGlobalOptions(Options{})
+
+	s1, err := NewSession(Config{Region: model.RegionForName("us-west-1")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s2, err := NewSession(Config{Region: model.RegionForName("us-west-1")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s1 != s2 {
+		t.Errorf("expected sessions for the same config to be shared")
+	}
+
+	s3, err := NewSession(Config{Region: model.RegionForName("us-east-1")})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s1 == s3 {
+		t.Errorf("expected sessions for different regions not to be shared")
+	}
+	if *s3.Config.Region != "us-east-1" {
+		t.Errorf("unexpected region: %s", *s3.Config.Region)
+	}
+}
+
+func TestNewSessionWithInvalidCABundle(t *testing.T) {
+	f, err := ioutil.TempFile("", "ca-bundle")
+	if err != nil {
+		t.Fatalf("failed to create a temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not a certificate")
+	f.Close()
+
+	if _, err := NewSession(Config{Region: model.RegionForName("us-west-1"), Options: Options{CABundle: f.Name()}}); err == nil {
+		t.Errorf("expected an error for a CA bundle without certificates")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	cases := []struct {
+		retryCount int
+		throttled  bool
+		min, max   time.Duration
+	}{
+		{0, false, 50 * time.Millisecond, 100 * time.Millisecond},
+		{3, false, 400 * time.Millisecond, 800 * time.Millisecond},
+		{0, true, 500 * time.Millisecond, 1 * time.Second},
+		{20, true, 10 * time.Second, 20 * time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 10; i++ {
+			d := RetryDelay(c.retryCount, c.throttled)
+			if d < c.min || d > c.max {
+				t.Errorf("delay for retry %d (throttled=%v) should be within [%s, %s] but was %s", c.retryCount, c.throttled, c.min, c.max, d)
+			}
+		}
+	}
+}
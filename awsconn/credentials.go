@@ -0,0 +1,166 @@
+package awsconn
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// assumeRoleDuration is long enough for a single kube-aws up or update to finish without prompting for another MFA
+	// token code in most cases
+	assumeRoleDuration = 1 * time.Hour
+	// assumeRoleExpiryWindow makes credentials refreshed before they expire in the middle of a series of API calls
+	assumeRoleExpiryWindow = 5 * time.Minute
+
+	assumeRoleProviderName = "KubeAWSAssumeRoleProvider"
+)
+
+// assumeRoleProvider retrieves temporary credentials of an IAM role via STS AssumeRole, and caches them in a directory
+// so that subsequent kube-aws commands reuse them until they expire instead of assuming the role and prompting for an
+// MFA token code again
+type assumeRoleProvider struct {
+	credentials.Expiry
+
+	client stscreds.AssumeRoler
+	// source is the credentials used to assume the role. Its access key ID tells identities apart even when they aren't
+	// distinguished by options e.g. ones given via environment variables
+	source        *credentials.Credentials
+	options       Options
+	tokenProvider func() (string, error)
+	now           func() time.Time
+}
+
+type cachedCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+func newAssumeRoleCredentials(client stscreds.AssumeRoler, source *credentials.Credentials, o Options) *credentials.Credentials {
+	return credentials.NewCredentials(&assumeRoleProvider{
+		client:        client,
+		source:        source,
+		options:       o,
+		tokenProvider: stscreds.StdinTokenProvider,
+		now:           time.Now,
+	})
+}
+
+func (p *assumeRoleProvider) Retrieve() (credentials.Value, error) {
+	if c, ok := p.readCache(); ok {
+		p.SetExpiration(c.Expiration, assumeRoleExpiryWindow)
+		return p.value(c), nil
+	}
+
+	sessionName := p.options.SessionName
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("kube-aws-%d", p.now().UTC().UnixNano())
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(p.options.AssumeRoleARN),
+		RoleSessionName: aws.String(sessionName),
+		DurationSeconds: aws.Int64(int64(assumeRoleDuration / time.Second)),
+	}
+	if p.options.ExternalID != "" {
+		input.ExternalId = aws.String(p.options.ExternalID)
+	}
+	if p.options.MFASerial != "" {
+		code, err := p.tokenProvider()
+		if err != nil {
+			return credentials.Value{ProviderName: assumeRoleProviderName}, fmt.Errorf("failed to read MFA token code for %s: %v", p.options.MFASerial, err)
+		}
+		input.SerialNumber = aws.String(p.options.MFASerial)
+		input.TokenCode = aws.String(code)
+	}
+
+	resp, err := p.client.AssumeRole(input)
+	if err != nil {
+		return credentials.Value{ProviderName: assumeRoleProviderName}, fmt.Errorf("failed to assume role %s: %v", p.options.AssumeRoleARN, err)
+	}
+
+	c := cachedCredentials{
+		AccessKeyID:     aws.StringValue(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.StringValue(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.StringValue(resp.Credentials.SessionToken),
+		Expiration:      aws.TimeValue(resp.Credentials.Expiration),
+	}
+	if err := p.writeCache(c); err != nil {
+		// Caching is an optimization. The credentials are still usable
+		fmt.Fprintf(os.Stderr, "WARNING: failed to cache credentials of %s: %v\n", p.options.AssumeRoleARN, err)
+	}
+	p.SetExpiration(c.Expiration, assumeRoleExpiryWindow)
+	return p.value(c), nil
+}
+
+func (p *assumeRoleProvider) value(c cachedCredentials) credentials.Value {
+	return credentials.Value{
+		AccessKeyID:     c.AccessKeyID,
+		SecretAccessKey: c.SecretAccessKey,
+		SessionToken:    c.SessionToken,
+		ProviderName:    assumeRoleProviderName,
+	}
+}
+
+// cachePath returns the path to the file caching the credentials, which is unique to the role and the source identity
+func (p *assumeRoleProvider) cachePath() string {
+	if p.options.CredentialsCacheDir == "" {
+		return ""
+	}
+	var sourceKeyID string
+	if p.source != nil {
+		v, err := p.source.Get()
+		if err != nil {
+			// Without the source identity, credentials cached for another identity could be reused
+			return ""
+		}
+		sourceKeyID = v.AccessKeyID
+	}
+	o := p.options
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s|%s|%s|%s", sourceKeyID, o.Profile, o.AssumeRoleARN, o.ExternalID, o.SessionName, o.MFASerial)))
+	return filepath.Join(o.CredentialsCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (p *assumeRoleProvider) readCache() (cachedCredentials, bool) {
+	var c cachedCredentials
+	path := p.cachePath()
+	if path == "" {
+		return c, false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return c, false
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, false
+	}
+	if !c.Expiration.After(p.now().Add(assumeRoleExpiryWindow)) {
+		return c, false
+	}
+	return c, true
+}
+
+func (p *assumeRoleProvider) writeCache(c cachedCredentials) error {
+	path := p.cachePath()
+	if path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,134 @@
+package awsconn
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+type fakeAssumeRoler struct {
+	inputs     []*sts.AssumeRoleInput
+	expiration time.Time
+}
+
+func (f *fakeAssumeRoler) AssumeRole(input *sts.AssumeRoleInput) (*sts.AssumeRoleOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &sts.AssumeRoleOutput{
+		Credentials: &sts.Credentials{
+			AccessKeyId:     aws.String("AKIDASSUMED"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+			Expiration:      aws.Time(f.expiration),
+		},
+	}, nil
+}
+
+func newTestProvider(client *fakeAssumeRoler, o Options, now time.Time) *assumeRoleProvider {
+	return &assumeRoleProvider{
+		client:        client,
+		source:        credentials.NewStaticCredentials("AKIDSOURCE", "secret", ""),
+		options:       o,
+		tokenProvider: func() (string, error) { return "123456", nil },
+		now:           func() time.Time { return now },
+	}
+}
+
+func TestAssumeRoleProvider(t *testing.T) {
+	now := time.Now()
+	client := &fakeAssumeRoler{expiration: now.Add(1 * time.Hour)}
+	p := newTestProvider(client, Options{
+		AssumeRoleARN: "arn:aws:iam::123456789012:role/kube-aws",
+		ExternalID:    "myexternalid",
+		SessionName:   "kube-aws-mycluster",
+		MFASerial:     "arn:aws:iam::123456789012:mfa/ci",
+	}, now)
+
+	v, err := p.Retrieve()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.AccessKeyID != "AKIDASSUMED" || v.SessionToken != "token" {
+		t.Errorf("unexpected credentials: %+v", v)
+	}
+	if p.IsExpired() {
+		t.Errorf("credentials should not be expired right after retrieved")
+	}
+
+	if len(client.inputs) != 1 {
+		t.Fatalf("expected 1 AssumeRole call but was %d", len(client.inputs))
+	}
+	input := client.inputs[0]
+	if aws.StringValue(input.ExternalId) != "myexternalid" ||
+		aws.StringValue(input.RoleSessionName) != "kube-aws-mycluster" ||
+		aws.StringValue(input.SerialNumber) != "arn:aws:iam::123456789012:mfa/ci" ||
+		aws.StringValue(input.TokenCode) != "123456" {
+		t.Errorf("unexpected AssumeRole input: %+v", input)
+	}
+}
+
+func TestAssumeRoleProviderCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-aws-sts-cache")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	o := Options{AssumeRoleARN: "arn:aws:iam::123456789012:role/kube-aws", CredentialsCacheDir: dir}
+
+	client := &fakeAssumeRoler{expiration: now.Add(1 * time.Hour)}
+	if _, err := newTestProvider(client, o, now).Retrieve(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("ReusedByAnotherCommand", func(t *testing.T) {
+		another := &fakeAssumeRoler{expiration: now.Add(1 * time.Hour)}
+		v, err := newTestProvider(another, o, now.Add(30*time.Minute)).Retrieve()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(another.inputs) != 0 {
+			t.Errorf("expected cached credentials to be reused but the role was assumed again")
+		}
+		if v.AccessKeyID != "AKIDASSUMED" {
+			t.Errorf("unexpected credentials: %+v", v)
+		}
+	})
+
+	t.Run("RefreshedWhenExpiring", func(t *testing.T) {
+		another := &fakeAssumeRoler{expiration: now.Add(2 * time.Hour)}
+		if _, err := newTestProvider(another, o, now.Add(57*time.Minute)).Retrieve(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(another.inputs) != 1 {
+			t.Errorf("expected the role to be assumed again for expiring credentials")
+		}
+	})
+
+	t.Run("NotSharedAmongRoles", func(t *testing.T) {
+		another := &fakeAssumeRoler{expiration: now.Add(1 * time.Hour)}
+		other := o
+		other.AssumeRoleARN = "arn:aws:iam::210987654321:role/kube-aws"
+		if _, err := newTestProvider(another, other, now).Retrieve(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(another.inputs) != 1 {
+			t.Errorf("expected credentials of another role not to be reused")
+		}
+	})
+	t.Run("NotSharedAmongSourceIdentities", func(t *testing.T) {
+		another := &fakeAssumeRoler{expiration: now.Add(1 * time.Hour)}
+		p := newTestProvider(another, o, now)
+		p.source = credentials.NewStaticCredentials("AKIDANOTHERSOURCE", "secret", "")
+		if _, err := p.Retrieve(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(another.inputs) != 1 {
+			t.Errorf("expected credentials assumed by another source identity not to be reused")
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package awsconn
+
+import (
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	baseRetryDelay      = 100 * time.Millisecond
+	baseThrottledDelay  = 1 * time.Second
+	maxRetryDelay       = 20 * time.Second
+	maxRetryDelayDouble = 10
+)
+
+var (
+	randMutex sync.Mutex
+	random    = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// retryer retries failed AWS API calls like the SDK's default retryer, with delays growing exponentially with jitter.
+// Throttled calls e.g. ones failed with `Throttling: Rate exceeded` wait longer so that concurrent callers spread out
+type retryer struct {
+	client.DefaultRetryer
+}
+
+func newRetryer(maxRetries int) retryer {
+	return retryer{client.DefaultRetryer{NumMaxRetries: maxRetries}}
+}
+
+func (r retryer) RetryRules(req *request.Request) time.Duration {
+	return RetryDelay(req.RetryCount, isThrottled(req))
+}
+
+func isThrottled(req *request.Request) bool {
+	if req.HTTPResponse != nil {
+		switch req.HTTPResponse.StatusCode {
+		case 429, 502, 503, 504:
+			return true
+		}
+	}
+	return req.IsErrorThrottle()
+}
+
+// RetryDelay returns how long to wait before the retry numbered retryCount, starting from 0.
+// The delay is chosen randomly from the upper half of an exponentially growing range capped at 20 seconds
+func RetryDelay(retryCount int, throttled bool) time.Duration {
+	if retryCount > maxRetryDelayDouble {
+		retryCount = maxRetryDelayDouble
+	}
+	base := baseRetryDelay
+	if throttled {
+		base = baseThrottledDelay
+	}
+	max := base << uint(retryCount)
+	if max > maxRetryDelay {
+		max = maxRetryDelay
+	}
+
+	randMutex.Lock()
+	jitter := time.Duration(random.Int63n(int64(max/2) + 1))
+	randMutex.Unlock()
+
+	return max/2 + jitter
+}
@@ -0,0 +1,654 @@
+package cfnlint
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Limits CloudFormation enforces per template
+const (
+	MaxResources  = 200
+	MaxOutputs    = 60
+	MaxParameters = 60
+	MaxMappings   = 100
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Problem is an issue found in a template. Path locates it in the template e.g. Resources.Controller.Properties.ImageId
+type Problem struct {
+	Template string
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", p.Severity, p.Template, p.Path, p.Message)
+}
+
+// Template is a rendered stack template. Name is the logical ID of the nested stack resource which creates a stack from
+// it in another template, or anything for the root template
+type Template struct {
+	Name string
+	Body []byte
+}
+
+// HasErrors returns true if any of problems is an error rather than a warning
+func HasErrors(problems []Problem) bool {
+	for _, p := range problems {
+		if p.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+var pseudoParameters = []string{
+	"AWS::AccountId",
+	"AWS::NotificationARNs",
+	"AWS::NoValue",
+	"AWS::Partition",
+	"AWS::Region",
+	"AWS::StackId",
+	"AWS::StackName",
+	"AWS::URLSuffix",
+}
+
+var templateKeys = []string{"AWSTemplateFormatVersion", "Conditions", "Description", "Mappings", "Metadata", "Outputs", "Parameters", "Resources", "Transform"}
+
+var resourceKeys = []string{"Condition", "CreationPolicy", "DeletionPolicy", "DependsOn", "Metadata", "Properties", "Type", "UpdatePolicy", "UpdateReplacePolicy"}
+
+var outputKeys = []string{"Condition", "Description", "Export", "Value"}
+
+var subVariable = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+type template struct {
+	name       string
+	parameters map[string]interface{}
+	mappings   map[string]interface{}
+	conditions map[string]interface{}
+	resources  map[string]interface{}
+	outputs    map[string]interface{}
+}
+
+type linter struct {
+	templates map[string]*template
+	// exports are names of exported outputs whose non-literal parts are replaced with wildcards
+	exports []string
+	// anyExport is true when an export name can't be determined statically so that no import can be checked
+	anyExport bool
+	problems  []Problem
+}
+
+// Lint checks templates of a stack and its nested stacks without calling AWS APIs. It resolves every Ref, Fn::GetAtt,
+// Fn::Sub, Fn::FindInMap and Fn::ImportValue, checks property names against the bundled resource specification and
+// enforces limits on the number of resources, outputs, parameters and mappings
+func Lint(templates []Template) []Problem {
+	l := &linter{templates: map[string]*template{}}
+
+	parsed := []*template{}
+	for _, t := range templates {
+		p, err := l.parse(t)
+		if err != nil {
+			l.errorf(t.Name, "", "%v", err)
+			continue
+		}
+		l.templates[t.Name] = p
+		parsed = append(parsed, p)
+	}
+
+	for _, t := range parsed {
+		l.collectExports(t)
+	}
+	for _, t := range parsed {
+		l.lint(t)
+	}
+
+	sort.Stable(byLocation(l.problems))
+	return l.problems
+}
+
+func (l *linter) parse(t Template) (*template, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(t.Body, &body); err != nil {
+		return nil, fmt.Errorf("template is not a valid JSON object: %v", err)
+	}
+
+	p := &template{name: t.Name}
+	sections := map[string]*map[string]interface{}{
+		"Parameters": &p.parameters,
+		"Mappings":   &p.mappings,
+		"Conditions": &p.conditions,
+		"Resources":  &p.resources,
+		"Outputs":    &p.outputs,
+	}
+	for _, key := range sortedKeys(body) {
+		if !contains(templateKeys, key) {
+			l.errorf(t.Name, key, "unknown template section")
+			continue
+		}
+		dst, ok := sections[key]
+		if !ok {
+			continue
+		}
+		m, ok := body[key].(map[string]interface{})
+		if !ok {
+			l.errorf(t.Name, key, "must be an object")
+			continue
+		}
+		*dst = m
+	}
+	return p, nil
+}
+
+func (l *linter) collectExports(t *template) {
+	for _, name := range sortedKeys(t.outputs) {
+		output, _ := t.outputs[name].(map[string]interface{})
+		export, ok := output["Export"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pattern, ok := namePattern(export["Name"])
+		if !ok {
+			l.anyExport = true
+			continue
+		}
+		l.exports = append(l.exports, pattern)
+	}
+}
+
+func (l *linter) lint(t *template) {
+	if len(t.resources) == 0 {
+		l.errorf(t.name, "Resources", "at least one resource is required")
+	}
+	if n := len(t.resources); n > MaxResources {
+		l.errorf(t.name, "Resources", "%d resources exceed the limit of %d", n, MaxResources)
+	}
+	if n := len(t.outputs); n > MaxOutputs {
+		l.errorf(t.name, "Outputs", "%d outputs exceed the limit of %d", n, MaxOutputs)
+	}
+	if n := len(t.parameters); n > MaxParameters {
+		l.errorf(t.name, "Parameters", "%d parameters exceed the limit of %d", n, MaxParameters)
+	}
+	if n := len(t.mappings); n > MaxMappings {
+		l.errorf(t.name, "Mappings", "%d mappings exceed the limit of %d", n, MaxMappings)
+	}
+
+	for _, name := range sortedKeys(t.parameters) {
+		path := "Parameters." + name
+		param, ok := t.parameters[name].(map[string]interface{})
+		if !ok {
+			l.errorf(t.name, path, "must be an object")
+			continue
+		}
+		if _, ok := param["Type"].(string); !ok {
+			l.errorf(t.name, path, "missing Type")
+		}
+	}
+
+	for _, name := range sortedKeys(t.conditions) {
+		l.walk(t, "Conditions."+name, t.conditions[name], true)
+	}
+
+	for _, name := range sortedKeys(t.resources) {
+		l.lintResource(t, name)
+	}
+
+	for _, name := range sortedKeys(t.outputs) {
+		path := "Outputs." + name
+		output, ok := t.outputs[name].(map[string]interface{})
+		if !ok {
+			l.errorf(t.name, path, "must be an object")
+			continue
+		}
+		for _, key := range sortedKeys(output) {
+			if !contains(outputKeys, key) {
+				l.errorf(t.name, path+"."+key, "unknown output attribute")
+			}
+		}
+		if _, ok := output["Value"]; !ok {
+			l.errorf(t.name, path, "missing Value")
+		}
+		l.checkConditionName(t, path+".Condition", output["Condition"])
+		l.walk(t, path+".Value", output["Value"], false)
+		if export, ok := output["Export"].(map[string]interface{}); ok {
+			l.walk(t, path+".Export.Name", export["Name"], false)
+		}
+	}
+}
+
+func (l *linter) lintResource(t *template, name string) {
+	path := "Resources." + name
+	res, ok := t.resources[name].(map[string]interface{})
+	if !ok {
+		l.errorf(t.name, path, "must be an object")
+		return
+	}
+	for _, key := range sortedKeys(res) {
+		if !contains(resourceKeys, key) {
+			l.errorf(t.name, path+"."+key, "unknown resource attribute")
+		}
+	}
+
+	typ, ok := res["Type"].(string)
+	if !ok {
+		l.errorf(t.name, path, "missing Type")
+	}
+
+	l.checkConditionName(t, path+".Condition", res["Condition"])
+
+	switch deps := res["DependsOn"].(type) {
+	case nil:
+	case string:
+		l.checkDependsOn(t, path+".DependsOn", name, deps)
+	case []interface{}:
+		for i, d := range deps {
+			s, ok := d.(string)
+			if !ok {
+				l.errorf(t.name, fmt.Sprintf("%s.DependsOn[%d]", path, i), "must be a string")
+				continue
+			}
+			l.checkDependsOn(t, fmt.Sprintf("%s.DependsOn[%d]", path, i), name, s)
+		}
+	default:
+		l.errorf(t.name, path+".DependsOn", "must be a string or a list of strings")
+	}
+
+	props, _ := res["Properties"].(map[string]interface{})
+	if _, exists := res["Properties"]; exists && props == nil {
+		l.errorf(t.name, path+".Properties", "must be an object")
+	}
+
+	spec, known := resourceSpecs[typ]
+	switch {
+	case !ok:
+	case strings.HasPrefix(typ, "Custom::") || typ == "AWS::CloudFormation::CustomResource":
+	case !known:
+		l.warnf(t.name, path+".Type", "%s is not in the bundled resource specification so that its properties are not checked", typ)
+	default:
+		// The bundled specification may lack properties recently added to the resource type, which CloudFormation
+		// accepts. Only a warning is given so that they don't fail validation
+		for _, p := range sortedKeys(props) {
+			if !contains(spec.Properties, p) {
+				l.warnf(t.name, path+".Properties."+p, "%s has no property named %s in the bundled resource specification", typ, p)
+			}
+		}
+	}
+
+	if typ == "AWS::CloudFormation::Stack" {
+		l.checkNestedStack(t, path, name, props)
+	}
+
+	for _, p := range sortedKeys(props) {
+		l.walk(t, path+".Properties."+p, props[p], false)
+	}
+	for _, key := range []string{"Metadata", "CreationPolicy", "UpdatePolicy"} {
+		if v, ok := res[key]; ok {
+			l.walk(t, path+"."+key, v, false)
+		}
+	}
+}
+
+func (l *linter) checkDependsOn(t *template, path string, self string, target string) {
+	if target == self {
+		l.errorf(t.name, path, "resource depends on itself")
+		return
+	}
+	if _, ok := t.resources[target]; !ok {
+		l.errorf(t.name, path, "depends on the undefined resource %s", target)
+	}
+}
+
+// checkNestedStack checks parameters passed to the nested stack created from the template named after its logical ID
+func (l *linter) checkNestedStack(t *template, path string, name string, props map[string]interface{}) {
+	if _, ok := props["TemplateURL"]; !ok {
+		l.errorf(t.name, path+".Properties", "missing TemplateURL")
+	}
+	nested, ok := l.templates[name]
+	if !ok {
+		return
+	}
+	params, _ := props["Parameters"].(map[string]interface{})
+	for _, p := range sortedKeys(params) {
+		if _, ok := nested.parameters[p]; !ok {
+			l.errorf(t.name, path+".Properties.Parameters."+p, "the nested stack template %s has no parameter named %s", name, p)
+		}
+	}
+	for _, p := range sortedKeys(nested.parameters) {
+		param, _ := nested.parameters[p].(map[string]interface{})
+		if _, hasDefault := param["Default"]; hasDefault {
+			continue
+		}
+		if _, ok := params[p]; !ok {
+			l.errorf(t.name, path+".Properties.Parameters", "the parameter %s required by the nested stack template %s is missing", p, name)
+		}
+	}
+}
+
+func (l *linter) checkConditionName(t *template, path string, v interface{}) {
+	if v == nil {
+		return
+	}
+	name, ok := v.(string)
+	if !ok {
+		l.errorf(t.name, path, "must be a string")
+		return
+	}
+	if _, ok := t.conditions[name]; !ok {
+		l.errorf(t.name, path, "refers to the undefined condition %s", name)
+	}
+}
+
+// walk resolves intrinsic functions found in v. Within conditions, Ref may only refer to parameters
+func (l *linter) walk(t *template, path string, v interface{}, inCondition bool) {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, e := range v {
+			l.walk(t, fmt.Sprintf("%s[%d]", path, i), e, inCondition)
+		}
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for fn, arg := range v {
+				if fn == "Ref" || fn == "Condition" || strings.HasPrefix(fn, "Fn::") {
+					l.function(t, path+"."+fn, fn, arg, inCondition)
+					return
+				}
+			}
+		}
+		for _, k := range sortedKeys(v) {
+			l.walk(t, path+"."+k, v[k], inCondition)
+		}
+	}
+}
+
+func (l *linter) function(t *template, path string, fn string, arg interface{}, inCondition bool) {
+	switch fn {
+	case "Ref":
+		name, ok := arg.(string)
+		if !ok {
+			l.errorf(t.name, path, "must be a string")
+			return
+		}
+		l.checkRef(t, path, name, inCondition)
+	case "Condition":
+		l.checkConditionName(t, path, arg)
+	case "Fn::GetAtt":
+		switch a := arg.(type) {
+		case string:
+			parts := strings.SplitN(a, ".", 2)
+			if len(parts) != 2 {
+				l.errorf(t.name, path, "must be in the form of LogicalID.Attribute")
+				return
+			}
+			l.checkGetAtt(t, path, parts[0], parts[1], inCondition)
+		case []interface{}:
+			if len(a) != 2 {
+				l.errorf(t.name, path, "must be a list of a logical ID and an attribute name")
+				return
+			}
+			res, ok := a[0].(string)
+			if !ok {
+				l.errorf(t.name, path+"[0]", "must be a string")
+				return
+			}
+			if attr, ok := a[1].(string); ok {
+				l.checkGetAtt(t, path, res, attr, inCondition)
+			} else {
+				l.walk(t, path+"[1]", a[1], inCondition)
+			}
+		default:
+			l.errorf(t.name, path, "must be a string or a list")
+		}
+	case "Fn::Sub":
+		var str interface{}
+		vars := map[string]interface{}{}
+		switch a := arg.(type) {
+		case string:
+			str = a
+		case []interface{}:
+			if len(a) != 2 {
+				l.errorf(t.name, path, "must be a list of a string and a map of variables")
+				return
+			}
+			str = a[0]
+			m, ok := a[1].(map[string]interface{})
+			if !ok {
+				l.errorf(t.name, path+"[1]", "must be an object")
+				return
+			}
+			vars = m
+			for _, k := range sortedKeys(vars) {
+				l.walk(t, path+"[1]."+k, vars[k], inCondition)
+			}
+		default:
+			l.errorf(t.name, path, "must be a string or a list")
+			return
+		}
+		s, ok := str.(string)
+		if !ok {
+			l.errorf(t.name, path, "the template of Fn::Sub must be a string")
+			return
+		}
+		for _, m := range subVariable.FindAllStringSubmatch(s, -1) {
+			name := m[1]
+			if strings.HasPrefix(name, "!") {
+				continue
+			}
+			if _, ok := vars[name]; ok {
+				continue
+			}
+			if i := strings.Index(name, "."); i > 0 && !strings.HasPrefix(name, "AWS::") {
+				l.checkGetAtt(t, path, name[:i], name[i+1:], inCondition)
+				continue
+			}
+			l.checkRef(t, path, name, inCondition)
+		}
+	case "Fn::FindInMap":
+		a, ok := arg.([]interface{})
+		if !ok || len(a) != 3 {
+			l.errorf(t.name, path, "must be a list of a map name, a top-level key and a second-level key")
+			return
+		}
+		l.walk(t, path, arg, inCondition)
+		name, ok := a[0].(string)
+		if !ok {
+			return
+		}
+		mapping, ok := t.mappings[name].(map[string]interface{})
+		if !ok {
+			l.errorf(t.name, path, "refers to the undefined mapping %s", name)
+			return
+		}
+		key1, ok := a[1].(string)
+		if !ok {
+			return
+		}
+		second, ok := mapping[key1].(map[string]interface{})
+		if !ok {
+			l.errorf(t.name, path, "the mapping %s has no key %s", name, key1)
+			return
+		}
+		if key2, ok := a[2].(string); ok {
+			if _, ok := second[key2]; !ok {
+				l.errorf(t.name, path, "the mapping %s has no key %s.%s", name, key1, key2)
+			}
+		}
+	case "Fn::If":
+		a, ok := arg.([]interface{})
+		if !ok || len(a) != 3 {
+			l.errorf(t.name, path, "must be a list of a condition name and two values")
+			return
+		}
+		l.checkConditionName(t, path+"[0]", a[0])
+		l.walk(t, path+"[1]", a[1], inCondition)
+		l.walk(t, path+"[2]", a[2], inCondition)
+	case "Fn::ImportValue":
+		l.walk(t, path, arg, inCondition)
+		pattern, ok := namePattern(arg)
+		if !ok || l.anyExport {
+			return
+		}
+		for _, e := range l.exports {
+			if patternsOverlap(pattern, e) {
+				return
+			}
+		}
+		// Literal names may be exported by stacks managed outside of kube-aws
+		if !strings.Contains(pattern, "*") {
+			l.warnf(t.name, path, "no template exports a value named %s. It must be exported by another stack", pattern)
+			return
+		}
+		l.errorf(t.name, path, "no template exports a value named %s", pattern)
+	case "Fn::Base64", "Fn::Cidr", "Fn::GetAZs", "Fn::Join", "Fn::Select", "Fn::Split", "Fn::Equals", "Fn::And", "Fn::Or", "Fn::Not", "Fn::Transform":
+		l.walk(t, path, arg, inCondition)
+	default:
+		l.errorf(t.name, path, "unknown intrinsic function %s", fn)
+	}
+}
+
+func (l *linter) checkRef(t *template, path string, name string, inCondition bool) {
+	if contains(pseudoParameters, name) {
+		return
+	}
+	if _, ok := t.parameters[name]; ok {
+		return
+	}
+	if _, ok := t.resources[name]; ok {
+		if inCondition {
+			l.errorf(t.name, path, "conditions can't refer to the resource %s", name)
+		}
+		return
+	}
+	l.errorf(t.name, path, "refers to the undefined parameter or resource %s", name)
+}
+
+func (l *linter) checkGetAtt(t *template, path string, name string, attr string, inCondition bool) {
+	if inCondition {
+		l.errorf(t.name, path, "conditions can't use attributes of the resource %s", name)
+		return
+	}
+	res, ok := t.resources[name].(map[string]interface{})
+	if !ok {
+		l.errorf(t.name, path, "refers to the undefined resource %s", name)
+		return
+	}
+	typ, _ := res["Type"].(string)
+	if typ == "AWS::CloudFormation::Stack" {
+		if !strings.HasPrefix(attr, "Outputs.") {
+			l.errorf(t.name, path, "attributes of the nested stack %s must be in the form of Outputs.NestedStackOutputName", name)
+			return
+		}
+		if nested, ok := l.templates[name]; ok {
+			output := strings.TrimPrefix(attr, "Outputs.")
+			if _, ok := nested.outputs[output]; !ok {
+				l.errorf(t.name, path, "the nested stack template %s has no output named %s", name, output)
+			}
+		}
+		return
+	}
+	spec, ok := resourceSpecs[typ]
+	if !ok {
+		return
+	}
+	if !contains(spec.Attributes, attr) {
+		l.errorf(t.name, path, "%s has no attribute named %s", typ, attr)
+	}
+}
+
+func (l *linter) errorf(template string, path string, format string, a ...interface{}) {
+	l.problems = append(l.problems, Problem{Template: template, Path: path, Severity: SeverityError, Message: fmt.Sprintf(format, a...)})
+}
+
+func (l *linter) warnf(template string, path string, format string, a ...interface{}) {
+	l.problems = append(l.problems, Problem{Template: template, Path: path, Severity: SeverityWarning, Message: fmt.Sprintf(format, a...)})
+}
+
+// namePattern returns an export or import name with every part unknown until stack creation replaced with "*"
+func namePattern(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case map[string]interface{}:
+		if len(v) != 1 {
+			return "", false
+		}
+		if s, ok := v["Fn::Sub"].(string); ok {
+			return subVariable.ReplaceAllStringFunc(s, func(m string) string {
+				if strings.HasPrefix(m, "${!") {
+					return "${" + m[3:]
+				}
+				return "*"
+			}), true
+		}
+		if a, ok := v["Fn::Join"].([]interface{}); ok && len(a) == 2 {
+			sep, ok1 := a[0].(string)
+			parts, ok2 := a[1].([]interface{})
+			if !ok1 || !ok2 {
+				return "", false
+			}
+			ss := []string{}
+			for _, p := range parts {
+				s, ok := namePattern(p)
+				if !ok {
+					s = "*"
+				}
+				ss = append(ss, s)
+			}
+			return strings.Join(ss, sep), true
+		}
+		return "*", true
+	}
+	return "", false
+}
+
+// patternsOverlap returns true if some name can match both patterns, where "*" in a pattern matches any string
+func patternsOverlap(a string, b string) bool {
+	if a == "" || b == "" {
+		return strings.Trim(a, "*") == "" && strings.Trim(b, "*") == ""
+	}
+	if a[0] == '*' {
+		return patternsOverlap(a[1:], b) || patternsOverlap(a, b[1:])
+	}
+	if b[0] == '*' {
+		return patternsOverlap(a, b[1:]) || patternsOverlap(a[1:], b)
+	}
+	return a[0] == b[0] && patternsOverlap(a[1:], b[1:])
+}
+
+type byLocation []Problem
+
+func (p byLocation) Len() int      { return len(p) }
+func (p byLocation) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byLocation) Less(i, j int) bool {
+	if p[i].Template != p[j].Template {
+		return p[i].Template < p[j].Template
+	}
+	return p[i].Path < p[j].Path
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package cfnlint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const rootTemplate = `{
+  "Resources": {
+    "Controlplane": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "TemplateURL": "https://s3.amazonaws.com/examplebucket/controlplane.json",
+        "Parameters": {"KeyName": "mykey"}
+      }
+    },
+    "Pool1": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "TemplateURL": "https://s3.amazonaws.com/examplebucket/pool1.json",
+        "Parameters": {"ControlPlaneStackName": {"Fn::GetAtt": ["Controlplane", "Outputs.StackName"]}}
+      }
+    }
+  }
+}`
+
+const controlPlaneTemplate = `{
+  "Parameters": {
+    "KeyName": {"Type": "String"},
+    "Env": {"Type": "String", "Default": "dev"}
+  },
+  "Conditions": {
+    "IsProd": {"Fn::Equals": [{"Ref": "Env"}, "prod"]}
+  },
+  "Mappings": {
+    "Sizes": {"dev": {"Instance": "t2.medium"}}
+  },
+  "Resources": {
+    "VPC": {
+      "Type": "AWS::EC2::VPC",
+      "Properties": {"CidrBlock": "10.0.0.0/16"}
+    },
+    "SecurityGroup": {
+      "Type": "AWS::EC2::SecurityGroup",
+      "Properties": {
+        "GroupDescription": {"Fn::Sub": "${AWS::StackName} in ${VPC.CidrBlock} ${!Literal}"},
+        "VpcId": {"Ref": "VPC"}
+      }
+    },
+    "Controllers": {
+      "Type": "AWS::AutoScaling::LaunchConfiguration",
+      "DependsOn": ["SecurityGroup"],
+      "Properties": {
+        "ImageId": "ami-12345678",
+        "KeyName": {"Ref": "KeyName"},
+        "InstanceType": {"Fn::If": ["IsProd", "m4.large", {"Fn::FindInMap": ["Sizes", "dev", "Instance"]}]},
+        "SecurityGroups": [{"Fn::GetAtt": "SecurityGroup.GroupId"}]
+      }
+    }
+  },
+  "Outputs": {
+    "StackName": {"Value": {"Ref": "AWS::StackName"}},
+    "VPC": {
+      "Value": {"Ref": "VPC"},
+      "Export": {"Name": {"Fn::Sub": "${AWS::StackName}-VPC"}}
+    }
+  }
+}`
+
+const nodePoolTemplate = `{
+  "Parameters": {
+    "ControlPlaneStackName": {"Type": "String"}
+  },
+  "Resources": {
+    "SecurityGroup": {
+      "Type": "AWS::EC2::SecurityGroup",
+      "Properties": {
+        "GroupDescription": "workers",
+        "VpcId": {"Fn::ImportValue": {"Fn::Sub": "${ControlPlaneStackName}-VPC"}}
+      }
+    }
+  }
+}`
+
+func lintWith(name string, body string) []Problem {
+	templates := map[string]string{
+		"root":         rootTemplate,
+		"Controlplane": controlPlaneTemplate,
+		"Pool1":        nodePoolTemplate,
+	}
+	if name != "" {
+		templates[name] = body
+	}
+	return Lint([]Template{
+		{Name: "root", Body: []byte(templates["root"])},
+		{Name: "Controlplane", Body: []byte(templates["Controlplane"])},
+		{Name: "Pool1", Body: []byte(templates["Pool1"])},
+	})
+}
+
+func expectProblem(t *testing.T, problems []Problem, severity Severity, path string, message string) {
+	for _, p := range problems {
+		if p.Severity == severity && p.Path == path && strings.Contains(p.Message, message) {
+			return
+		}
+	}
+	t.Errorf("expected %s at %s containing %q, got %v", severity, path, message, problems)
+}
+
+func TestLint(t *testing.T) {
+	t.Run("Valid", func(t *testing.T) {
+		if problems := lintWith("", ""); len(problems) != 0 {
+			t.Errorf("expected no problems, got %v", problems)
+		}
+	})
+
+	t.Run("InvalidJSON", func(t *testing.T) {
+		problems := lintWith("Pool1", `{"Resources":`)
+		expectProblem(t, problems, SeverityError, "", "not a valid JSON object")
+	})
+
+	t.Run("BrokenRef", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `{"Ref": "KeyName"}`, `{"Ref": "KeyNam"}`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controllers.Properties.KeyName.Ref", "undefined parameter or resource KeyNam")
+	})
+
+	t.Run("RefToResourceInCondition", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `{"Ref": "Env"}`, `{"Ref": "VPC"}`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Conditions.IsProd.Fn::Equals[0].Ref", "can't refer to the resource VPC")
+	})
+
+	t.Run("MisspelledGetAttAttribute", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `SecurityGroup.GroupId`, `SecurityGroup.GroupID`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controllers.Properties.SecurityGroups[0].Fn::GetAtt", "AWS::EC2::SecurityGroup has no attribute named GroupID")
+	})
+
+	t.Run("MissingNestedStackOutput", func(t *testing.T) {
+		body := strings.Replace(rootTemplate, `Outputs.StackName`, `Outputs.Name`, 1)
+		problems := lintWith("root", body)
+		expectProblem(t, problems, SeverityError, "Resources.Pool1.Properties.Parameters.ControlPlaneStackName.Fn::GetAtt", "has no output named Name")
+	})
+
+	t.Run("BrokenSubVariable", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `${VPC.CidrBlock}`, `${Vpc.CidrBlock}`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.SecurityGroup.Properties.GroupDescription.Fn::Sub", "undefined resource Vpc")
+	})
+
+	t.Run("MissingDependsOnTarget", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `["SecurityGroup"]`, `["SecurityGroups"]`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controllers.DependsOn[0]", "undefined resource SecurityGroups")
+	})
+
+	t.Run("UndefinedCondition", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `["IsProd",`, `["IsProduction",`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controllers.Properties.InstanceType.Fn::If[0]", "undefined condition IsProduction")
+	})
+
+	t.Run("MissingMappingKey", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `"dev", "Instance"`, `"prod", "Instance"`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controllers.Properties.InstanceType.Fn::If[2].Fn::FindInMap", "mapping Sizes has no key prod")
+	})
+
+	t.Run("UnknownProperty", func(t *testing.T) {
+		body := strings.Replace(controlPlaneTemplate, `"CidrBlock": "10.0.0.0/16"`, `"CIDRBlock": "10.0.0.0/16"`, 1)
+		problems := lintWith("Controlplane", body)
+		expectProblem(t, problems, SeverityWarning, "Resources.VPC.Properties.CIDRBlock", "AWS::EC2::VPC has no property named CIDRBlock")
+		if HasErrors(problems) {
+			t.Errorf("expected only warnings, got %v", problems)
+		}
+	})
+
+	t.Run("UnknownResourceType", func(t *testing.T) {
+		body := strings.Replace(nodePoolTemplate, `AWS::EC2::SecurityGroup`, `AWS::Foo::Bar`, 1)
+		problems := lintWith("Pool1", body)
+		expectProblem(t, problems, SeverityWarning, "Resources.SecurityGroup.Type", "not in the bundled resource specification")
+		if HasErrors(problems) {
+			t.Errorf("expected only warnings, got %v", problems)
+		}
+	})
+
+	t.Run("UnresolvedImport", func(t *testing.T) {
+		body := strings.Replace(nodePoolTemplate, `-VPC"`, `-Vpc"`, 1)
+		problems := lintWith("Pool1", body)
+		expectProblem(t, problems, SeverityError, "Resources.SecurityGroup.Properties.VpcId.Fn::ImportValue", "no template exports a value named *-Vpc")
+	})
+
+	t.Run("ImportFromOutsideOfCluster", func(t *testing.T) {
+		body := strings.Replace(nodePoolTemplate, `{"Fn::Sub": "${ControlPlaneStackName}-VPC"}`, `"shared-vpc"`, 1)
+		problems := lintWith("Pool1", body)
+		expectProblem(t, problems, SeverityWarning, "Resources.SecurityGroup.Properties.VpcId.Fn::ImportValue", "exported by another stack")
+	})
+
+	t.Run("NestedStackParameters", func(t *testing.T) {
+		body := strings.Replace(rootTemplate, `{"KeyName": "mykey"}`, `{"Keyname": "mykey"}`, 1)
+		problems := lintWith("root", body)
+		expectProblem(t, problems, SeverityError, "Resources.Controlplane.Properties.Parameters.Keyname", "has no parameter named Keyname")
+		expectProblem(t, problems, SeverityError, "Resources.Controlplane.Properties.Parameters", "parameter KeyName required by the nested stack template Controlplane is missing")
+	})
+
+	t.Run("TooManyResourcesAndOutputs", func(t *testing.T) {
+		resources := []string{}
+		outputs := []string{}
+		for i := 0; i <= MaxResources; i++ {
+			resources = append(resources, fmt.Sprintf(`"Handle%d": {"Type": "AWS::CloudFormation::WaitConditionHandle"}`, i))
+		}
+		for i := 0; i <= MaxOutputs; i++ {
+			outputs = append(outputs, fmt.Sprintf(`"Handle%d": {"Value": {"Ref": "Handle%d"}}`, i, i))
+		}
+		body := fmt.Sprintf(`{"Resources": {%s}, "Outputs": {%s}}`, strings.Join(resources, ","), strings.Join(outputs, ","))
+		problems := Lint([]Template{{Name: "root", Body: []byte(body)}})
+		expectProblem(t, problems, SeverityError, "Resources", "201 resources exceed the limit of 200")
+		expectProblem(t, problems, SeverityError, "Outputs", "61 outputs exceed the limit of 60")
+		if len(problems) != 2 {
+			t.Errorf("expected only the limits to be exceeded, got %v", problems)
+		}
+	})
+}
+
+func TestPatternsOverlap(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"mycluster-VPC", "mycluster-VPC", true},
+		{"*-VPC", "*-VPC", true},
+		{"*-VPC", "mycluster-VPC", true},
+		{"*-VPC", "*-Subnet0", false},
+		{"*-PublicSubnet*", "*-Public*", true},
+		{"*", "anything", true},
+		{"a", "", false},
+	}
+	for _, c := range cases {
+		if actual := patternsOverlap(c.a, c.b); actual != c.expected {
+			t.Errorf("expected patternsOverlap(%q, %q) to be %v, got %v", c.a, c.b, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package cfnlint
+
+// resourceSpec lists the properties and the attributes available via Fn::GetAtt of a resource type, taken from the
+// CloudFormation resource specification
+type resourceSpec struct {
+	Properties []string
+	Attributes []string
+}
+
+// resourceSpecs is the bundled subset of the CloudFormation resource specification covering resource types kube-aws
+// templates use or are commonly added to them. Properties of other types are not checked
+var resourceSpecs = map[string]resourceSpec{
+	"AWS::AutoScaling::AutoScalingGroup": {
+		Properties: []string{"AutoScalingGroupName", "AvailabilityZones", "Cooldown", "DesiredCapacity", "HealthCheckGracePeriod", "HealthCheckType", "InstanceId", "LaunchConfigurationName", "LaunchTemplate", "LifecycleHookSpecificationList", "LoadBalancerNames", "MaxSize", "MetricsCollection", "MinSize", "NotificationConfigurations", "PlacementGroup", "ServiceLinkedRoleARN", "Tags", "TargetGroupARNs", "TerminationPolicies", "VPCZoneIdentifier"},
+	},
+	"AWS::AutoScaling::LaunchConfiguration": {
+		Properties: []string{"AssociatePublicIpAddress", "BlockDeviceMappings", "ClassicLinkVPCId", "ClassicLinkVPCSecurityGroups", "EbsOptimized", "IamInstanceProfile", "ImageId", "InstanceId", "InstanceMonitoring", "InstanceType", "KernelId", "KeyName", "LaunchConfigurationName", "PlacementTenancy", "RamDiskId", "SecurityGroups", "SpotPrice", "UserData"},
+	},
+	"AWS::AutoScaling::LifecycleHook": {
+		Properties: []string{"AutoScalingGroupName", "DefaultResult", "HeartbeatTimeout", "LifecycleHookName", "LifecycleTransition", "NotificationMetadata", "NotificationTargetARN", "RoleARN"},
+	},
+	"AWS::AutoScaling::ScalingPolicy": {
+		Properties: []string{"AdjustmentType", "AutoScalingGroupName", "Cooldown", "EstimatedInstanceWarmup", "MetricAggregationType", "MinAdjustmentMagnitude", "PolicyType", "ScalingAdjustment", "StepAdjustments", "TargetTrackingConfiguration"},
+	},
+	"AWS::CloudFormation::Stack": {
+		Properties: []string{"NotificationARNs", "Parameters", "Tags", "TemplateURL", "TimeoutInMinutes"},
+	},
+	"AWS::CloudFormation::WaitCondition": {
+		Properties: []string{"Count", "Handle", "Timeout"},
+		Attributes: []string{"Data"},
+	},
+	"AWS::CloudFormation::WaitConditionHandle": {},
+	"AWS::CloudWatch::Alarm": {
+		Properties: []string{"ActionsEnabled", "AlarmActions", "AlarmDescription", "AlarmName", "ComparisonOperator", "Dimensions", "EvaluateLowSampleCountPercentile", "EvaluationPeriods", "ExtendedStatistic", "InsufficientDataActions", "MetricName", "Namespace", "OKActions", "Period", "Statistic", "Threshold", "TreatMissingData", "Unit"},
+		Attributes: []string{"Arn"},
+	},
+	"AWS::EC2::EIP": {
+		Properties: []string{"Domain", "InstanceId"},
+		Attributes: []string{"AllocationId"},
+	},
+	"AWS::EC2::EIPAssociation": {
+		Properties: []string{"AllocationId", "EIP", "InstanceId", "NetworkInterfaceId", "PrivateIpAddress"},
+	},
+	"AWS::EC2::Instance": {
+		Properties: []string{"AdditionalInfo", "Affinity", "AvailabilityZone", "BlockDeviceMappings", "CreditSpecification", "DisableApiTermination", "EbsOptimized", "ElasticGpuSpecifications", "HostId", "IamInstanceProfile", "ImageId", "InstanceInitiatedShutdownBehavior", "InstanceType", "Ipv6AddressCount", "Ipv6Addresses", "KernelId", "KeyName", "Monitoring", "NetworkInterfaces", "PlacementGroupName", "PrivateIpAddress", "RamdiskId", "SecurityGroupIds", "SecurityGroups", "SourceDestCheck", "SsmAssociations", "SubnetId", "Tags", "Tenancy", "UserData", "Volumes"},
+		Attributes: []string{"AvailabilityZone", "PrivateDnsName", "PrivateIp", "PublicDnsName", "PublicIp"},
+	},
+	"AWS::EC2::InternetGateway": {
+		Properties: []string{"Tags"},
+	},
+	"AWS::EC2::NatGateway": {
+		Properties: []string{"AllocationId", "SubnetId", "Tags"},
+	},
+	"AWS::EC2::NetworkInterface": {
+		Properties: []string{"Description", "GroupSet", "Ipv6AddressCount", "Ipv6Addresses", "PrivateIpAddress", "PrivateIpAddresses", "SecondaryPrivateIpAddressCount", "SourceDestCheck", "SubnetId", "Tags"},
+		Attributes: []string{"PrimaryPrivateIpAddress", "SecondaryPrivateIpAddresses"},
+	},
+	"AWS::EC2::NetworkInterfaceAttachment": {
+		Properties: []string{"DeleteOnTermination", "DeviceIndex", "InstanceId", "NetworkInterfaceId"},
+	},
+	"AWS::EC2::Route": {
+		Properties: []string{"DestinationCidrBlock", "DestinationIpv6CidrBlock", "EgressOnlyInternetGatewayId", "GatewayId", "InstanceId", "NatGatewayId", "NetworkInterfaceId", "RouteTableId", "VpcPeeringConnectionId"},
+	},
+	"AWS::EC2::RouteTable": {
+		Properties: []string{"Tags", "VpcId"},
+	},
+	"AWS::EC2::SecurityGroup": {
+		Properties: []string{"GroupDescription", "GroupName", "SecurityGroupEgress", "SecurityGroupIngress", "Tags", "VpcId"},
+		Attributes: []string{"GroupId", "VpcId"},
+	},
+	"AWS::EC2::SecurityGroupEgress": {
+		Properties: []string{"CidrIp", "CidrIpv6", "Description", "DestinationPrefixListId", "DestinationSecurityGroupId", "FromPort", "GroupId", "IpProtocol", "ToPort"},
+	},
+	"AWS::EC2::SecurityGroupIngress": {
+		Properties: []string{"CidrIp", "CidrIpv6", "Description", "FromPort", "GroupId", "GroupName", "IpProtocol", "SourcePrefixListId", "SourceSecurityGroupId", "SourceSecurityGroupName", "SourceSecurityGroupOwnerId", "ToPort"},
+	},
+	"AWS::EC2::SpotFleet": {
+		Properties: []string{"SpotFleetRequestConfigData"},
+	},
+	"AWS::EC2::Subnet": {
+		Properties: []string{"AssignIpv6AddressOnCreation", "AvailabilityZone", "CidrBlock", "Ipv6CidrBlock", "MapPublicIpOnLaunch", "Tags", "VpcId"},
+		Attributes: []string{"AvailabilityZone", "Ipv6CidrBlocks", "NetworkAclAssociationId", "VpcId"},
+	},
+	"AWS::EC2::SubnetRouteTableAssociation": {
+		Properties: []string{"RouteTableId", "SubnetId"},
+	},
+	"AWS::EC2::VPC": {
+		Properties: []string{"CidrBlock", "EnableDnsHostnames", "EnableDnsSupport", "InstanceTenancy", "Tags"},
+		Attributes: []string{"CidrBlock", "CidrBlockAssociations", "DefaultNetworkAcl", "DefaultSecurityGroup", "Ipv6CidrBlocks"},
+	},
+	"AWS::EC2::VPCEndpoint": {
+		Properties: []string{"PolicyDocument", "PrivateDnsEnabled", "RouteTableIds", "SecurityGroupIds", "ServiceName", "SubnetIds", "VpcEndpointType", "VpcId"},
+	},
+	"AWS::EC2::VPCGatewayAttachment": {
+		Properties: []string{"InternetGatewayId", "VpnGatewayId", "VpcId"},
+	},
+	"AWS::EC2::Volume": {
+		Properties: []string{"AutoEnableIO", "AvailabilityZone", "Encrypted", "Iops", "KmsKeyId", "Size", "SnapshotId", "Tags", "VolumeType"},
+	},
+	"AWS::EC2::VolumeAttachment": {
+		Properties: []string{"Device", "InstanceId", "VolumeId"},
+	},
+	"AWS::EFS::FileSystem": {
+		Properties: []string{"Encrypted", "FileSystemTags", "KmsKeyId", "PerformanceMode"},
+	},
+	"AWS::EFS::MountTarget": {
+		Properties: []string{"FileSystemId", "IpAddress", "SecurityGroups", "SubnetId"},
+		Attributes: []string{"IpAddress"},
+	},
+	"AWS::ElasticLoadBalancing::LoadBalancer": {
+		Properties: []string{"AccessLoggingPolicy", "AppCookieStickinessPolicy", "AvailabilityZones", "ConnectionDrainingPolicy", "ConnectionSettings", "CrossZone", "HealthCheck", "Instances", "LBCookieStickinessPolicy", "Listeners", "LoadBalancerName", "Policies", "Scheme", "SecurityGroups", "Subnets", "Tags"},
+		Attributes: []string{"CanonicalHostedZoneName", "CanonicalHostedZoneNameID", "DNSName", "SourceSecurityGroup.GroupName", "SourceSecurityGroup.OwnerAlias"},
+	},
+	"AWS::ElasticLoadBalancingV2::Listener": {
+		Properties: []string{"Certificates", "DefaultActions", "LoadBalancerArn", "Port", "Protocol", "SslPolicy"},
+	},
+	"AWS::ElasticLoadBalancingV2::LoadBalancer": {
+		Properties: []string{"IpAddressType", "LoadBalancerAttributes", "Name", "Scheme", "SecurityGroups", "SubnetMappings", "Subnets", "Tags", "Type"},
+		Attributes: []string{"CanonicalHostedZoneID", "DNSName", "LoadBalancerFullName", "LoadBalancerName", "SecurityGroups"},
+	},
+	"AWS::ElasticLoadBalancingV2::TargetGroup": {
+		Properties: []string{"HealthCheckIntervalSeconds", "HealthCheckPath", "HealthCheckPort", "HealthCheckProtocol", "HealthCheckTimeoutSeconds", "HealthyThresholdCount", "Matcher", "Name", "Port", "Protocol", "Tags", "TargetGroupAttributes", "TargetType", "Targets", "UnhealthyThresholdCount", "VpcId"},
+		Attributes: []string{"LoadBalancerArns", "TargetGroupFullName", "TargetGroupName"},
+	},
+	"AWS::IAM::InstanceProfile": {
+		Properties: []string{"InstanceProfileName", "Path", "Roles"},
+		Attributes: []string{"Arn"},
+	},
+	"AWS::IAM::ManagedPolicy": {
+		Properties: []string{"Description", "Groups", "ManagedPolicyName", "Path", "PolicyDocument", "Roles", "Users"},
+	},
+	"AWS::IAM::Policy": {
+		Properties: []string{"Groups", "PolicyDocument", "PolicyName", "Roles", "Users"},
+	},
+	"AWS::IAM::Role": {
+		Properties: []string{"AssumeRolePolicyDocument", "ManagedPolicyArns", "Path", "Policies", "RoleName"},
+		Attributes: []string{"Arn", "RoleId"},
+	},
+	"AWS::KMS::Key": {
+		Properties: []string{"Description", "EnableKeyRotation", "Enabled", "KeyPolicy", "KeyUsage", "Tags"},
+		Attributes: []string{"Arn"},
+	},
+	"AWS::Route53::HostedZone": {
+		Properties: []string{"HostedZoneConfig", "HostedZoneTags", "Name", "QueryLoggingConfig", "VPCs"},
+		Attributes: []string{"NameServers"},
+	},
+	"AWS::Route53::RecordSet": {
+		Properties: []string{"AliasTarget", "Comment", "Failover", "GeoLocation", "HealthCheckId", "HostedZoneId", "HostedZoneName", "Name", "Region", "ResourceRecords", "SetIdentifier", "TTL", "Type", "Weight"},
+	},
+	"AWS::S3::Bucket": {
+		Properties: []string{"AccelerateConfiguration", "AccessControl", "AnalyticsConfigurations", "BucketEncryption", "BucketName", "CorsConfiguration", "InventoryConfigurations", "LifecycleConfiguration", "LoggingConfiguration", "MetricsConfigurations", "NotificationConfiguration", "ReplicationConfiguration", "Tags", "VersioningConfiguration", "WebsiteConfiguration"},
+		Attributes: []string{"Arn", "DomainName", "DualStackDomainName", "WebsiteURL"},
+	},
+	"AWS::SNS::Topic": {
+		Properties: []string{"DisplayName", "Subscription", "TopicName"},
+		Attributes: []string{"TopicName"},
+	},
+	"AWS::SQS::Queue": {
+		Properties: []string{"ContentBasedDeduplication", "DelaySeconds", "FifoQueue", "KmsDataKeyReusePeriodSeconds", "KmsMasterKeyId", "MaximumMessageSize", "MessageRetentionPeriod", "QueueName", "ReceiveMessageWaitTimeSeconds", "RedrivePolicy", "VisibilityTimeout"},
+		Attributes: []string{"Arn", "QueueName"},
+	},
+}
@@ -0,0 +1,151 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// s3DeleteObjectsLimit is the maximum number of keys accepted by a DeleteObjects request
+const s3DeleteObjectsLimit = 1000
+
+type S3ObjectsService interface {
+	ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
+	DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error)
+}
+
+// fingerprintedAssetName matches names of assets with fingerprints e.g. userdata-controller-<sha256>.
+// The submatch is the name of the asset without the fingerprint, used to group revisions of the same asset
+var fingerprintedAssetName = regexp.MustCompile(`^(.+)-[0-9a-f]{64}$`)
+
+// ListObjects returns all the objects under the S3 URI
+func ListObjects(s3Svc S3ObjectsService, s3URI string) ([]*s3.Object, error) {
+	uri, err := S3URIFromString(s3URI)
+	if err != nil {
+		return nil, err
+	}
+	prefix := strings.Join(uri.PathComponents(), "/")
+	if prefix != "" {
+		prefix = prefix + "/"
+	}
+
+	objects := []*s3.Object{}
+	var continuationToken *string
+	for {
+		resp, err := s3Svc.ListObjectsV2(&s3.ListObjectsV2Input{
+			Bucket:            aws.String(uri.Bucket()),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects in %s: %v", s3URI, err)
+		}
+		objects = append(objects, resp.Contents...)
+		if !aws.BoolValue(resp.IsTruncated) {
+			return objects, nil
+		}
+		continuationToken = resp.NextContinuationToken
+	}
+}
+
+// DeleteObjects deletes the objects from the bucket in batches
+func DeleteObjects(s3Svc S3ObjectsService, bucket string, objects []*s3.Object) error {
+	for start := 0; start < len(objects); start += s3DeleteObjectsLimit {
+		end := start + s3DeleteObjectsLimit
+		if end > len(objects) {
+			end = len(objects)
+		}
+
+		ids := []*s3.ObjectIdentifier{}
+		for _, o := range objects[start:end] {
+			ids = append(ids, &s3.ObjectIdentifier{Key: o.Key})
+		}
+
+		resp, err := s3Svc.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{
+				Objects: ids,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete objects from bucket %s: %v", bucket, err)
+		}
+		if len(resp.Errors) > 0 {
+			e := resp.Errors[0]
+			return fmt.Errorf("failed to delete %d object(s) from bucket %s e.g. %s: %s", len(resp.Errors), bucket, aws.StringValue(e.Key), aws.StringValue(e.Message))
+		}
+	}
+	return nil
+}
+
+// DeleteAllObjects deletes every object under the S3 URI and returns the number of deleted objects
+func DeleteAllObjects(s3Svc S3ObjectsService, s3URI string) (int, error) {
+	uri, err := S3URIFromString(s3URI)
+	if err != nil {
+		return 0, err
+	}
+	objects, err := ListObjects(s3Svc, s3URI)
+	if err != nil {
+		return 0, err
+	}
+	if err := DeleteObjects(s3Svc, uri.Bucket(), objects); err != nil {
+		return 0, err
+	}
+	return len(objects), nil
+}
+
+// StaleObjects returns fingerprinted assets e.g. userdata-controller-<sha256> which are referenced from none of the
+// templates, excluding the `keep` most recent revisions of each asset.
+// Assets without fingerprints e.g. stack.json are overwritten on every upload and therefore never stale
+func StaleObjects(objects []*s3.Object, templates []string, keep int) []*s3.Object {
+	revisions := map[string][]*s3.Object{}
+	groups := []string{}
+
+	for _, o := range objects {
+		key := aws.StringValue(o.Key)
+		matches := fingerprintedAssetName.FindStringSubmatch(path.Base(key))
+		if matches == nil {
+			continue
+		}
+		if isReferencedFromAny(key, templates) {
+			continue
+		}
+		group := path.Join(path.Dir(key), matches[1])
+		if _, ok := revisions[group]; !ok {
+			groups = append(groups, group)
+		}
+		revisions[group] = append(revisions[group], o)
+	}
+
+	stale := []*s3.Object{}
+	for _, g := range groups {
+		rs := revisions[g]
+		sort.Stable(objectsByNewest(rs))
+		if len(rs) > keep {
+			stale = append(stale, rs[keep:]...)
+		}
+	}
+	return stale
+}
+
+func isReferencedFromAny(key string, templates []string) bool {
+	for _, t := range templates {
+		if strings.Contains(t, key) {
+			return true
+		}
+	}
+	return false
+}
+
+type objectsByNewest []*s3.Object
+
+func (s objectsByNewest) Len() int      { return len(s) }
+func (s objectsByNewest) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s objectsByNewest) Less(i, j int) bool {
+	return aws.TimeValue(s[i].LastModified).After(aws.TimeValue(s[j].LastModified))
+}
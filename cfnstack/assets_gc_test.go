@@ -0,0 +1,61 @@
+package cfnstack
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaleObjects(t *testing.T) {
+	prefix := "mydir/kube-aws/clusters/mycluster/exported/stacks"
+	now := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := func(c string) string {
+		return strings.Repeat(c, 64)
+	}
+	object := func(key string, age time.Duration) *s3.Object {
+		return &s3.Object{
+			Key:          aws.String(prefix + "/" + key),
+			LastModified: aws.Time(now.Add(-age)),
+		}
+	}
+
+	objects := []*s3.Object{
+		object("mycluster/stack.json", 1*time.Hour),
+		object("control-plane/stack.json", 1*time.Hour),
+		object("control-plane/userdata-controller-"+fingerprint("a"), 1*time.Hour),
+		object("control-plane/userdata-controller-"+fingerprint("b"), 2*time.Hour),
+		object("control-plane/userdata-controller-"+fingerprint("c"), 3*time.Hour),
+		object("control-plane/userdata-controller-"+fingerprint("d"), 4*time.Hour),
+		object("control-plane/userdata-etcd-"+fingerprint("e"), 5*time.Hour),
+		object("pool1/userdata-worker-"+fingerprint("f"), 6*time.Hour),
+	}
+
+	templates := []string{
+		`{"UserData": "aws s3 cp s3://mybucket/` + prefix + `/control-plane/userdata-controller-` + fingerprint("a") + `"}`,
+		`{"UserData": "aws s3 cp s3://mybucket/` + prefix + `/pool1/userdata-worker-` + fingerprint("f") + `"}`,
+	}
+
+	stale := StaleObjects(objects, templates, 1)
+
+	actual := []string{}
+	for _, o := range stale {
+		actual = append(actual, strings.TrimPrefix(*o.Key, prefix+"/"))
+	}
+
+	// userdata-controller-b is the most recent unreferenced revision and therefore kept, as well as userdata-etcd-e
+	expected := []string{
+		"control-plane/userdata-controller-" + fingerprint("c"),
+		"control-plane/userdata-controller-" + fingerprint("d"),
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected stale objects: expected=%v, actual=%v", expected, actual)
+	}
+
+	if len(StaleObjects(objects, templates, 0)) != 4 {
+		t.Errorf("expected all the unreferenced revisions to be stale when nothing is kept")
+	}
+}
@@ -25,6 +25,18 @@ type CRUDService interface {
 	EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error)
 }
 
+type ChangeSetService interface {
+	CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error)
+	DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error)
+	DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error)
+	ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error)
+}
+
+type ChangeSetCRUDService interface {
+	CRUDService
+	ChangeSetService
+}
+
 type S3ObjectPutterService interface {
 	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
 }
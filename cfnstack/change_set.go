@@ -0,0 +1,218 @@
+package cfnstack
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
+	"text/tabwriter"
+)
+
+// ChangeSet is a summary of a CloudFormation change set which is enough to let users review which resources are going
+// to be added, modified, removed and replaced before actually updating a stack
+type ChangeSet struct {
+	StackName string
+	Name      string
+	ID        string
+	Changes   []ResourceChange
+}
+
+type ResourceChange struct {
+	Action             string
+	LogicalResourceId  string
+	PhysicalResourceId string
+	ResourceType       string
+	Replacement        string
+}
+
+// RequiresReplacement returns true when CloudFormation would, or might depending on runtime values, replace the resource
+func (r ResourceChange) RequiresReplacement() bool {
+	return r.Replacement == cloudformation.ReplacementTrue || r.Replacement == cloudformation.ReplacementConditional
+}
+
+func (c *ChangeSet) HasChanges() bool {
+	return len(c.Changes) > 0
+}
+
+func (c *ChangeSet) Replacements() []ResourceChange {
+	replacements := []ResourceChange{}
+	for _, r := range c.Changes {
+		if r.RequiresReplacement() {
+			replacements = append(replacements, r)
+		}
+	}
+	return replacements
+}
+
+func (c *ChangeSet) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 1, ' ', 0)
+
+	fmt.Fprintf(w, "Stack %s:\n", c.StackName)
+	if !c.HasChanges() {
+		fmt.Fprintf(w, "  (no changes)\n")
+	}
+	for _, r := range c.Changes {
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = "-"
+		}
+		fmt.Fprintf(w, "  %s\t%s\t%s\treplacement=%s\n", r.Action, r.LogicalResourceId, r.ResourceType, replacement)
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+func newChangeSetFromDescriptions(stackName string, changeSetName string, descs []*cloudformation.DescribeChangeSetOutput) *ChangeSet {
+	changeSet := &ChangeSet{
+		StackName: stackName,
+		Name:      changeSetName,
+		Changes:   []ResourceChange{},
+	}
+	for _, d := range descs {
+		changeSet.ID = aws.StringValue(d.ChangeSetId)
+		for _, c := range d.Changes {
+			r := c.ResourceChange
+			if r == nil {
+				continue
+			}
+			changeSet.Changes = append(changeSet.Changes, ResourceChange{
+				Action:             aws.StringValue(r.Action),
+				LogicalResourceId:  aws.StringValue(r.LogicalResourceId),
+				PhysicalResourceId: aws.StringValue(r.PhysicalResourceId),
+				ResourceType:       aws.StringValue(r.ResourceType),
+				Replacement:        aws.StringValue(r.Replacement),
+			})
+		}
+	}
+	return changeSet
+}
+
+func (c *Provisioner) baseCreateChangeSetInput(changeSetName string) *cloudformation.CreateChangeSetInput {
+	var tags []*cloudformation.Tag
+	for k, v := range c.stackTags {
+		key := k
+		value := v
+		tags = append(tags, &cloudformation.Tag{Key: &key, Value: &value})
+	}
+
+	return &cloudformation.CreateChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+		ChangeSetType: aws.String(cloudformation.ChangeSetTypeUpdate),
+		Capabilities:  []*string{aws.String(cloudformation.CapabilityCapabilityIam), aws.String(cloudformation.CapabilityCapabilityNamedIam)},
+		StackName:     aws.String(c.stackName),
+		Tags:          tags,
+	}
+}
+
+// CreateChangeSetAtURLAndWait creates a change set to update the stack with the template at the URL and then waits for
+// CloudFormation to finish calculating changes.
+// An empty change set is returned instead of an error when the template and the parameters result in no change
+func (c *Provisioner) CreateChangeSetAtURLAndWait(cfSvc ChangeSetService, changeSetName string, templateURL string, parameters []*cloudformation.Parameter) (*ChangeSet, error) {
+	input := c.baseCreateChangeSetInput(changeSetName)
+	input.TemplateURL = aws.String(templateURL)
+	input.Parameters = parameters
+
+	if _, err := cfSvc.CreateChangeSet(input); err != nil {
+		return nil, fmt.Errorf("failed to create change set %s for stack %s: %v", changeSetName, c.stackName, err)
+	}
+
+	return c.waitUntilChangeSetGetsCreated(cfSvc, changeSetName)
+}
+
+func (c *Provisioner) waitUntilChangeSetGetsCreated(cfSvc ChangeSetService, changeSetName string) (*ChangeSet, error) {
+	backoff := newBackoff()
+	for {
+		descs, err := c.describeChangeSet(cfSvc, changeSetName)
+		if err != nil {
+			return nil, err
+		}
+		status := aws.StringValue(descs[0].Status)
+		switch status {
+		case cloudformation.ChangeSetStatusCreateComplete:
+			return newChangeSetFromDescriptions(c.stackName, changeSetName, descs), nil
+		case cloudformation.ChangeSetStatusFailed:
+			reason := aws.StringValue(descs[0].StatusReason)
+			if isNoChangesReason(reason) {
+				return newChangeSetFromDescriptions(c.stackName, changeSetName, descs), nil
+			}
+			// A failed change set can't be executed and is left in the stack unless deleted
+			if err := c.DeleteChangeSet(cfSvc, changeSetName); err != nil {
+				return nil, fmt.Errorf("change set %s for stack %s failed: %s, and then %v", changeSetName, c.stackName, reason, err)
+			}
+			return nil, fmt.Errorf("change set %s for stack %s failed: %s", changeSetName, c.stackName, reason)
+		case cloudformation.ChangeSetStatusCreatePending, cloudformation.ChangeSetStatusCreateInProgress:
+			if err := backoff.sleep(c.context()); err != nil {
+				return nil, fmt.Errorf("stopped waiting for change set %s for stack %s: %v", changeSetName, c.stackName, err)
+			}
+			continue
+		default:
+			return nil, fmt.Errorf("unexpected change set status: %s", status)
+		}
+	}
+}
+
+// describeChangeSet fetches every page of the change set description
+func (c *Provisioner) describeChangeSet(cfSvc ChangeSetService, changeSetName string) ([]*cloudformation.DescribeChangeSetOutput, error) {
+	descs := []*cloudformation.DescribeChangeSetOutput{}
+	var nextToken *string
+	for {
+		resp, err := cfSvc.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{
+			ChangeSetName: aws.String(changeSetName),
+			StackName:     aws.String(c.stackName),
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe change set %s for stack %s: %v", changeSetName, c.stackName, err)
+		}
+		descs = append(descs, resp)
+		if aws.StringValue(resp.NextToken) == "" {
+			return descs, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+func isNoChangesReason(reason string) bool {
+	return strings.Contains(reason, "didn't contain changes") || strings.Contains(reason, "No updates are to be performed")
+}
+
+func (c *Provisioner) DeleteChangeSet(cfSvc ChangeSetService, changeSetName string) error {
+	_, err := cfSvc.DeleteChangeSet(&cloudformation.DeleteChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+		StackName:     aws.String(c.stackName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete change set %s for stack %s: %v", changeSetName, c.stackName, err)
+	}
+	return nil
+}
+
+// ExecuteChangeSetAndWait executes the previously created change set as-is and waits for the stack update to finish
+func (c *Provisioner) ExecuteChangeSetAndWait(cfSvc ChangeSetCRUDService, changeSetName string) (string, error) {
+	descs, err := c.describeChangeSet(cfSvc, changeSetName)
+	if err != nil {
+		return "", err
+	}
+
+	desc := descs[0]
+	if status := aws.StringValue(desc.Status); status != cloudformation.ChangeSetStatusCreateComplete {
+		return "", fmt.Errorf("change set %s for stack %s is not executable: status is %s: %s", changeSetName, c.stackName, status, aws.StringValue(desc.StatusReason))
+	}
+	if status := aws.StringValue(desc.ExecutionStatus); status != cloudformation.ExecutionStatusAvailable {
+		return "", fmt.Errorf("change set %s for stack %s is not executable: execution status is %s", changeSetName, c.stackName, status)
+	}
+
+	_, err = cfSvc.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{
+		ChangeSetName: aws.String(changeSetName),
+		StackName:     aws.String(c.stackName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error executing change set %s for stack %s: %v", changeSetName, c.stackName, err)
+	}
+
+	return c.waitUntilStackGetsUpdated(cfSvc, &cloudformation.UpdateStackOutput{StackId: desc.StackId})
+}
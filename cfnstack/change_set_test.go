@@ -0,0 +1,136 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/model"
+	"testing"
+)
+
+type dummyChangeSetService struct {
+	pages        []*cloudformation.DescribeChangeSetOutput
+	createInput  *cloudformation.CreateChangeSetInput
+	deletedNames []string
+}
+
+func (s *dummyChangeSetService) CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	s.createInput = input
+	return &cloudformation.CreateChangeSetOutput{Id: aws.String("arn:changeset")}, nil
+}
+
+func (s *dummyChangeSetService) DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
+	if input.NextToken == nil {
+		return s.pages[0], nil
+	}
+	var i int
+	if _, err := fmt.Sscanf(*input.NextToken, "page%d", &i); err != nil {
+		return nil, err
+	}
+	return s.pages[i], nil
+}
+
+func (s *dummyChangeSetService) DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	s.deletedNames = append(s.deletedNames, *input.ChangeSetName)
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+func (s *dummyChangeSetService) ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func resourceChange(action string, logicalID string, replacement string) *cloudformation.Change {
+	return &cloudformation.Change{
+		Type: aws.String("Resource"),
+		ResourceChange: &cloudformation.ResourceChange{
+			Action:            aws.String(action),
+			LogicalResourceId: aws.String(logicalID),
+			ResourceType:      aws.String("AWS::EC2::Instance"),
+			Replacement:       aws.String(replacement),
+		},
+	}
+}
+
+func TestCreateChangeSetAtURLAndWait(t *testing.T) {
+	cfSvc := &dummyChangeSetService{
+		pages: []*cloudformation.DescribeChangeSetOutput{
+			{
+				Status:    aws.String(cloudformation.ChangeSetStatusCreateComplete),
+				NextToken: aws.String("page1"),
+				Changes: []*cloudformation.Change{
+					resourceChange(cloudformation.ChangeActionModify, "InstanceEtcd0", cloudformation.ReplacementTrue),
+				},
+			},
+			{
+				Status: aws.String(cloudformation.ChangeSetStatusCreateComplete),
+				Changes: []*cloudformation.Change{
+					resourceChange(cloudformation.ChangeActionModify, "Workers", cloudformation.ReplacementFalse),
+					resourceChange(cloudformation.ChangeActionAdd, "Controllers", ""),
+				},
+			},
+		},
+	}
+
+	provisioner := NewProvisioner("test-cluster-name", map[string]string{"foo": "bar"}, "s3://mybucket/mykey", model.RegionForName("us-east-1"), "", nil)
+
+	changeSet, err := provisioner.CreateChangeSetAtURLAndWait(cfSvc, "plan1", "https://example.com/stack.json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if *cfSvc.createInput.TemplateURL != "https://example.com/stack.json" {
+		t.Errorf("unexpected template url: %s", *cfSvc.createInput.TemplateURL)
+	}
+	if len(cfSvc.createInput.Tags) != 1 {
+		t.Errorf("unexpected tags: %v", cfSvc.createInput.Tags)
+	}
+	if len(changeSet.Changes) != 3 {
+		t.Errorf("expected changes from every page to be collected, but got: %+v", changeSet.Changes)
+	}
+
+	replacements := changeSet.Replacements()
+	if len(replacements) != 1 || replacements[0].LogicalResourceId != "InstanceEtcd0" {
+		t.Errorf("unexpected replacements: %+v", replacements)
+	}
+}
+
+func TestCreateChangeSetAtURLAndWaitWithoutChanges(t *testing.T) {
+	cfSvc := &dummyChangeSetService{
+		pages: []*cloudformation.DescribeChangeSetOutput{
+			{
+				Status:       aws.String(cloudformation.ChangeSetStatusFailed),
+				StatusReason: aws.String("The submitted information didn't contain changes. Submit different information to create a change set."),
+			},
+		},
+	}
+
+	provisioner := NewProvisioner("test-cluster-name", map[string]string{}, "s3://mybucket/mykey", model.RegionForName("us-east-1"), "", nil)
+
+	changeSet, err := provisioner.CreateChangeSetAtURLAndWait(cfSvc, "plan1", "https://example.com/stack.json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changeSet.HasChanges() {
+		t.Errorf("expected no changes, but got: %+v", changeSet.Changes)
+	}
+}
+
+func TestCreateChangeSetAtURLAndWaitFailure(t *testing.T) {
+	cfSvc := &dummyChangeSetService{
+		pages: []*cloudformation.DescribeChangeSetOutput{
+			{
+				Status:       aws.String(cloudformation.ChangeSetStatusFailed),
+				StatusReason: aws.String("Template format error"),
+			},
+		},
+	}
+
+	provisioner := NewProvisioner("test-cluster-name", map[string]string{}, "s3://mybucket/mykey", model.RegionForName("us-east-1"), "", nil)
+
+	if _, err := provisioner.CreateChangeSetAtURLAndWait(cfSvc, "plan1", "https://example.com/stack.json", nil); err == nil {
+		t.Error("expected an error, but got none")
+	}
+	if len(cfSvc.deletedNames) != 1 || cfSvc.deletedNames[0] != "plan1" {
+		t.Errorf("expected the failed change set to be deleted, but deleted: %v", cfSvc.deletedNames)
+	}
+}
@@ -0,0 +1,292 @@
+package cfnstack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type DestroyService interface {
+	DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error)
+	DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+	ListStackResources(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error)
+}
+
+type Destroyer struct {
+	stackName string
+	session   *session.Session
+	ctx       context.Context
+}
+
+func NewDestroyer(stackName string, session *session.Session) *Destroyer {
+	return &Destroyer{
+		stackName: stackName,
+		session:   session,
+	}
+}
+
+// WithContext returns a copy of the destroyer which stops waiting for deletions once the context is done
+func (c *Destroyer) WithContext(ctx context.Context) *Destroyer {
+	d := *c
+	d.ctx = ctx
+	return &d
+}
+
+func (c *Destroyer) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c *Destroyer) Destroy() error {
+	cfSvc := cloudformation.New(c.session)
+	dreq := &cloudformation.DeleteStackInput{
+		StackName: aws.String(c.stackName),
+	}
+	_, err := cfSvc.DeleteStack(dreq)
+	return err
+}
+
+// DestroyAndWait deletes the stack and waits until the stack and all of its nested stacks are deleted.
+// A *DeletionFailedError is returned when the deletion failed, reporting which resources blocked it
+func (c *Destroyer) DestroyAndWait() error {
+	return c.destroyAndWait(cloudformation.New(c.session))
+}
+
+func (c *Destroyer) destroyAndWait(cfSvc DestroyService) error {
+	// A deleted stack can only be described by its stack id, not by its name
+	stackID, err := c.stackID(cfSvc)
+	if err != nil {
+		return err
+	}
+	return deleteStackAndWait(c.context(), cfSvc, stackID, c.stackName, []string{})
+}
+
+// RetainStuckResourcesAndDestroy retries the failed deletion while retaining the resources which blocked it.
+// Stuck resources are retained only in the innermost stacks containing them so that nested stacks themselves are still
+// deleted
+func (c *Destroyer) RetainStuckResourcesAndDestroy(failure *DeletionFailedError) error {
+	return c.retainStuckResourcesAndDestroy(cloudformation.New(c.session), failure)
+}
+
+func (c *Destroyer) retainStuckResourcesAndDestroy(cfSvc DestroyService, failure *DeletionFailedError) error {
+	retained := map[string][]string{}
+	tags := map[string]string{}
+	order := []string{}
+	for _, r := range failure.StuckResources {
+		if _, ok := retained[r.StackID]; !ok {
+			order = append(order, r.StackID)
+		}
+		retained[r.StackID] = append(retained[r.StackID], r.LogicalResourceId)
+		tags[r.StackID] = r.StackTag
+	}
+
+	// Stuck resources are collected from outer to inner stacks. Delete inner stacks first
+	for i := len(order) - 1; i >= 0; i-- {
+		stackID := order[i]
+		if stackID == failure.StackID {
+			continue
+		}
+		if err := deleteStackAndWait(c.context(), cfSvc, stackID, tags[stackID], retained[stackID]); err != nil {
+			return err
+		}
+	}
+
+	return deleteStackAndWait(c.context(), cfSvc, failure.StackID, c.stackName, retained[failure.StackID])
+}
+
+func (c *Destroyer) stackID(cfSvc DestroyService) (string, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(c.stackName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing stack %s: %v", c.stackName, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return "", fmt.Errorf("could not find a stack with name %s", c.stackName)
+	}
+	return aws.StringValue(resp.Stacks[0].StackId), nil
+}
+
+func deleteStackAndWait(ctx context.Context, cfSvc DestroyService, stackID string, tag string, retainResources []string) error {
+	dreq := &cloudformation.DeleteStackInput{
+		StackName: aws.String(stackID),
+	}
+	if len(retainResources) > 0 {
+		dreq.RetainResources = aws.StringSlice(retainResources)
+	}
+	if _, err := cfSvc.DeleteStack(dreq); err != nil {
+		return fmt.Errorf("failed to delete stack %s: %v", tag, err)
+	}
+	return waitUntilStackGetsDeleted(ctx, cfSvc, stackID, tag)
+}
+
+func waitUntilStackGetsDeleted(ctx context.Context, cfSvc DestroyService, stackID string, tag string) error {
+	req := cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackID),
+	}
+	streamer := NewStackEventStreamer(cfSvc, stackID, tag, time.Now().Add(-1*time.Minute), stdout)
+	backoff := newBackoff()
+
+	for {
+		resp, err := cfSvc.DescribeStacks(&req)
+		if err != nil {
+			return err
+		}
+		if len(resp.Stacks) == 0 {
+			return fmt.Errorf("stack not found")
+		}
+		pollStackEvents(streamer)
+		statusString := aws.StringValue(resp.Stacks[0].StackStatus)
+		switch statusString {
+		case cloudformation.StackStatusDeleteComplete:
+			return nil
+		case cloudformation.StackStatusDeleteFailed:
+			stuck, err := collectStuckResources(cfSvc, stackID, tag)
+			if err != nil {
+				return fmt.Errorf("stack deletion failed: %s, and then failed to collect resources blocking the deletion: %v", aws.StringValue(resp.Stacks[0].StackStatusReason), err)
+			}
+			return &DeletionFailedError{
+				StackID:        stackID,
+				StackName:      tag,
+				Reason:         aws.StringValue(resp.Stacks[0].StackStatusReason),
+				StuckResources: stuck,
+			}
+		case cloudformation.StackStatusDeleteInProgress:
+			if err := backoff.sleep(ctx); err != nil {
+				return &WaitInterruptedError{
+					StackID:     stackID,
+					StackName:   tag,
+					Operation:   OperationDelete,
+					StackStatus: statusString,
+					Err:         err,
+				}
+			}
+			continue
+		default:
+			return fmt.Errorf("unexpected stack status: %s", statusString)
+		}
+	}
+}
+
+// collectStuckResources returns resources failed to be deleted in the stack and its nested stacks.
+// A failed nested stack is not reported itself but the resources inside it which caused it to fail are
+func collectStuckResources(cfSvc DestroyService, stackID string, tag string) ([]StuckResource, error) {
+	stuck := []StuckResource{}
+	nested := []StuckResource{}
+
+	var nextToken *string
+	for {
+		resp, err := cfSvc.ListStackResources(&cloudformation.ListStackResourcesInput{
+			StackName: aws.String(stackID),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resources of stack %s: %v", tag, err)
+		}
+		for _, r := range resp.StackResourceSummaries {
+			if aws.StringValue(r.ResourceStatus) != cloudformation.ResourceStatusDeleteFailed {
+				continue
+			}
+			s := StuckResource{
+				StackID:            stackID,
+				StackTag:           tag,
+				LogicalResourceId:  aws.StringValue(r.LogicalResourceId),
+				PhysicalResourceId: aws.StringValue(r.PhysicalResourceId),
+				ResourceType:       aws.StringValue(r.ResourceType),
+				Reason:             aws.StringValue(r.ResourceStatusReason),
+			}
+			if s.ResourceType == "AWS::CloudFormation::Stack" && s.PhysicalResourceId != "" {
+				nested = append(nested, s)
+			} else {
+				stuck = append(stuck, s)
+			}
+		}
+		if aws.StringValue(resp.NextToken) == "" {
+			break
+		}
+		nextToken = resp.NextToken
+	}
+
+	for _, n := range nested {
+		inner, err := collectStuckResources(cfSvc, n.PhysicalResourceId, n.LogicalResourceId)
+		if err != nil {
+			return nil, err
+		}
+		if len(inner) == 0 {
+			// Nothing inside the nested stack explains the failure. Report the nested stack itself
+			stuck = append(stuck, n)
+		}
+		stuck = append(stuck, inner...)
+	}
+
+	return stuck, nil
+}
+
+// StuckResource is a resource which CloudFormation failed to delete
+type StuckResource struct {
+	StackID            string
+	StackTag           string
+	LogicalResourceId  string
+	PhysicalResourceId string
+	ResourceType       string
+	Reason             string
+}
+
+// Hint returns a human-readable explanation of frequent causes of the failure, if any
+func (r StuckResource) Hint() string {
+	reason := strings.ToLower(r.Reason)
+	switch {
+	case r.ResourceType == "AWS::S3::Bucket" && strings.Contains(reason, "not empty"):
+		return "the bucket is not empty. Empty it and then retry"
+	case r.ResourceType == "AWS::EC2::SecurityGroup" && strings.Contains(reason, "dependent object"):
+		return "the security group is still referenced by other security groups or by network interfaces e.g. those of load balancers created by Kubernetes services of type LoadBalancer"
+	case r.ResourceType == "AWS::EC2::Subnet" || r.ResourceType == "AWS::EC2::VPC" || r.ResourceType == "AWS::EC2::InternetGateway":
+		if strings.Contains(reason, "dependencies") || strings.Contains(reason, "dependency") {
+			return "network interfaces left behind e.g. by ELBs created by Kubernetes services of type LoadBalancer are still attached. Delete those services or load balancers and then retry"
+		}
+	case r.ResourceType == "AWS::CloudFormation::Stack":
+		return "the nested stack failed to be deleted. See its stack events for details"
+	}
+	return ""
+}
+
+// DeletionFailedError is returned when a stack ended up in DELETE_FAILED
+type DeletionFailedError struct {
+	StackID        string
+	StackName      string
+	Reason         string
+	StuckResources []StuckResource
+}
+
+func (e *DeletionFailedError) Error() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "stack %s failed to be deleted: %s\n", e.StackName, e.Reason)
+
+	if len(e.StuckResources) == 0 {
+		return buf.String()
+	}
+
+	fmt.Fprintf(buf, "\nResources blocking the deletion:\n")
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+	for _, r := range e.StuckResources {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", r.StackTag, r.LogicalResourceId, r.ResourceType, r.PhysicalResourceId, r.Reason)
+	}
+	w.Flush()
+
+	for _, r := range e.StuckResources {
+		if hint := r.Hint(); hint != "" {
+			fmt.Fprintf(buf, "  * %s: %s\n", r.LogicalResourceId, hint)
+		}
+	}
+	return buf.String()
+}
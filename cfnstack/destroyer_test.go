@@ -0,0 +1,128 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type dummyDestroyService struct {
+	dummyStackEventsService
+	statuses  map[string]string
+	resources map[string][]*cloudformation.StackResourceSummary
+	deletions []*cloudformation.DeleteStackInput
+	// dependencies maps stacks to their nested stacks which must be deleted beforehand
+	dependencies map[string]string
+	// failing is the set of stacks which fail to be deleted unless stuck resources are retained
+	failing map[string]bool
+}
+
+func (s *dummyDestroyService) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	s.deletions = append(s.deletions, input)
+	id := *input.StackName
+	if dep, ok := s.dependencies[id]; ok && s.statuses[dep] != cloudformation.StackStatusDeleteComplete {
+		s.DeleteStack(&cloudformation.DeleteStackInput{StackName: aws.String(dep)})
+		if s.statuses[dep] != cloudformation.StackStatusDeleteComplete {
+			s.statuses[id] = cloudformation.StackStatusDeleteFailed
+			return &cloudformation.DeleteStackOutput{}, nil
+		}
+	}
+	if s.failing[id] && len(input.RetainResources) == 0 {
+		s.statuses[id] = cloudformation.StackStatusDeleteFailed
+	} else {
+		s.statuses[id] = cloudformation.StackStatusDeleteComplete
+	}
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (s *dummyDestroyService) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	id := *input.StackName
+	if id == "mycluster" {
+		id = "root"
+	}
+	status, ok := s.statuses[id]
+	if !ok {
+		return nil, fmt.Errorf("stack %s does not exist", id)
+	}
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{StackId: aws.String(id), StackStatus: aws.String(status), StackStatusReason: aws.String("The following resource(s) failed to delete: [Controlplane]")},
+		},
+	}, nil
+}
+
+func (s *dummyDestroyService) ListStackResources(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error) {
+	return &cloudformation.ListStackResourcesOutput{
+		StackResourceSummaries: s.resources[*input.StackName],
+	}, nil
+}
+
+func resourceSummary(logicalID string, resourceType string, physicalID string, status string, reason string) *cloudformation.StackResourceSummary {
+	return &cloudformation.StackResourceSummary{
+		LogicalResourceId:    aws.String(logicalID),
+		ResourceType:         aws.String(resourceType),
+		PhysicalResourceId:   aws.String(physicalID),
+		ResourceStatus:       aws.String(status),
+		ResourceStatusReason: aws.String(reason),
+	}
+}
+
+func TestDestroyAndWaitReportsStuckResources(t *testing.T) {
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = ioutil.Discard
+
+	cfSvc := &dummyDestroyService{
+		dummyStackEventsService: dummyStackEventsService{events: map[string][]*cloudformation.StackEvent{"root": {}, "cp": {}}},
+		statuses:                map[string]string{"root": cloudformation.StackStatusCreateComplete, "cp": cloudformation.StackStatusCreateComplete},
+		resources: map[string][]*cloudformation.StackResourceSummary{
+			"root": {
+				resourceSummary("Controlplane", "AWS::CloudFormation::Stack", "cp", cloudformation.ResourceStatusDeleteFailed, "Embedded stack cp was not successfully deleted"),
+			},
+			"cp": {
+				resourceSummary("SecurityGroupWorker", "AWS::EC2::SecurityGroup", "sg-1234", cloudformation.ResourceStatusDeleteFailed, "resource sg-1234 has a dependent object"),
+				resourceSummary("Subnet0", "AWS::EC2::Subnet", "subnet-1234", cloudformation.ResourceStatusDeleteComplete, ""),
+			},
+		},
+		dependencies: map[string]string{"root": "cp"},
+		failing:      map[string]bool{"cp": true},
+	}
+
+	destroyer := NewDestroyer("mycluster", nil)
+
+	err := destroyer.destroyAndWait(cfSvc)
+	failure, ok := err.(*DeletionFailedError)
+	if !ok {
+		t.Fatalf("expected a DeletionFailedError, but got: %v", err)
+	}
+
+	if len(failure.StuckResources) != 1 {
+		t.Fatalf("expected exactly one stuck resource, but got: %+v", failure.StuckResources)
+	}
+	stuck := failure.StuckResources[0]
+	if stuck.StackID != "cp" || stuck.StackTag != "Controlplane" || stuck.LogicalResourceId != "SecurityGroupWorker" {
+		t.Errorf("unexpected stuck resource: %+v", stuck)
+	}
+	if !strings.Contains(failure.Error(), "SecurityGroupWorker") || stuck.Hint() == "" {
+		t.Errorf("expected the report to include the stuck resource and a hint, but got: %s", failure.Error())
+	}
+
+	cfSvc.deletions = nil
+	if err := destroyer.retainStuckResourcesAndDestroy(cfSvc, failure); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cfSvc.deletions) != 2 {
+		t.Fatalf("expected the nested stack and then the root stack to be deleted, but got: %v", cfSvc.deletions)
+	}
+	if *cfSvc.deletions[0].StackName != "cp" || !reflect.DeepEqual(aws.StringValueSlice(cfSvc.deletions[0].RetainResources), []string{"SecurityGroupWorker"}) {
+		t.Errorf("unexpected deletion of the nested stack: %v", cfSvc.deletions[0])
+	}
+	if *cfSvc.deletions[1].StackName != "root" || len(cfSvc.deletions[1].RetainResources) != 0 {
+		t.Errorf("unexpected deletion of the root stack: %v", cfSvc.deletions[1])
+	}
+}
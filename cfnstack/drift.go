@@ -0,0 +1,158 @@
+package cfnstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"sort"
+)
+
+// Top-level sections of a CloudFormation template which are compared entry by entry
+var templateSectionsWithEntries = map[string]bool{
+	"Conditions": true,
+	"Mappings":   true,
+	"Outputs":    true,
+	"Parameters": true,
+	"Resources":  true,
+}
+
+// TemplateDifference is a difference between two CloudFormation templates
+type TemplateDifference struct {
+	// Section is the top-level key of the template e.g. Resources
+	Section string
+	// LogicalID is the key of the entry in the section e.g. the logical ID of a resource. Empty when the section itself differs
+	LogicalID string
+	// ResourceType is the type of the resource in either template. Empty for entries other than resources
+	ResourceType string
+	// Path is the dot-separated path to the differing value in the entry e.g. Properties.MaxSize.
+	// Empty when the entry is missing in one of the templates
+	Path string
+	// Expected and Actual are the values in the templates, nil when missing
+	Expected interface{}
+	Actual   interface{}
+}
+
+// DiffTemplates compares two JSON CloudFormation templates structurally. Differences in formatting and key order are ignored,
+// and scalars are compared by their string representations as CloudFormation does e.g. 1 equals to "1"
+func DiffTemplates(expected string, actual string) ([]TemplateDifference, error) {
+	var e, a map[string]interface{}
+	if err := json.Unmarshal([]byte(expected), &e); err != nil {
+		return nil, fmt.Errorf("failed to parse the expected template: %v", err)
+	}
+	if err := json.Unmarshal([]byte(actual), &a); err != nil {
+		return nil, fmt.Errorf("failed to parse the actual template: %v", err)
+	}
+
+	diffs := []TemplateDifference{}
+	for _, section := range unionKeys(e, a) {
+		eEntries, eOk := e[section].(map[string]interface{})
+		aEntries, aOk := a[section].(map[string]interface{})
+		if !templateSectionsWithEntries[section] || !eOk || !aOk {
+			for _, d := range diffValues("", e[section], a[section]) {
+				d.Section = section
+				diffs = append(diffs, d)
+			}
+			continue
+		}
+
+		for _, id := range unionKeys(eEntries, aEntries) {
+			resourceType := ""
+			if section == "Resources" {
+				resourceType = typeOfResource(eEntries[id])
+				if resourceType == "" {
+					resourceType = typeOfResource(aEntries[id])
+				}
+			}
+			for _, d := range diffValues("", eEntries[id], aEntries[id]) {
+				d.Section = section
+				d.LogicalID = id
+				d.ResourceType = resourceType
+				diffs = append(diffs, d)
+			}
+		}
+	}
+	return diffs, nil
+}
+
+// GetTemplateBody returns the template currently used by the stack
+func GetTemplateBody(cfSvc *cloudformation.CloudFormation, stackName string) (string, error) {
+	resp, err := cfSvc.GetTemplate(&cloudformation.GetTemplateInput{
+		StackName: aws.String(stackName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the template of stack %s: %v", stackName, err)
+	}
+	return aws.StringValue(resp.TemplateBody), nil
+}
+
+func typeOfResource(r interface{}) string {
+	m, ok := r.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	t, _ := m["Type"].(string)
+	return t
+}
+
+func diffValues(path string, expected interface{}, actual interface{}) []TemplateDifference {
+	if expected == nil || actual == nil {
+		if expected == nil && actual == nil {
+			return nil
+		}
+		return []TemplateDifference{{Path: path, Expected: expected, Actual: actual}}
+	}
+
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return []TemplateDifference{{Path: path, Expected: expected, Actual: actual}}
+		}
+		diffs := []TemplateDifference{}
+		for _, k := range unionKeys(e, a) {
+			diffs = append(diffs, diffValues(joinPath(path, k), e[k], a[k])...)
+		}
+		return diffs
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok || len(e) != len(a) {
+			return []TemplateDifference{{Path: path, Expected: expected, Actual: actual}}
+		}
+		diffs := []TemplateDifference{}
+		for i := range e {
+			diffs = append(diffs, diffValues(fmt.Sprintf("%s[%d]", path, i), e[i], a[i])...)
+		}
+		return diffs
+	default:
+		switch actual.(type) {
+		case map[string]interface{}, []interface{}:
+			return []TemplateDifference{{Path: path, Expected: expected, Actual: actual}}
+		}
+		if fmt.Sprint(expected) != fmt.Sprint(actual) {
+			return []TemplateDifference{{Path: path, Expected: expected, Actual: actual}}
+		}
+		return nil
+	}
+}
+
+func joinPath(path string, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func unionKeys(a map[string]interface{}, b map[string]interface{}) []string {
+	keys := []string{}
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package cfnstack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffTemplates(t *testing.T) {
+	expected := `{
+  "AWSTemplateFormatVersion": "2010-09-09",
+  "Resources": {
+    "Workers": {
+      "Type": "AWS::AutoScaling::AutoScalingGroup",
+      "Properties": {"MinSize": "1", "MaxSize": "3", "HealthCheckGracePeriod": 600}
+    },
+    "WorkersLC": {
+      "Type": "AWS::AutoScaling::LaunchConfiguration",
+      "Properties": {"InstanceType": "t2.medium", "SecurityGroups": [{"Ref": "SG1"}, {"Ref": "SG2"}]}
+    },
+    "NewResource": {"Type": "AWS::EC2::EIP"}
+  },
+  "Outputs": {"StackName": {"Value": {"Ref": "AWS::StackName"}}}
+}`
+	actual := `{"Outputs": {"StackName": {"Value": {"Ref": "AWS::StackName"}}}, "AWSTemplateFormatVersion": "2010-09-09", "Resources": {
+  "WorkersLC": {"Type": "AWS::AutoScaling::LaunchConfiguration", "Properties": {"InstanceType": "m4.large", "SecurityGroups": [{"Ref": "SG1"}]}},
+  "Workers": {"Type": "AWS::AutoScaling::AutoScalingGroup", "Properties": {"MaxSize": "3", "MinSize": 1, "HealthCheckGracePeriod": "600"}}
+}}`
+
+	diffs, err := DiffTemplates(expected, actual)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedDiffs := []TemplateDifference{
+		{Section: "Resources", LogicalID: "NewResource", ResourceType: "AWS::EC2::EIP", Expected: map[string]interface{}{"Type": "AWS::EC2::EIP"}},
+		{Section: "Resources", LogicalID: "WorkersLC", ResourceType: "AWS::AutoScaling::LaunchConfiguration", Path: "Properties.InstanceType", Expected: "t2.medium", Actual: "m4.large"},
+		{
+			Section:      "Resources",
+			LogicalID:    "WorkersLC",
+			ResourceType: "AWS::AutoScaling::LaunchConfiguration",
+			Path:         "Properties.SecurityGroups",
+			Expected:     []interface{}{map[string]interface{}{"Ref": "SG1"}, map[string]interface{}{"Ref": "SG2"}},
+			Actual:       []interface{}{map[string]interface{}{"Ref": "SG1"}},
+		},
+	}
+	if !reflect.DeepEqual(diffs, expectedDiffs) {
+		t.Errorf("unexpected differences:\nexpected=%+v\nactual=%+v", expectedDiffs, diffs)
+	}
+}
+
+func TestDiffTemplatesWithIdenticalTemplates(t *testing.T) {
+	diffs, err := DiffTemplates(`{"Resources": {"A": {"Type": "T", "Properties": {"X": [1, 2]}}}}`, `{"Resources":{"A":{"Properties":{"X":["1","2"]},"Type":"T"}}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(diffs) != 0 {
+		t.Errorf("expected no difference but got: %+v", diffs)
+	}
+}
+
+func TestDiffTemplatesWithInvalidTemplate(t *testing.T) {
+	if _, err := DiffTemplates(`{}`, `not json`); err == nil {
+		t.Errorf("expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,153 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// stdout is where stack events are streamed to while waiting for stack operations
+var stdout io.Writer = os.Stdout
+
+type StackEventsService interface {
+	DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+}
+
+// StackEventStreamer prints stack events of a stack and its nested stacks as they happen.
+// Each call to Poll prints events which have been emitted since the previous call, oldest first
+type StackEventStreamer struct {
+	cfSvc  StackEventsService
+	out    io.Writer
+	since  time.Time
+	stacks []*trackedStack
+	seen   map[string]bool
+}
+
+type trackedStack struct {
+	stackID string
+	tag     string
+}
+
+// NewStackEventStreamer returns a streamer which prints events of the stack emitted at or after `since`.
+// Events of the root stack are tagged with `tag` while events of nested stacks are tagged with their logical IDs in the
+// parent stack e.g. Controlplane or the name of a node pool
+func NewStackEventStreamer(cfSvc StackEventsService, stackID string, tag string, since time.Time, out io.Writer) *StackEventStreamer {
+	return &StackEventStreamer{
+		cfSvc: cfSvc,
+		out:   out,
+		since: since,
+		stacks: []*trackedStack{
+			{stackID: stackID, tag: tag},
+		},
+		seen: map[string]bool{},
+	}
+}
+
+func (s *StackEventStreamer) isTracked(stackID string) bool {
+	for _, t := range s.stacks {
+		if t.stackID == stackID {
+			return true
+		}
+	}
+	return false
+}
+
+// Poll fetches and prints new events from every stack tracked so far.
+// Nested stacks are tracked as soon as events for their AWS::CloudFormation::Stack resources are seen
+func (s *StackEventStreamer) Poll() error {
+	newEvents := []taggedEvent{}
+
+	// s.stacks may grow while iterating as nested stacks are discovered
+	for i := 0; i < len(s.stacks); i++ {
+		stack := s.stacks[i]
+		events, err := s.newEventsFor(stack.stackID)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			newEvents = append(newEvents, taggedEvent{tag: stack.tag, event: e})
+
+			physicalID := aws.StringValue(e.PhysicalResourceId)
+			if aws.StringValue(e.ResourceType) == "AWS::CloudFormation::Stack" && physicalID != "" && physicalID != stack.stackID && !s.isTracked(physicalID) {
+				s.stacks = append(s.stacks, &trackedStack{stackID: physicalID, tag: aws.StringValue(e.LogicalResourceId)})
+			}
+		}
+	}
+
+	sort.Stable(taggedEventsByTime(newEvents))
+
+	w := tabwriter.NewWriter(s.out, 0, 8, 2, ' ', 0)
+	for _, e := range newEvents {
+		fmt.Fprintln(w, FormatStackEvent(e.tag, e.event))
+	}
+	return w.Flush()
+}
+
+// newEventsFor returns events of the stack which are not seen yet, newest first
+func (s *StackEventStreamer) newEventsFor(stackID string) ([]*cloudformation.StackEvent, error) {
+	events := []*cloudformation.StackEvent{}
+	var nextToken *string
+	for {
+		resp, err := s.cfSvc.DescribeStackEvents(&cloudformation.DescribeStackEventsInput{
+			StackName: aws.String(stackID),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe events of stack %s: %v", stackID, err)
+		}
+		for _, e := range resp.StackEvents {
+			id := aws.StringValue(e.EventId)
+			// Events are returned newest first. Once we have reached an already seen or old event, the rest are also
+			if s.seen[id] || aws.TimeValue(e.Timestamp).Before(s.since) {
+				return events, nil
+			}
+			s.seen[id] = true
+			events = append(events, e)
+		}
+		if aws.StringValue(resp.NextToken) == "" {
+			return events, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+func FormatStackEvent(tag string, e *cloudformation.StackEvent) string {
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
+		aws.TimeValue(e.Timestamp).UTC().Format(time.RFC3339),
+		tag,
+		aws.StringValue(e.LogicalResourceId),
+		aws.StringValue(e.ResourceType),
+		aws.StringValue(e.ResourceStatus),
+		aws.StringValue(e.ResourceStatusReason),
+	)
+}
+
+func (c *Provisioner) newStackEventStreamer(cfSvc StackEventsService, stackID string) *StackEventStreamer {
+	// Allow a small clock skew between the local machine and AWS
+	since := time.Now().Add(-1 * time.Minute)
+	return NewStackEventStreamer(cfSvc, stackID, c.stackName, since, stdout)
+}
+
+func pollStackEvents(streamer *StackEventStreamer) {
+	if err := streamer.Poll(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: %v\n", err)
+	}
+}
+
+type taggedEvent struct {
+	tag   string
+	event *cloudformation.StackEvent
+}
+
+type taggedEventsByTime []taggedEvent
+
+func (s taggedEventsByTime) Len() int      { return len(s) }
+func (s taggedEventsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s taggedEventsByTime) Less(i, j int) bool {
+	return aws.TimeValue(s[i].event.Timestamp).Before(aws.TimeValue(s[j].event.Timestamp))
+}
@@ -0,0 +1,94 @@
+package cfnstack
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dummyStackEventsService struct {
+	// events are keyed by stack ids and ordered newest first as DescribeStackEvents does
+	events map[string][]*cloudformation.StackEvent
+}
+
+func (s *dummyStackEventsService) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	events, ok := s.events[*input.StackName]
+	if !ok {
+		return nil, fmt.Errorf("stack %s not found", *input.StackName)
+	}
+	// Return one event per page to exercise pagination
+	start := 0
+	if input.NextToken != nil {
+		fmt.Sscanf(*input.NextToken, "%d", &start)
+	}
+	if start >= len(events) {
+		return &cloudformation.DescribeStackEventsOutput{}, nil
+	}
+	out := &cloudformation.DescribeStackEventsOutput{
+		StackEvents: events[start : start+1],
+	}
+	if start+1 < len(events) {
+		out.NextToken = aws.String(fmt.Sprintf("%d", start+1))
+	}
+	return out, nil
+}
+
+func (s *dummyStackEventsService) emit(stackID string, e *cloudformation.StackEvent) {
+	s.events[stackID] = append([]*cloudformation.StackEvent{e}, s.events[stackID]...)
+}
+
+func stackEvent(id string, at time.Time, logicalID string, resourceType string, physicalID string, status string) *cloudformation.StackEvent {
+	return &cloudformation.StackEvent{
+		EventId:            aws.String(id),
+		Timestamp:          aws.Time(at),
+		LogicalResourceId:  aws.String(logicalID),
+		ResourceType:       aws.String(resourceType),
+		PhysicalResourceId: aws.String(physicalID),
+		ResourceStatus:     aws.String(status),
+	}
+}
+
+func TestStackEventStreamer(t *testing.T) {
+	start := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	cfSvc := &dummyStackEventsService{events: map[string][]*cloudformation.StackEvent{}}
+	cfSvc.emit("root", stackEvent("old", start.Add(-1*time.Hour), "mycluster", "AWS::CloudFormation::Stack", "root", "UPDATE_COMPLETE"))
+	cfSvc.emit("root", stackEvent("r1", start.Add(1*time.Second), "mycluster", "AWS::CloudFormation::Stack", "root", "CREATE_IN_PROGRESS"))
+	cfSvc.emit("root", stackEvent("r2", start.Add(2*time.Second), "Controlplane", "AWS::CloudFormation::Stack", "cp", "CREATE_IN_PROGRESS"))
+	cfSvc.events["cp"] = []*cloudformation.StackEvent{}
+	cfSvc.emit("cp", stackEvent("c1", start.Add(3*time.Second), "InstanceEtcd0", "AWS::EC2::Instance", "", "CREATE_IN_PROGRESS"))
+
+	out := new(bytes.Buffer)
+	streamer := NewStackEventStreamer(cfSvc, "root", "mycluster", start, out)
+
+	if err := streamer.Poll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines but got %d: %s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], "mycluster") || !strings.Contains(lines[1], "Controlplane") {
+		t.Errorf("events are not ordered or tagged as expected: %s", out.String())
+	}
+	if !strings.HasPrefix(lines[2], "2017-03-01T00:00:03Z") || !strings.Contains(lines[2], "InstanceEtcd0") || !strings.Contains(lines[2], "Controlplane") {
+		t.Errorf("expected an event from the nested stack tagged with its logical id, but got: %s", lines[2])
+	}
+
+	out.Reset()
+	cfSvc.emit("cp", stackEvent("c2", start.Add(4*time.Second), "InstanceEtcd0", "AWS::EC2::Instance", "i-1234", "CREATE_COMPLETE"))
+
+	if err := streamer.Poll(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines = strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], "CREATE_COMPLETE") {
+		t.Errorf("expected only the new event to be printed, but got: %s", out.String())
+	}
+}
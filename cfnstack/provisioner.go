@@ -1,6 +1,7 @@
 package cfnstack
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go/aws"
@@ -9,7 +10,6 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/coreos/kube-aws/model"
 	"strings"
-	"time"
 )
 
 type Provisioner struct {
@@ -19,6 +19,8 @@ type Provisioner struct {
 	session         *session.Session
 	s3URI           string
 	region          model.Region
+	s3Options       model.S3
+	ctx             context.Context
 }
 
 func NewProvisioner(name string, stackTags map[string]string, s3URI string, region model.Region, stackPolicyBody string, session *session.Session) *Provisioner {
@@ -38,45 +40,14 @@ func (c *Provisioner) uploadFile(s3Svc S3ObjectPutterService, content string, fi
 	if err != nil {
 		return "", err
 	}
-	bucket := loc.Bucket
-	key := loc.Key
 
-	contentLength := int64(len(content))
-	body := strings.NewReader(content)
-
-	_, err = s3Svc.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(bucket),
-		Key:           aws.String(key),
-		Body:          body,
-		ContentLength: aws.Int64(contentLength),
-		ContentType:   aws.String("application/json"),
-	})
-
-	if err != nil {
+	if err := c.uploadAsset(s3Svc, Asset{AssetLocation: *loc, Content: content}); err != nil {
 		return "", err
 	}
 
 	return loc.URL(), nil
 }
 
-func (c *Provisioner) uploadAsset(s3Svc S3ObjectPutterService, asset Asset) error {
-	bucket := asset.Bucket
-	key := asset.Key
-	content := asset.Content
-	contentLength := int64(len(content))
-	body := strings.NewReader(content)
-
-	_, err := s3Svc.PutObject(&s3.PutObjectInput{
-		Bucket:        aws.String(bucket),
-		Key:           aws.String(key),
-		Body:          body,
-		ContentLength: aws.Int64(contentLength),
-		ContentType:   aws.String("application/json"),
-	})
-
-	return err
-}
-
 func (c *Provisioner) uploadStackAssets(s3Svc S3ObjectPutterService, stackTemplate string, cloudConfigs map[string]string) (*string, error) {
 	templateURL, err := c.uploadFile(s3Svc, stackTemplate, "stack.json")
 	if err != nil {
@@ -92,16 +63,6 @@ func (c *Provisioner) uploadStackAssets(s3Svc S3ObjectPutterService, stackTempla
 	return &templateURL, nil
 }
 
-func (c *Provisioner) UploadAssets(s3Svc S3ObjectPutterService, assets Assets) error {
-	for _, a := range assets.AsMap() {
-		err := c.uploadAsset(s3Svc, a)
-		if err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func (c *Provisioner) CreateStack(cfSvc CreationService, s3Svc S3ObjectPutterService, stackTemplate string, cloudConfigs map[string]string) (*cloudformation.CreateStackOutput, error) {
 	templateURL, uploadErr := c.uploadStackAssets(s3Svc, stackTemplate, cloudConfigs)
 
@@ -148,12 +109,15 @@ func (c *Provisioner) waitUntilStackGetsCreated(cfSvc CRUDService, resp *cloudfo
 	req := cloudformation.DescribeStacksInput{
 		StackName: resp.StackId,
 	}
+	streamer := c.newStackEventStreamer(cfSvc, aws.StringValue(resp.StackId))
+	backoff := newBackoff()
 
 	for {
 		resp, err := cfSvc.DescribeStacks(&req)
 		if err != nil {
 			return err
 		}
+		pollStackEvents(streamer)
 		if len(resp.Stacks) == 0 {
 			return fmt.Errorf("stack not found")
 		}
@@ -161,7 +125,7 @@ func (c *Provisioner) waitUntilStackGetsCreated(cfSvc CRUDService, resp *cloudfo
 		switch statusString {
 		case cloudformation.ResourceStatusCreateComplete:
 			return nil
-		case cloudformation.ResourceStatusCreateFailed:
+		case cloudformation.ResourceStatusCreateFailed, cloudformation.StackStatusRollbackComplete, cloudformation.StackStatusRollbackFailed:
 			errMsg := fmt.Sprintf(
 				"Stack creation failed: %s : %s",
 				statusString,
@@ -178,8 +142,16 @@ func (c *Provisioner) waitUntilStackGetsCreated(cfSvc CRUDService, resp *cloudfo
 			}
 			errMsg = errMsg + strings.Join(StackEventErrMsgs(stackEventsOutput.StackEvents), "\n")
 			return errors.New(errMsg)
-		case cloudformation.ResourceStatusCreateInProgress:
-			time.Sleep(3 * time.Second)
+		case cloudformation.ResourceStatusCreateInProgress, cloudformation.StackStatusRollbackInProgress:
+			if err := backoff.sleep(c.context()); err != nil {
+				return &WaitInterruptedError{
+					StackID:     aws.StringValue(req.StackName),
+					StackName:   c.stackName,
+					Operation:   OperationCreate,
+					StackStatus: statusString,
+					Err:         err,
+				}
+			}
 			continue
 		default:
 			return fmt.Errorf("unexpected stack status: %s", statusString)
@@ -260,11 +232,15 @@ func (c *Provisioner) waitUntilStackGetsUpdated(cfSvc CRUDService, updateOutput
 	req := cloudformation.DescribeStacksInput{
 		StackName: updateOutput.StackId,
 	}
+	streamer := c.newStackEventStreamer(cfSvc, aws.StringValue(updateOutput.StackId))
+	backoff := newBackoff()
+
 	for {
 		resp, err := cfSvc.DescribeStacks(&req)
 		if err != nil {
 			return "", err
 		}
+		pollStackEvents(streamer)
 		if len(resp.Stacks) == 0 {
 			return "", fmt.Errorf("stack not found")
 		}
@@ -275,8 +251,19 @@ func (c *Provisioner) waitUntilStackGetsUpdated(cfSvc CRUDService, updateOutput
 		case cloudformation.ResourceStatusUpdateFailed, cloudformation.StackStatusUpdateRollbackComplete, cloudformation.StackStatusUpdateRollbackFailed:
 			errMsg := fmt.Sprintf("Stack status: %s : %s", statusString, aws.StringValue(resp.Stacks[0].StackStatusReason))
 			return "", errors.New(errMsg)
-		case cloudformation.ResourceStatusUpdateInProgress, cloudformation.StackStatusUpdateCompleteCleanupInProgress:
-			time.Sleep(3 * time.Second)
+		case cloudformation.ResourceStatusUpdateInProgress,
+			cloudformation.StackStatusUpdateCompleteCleanupInProgress,
+			cloudformation.StackStatusUpdateRollbackInProgress,
+			cloudformation.StackStatusUpdateRollbackCompleteCleanupInProgress:
+			if err := backoff.sleep(c.context()); err != nil {
+				return "", &WaitInterruptedError{
+					StackID:     aws.StringValue(req.StackName),
+					StackName:   c.stackName,
+					Operation:   OperationUpdate,
+					StackStatus: statusString,
+					Err:         err,
+				}
+			}
 			continue
 		default:
 			return "", fmt.Errorf("unexpected stack status: %s", statusString)
@@ -305,24 +292,3 @@ func (c *Provisioner) Validate(stackBody string) (string, error) {
 
 	return validationReport.String(), nil
 }
-
-type Destroyer struct {
-	stackName string
-	session   *session.Session
-}
-
-func NewDestroyer(stackName string, session *session.Session) *Destroyer {
-	return &Destroyer{
-		stackName: stackName,
-		session:   session,
-	}
-}
-
-func (c *Destroyer) Destroy() error {
-	cfSvc := cloudformation.New(c.session)
-	dreq := &cloudformation.DeleteStackInput{
-		StackName: aws.String(c.stackName),
-	}
-	_, err := cfSvc.DeleteStack(dreq)
-	return err
-}
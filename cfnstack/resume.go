@@ -0,0 +1,78 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+// ResumeService is the set of CloudFormation APIs required to wait for any kind of stack operation
+type ResumeService interface {
+	CreateStack(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error)
+	UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error)
+	DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error)
+	DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error)
+	DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error)
+	EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error)
+	ListStackResources(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error)
+}
+
+// ResumedOperation is the stack operation found in progress and waited for by ResumeWaiting
+type ResumedOperation struct {
+	// Operation is one of OperationCreate, OperationUpdate or OperationDelete, or empty when no operation was in progress
+	Operation string
+	StackID   string
+	// StackStatus is the status of the stack when ResumeWaiting has started waiting
+	StackStatus string
+	// Report is the report of the stack update, if any
+	Report string
+}
+
+// InProgressOperation returns the operation of which the stack status indicates that it is in progress, or an empty string
+// when the stack is not changing.
+// Rollbacks are considered parts of the operations which triggered them
+func InProgressOperation(stackStatus string) string {
+	switch stackStatus {
+	case cloudformation.StackStatusCreateInProgress, cloudformation.StackStatusRollbackInProgress:
+		return OperationCreate
+	case cloudformation.StackStatusUpdateInProgress,
+		cloudformation.StackStatusUpdateCompleteCleanupInProgress,
+		cloudformation.StackStatusUpdateRollbackInProgress,
+		cloudformation.StackStatusUpdateRollbackCompleteCleanupInProgress:
+		return OperationUpdate
+	case cloudformation.StackStatusDeleteInProgress:
+		return OperationDelete
+	}
+	return ""
+}
+
+// ResumeWaiting finds the operation in progress on the stack and waits for it in the same way as the command which has
+// started the operation does
+func (c *Provisioner) ResumeWaiting(cfSvc ResumeService) (*ResumedOperation, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(c.stackName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing stack %s: %v", c.stackName, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return nil, fmt.Errorf("could not find a stack with name %s", c.stackName)
+	}
+	stack := resp.Stacks[0]
+
+	op := &ResumedOperation{
+		Operation:   InProgressOperation(aws.StringValue(stack.StackStatus)),
+		StackID:     aws.StringValue(stack.StackId),
+		StackStatus: aws.StringValue(stack.StackStatus),
+	}
+
+	switch op.Operation {
+	case OperationCreate:
+		err = c.waitUntilStackGetsCreated(cfSvc, &cloudformation.CreateStackOutput{StackId: stack.StackId})
+	case OperationUpdate:
+		op.Report, err = c.waitUntilStackGetsUpdated(cfSvc, &cloudformation.UpdateStackOutput{StackId: stack.StackId})
+	case OperationDelete:
+		err = waitUntilStackGetsDeleted(c.context(), cfSvc, op.StackID, c.stackName)
+	}
+	return op, err
+}
@@ -0,0 +1,116 @@
+package cfnstack
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/model"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+type dummyResumeService struct {
+	dummyDestroyService
+	// statuses are returned by DescribeStacks one by one. The last one is repeated
+	statusSequence []string
+	lastStatus     string
+}
+
+func (s *dummyResumeService) CreateStack(*cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	panic("unexpected call to CreateStack")
+}
+
+func (s *dummyResumeService) UpdateStack(*cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	panic("unexpected call to UpdateStack")
+}
+
+func (s *dummyResumeService) EstimateTemplateCost(*cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+	panic("unexpected call to EstimateTemplateCost")
+}
+
+func (s *dummyResumeService) DescribeStackEvents(*cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	return &cloudformation.DescribeStackEventsOutput{}, nil
+}
+
+func (s *dummyResumeService) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	status := s.statusSequence[0]
+	if len(s.statusSequence) > 1 {
+		s.statusSequence = s.statusSequence[1:]
+	}
+	s.lastStatus = status
+	return &cloudformation.DescribeStacksOutput{
+		Stacks: []*cloudformation.Stack{
+			{StackId: aws.String("arn:mycluster"), StackName: aws.String("mycluster"), StackStatus: aws.String(status)},
+		},
+	}, nil
+}
+
+func TestResumeWaiting(t *testing.T) {
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = ioutil.Discard
+	InitialPollInterval = time.Millisecond
+	defer func() { InitialPollInterval = 3 * time.Second }()
+
+	testCases := []struct {
+		statuses  []string
+		operation string
+		// failedWith is the stack status which the operation is expected to fail with, if any
+		failedWith string
+	}{
+		{
+			statuses:  []string{"CREATE_IN_PROGRESS", "CREATE_IN_PROGRESS", "CREATE_COMPLETE"},
+			operation: OperationCreate,
+		},
+		{
+			statuses:  []string{"UPDATE_IN_PROGRESS", "UPDATE_COMPLETE_CLEANUP_IN_PROGRESS", "UPDATE_COMPLETE"},
+			operation: OperationUpdate,
+		},
+		{
+			statuses:   []string{"UPDATE_ROLLBACK_IN_PROGRESS", "UPDATE_ROLLBACK_COMPLETE_CLEANUP_IN_PROGRESS", "UPDATE_ROLLBACK_COMPLETE"},
+			operation:  OperationUpdate,
+			failedWith: "UPDATE_ROLLBACK_COMPLETE",
+		},
+		{
+			statuses:   []string{"UPDATE_ROLLBACK_COMPLETE_CLEANUP_IN_PROGRESS", "UPDATE_ROLLBACK_COMPLETE"},
+			operation:  OperationUpdate,
+			failedWith: "UPDATE_ROLLBACK_COMPLETE",
+		},
+		{
+			statuses:   []string{"ROLLBACK_IN_PROGRESS", "ROLLBACK_IN_PROGRESS", "ROLLBACK_COMPLETE"},
+			operation:  OperationCreate,
+			failedWith: "ROLLBACK_COMPLETE",
+		},
+		{
+			statuses:  []string{"DELETE_IN_PROGRESS", "DELETE_COMPLETE"},
+			operation: OperationDelete,
+		},
+		{
+			statuses:  []string{"UPDATE_COMPLETE"},
+			operation: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		cfSvc := &dummyResumeService{statusSequence: tc.statuses}
+		p := NewProvisioner("mycluster", map[string]string{}, "s3://mybucket", model.RegionForName("us-west-1"), "", nil)
+
+		op, err := p.ResumeWaiting(cfSvc)
+
+		if tc.failedWith != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.failedWith) {
+				t.Errorf("expected waiting for %v to fail with %s, but it didn't: %v", tc.statuses, tc.failedWith, err)
+			}
+			if cfSvc.lastStatus != tc.failedWith {
+				t.Errorf("expected waiting for %v until %s, but it stopped at %s", tc.statuses, tc.failedWith, cfSvc.lastStatus)
+			}
+		}
+		if tc.failedWith == "" && err != nil {
+			t.Errorf("unexpected error waiting for %v: %v", tc.statuses, err)
+		}
+		if op == nil || op.Operation != tc.operation || op.StackStatus != tc.statuses[0] || op.StackID != "arn:mycluster" {
+			t.Errorf("unexpected operation resumed for %v: %+v", tc.statuses, op)
+		}
+	}
+}
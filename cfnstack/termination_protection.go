@@ -0,0 +1,89 @@
+package cfnstack
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
+)
+
+// The vendored aws-sdk-go predates the UpdateTerminationProtection API. These mirror the input and the output of the API
+// so that the request can be built and sent by the CloudFormation client as usual
+type updateTerminationProtectionInput struct {
+	_ struct{} `type:"structure"`
+
+	EnableTerminationProtection *bool `type:"boolean" required:"true"`
+
+	StackName *string `min:"1" type:"string" required:"true"`
+}
+
+type updateTerminationProtectionOutput struct {
+	_ struct{} `type:"structure"`
+
+	StackId *string `type:"string"`
+}
+
+// describeStacksOutput is the output of DescribeStacks which reports whether termination protection is enabled
+type describeStacksOutput struct {
+	_ struct{} `type:"structure"`
+
+	Stacks []*stackWithTerminationProtection `type:"list"`
+}
+
+type stackWithTerminationProtection struct {
+	_ struct{} `type:"structure"`
+
+	EnableTerminationProtection *bool `type:"boolean"`
+
+	StackName *string `type:"string" required:"true"`
+}
+
+// UpdateTerminationProtection enables or disables termination protection of the stack, which prevents the stack and its
+// nested stacks from being deleted
+func UpdateTerminationProtection(cfSvc *cloudformation.CloudFormation, stackName string, enabled bool) error {
+	if err := updateTerminationProtectionRequest(cfSvc, stackName, enabled).Send(); err != nil {
+		return fmt.Errorf("failed to update termination protection of stack %s: %v", stackName, err)
+	}
+	return nil
+}
+
+func updateTerminationProtectionRequest(cfSvc *cloudformation.CloudFormation, stackName string, enabled bool) *request.Request {
+	op := &request.Operation{
+		Name:       "UpdateTerminationProtection",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	input := &updateTerminationProtectionInput{
+		EnableTerminationProtection: aws.Bool(enabled),
+		StackName:                   aws.String(stackName),
+	}
+	return cfSvc.NewRequest(op, input, &updateTerminationProtectionOutput{})
+}
+
+// TerminationProtectionEnabled returns true when termination protection of the stack is enabled
+func TerminationProtectionEnabled(cfSvc *cloudformation.CloudFormation, stackName string) (bool, error) {
+	req, out := describeStacksRequest(cfSvc, stackName)
+	if err := req.Send(); err != nil {
+		return false, fmt.Errorf("failed to describe termination protection of stack %s: %v", stackName, err)
+	}
+	if len(out.Stacks) != 1 {
+		return false, fmt.Errorf("expected exactly one stack named %s but found %d", stackName, len(out.Stacks))
+	}
+	return aws.BoolValue(out.Stacks[0].EnableTerminationProtection), nil
+}
+
+func describeStacksRequest(cfSvc *cloudformation.CloudFormation, stackName string) (*request.Request, *describeStacksOutput) {
+	op := &request.Operation{
+		Name:       "DescribeStacks",
+		HTTPMethod: "POST",
+		HTTPPath:   "/",
+	}
+	out := &describeStacksOutput{}
+	return cfSvc.NewRequest(op, &cloudformation.DescribeStacksInput{StackName: aws.String(stackName)}, out), out
+}
+
+// IsTerminationProtectionError returns true when the error is returned from DeleteStack due to termination protection
+func IsTerminationProtectionError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "TerminationProtection is enabled")
+}
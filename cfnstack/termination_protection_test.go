@@ -0,0 +1,67 @@
+package cfnstack
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestUpdateTerminationProtectionRequest(t *testing.T) {
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-west-1").
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := updateTerminationProtectionRequest(cloudformation.New(sess), "mycluster", true)
+	if err := req.Build(); err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	body, err := ioutil.ReadAll(req.GetBody())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "Action=UpdateTerminationProtection&EnableTerminationProtection=true&StackName=mycluster&Version=2010-05-15"
+	if string(body) != expected {
+		t.Errorf("unexpected request body: expected %s, but was %s", expected, string(body))
+	}
+}
+
+func TestDescribeStacksRequestReadsTerminationProtection(t *testing.T) {
+	sess, err := session.NewSession(aws.NewConfig().
+		WithRegion("us-west-1").
+		WithCredentials(credentials.NewStaticCredentials("id", "secret", "")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req, out := describeStacksRequest(cloudformation.New(sess), "mycluster")
+	response := `<DescribeStacksResponse xmlns="http://cloudformation.amazonaws.com/doc/2010-05-15/">
+  <DescribeStacksResult>
+    <Stacks>
+      <member>
+        <StackName>mycluster</StackName>
+        <StackStatus>CREATE_COMPLETE</StackStatus>
+        <EnableTerminationProtection>true</EnableTerminationProtection>
+      </member>
+    </Stacks>
+  </DescribeStacksResult>
+</DescribeStacksResponse>`
+	req.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(strings.NewReader(response))}
+	req.Handlers.Unmarshal.Run(req)
+	if req.Error != nil {
+		t.Fatalf("failed to unmarshal response: %v", req.Error)
+	}
+
+	if len(out.Stacks) != 1 || !aws.BoolValue(out.Stacks[0].EnableTerminationProtection) {
+		t.Errorf("expected termination protection to be read as enabled, but was %+v", out.Stacks)
+	}
+}
@@ -0,0 +1,159 @@
+package cfnstack
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/model"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maxConcurrentUploads is the number of assets uploaded to S3 at once
+var maxConcurrentUploads = 8
+
+// S3ObjectRequesterService is implemented by *s3.S3. When the S3 service passed to UploadAssets implements it,
+// objects are uploaded with Content-MD5 so that S3 rejects corrupted uploads
+type S3ObjectRequesterService interface {
+	PutObjectRequest(input *s3.PutObjectInput) (*request.Request, *s3.PutObjectOutput)
+}
+
+// S3ObjectHeaderService is implemented by *s3.S3. When the S3 service passed to UploadAssets implements it,
+// assets identical to the objects already in S3 are not uploaded again
+type S3ObjectHeaderService interface {
+	HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error)
+}
+
+// WithS3Options returns a copy of the provisioner which uploads assets with the server-side encryption and tags
+func (c *Provisioner) WithS3Options(options model.S3) *Provisioner {
+	p := *c
+	p.s3Options = options
+	return &p
+}
+
+func (c *Provisioner) UploadAssets(s3Svc S3ObjectPutterService, assets Assets) error {
+	m := assets.AsMap()
+
+	// Upload in a stable order so that a failure is reported consistently
+	keys := []string{}
+	byKey := map[string]Asset{}
+	for _, a := range m {
+		keys = append(keys, a.Key)
+		byKey[a.Key] = a
+	}
+	sort.Strings(keys)
+
+	queue := make(chan Asset, len(keys))
+	for _, k := range keys {
+		queue <- byKey[k]
+	}
+	close(queue)
+
+	workers := maxConcurrentUploads
+	if workers > len(keys) {
+		workers = len(keys)
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	errs := map[string]error{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range queue {
+				if err := c.uploadAsset(s3Svc, a); err != nil {
+					mu.Lock()
+					errs[a.Key] = err
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := []string{}
+	for _, k := range keys {
+		if err, ok := errs[k]; ok {
+			msgs = append(msgs, fmt.Sprintf("s3://%s/%s: %v", byKey[k].Bucket, k, err))
+		}
+	}
+	return fmt.Errorf("failed to upload %d asset(s): %s", len(msgs), strings.Join(msgs, ", "))
+}
+
+func (c *Provisioner) uploadAsset(s3Svc S3ObjectPutterService, asset Asset) error {
+	content := asset.Content
+	sum := md5.Sum([]byte(content))
+
+	input := &s3.PutObjectInput{
+		Bucket:        aws.String(asset.Bucket),
+		Key:           aws.String(asset.Key),
+		Body:          strings.NewReader(content),
+		ContentLength: aws.Int64(int64(len(content))),
+		ContentType:   aws.String(contentTypeFor(asset.Key, content)),
+	}
+	if c.s3Options.ServerSideEncryption != "" {
+		input.ServerSideEncryption = aws.String(c.s3Options.ServerSideEncryption)
+	}
+	if c.s3Options.KMSKeyARN != "" {
+		input.SSEKMSKeyId = aws.String(c.s3Options.KMSKeyARN)
+	}
+	if tagging := c.s3Options.Tagging(); tagging != "" {
+		input.Tagging = aws.String(tagging)
+	}
+
+	if headSvc, ok := s3Svc.(S3ObjectHeaderService); ok && c.uploaded(headSvc, input, sum) {
+		return nil
+	}
+
+	reqSvc, ok := s3Svc.(S3ObjectRequesterService)
+	if !ok {
+		_, err := s3Svc.PutObject(input)
+		return err
+	}
+	req, _ := reqSvc.PutObjectRequest(input)
+	req.HTTPRequest.Header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	return req.Send()
+}
+
+// uploaded returns true when the object in S3 has the same content, content type and encryption as the one to be uploaded.
+// Objects encrypted with SSE-KMS are always uploaded as their ETags are not MD5 digests of their contents.
+// Tags are not compared, so changing `s3.tags` takes effect on objects uploaded afterwards
+func (c *Provisioner) uploaded(s3Svc S3ObjectHeaderService, input *s3.PutObjectInput, sum [md5.Size]byte) bool {
+	if c.s3Options.ServerSideEncryption == model.S3ServerSideEncryptionKMS {
+		return false
+	}
+	head, err := s3Svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: input.Bucket,
+		Key:    input.Key,
+	})
+	if err != nil {
+		return false
+	}
+	return strings.Trim(aws.StringValue(head.ETag), `"`) == hex.EncodeToString(sum[:]) &&
+		aws.StringValue(head.ContentType) == aws.StringValue(input.ContentType) &&
+		aws.StringValue(head.ServerSideEncryption) == aws.StringValue(input.ServerSideEncryption)
+}
+
+// contentTypeFor returns the MIME type of the asset stored in the key
+func contentTypeFor(key string, content string) string {
+	switch {
+	case filepath.Ext(key) == ".json":
+		return "application/json"
+	case strings.HasPrefix(content, "#cloud-config"):
+		return "text/cloud-config"
+	case strings.HasPrefix(content, "#!"):
+		return "text/x-shellscript"
+	default:
+		return "text/plain"
+	}
+}
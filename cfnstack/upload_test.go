@@ -0,0 +1,136 @@
+package cfnstack
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/model"
+	"sync"
+	"testing"
+)
+
+type recordingS3Service struct {
+	mu      sync.Mutex
+	puts    map[string]*s3.PutObjectInput
+	heads   map[string]*s3.HeadObjectOutput
+	failKey string
+}
+
+func newRecordingS3Service() *recordingS3Service {
+	return &recordingS3Service{
+		puts:  map[string]*s3.PutObjectInput{},
+		heads: map[string]*s3.HeadObjectOutput{},
+	}
+}
+
+func (s *recordingS3Service) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if aws.StringValue(input.Key) == s.failKey {
+		return nil, fmt.Errorf("access denied")
+	}
+	s.puts[aws.StringValue(input.Key)] = input
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (s *recordingS3Service) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	head, ok := s.heads[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	return head, nil
+}
+
+func testAssets() Assets {
+	return NewAssetsBuilder("mystack", "s3://mybucket/mydir", model.RegionForName("us-west-1")).
+		Add("stack.json", "{}").
+		Add("userdata-controller", "#cloud-config\n").
+		Add("userdata-worker", "#cloud-config\nfoo: bar\n").
+		Build()
+}
+
+func TestUploadAssets(t *testing.T) {
+	s3Svc := newRecordingS3Service()
+	p := NewProvisioner("mystack", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "", nil).
+		WithS3Options(model.S3{
+			ServerSideEncryption: "aws:kms",
+			KMSKeyARN:            "arn:aws:kms:us-west-1:123456789012:key/mykey",
+			Tags:                 map[string]string{"team": "a b", "env": "prod"},
+		})
+
+	if err := p.UploadAssets(s3Svc, testAssets()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedContentTypes := map[string]string{
+		"mydir/mystack/stack.json":          "application/json",
+		"mydir/mystack/userdata-controller": "text/cloud-config",
+		"mydir/mystack/userdata-worker":     "text/cloud-config",
+	}
+	if len(s3Svc.puts) != len(expectedContentTypes) {
+		t.Fatalf("expected %d uploads but got %d: %+v", len(expectedContentTypes), len(s3Svc.puts), s3Svc.puts)
+	}
+	for key, contentType := range expectedContentTypes {
+		put, ok := s3Svc.puts[key]
+		if !ok {
+			t.Errorf("expected %s to be uploaded but it was not", key)
+			continue
+		}
+		if aws.StringValue(put.ContentType) != contentType {
+			t.Errorf("unexpected content type of %s: expected=%s, actual=%s", key, contentType, aws.StringValue(put.ContentType))
+		}
+		if aws.StringValue(put.ServerSideEncryption) != "aws:kms" || aws.StringValue(put.SSEKMSKeyId) != "arn:aws:kms:us-west-1:123456789012:key/mykey" {
+			t.Errorf("expected %s to be encrypted with the kms key but it was not: %+v", key, put)
+		}
+		if aws.StringValue(put.Tagging) != "env=prod&team=a+b" {
+			t.Errorf("unexpected tagging of %s: %s", key, aws.StringValue(put.Tagging))
+		}
+	}
+}
+
+func TestUploadAssetsSkipsUnchangedObjects(t *testing.T) {
+	s3Svc := newRecordingS3Service()
+	sum := md5.Sum([]byte("{}"))
+	s3Svc.heads["mydir/mystack/stack.json"] = &s3.HeadObjectOutput{
+		ETag:        aws.String(fmt.Sprintf(`"%s"`, hex.EncodeToString(sum[:]))),
+		ContentType: aws.String("application/json"),
+	}
+	s3Svc.heads["mydir/mystack/userdata-controller"] = &s3.HeadObjectOutput{
+		ETag:        aws.String(`"0123456789abcdef0123456789abcdef"`),
+		ContentType: aws.String("text/cloud-config"),
+	}
+	p := NewProvisioner("mystack", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "", nil)
+
+	if err := p.UploadAssets(s3Svc, testAssets()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok := s3Svc.puts["mydir/mystack/stack.json"]; ok {
+		t.Errorf("expected stack.json not to be uploaded as its etag matched")
+	}
+	if len(s3Svc.puts) != 2 {
+		t.Errorf("expected 2 uploads but got %d: %+v", len(s3Svc.puts), s3Svc.puts)
+	}
+}
+
+func TestUploadAssetsReportsFailures(t *testing.T) {
+	s3Svc := newRecordingS3Service()
+	s3Svc.failKey = "mydir/mystack/userdata-worker"
+	p := NewProvisioner("mystack", map[string]string{}, "s3://mybucket/mydir", model.RegionForName("us-west-1"), "", nil)
+
+	err := p.UploadAssets(s3Svc, testAssets())
+	if err == nil {
+		t.Fatalf("expected an error but got none")
+	}
+	expected := "failed to upload 1 asset(s): s3://mybucket/mydir/mystack/userdata-worker: access denied"
+	if err.Error() != expected {
+		t.Errorf("unexpected error: expected=%s, actual=%s", expected, err.Error())
+	}
+	if len(s3Svc.puts) != 2 {
+		t.Errorf("expected the other assets to be uploaded but got %d uploads", len(s3Svc.puts))
+	}
+}
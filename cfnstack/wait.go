@@ -0,0 +1,95 @@
+package cfnstack
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+var (
+	// InitialPollInterval and MaxPollInterval bound intervals between polls of in-progress stack operations.
+	// Tests against a fake backend shorten them
+	InitialPollInterval = 3 * time.Second
+	MaxPollInterval     = 30 * time.Second
+)
+
+// backoff returns intervals between polls growing exponentially from InitialPollInterval up to MaxPollInterval
+type backoff struct {
+	next time.Duration
+}
+
+func newBackoff() *backoff {
+	return &backoff{next: InitialPollInterval}
+}
+
+func (b *backoff) interval() time.Duration {
+	d := b.next
+	b.next = b.next * 3 / 2
+	if b.next > MaxPollInterval {
+		b.next = MaxPollInterval
+	}
+	return d
+}
+
+// sleep waits for the next interval or until the context is done, whichever comes first.
+// ctx.Err() is returned in the latter case
+func (b *backoff) sleep(ctx context.Context) error {
+	t := time.NewTimer(b.interval())
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// WaitInterruptedError is returned when kube-aws stopped waiting for a stack operation due to a timeout or an interrupt.
+// The operation itself keeps going on in CloudFormation
+type WaitInterruptedError struct {
+	StackID   string
+	StackName string
+	// Operation is one of OperationCreate, OperationUpdate or OperationDelete
+	Operation string
+	// StackStatus is the status of the stack seen last time
+	StackStatus string
+	// S3URI is the S3 location given to kube-aws, which is required by `kube-aws wait` to record a revision
+	S3URI string
+	Err   error
+}
+
+func (e *WaitInterruptedError) Error() string {
+	reason := "interrupted"
+	if e.Err == context.DeadlineExceeded {
+		reason = "timed out"
+	}
+	return fmt.Sprintf("%s while waiting for stack %s to %s. The stack status was %s", reason, e.StackName, e.Operation, e.StackStatus)
+}
+
+// ResumeCommand returns a command to resume waiting for the operation
+func (e *WaitInterruptedError) ResumeCommand() string {
+	if e.S3URI == "" {
+		return "kube-aws wait"
+	}
+	return fmt.Sprintf("kube-aws wait --s3-uri %s", e.S3URI)
+}
+
+func (c *Provisioner) context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// WithContext returns a copy of the provisioner which stops waiting for stack operations once the context is done
+func (c *Provisioner) WithContext(ctx context.Context) *Provisioner {
+	p := *c
+	p.ctx = ctx
+	return &p
+}
@@ -0,0 +1,72 @@
+package cfnstack
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff()
+
+	expected := []time.Duration{
+		3 * time.Second,
+		4500 * time.Millisecond,
+		6750 * time.Millisecond,
+		10125 * time.Millisecond,
+		15187500 * time.Microsecond,
+		22781250 * time.Microsecond,
+		30 * time.Second,
+		30 * time.Second,
+	}
+	for i, e := range expected {
+		if actual := b.interval(); actual != e {
+			t.Errorf("unexpected interval #%d: expected %v, but was %v", i, e, actual)
+		}
+	}
+}
+
+func TestWaitUntilStackGetsDeletedInterrupted(t *testing.T) {
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = ioutil.Discard
+
+	cfSvc := &dummyDestroyService{
+		statuses: map[string]string{"root": cloudformation.StackStatusDeleteInProgress},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := waitUntilStackGetsDeleted(ctx, cfSvc, "root", "mycluster")
+
+	interrupted, ok := err.(*WaitInterruptedError)
+	if !ok {
+		t.Fatalf("expected a WaitInterruptedError, but got: %v", err)
+	}
+	if interrupted.StackStatus != cloudformation.StackStatusDeleteInProgress || interrupted.Operation != OperationDelete {
+		t.Errorf("unexpected error: %+v", interrupted)
+	}
+	if !strings.HasPrefix(interrupted.Error(), "interrupted while waiting for stack mycluster to delete") {
+		t.Errorf("unexpected error message: %s", interrupted.Error())
+	}
+}
+
+func TestWaitInterruptedErrorOnTimeout(t *testing.T) {
+	err := &WaitInterruptedError{StackName: "mycluster", Operation: OperationCreate, StackStatus: "CREATE_IN_PROGRESS", Err: context.DeadlineExceeded}
+
+	if !strings.HasPrefix(err.Error(), "timed out while waiting for stack mycluster to create") {
+		t.Errorf("unexpected error message: %s", err.Error())
+	}
+	if err.ResumeCommand() != "kube-aws wait" {
+		t.Errorf("unexpected resume command: %s", err.ResumeCommand())
+	}
+
+	err.S3URI = "s3://mybucket/mydir"
+	if err.ResumeCommand() != "kube-aws wait --s3-uri s3://mybucket/mydir" {
+		t.Errorf("unexpected resume command: %s", err.ResumeCommand())
+	}
+}
@@ -1,27 +1,31 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	controlplane "github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root"
+	"github.com/coreos/kube-aws/core/root/config"
+	"github.com/coreos/kube-aws/pricing"
 	"github.com/spf13/cobra"
 	"strings"
 )
 
-//TODO this is a first step to calculate the stack cost
-//this command could scrap aws to print the total cost, rather just showing the link
-
 var (
 	cmdCalculator = &cobra.Command{
 		Use:          "calculator",
 		Short:        "Discovery the monthly cost of your cluster",
-		Long:         ``,
+		Long:         `Prints links to the AWS Simple Monthly Calculator for the root, control-plane and node pool stacks. With --offline, computes a monthly breakdown per component from cluster.yaml and a bundled price table instead, without calling AWS APIs.`,
 		RunE:         runCmdCalculator,
 		SilenceUsage: true,
 	}
 
 	calculatorOpts = struct {
-		awsDebug bool
-		s3URI    string
+		awsDebug   bool
+		offline    bool
+		s3URI      string
+		priceTable string
+		output     string
 	}{}
 )
 
@@ -29,9 +33,15 @@ func init() {
 	RootCmd.AddCommand(cmdCalculator)
 	cmdCalculator.Flags().BoolVar(&calculatorOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
 	cmdCalculator.Flags().StringVar(&calculatorOpts.s3URI, "s3-uri", "", "When your template is bigger than the cloudformation limit of 51200 bytes, upload the template to the specified location in S3. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdCalculator.Flags().BoolVar(&calculatorOpts.offline, "offline", false, "Estimate the monthly cost from a price table without calling AWS APIs")
+	cmdCalculator.Flags().StringVar(&calculatorOpts.priceTable, "price-table", "", "Path to a JSON price table used with --offline instead of the bundled one")
+	cmdCalculator.Flags().StringVarP(&calculatorOpts.output, "output", "o", "", "Output format of --offline. One of: json. Defaults to human-readable text")
 }
 
 func runCmdCalculator(cmd *cobra.Command, args []string) error {
+	if calculatorOpts.offline {
+		return runCmdCalculatorOffline()
+	}
 
 	if err := validateRequired(flag{"--s3-uri", calculatorOpts.s3URI}); err != nil {
 		return err
@@ -58,3 +68,50 @@ func runCmdCalculator(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func runCmdCalculatorOffline() error {
+	switch calculatorOpts.output {
+	case "", "json":
+	default:
+		return fmt.Errorf("Unsupported output format %q: must be json", calculatorOpts.output)
+	}
+
+	table := pricing.Default
+	if calculatorOpts.priceTable != "" {
+		t, err := pricing.TableFromFile(calculatorOpts.priceTable)
+		if err != nil {
+			return fmt.Errorf("Failed to load price table: %v", err)
+		}
+		table = t
+	}
+
+	// Loading the whole config looks up the latest CoreOS AMI over the network unless amiId is set
+	main, err := controlplane.ClusterFromFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+	if main.AmiId == "" {
+		return fmt.Errorf("`amiId` must be set in cluster.yaml to estimate the cost offline, as the latest CoreOS AMI is looked up over the network otherwise")
+	}
+
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	estimate, err := root.EstimateMonthlyCost(cfg, table)
+	if err != nil {
+		return fmt.Errorf("Failed to estimate monthly cost: %v", err)
+	}
+
+	if calculatorOpts.output == "json" {
+		out, err := json.MarshalIndent(estimate, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to marshal cost estimate: %v", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	fmt.Print(estimate.String())
+	return nil
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coreos/kube-aws/core/controlplane/config"
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	cmdCerts = &cobra.Command{
+		Use:   "certs",
+		Short: "Manage TLS certificates of the cluster",
+		Long:  ``,
+	}
+
+	cmdCertsRotate = &cobra.Command{
+		Use:          "rotate",
+		Short:        "Rotate TLS certificates and roll them out to nodes",
+		Long:         `Re-issues the certificates in ./credentials from the existing CA and updates the cluster so that etcd nodes, controller nodes and workers are replaced one by one. With --ca, a new CA is generated and rolled out in three updates: the new CA is trusted alongside the old one, certificates are re-issued by the new CA, and then the old CA is removed. An interrupted CA rotation is resumed by running the command again.`,
+		RunE:         runCmdCertsRotate,
+		SilenceUsage: true,
+	}
+
+	cmdCertsCheck = &cobra.Command{
+		Use:          "check",
+		Short:        "Check TLS certificates for expiry and misconfiguration",
+		Long:         `Prints the subject, SANs, issuer, serial and expiry of every certificate in ./credentials. Fails when any certificate expires within --expiry-window-days, isn't signed by a CA in ca.pem, or the apiserver certificate isn't valid for externalDNSName and the IP of the kubernetes service derived from serviceCIDR. No AWS API is called.`,
+		RunE:         runCmdCertsCheck,
+		SilenceUsage: true,
+	}
+
+	certsCheckOpts = struct {
+		expiryWindowDays int
+		output           string
+	}{}
+
+	certsRotateOpts = struct {
+		awsDebug bool
+		s3URI    string
+		rotation root.CertsRotationOptions
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdCerts)
+
+	cmdCerts.AddCommand(cmdCertsCheck)
+	cmdCertsCheck.Flags().IntVar(&certsCheckOpts.expiryWindowDays, "expiry-window-days", config.DefaultCertExpiryWindowDays, "Fail when a certificate expires within this number of days")
+	cmdCertsCheck.Flags().StringVarP(&certsCheckOpts.output, "output", "o", "", "Output format. One of: json, yaml. Defaults to human-readable text")
+
+	cmdCerts.AddCommand(cmdCertsRotate)
+	cmdCertsRotate.Flags().BoolVar(&certsRotateOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdCertsRotate.Flags().StringVar(&certsRotateOpts.s3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdCertsRotate.Flags().BoolVar(&certsRotateOpts.rotation.CA, "ca", false, "Rotate the CA as well as the certificates signed by it")
+	cmdCertsRotate.Flags().StringVar(&certsRotateOpts.rotation.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA or ECDSA key. A new CA key is written to it when rotating the CA")
+	cmdCertsRotate.Flags().BoolVar(&certsRotateOpts.rotation.RenderOnly, "render-only", false, "Only rewrite ./credentials for the next rotation step. Roll it out with kube-aws update")
+	cmdCertsRotate.Flags().BoolVar(&certsRotateOpts.rotation.Force, "force", false, "Rotate even if replacing nodes one by one would lose the quorum of etcd or the Kubernetes API")
+}
+
+func runCmdCertsCheck(cmd *cobra.Command, args []string) error {
+	switch certsCheckOpts.output {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("Unsupported output format %q: must be one of json, yaml", certsCheckOpts.output)
+	}
+
+	report, err := root.CheckCerts(configPath, root.NewOptions("", false, false), certsCheckOpts.expiryWindowDays)
+	if err != nil {
+		return fmt.Errorf("Failed to check certificates: %v", err)
+	}
+
+	switch certsCheckOpts.output {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to marshal certificates report: %v", err)
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := yaml.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal certificates report: %v", err)
+		}
+		fmt.Print(string(out))
+	default:
+		fmt.Print(report.String())
+	}
+
+	if report.HasProblems() {
+		return fmt.Errorf("Certificates have problems")
+	}
+	return nil
+}
+
+func runCmdCertsRotate(cmd *cobra.Command, args []string) error {
+	if !certsRotateOpts.rotation.RenderOnly {
+		if err := validateRequired(flag{"--s3-uri", certsRotateOpts.s3URI}); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := newInterruptibleContext()
+	defer cancel()
+
+	opts := root.NewOptions(certsRotateOpts.s3URI, false, false)
+	opts.Context = ctx
+
+	if err := root.RotateCerts(configPath, opts, certsRotateOpts.awsDebug, certsRotateOpts.rotation); err != nil {
+		reportInterruption(err)
+		return fmt.Errorf("Failed to rotate certificates: %v", err)
+	}
+
+	if certsRotateOpts.rotation.RenderOnly {
+		fmt.Printf("Success! Roll out the rotated TLS assets with \"kube-aws update\"\n")
+		return nil
+	}
+	fmt.Printf("Success! TLS certificates have been rotated\n")
+	return nil
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/coreos/kube-aws/cfnstack"
 	"github.com/coreos/kube-aws/core/root"
 )
 
@@ -17,23 +21,83 @@ var (
 		SilenceUsage: true,
 	}
 	destroyOpts = root.DestroyOptions{}
+
+	destroyRetainStuckResources bool
 )
 
 func init() {
 	RootCmd.AddCommand(cmdDestroy)
 	cmdDestroy.Flags().BoolVar(&destroyOpts.AwsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdDestroy.Flags().BoolVar(&destroyOpts.Wait, "wait", false, "Wait until the cluster is deleted and report resources blocking the deletion if any")
+	cmdDestroy.Flags().BoolVar(&destroyOpts.DeleteAssets, "delete-assets", false, "Delete stack templates and userdata uploaded to S3 for the cluster once the cluster is deleted. Implies --wait")
+	cmdDestroy.Flags().StringVar(&destroyOpts.S3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. Required when --delete-assets is specified")
+	cmdDestroy.Flags().DurationVar(&destroyOpts.Timeout, "timeout", 0, "When used with --wait, how long to wait for the stack to be deleted e.g. 30m. Waits until the deletion finishes by default")
+	cmdDestroy.Flags().BoolVar(&destroyRetainStuckResources, "retain-stuck-resources", false, "When used with --wait, retry the failed deletion retaining the resources blocking it without asking")
 }
 
 func runCmdDestroy(cmd *cobra.Command, args []string) error {
+	if destroyOpts.DeleteAssets {
+		if err := validateRequired(flag{"--s3-uri", destroyOpts.S3URI}); err != nil {
+			return err
+		}
+		// Assets must be kept until the stacks are deleted, so that a failed deletion can be recovered by an update
+		destroyOpts.Wait = true
+	}
+
+	ctx, cancel := newInterruptibleContext()
+	defer cancel()
+	destroyOpts.Context = ctx
+
 	c, err := root.ClusterDestroyerFromFile(configPath, destroyOpts)
 	if err != nil {
 		return fmt.Errorf("Error parsing config: %v", err)
 	}
 
-	if err := c.Destroy(); err != nil {
+	if !destroyOpts.Wait {
+		if err := c.Destroy(); err != nil {
+			reportTerminationProtection(err)
+			return fmt.Errorf("Failed destroying cluster: %v", err)
+		}
+
+		fmt.Println("CloudFormation stack is being destroyed. This will take several minutes")
+		return nil
+	}
+
+	fmt.Println("Destroying the CloudFormation stack. Please wait. It may take several minutes.")
+
+	err = c.Destroy()
+	if failure, ok := err.(*cfnstack.DeletionFailedError); ok {
+		fmt.Fprintln(os.Stderr, failure.Error())
+
+		if !destroyRetainStuckResources && !confirm("Retry the deletion retaining the resources listed above? They will be left in your AWS account and must be deleted manually") {
+			return fmt.Errorf("Failed destroying cluster: %s", failure.Reason)
+		}
+
+		err = c.RetainStuckResourcesAndDestroy(failure)
+	}
+	if err != nil {
+		reportInterruption(err)
+		reportTerminationProtection(err)
 		return fmt.Errorf("Failed destroying cluster: %v", err)
 	}
 
-	fmt.Println("CloudFormation stack is being destroyed. This will take several minutes")
+	fmt.Println("CloudFormation stack has been destroyed")
 	return nil
 }
+
+func reportTerminationProtection(err error) {
+	if !cfnstack.IsTerminationProtectionError(err) {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "The stack is protected from being deleted. Set `enableTerminationProtection: false` in cluster.yaml and run `kube-aws update`, or disable termination protection of the stack in the CloudFormation console, before destroying the cluster")
+}
+
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
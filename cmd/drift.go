@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	cmdDrift = &cobra.Command{
+		Use:          "drift",
+		Short:        "Detect differences between cluster.yaml and the running cluster",
+		Long:         `Compares the stack templates rendered from cluster.yaml with the ones deployed to the root, control-plane and node pool stacks, and key properties of running resources such as ASG sizes and instance types with the deployed templates. Every difference is going to be reverted by the next kube-aws update. Exits non-zero when any drift is found.`,
+		RunE:         runCmdDrift,
+		SilenceUsage: true,
+	}
+
+	driftOpts = struct {
+		awsDebug      bool
+		s3URI, output string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdDrift)
+	cmdDrift.Flags().BoolVar(&driftOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdDrift.Flags().StringVar(&driftOpts.s3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdDrift.Flags().StringVarP(&driftOpts.output, "output", "o", "", "Output format. One of: json, yaml. Defaults to human-readable text")
+}
+
+func runCmdDrift(cmd *cobra.Command, args []string) error {
+	if err := validateRequired(flag{"--s3-uri", driftOpts.s3URI}); err != nil {
+		return err
+	}
+	switch driftOpts.output {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("Unsupported output format %q: must be one of json, yaml", driftOpts.output)
+	}
+
+	opts := root.NewOptions(driftOpts.s3URI, false, false)
+
+	cluster, err := root.ClusterFromFile(configPath, opts, driftOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	report, err := cluster.Drift()
+	if err != nil {
+		return fmt.Errorf("Failed to detect drift: %v", err)
+	}
+
+	switch driftOpts.output {
+	case "json":
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to marshal drift report: %v", err)
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := yaml.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal drift report: %v", err)
+		}
+		fmt.Print(string(out))
+	default:
+		fmt.Print(report.String())
+	}
+
+	if report.HasDrifts() {
+		return fmt.Errorf("%d drift(s) found", len(report.Drifts))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdGC = &cobra.Command{
+		Use:          "gc",
+		Short:        "Delete stale stack templates and userdata uploaded to S3",
+		Long:         `Deletes userdata files uploaded to S3 by kube-aws up and update which are no longer referenced from the templates of the cluster's live stacks, keeping the most recent revisions of each.`,
+		RunE:         runCmdGC,
+		SilenceUsage: true,
+	}
+
+	gcOpts = root.GCOptions{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdGC)
+	cmdGC.Flags().BoolVar(&gcOpts.AwsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdGC.Flags().StringVar(&gcOpts.S3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdGC.Flags().IntVar(&gcOpts.Keep, "keep", 3, "Number of the most recent cluster revisions and unreferenced revisions of each file to keep")
+	cmdGC.Flags().BoolVar(&gcOpts.DryRun, "dry-run", false, "Only print files to be deleted")
+}
+
+func runCmdGC(cmd *cobra.Command, args []string) error {
+	if err := validateRequired(flag{"--s3-uri", gcOpts.S3URI}); err != nil {
+		return err
+	}
+	if gcOpts.Keep < 0 {
+		return fmt.Errorf("--keep must not be negative: %d", gcOpts.Keep)
+	}
+
+	collector, err := root.AssetsCollectorFromFile(configPath, gcOpts)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	keys, err := collector.Collect()
+	if err != nil {
+		return fmt.Errorf("Failed to collect stale files: %v", err)
+	}
+
+	verb := "Deleted"
+	if gcOpts.DryRun {
+		verb = "Would delete"
+	}
+	for _, k := range keys {
+		fmt.Printf("%s %s\n", verb, k)
+	}
+	fmt.Printf("%s %d stale file(s)\n", verb, len(keys))
+
+	return nil
+}
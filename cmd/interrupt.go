@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/coreos/kube-aws/cfnstack"
+)
+
+// newInterruptibleContext returns a context which is cancelled on the first SIGINT or SIGTERM so that kube-aws stops
+// waiting for the stack operation in flight and reports it. Subsequent signals are handled as usual
+func newInterruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+	return ctx, cancel
+}
+
+// reportInterruption tells how to keep following the stack operation when kube-aws stopped waiting for it
+func reportInterruption(err error) {
+	interrupted, ok := err.(*cfnstack.WaitInterruptedError)
+	if !ok {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "The %s of stack %s is still in progress in CloudFormation. The stack status was %s.\n", interrupted.Operation, interrupted.StackName, interrupted.StackStatus)
+	fmt.Fprintf(os.Stderr, "To resume waiting for it, run:\n\n  %s\n\n", interrupted.ResumeCommand())
+}
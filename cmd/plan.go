@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdPlan = &cobra.Command{
+		Use:          "plan",
+		Short:        "Preview changes to an existing Kubernetes cluster",
+		Long:         `Creates CloudFormation change sets for the root stack and its nested stacks and prints every resource to be added, modified, removed or replaced. Exits non-zero when any resource would be replaced.`,
+		RunE:         runCmdPlan,
+		SilenceUsage: true,
+	}
+
+	planOpts = struct {
+		awsDebug, prettyPrint bool
+		s3URI, changeSetName  string
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdPlan)
+	cmdPlan.Flags().BoolVar(&planOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdPlan.Flags().BoolVar(&planOpts.prettyPrint, "pretty-print", false, "Pretty print the resulting CloudFormation")
+	cmdPlan.Flags().StringVar(&planOpts.s3URI, "s3-uri", "", "When your template is bigger than the cloudformation limit of 51200 bytes, upload the template to the specified location in S3. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdPlan.Flags().StringVar(&planOpts.changeSetName, "change-set-name", "", "Name of the change set to be created for the root stack. Defaults to kube-aws-plan-<timestamp>")
+}
+
+func runCmdPlan(cmd *cobra.Command, args []string) error {
+	if err := validateRequired(flag{"--s3-uri", planOpts.s3URI}); err != nil {
+		return err
+	}
+
+	opts := root.NewOptions(planOpts.s3URI, planOpts.prettyPrint, false)
+
+	cluster, err := root.ClusterFromFile(configPath, opts, planOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if _, err := cluster.ValidateStack(); err != nil {
+		return err
+	}
+
+	changeSetName := planOpts.changeSetName
+	if changeSetName == "" {
+		changeSetName = root.NewChangeSetName()
+	}
+
+	plan, err := cluster.Plan(changeSetName)
+	if err != nil {
+		return fmt.Errorf("Error planning cluster update: %v", err)
+	}
+
+	fmt.Print(plan.String())
+
+	if plan.ChangeSetName == "" {
+		fmt.Printf("\nThe cluster is up to date.\n")
+		return nil
+	}
+
+	fmt.Printf("\nTo apply exactly these changes, run:\n  kube-aws update --s3-uri %s --change-set %s\n", planOpts.s3URI, plan.ChangeSetName)
+
+	if replacements := plan.Replacements(); len(replacements) > 0 {
+		return fmt.Errorf("%d resource(s) would be replaced", len(replacements))
+	}
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root"
+	"github.com/coreos/kube-aws/tlsutil"
 	"github.com/spf13/cobra"
 )
 
@@ -42,8 +43,12 @@ func init() {
 
 	cmdRender.AddCommand(cmdRenderCredentials)
 	cmdRenderCredentials.Flags().BoolVar(&renderCredentialsOpts.GenerateCA, "generate-ca", false, "if generating credentials, generate root CA key and cert. NOT RECOMMENDED FOR PRODUCTION USE- use '-ca-key-path' and '-ca-cert-path' options to provide your own certificate authority assets")
-	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA key")
-	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaKeyPath, "ca-key-path", "./credentials/ca-key.pem", "path to pem-encoded CA RSA or ECDSA key")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.CaCertPath, "ca-cert-path", "./credentials/ca.pem", "path to pem-encoded CA x509 certificate. Append the certificates of its issuers up to the root to use an intermediate CA")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.VaultAddr, "vault-addr", "", "address of Vault to sign certificates with its PKI secret backend instead of a local CA e.g. https://vault.example.com:8200. The token is read from VAULT_TOKEN")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.VaultPKIMount, "vault-pki-path", tlsutil.DefaultVaultPKIMount, "path the PKI secret backend is mounted at in Vault")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.VaultRole, "vault-role", "", "role of the PKI secret backend to sign certificates with")
+	cmdRenderCredentials.Flags().StringVar(&renderCredentialsOpts.VaultAdminRole, "vault-admin-role", "", "role of the PKI secret backend to sign the admin certificate with. It must set the organization to system:masters")
 
 	cmdRender.AddCommand(cmdRenderStack)
 }
@@ -53,7 +58,7 @@ func runCmdRender(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("render takes no arguments\n")
 	}
 
-	if _, err := os.Stat(renderCredentialsOpts.CaKeyPath); os.IsNotExist(err) {
+	if _, err := os.Stat(renderCredentialsOpts.CaKeyPath); os.IsNotExist(err) && renderCredentialsOpts.VaultAddr == "" {
 		renderCredentialsOpts.GenerateCA = true
 	}
 	if err := runCmdRenderCredentials(cmdRenderCredentials, args); err != nil {
@@ -91,6 +96,18 @@ Next steps:
 }
 
 func runCmdRenderCredentials(cmd *cobra.Command, args []string) error {
+	if renderCredentialsOpts.VaultAddr != "" {
+		if err := validateRequired(
+			flag{"--vault-role", renderCredentialsOpts.VaultRole},
+			flag{"--vault-admin-role", renderCredentialsOpts.VaultAdminRole},
+		); err != nil {
+			return err
+		}
+		if renderCredentialsOpts.VaultToken = os.Getenv("VAULT_TOKEN"); renderCredentialsOpts.VaultToken == "" {
+			return fmt.Errorf("VAULT_TOKEN must be set to sign certificates with Vault")
+		}
+	}
+
 	cluster, err := root.CredentialsRendererFromFile(configPath)
 	if err != nil {
 		return fmt.Errorf("failed to read cluster config: %v", err)
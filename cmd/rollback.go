@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdRollback = &cobra.Command{
+		Use:          "rollback",
+		Short:        "Roll back an existing Kubernetes cluster to a previously deployed revision",
+		Long:         `Updates the cluster to use stack templates and userdata recorded for a revision by a successful kube-aws up, update or rollback. Defaults to the revision before the latest one.`,
+		RunE:         runCmdRollback,
+		SilenceUsage: true,
+	}
+
+	rollbackOpts = struct {
+		awsDebug, list bool
+		s3URI          string
+		to             int
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdRollback)
+	cmdRollback.Flags().BoolVar(&rollbackOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdRollback.Flags().StringVar(&rollbackOpts.s3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. S3 location expressed as s3://<bucket>/path/to/dir")
+	cmdRollback.Flags().IntVar(&rollbackOpts.to, "to", 0, "Number of the revision to roll back to. Defaults to the revision before the latest one")
+	cmdRollback.Flags().BoolVar(&rollbackOpts.list, "list", false, "Only list recorded revisions")
+}
+
+func runCmdRollback(cmd *cobra.Command, args []string) error {
+	if err := validateRequired(flag{"--s3-uri", rollbackOpts.s3URI}); err != nil {
+		return err
+	}
+
+	opts := root.NewOptions(rollbackOpts.s3URI, false, false)
+
+	cluster, err := root.ClusterFromFile(configPath, opts, rollbackOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	if rollbackOpts.list {
+		revisions, err := cluster.Revisions()
+		if err != nil {
+			return fmt.Errorf("Failed to list revisions: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "REVISION\tCREATED\tKUBE-AWS VERSION\tCONFIG HASH")
+		for _, r := range revisions {
+			fmt.Fprintln(w, r.String())
+		}
+		return w.Flush()
+	}
+
+	revision, err := cluster.Rollback(rollbackOpts.to)
+	if err != nil {
+		return fmt.Errorf("Error rolling back cluster: %v", err)
+	}
+
+	info, err := cluster.Info()
+	if err != nil {
+		return fmt.Errorf("Failed fetching cluster info: %v", err)
+	}
+
+	successMsg :=
+		`Success! Your AWS resources have been rolled back to revision %d as revision %d:
+%s
+`
+	fmt.Printf(successMsg, revision.RollbackOf, revision.Number, info.String())
+
+	return nil
+}
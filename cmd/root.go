@@ -1,15 +1,71 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/spf13/cobra"
 )
 
 var (
 	RootCmd = &cobra.Command{
-		Use:   "kube-aws",
-		Short: "Manage Kubernetes clusters on AWS",
-		Long:  ``,
+		Use:               "kube-aws",
+		Short:             "Manage Kubernetes clusters on AWS",
+		Long:              ``,
+		PersistentPreRunE: configureAWS,
 	}
 
 	configPath = "cluster.yaml"
+
+	awsOpts = struct {
+		profile, caBundle                                 string
+		assumeRoleARN, externalID, sessionName, mfaSerial string
+		credentialsCacheDir                               string
+		endpoints                                         []string
+		maxRetries                                        int
+	}{}
 )
+
+func init() {
+	RootCmd.PersistentFlags().StringVar(&awsOpts.profile, "profile", "", "Name of the AWS profile to use. Defaults to AWS_PROFILE")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.assumeRoleARN, "assume-role-arn", "", "ARN of the IAM role to assume for every AWS API call. Overrides aws.assumeRoleArn in cluster.yaml")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.externalID, "external-id", "", "External ID passed when assuming the role specified via --assume-role-arn")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.sessionName, "role-session-name", "", "Session name of the role specified via --assume-role-arn")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.mfaSerial, "mfa-serial", "", "Serial number or ARN of the MFA device required to assume the role specified via --assume-role-arn. A token code is prompted on stdin")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.credentialsCacheDir, "credentials-cache-dir", defaultCredentialsCacheDir(), "Directory to cache credentials of assumed roles in until they expire. Set to empty to disable caching")
+	RootCmd.PersistentFlags().StringVar(&awsOpts.caBundle, "ca-bundle", "", "Path to a PEM file of CA certificates to trust in addition to the system ones when calling AWS APIs e.g. behind a TLS-intercepting proxy. Defaults to AWS_CA_BUNDLE")
+	RootCmd.PersistentFlags().StringSliceVar(&awsOpts.endpoints, "endpoint", []string{}, "Comma-separated overrides of AWS endpoints in the form of <service id>=<url> e.g. s3=https://s3.example.com,cloudformation=https://cfn.example.com")
+	RootCmd.PersistentFlags().IntVar(&awsOpts.maxRetries, "max-retries", awsconn.DefaultMaxRetries, "Maximum number of retries for a throttled or failed AWS API call")
+}
+
+func defaultCredentialsCacheDir() string {
+	home := os.Getenv("HOME")
+	if home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".kube-aws", "cache", "sts")
+}
+
+func configureAWS(cmd *cobra.Command, args []string) error {
+	if awsOpts.assumeRoleARN == "" && (awsOpts.externalID != "" || awsOpts.sessionName != "" || awsOpts.mfaSerial != "") {
+		return fmt.Errorf("--external-id, --role-session-name and --mfa-serial require --assume-role-arn")
+	}
+	endpoints, err := awsconn.ParseEndpoints(awsOpts.endpoints)
+	if err != nil {
+		return fmt.Errorf("Invalid --endpoint: %v", err)
+	}
+	awsconn.SetGlobalOptions(awsconn.Options{
+		Profile:             awsOpts.profile,
+		AssumeRoleARN:       awsOpts.assumeRoleARN,
+		ExternalID:          awsOpts.externalID,
+		SessionName:         awsOpts.sessionName,
+		MFASerial:           awsOpts.mfaSerial,
+		CredentialsCacheDir: awsOpts.credentialsCacheDir,
+		CABundle:            awsOpts.caBundle,
+		Endpoints:           endpoints,
+		MaxRetries:          awsOpts.maxRetries,
+	})
+	return nil
+}
@@ -1,27 +1,40 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/coreos/kube-aws/core/root"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
 )
 
 var (
 	cmdStatus = &cobra.Command{
 		Use:          "status",
 		Short:        "Describe an existing Kubernetes cluster",
-		Long:         ``,
+		Long:         `Describes the control plane, etcd instances and node pools of the cluster. Use -o json or -o yaml to produce machine-readable output.`,
 		RunE:         runCmdStatus,
 		SilenceUsage: true,
 	}
+
+	statusOpts = struct {
+		output string
+	}{}
 )
 
 func init() {
 	RootCmd.AddCommand(cmdStatus)
+	cmdStatus.Flags().StringVarP(&statusOpts.output, "output", "o", "", "Output format. One of: json, yaml. Defaults to human-readable text")
 }
 
 func runCmdStatus(cmd *cobra.Command, args []string) error {
+	switch statusOpts.output {
+	case "", "json", "yaml":
+	default:
+		return fmt.Errorf("Unsupported output format %q: must be one of json, yaml", statusOpts.output)
+	}
+
 	describer, err := root.ClusterDescriberFromFile(configPath)
 	if err != nil {
 		return fmt.Errorf("Failed to read cluster config: %v", err)
@@ -32,6 +45,21 @@ func runCmdStatus(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("Failed fetching cluster info: %v", err)
 	}
 
-	fmt.Print(info.String())
+	switch statusOpts.output {
+	case "json":
+		out, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("Failed to marshal cluster info: %v", err)
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := yaml.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("Failed to marshal cluster info: %v", err)
+		}
+		fmt.Print(string(out))
+	default:
+		fmt.Print(info.String())
+	}
 	return nil
 }
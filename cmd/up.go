@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/coreos/kube-aws/core/root"
 	"github.com/spf13/cobra"
@@ -19,6 +20,7 @@ var (
 	upOpts = struct {
 		awsDebug, export, prettyPrint, skipWait bool
 		s3URI                                   string
+		timeout                                 time.Duration
 	}{}
 )
 
@@ -29,6 +31,7 @@ func init() {
 	cmdUp.Flags().BoolVar(&upOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
 	cmdUp.Flags().StringVar(&upOpts.s3URI, "s3-uri", "", "When your template is bigger than the cloudformation limit of 51200 bytes, upload the template to the specified location in S3. S3 location expressed as s3://<bucket>/path/to/dir")
 	cmdUp.Flags().BoolVar(&upOpts.skipWait, "skip-wait", false, "Don't wait for the cluster components be ready")
+	cmdUp.Flags().DurationVar(&upOpts.timeout, "timeout", 0, "How long to wait for the stack to be created e.g. 45m. Defaults to the sum of controllerCreateTimeout and createTimeout of node pools in cluster.yaml plus some margin")
 }
 
 func runCmdUp(cmd *cobra.Command, args []string) error {
@@ -39,7 +42,12 @@ func runCmdUp(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	ctx, cancel := newInterruptibleContext()
+	defer cancel()
+
 	opts := root.NewOptions(upOpts.s3URI, upOpts.prettyPrint, upOpts.skipWait)
+	opts.Context = ctx
+	opts.Timeout = upOpts.timeout
 
 	cluster, err := root.ClusterFromFile(configPath, opts, upOpts.awsDebug)
 	if err != nil {
@@ -59,9 +67,14 @@ func runCmdUp(cmd *cobra.Command, args []string) error {
 
 	fmt.Printf("Creating AWS resources. Please wait. It may take a few minutes.\n")
 	if err := cluster.Create(); err != nil {
+		reportInterruption(err)
 		return fmt.Errorf("Error creating cluster: %v", err)
 	}
 
+	return printUpSuccess(cluster)
+}
+
+func printUpSuccess(cluster root.Cluster) error {
 	info, err := cluster.Info()
 	if err != nil {
 		return fmt.Errorf("Failed fetching cluster info: %v", err)
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/coreos/kube-aws/core/root"
 	"github.com/spf13/cobra"
@@ -18,7 +19,9 @@ var (
 
 	updateOpts = struct {
 		awsDebug, prettyPrint, skipWait bool
-		s3URI                           string
+		s3URI, changeSet                string
+		timeout                         time.Duration
+		allowReplace                    []string
 	}{}
 )
 
@@ -28,6 +31,9 @@ func init() {
 	cmdUpdate.Flags().BoolVar(&updateOpts.prettyPrint, "pretty-print", false, "Pretty print the resulting CloudFormation")
 	cmdUpdate.Flags().StringVar(&updateOpts.s3URI, "s3-uri", "", "When your template is bigger than the cloudformation limit of 51200 bytes, upload the template to the specified location in S3. S3 location expressed as s3://<bucket>/path/to/dir")
 	cmdUpdate.Flags().BoolVar(&updateOpts.skipWait, "skip-wait", false, "Don't wait the resources finish")
+	cmdUpdate.Flags().DurationVar(&updateOpts.timeout, "timeout", 0, "How long to wait for the stack to be updated e.g. 1h. Waits until the update finishes by default")
+	cmdUpdate.Flags().StringVar(&updateOpts.changeSet, "change-set", "", "Execute the change set previously created by kube-aws plan instead of re-rendering and uploading stack templates")
+	cmdUpdate.Flags().StringSliceVar(&updateOpts.allowReplace, "allow-replace", []string{}, "Comma-separated logical IDs of resources in the control-plane or node pool stacks e.g. Etcd0,Etcd0EBS to be allowed to be replaced or deleted during this update regardless of the stack policies")
 }
 
 func runCmdUpdate(cmd *cobra.Command, args []string) error {
@@ -35,21 +41,38 @@ func runCmdUpdate(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	ctx, cancel := newInterruptibleContext()
+	defer cancel()
+
 	opts := root.NewOptions(updateOpts.s3URI, updateOpts.prettyPrint, updateOpts.skipWait)
+	opts.Context = ctx
+	opts.Timeout = updateOpts.timeout
+	opts.AllowReplace = updateOpts.allowReplace
 
 	cluster, err := root.ClusterFromFile(configPath, opts, updateOpts.awsDebug)
 	if err != nil {
 		return fmt.Errorf("Failed to read cluster config: %v", err)
 	}
 
-	if _, err := cluster.ValidateStack(); err != nil {
-		return err
+	var report string
+	if updateOpts.changeSet != "" {
+		// Validating the stack re-uploads templates the change set refers to. Skip it so that we execute exactly what was planned
+		report, err = cluster.UpdateWithChangeSet(updateOpts.changeSet)
+	} else {
+		if _, err := cluster.ValidateStack(); err != nil {
+			return err
+		}
+		report, err = cluster.Update()
 	}
-
-	report, err := cluster.Update()
 	if err != nil {
+		reportInterruption(err)
 		return fmt.Errorf("Error updating cluster: %v", err)
 	}
+
+	return printUpdateSuccess(cluster, report)
+}
+
+func printUpdateSuccess(cluster root.Cluster, report string) error {
 	if report != "" {
 		fmt.Printf("Update stack: %s\n", report)
 	}
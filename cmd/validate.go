@@ -3,7 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"text/tabwriter"
 
+	"github.com/coreos/kube-aws/cfnlint"
+	"github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root"
 	"github.com/spf13/cobra"
 )
@@ -12,18 +15,24 @@ var (
 	cmdValidate = &cobra.Command{
 		Use:          "validate",
 		Short:        "Validate cluster assets",
-		Long:         ``,
+		Long:         `Lints the rendered root, control-plane and node pool stack templates and checks the certificates in ./credentials, then validates userdata and the stack templates with AWS. With --offline, userdata is rendered with raw credentials instead of ones encrypted with KMS, and no AWS API is called.`,
 		RunE:         runCmdValidate,
 		SilenceUsage: true,
 	}
 
 	validateOpts = struct {
-		awsDebug bool
-		skipWait bool
-		s3URI    string
+		awsDebug         bool
+		offline          bool
+		skipWait         bool
+		s3URI            string
+		expiryWindowDays int
 	}{}
 )
 
+// offlineS3URI is used to render template URLs of nested stacks when validating offline without --s3-uri.
+// Nothing is uploaded to it
+const offlineS3URI = "s3://kube-aws-offline-validation"
+
 func init() {
 	RootCmd.AddCommand(cmdValidate)
 	cmdValidate.Flags().BoolVar(
@@ -32,6 +41,18 @@ func init() {
 		false,
 		"Log debug information from aws-sdk-go library",
 	)
+	cmdValidate.Flags().BoolVar(
+		&validateOpts.offline,
+		"offline",
+		false,
+		"Only lint the rendered stack templates without calling AWS APIs including KMS. --s3-uri is optional",
+	)
+	cmdValidate.Flags().IntVar(
+		&validateOpts.expiryWindowDays,
+		"expiry-window-days",
+		config.DefaultCertExpiryWindowDays,
+		"Fail when a certificate in ./credentials expires within this number of days",
+	)
 	cmdValidate.Flags().StringVar(
 		&validateOpts.s3URI,
 		"s3-uri",
@@ -41,13 +62,73 @@ func init() {
 }
 
 func runCmdValidate(cmd *cobra.Command, args []string) error {
-	opts := root.NewOptions(validateOpts.s3URI, validateOpts.awsDebug, validateOpts.skipWait)
+	s3URI := validateOpts.s3URI
+	if validateOpts.offline && s3URI == "" {
+		s3URI = offlineS3URI
+	}
+	opts := root.NewOptions(s3URI, validateOpts.awsDebug, validateOpts.skipWait)
+	// Raw assets are rendered instead of the ones encrypted with KMS so that no network access is needed
+	opts.SkipAssetsEncryption = validateOpts.offline
 
 	cluster, err := root.ClusterFromFile(configPath, opts, validateOpts.awsDebug)
 	if err != nil {
 		return fmt.Errorf("Failed to initialize cluster driver: %v", err)
 	}
 
+	fmt.Printf("Linting stack templates...\n")
+	problems, err := cluster.LintTemplates()
+	if err != nil {
+		return fmt.Errorf("Failed to lint stack templates: %v", err)
+	}
+	if len(problems) > 0 {
+		w := tabwriter.NewWriter(os.Stderr, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "SEVERITY\tTEMPLATE\tPATH\tMESSAGE")
+		for _, p := range problems {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.Severity, p.Template, p.Path, p.Message)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if cfnlint.HasErrors(problems) {
+		return fmt.Errorf("Stack templates have errors")
+	}
+	fmt.Printf("stack templates passed lint.\n\n")
+
+	fmt.Printf("Sizing userdata...\n")
+	sizes, err := cluster.UserDataSizes()
+	if err != nil {
+		return fmt.Errorf("Failed to size userdata: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STACK\tROLE\tFORMAT\tCONTENT\tDELIVERY\tSIZE\tLIMIT\tHEADROOM")
+	for _, s := range sizes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%d\t%d\t%d\n", s.Stack, s.Role, s.Format, s.Content, s.Delivery(), s.Size(), config.MaxUserDataSize, s.Headroom())
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if validateOpts.offline {
+		fmt.Fprintf(os.Stderr, "WARNING: userdata was sized with credentials not encrypted with KMS. Encrypted credentials are larger and don't compress, so the sizes above aren't representative of the deployed userdata. Run kube-aws validate without --offline to size it\n")
+	}
+	fmt.Println()
+
+	fmt.Printf("Checking certificates...\n")
+	certs, err := root.CheckCerts(configPath, opts, validateOpts.expiryWindowDays)
+	if err != nil {
+		return fmt.Errorf("Failed to check certificates: %v", err)
+	}
+	fmt.Print(certs.String())
+	if certs.HasProblems() {
+		return fmt.Errorf("Certificates have problems")
+	}
+	fmt.Printf("certificates passed checks.\n\n")
+
+	if validateOpts.offline {
+		fmt.Printf("Validation OK!\n")
+		return nil
+	}
+
 	fmt.Printf("Validating UserData and stack template...\n")
 	report, err := cluster.ValidateStack()
 	if report != "" {
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdWait = &cobra.Command{
+		Use:          "wait",
+		Aliases:      []string{"resume"},
+		Short:        "Wait for the stack operation in progress started by a previous kube-aws run",
+		Long:         `Finds the creation, update or deletion in progress on the root stack of the cluster e.g. after kube-aws up has been killed or timed out, and waits for it while printing stack events. Exits with the same status and message as the command which has started the operation.`,
+		RunE:         runCmdWait,
+		SilenceUsage: true,
+	}
+
+	waitOpts = struct {
+		awsDebug bool
+		s3URI    string
+		timeout  time.Duration
+	}{}
+)
+
+func init() {
+	RootCmd.AddCommand(cmdWait)
+	cmdWait.Flags().BoolVar(&waitOpts.awsDebug, "aws-debug", false, "Log debug information from aws-sdk-go library")
+	cmdWait.Flags().StringVar(&waitOpts.s3URI, "s3-uri", "", "The S3 location the cluster has been created with by kube-aws up. When specified, a revision is recorded after a successful creation or update so that it can be rolled back to")
+	cmdWait.Flags().DurationVar(&waitOpts.timeout, "timeout", 0, "How long to wait for the operation to finish e.g. 30m. Waits until the operation finishes by default")
+}
+
+func runCmdWait(cmd *cobra.Command, args []string) error {
+	ctx, cancel := newInterruptibleContext()
+	defer cancel()
+
+	opts := root.NewOptions(waitOpts.s3URI, false, false)
+	opts.Context = ctx
+	opts.Timeout = waitOpts.timeout
+
+	cluster, err := root.ClusterFromFile(configPath, opts, waitOpts.awsDebug)
+	if err != nil {
+		return fmt.Errorf("Failed to read cluster config: %v", err)
+	}
+
+	op, err := cluster.Resume()
+	if err != nil {
+		reportInterruption(err)
+		if op == nil {
+			return fmt.Errorf("Failed to find the operation in progress: %v", err)
+		}
+		switch op.Operation {
+		case cfnstack.OperationCreate:
+			return fmt.Errorf("Error creating cluster: %v", err)
+		case cfnstack.OperationUpdate:
+			return fmt.Errorf("Error updating cluster: %v", err)
+		case cfnstack.OperationDelete:
+			return fmt.Errorf("Failed destroying cluster: %v", err)
+		}
+		return err
+	}
+
+	switch op.Operation {
+	case cfnstack.OperationCreate:
+		return printUpSuccess(cluster)
+	case cfnstack.OperationUpdate:
+		return printUpdateSuccess(cluster, op.Report)
+	case cfnstack.OperationDelete:
+		fmt.Println("CloudFormation stack has been destroyed")
+		return nil
+	}
+
+	// The last operation has already finished. Fail if it has failed, as the command which has started it would have done
+	if strings.HasSuffix(op.StackStatus, "FAILED") || strings.HasSuffix(op.StackStatus, "ROLLBACK_COMPLETE") {
+		return fmt.Errorf("No operation is in progress but the last one has failed. The stack status is %s", op.StackStatus)
+	}
+	fmt.Printf("No operation is in progress. The stack status is %s\n", op.StackStatus)
+	return nil
+}
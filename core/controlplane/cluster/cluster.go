@@ -15,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/route53"
 
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/cfnstack"
 	"github.com/coreos/kube-aws/core/controlplane/config"
 )
@@ -24,19 +25,16 @@ var VERSION = "UNKNOWN"
 
 const STACK_TEMPLATE_FILENAME = "stack.json"
 
-func NewClusterRef(cfg *config.Cluster, awsDebug bool) *ClusterRef {
-	awsConfig := aws.NewConfig().
-		WithRegion(cfg.Region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	if awsDebug {
-		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
+func NewClusterRef(cfg *config.Cluster, awsDebug bool) (*ClusterRef, error) {
+	session, err := awsconn.NewSession(awsconn.Config{Region: cfg.Region, Debug: awsDebug, Options: cfg.AWSConnOptions()})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ClusterRef{
 		Cluster: cfg,
-		session: session.New(awsConfig),
-	}
+		session: session,
+	}, nil
 }
 
 type ClusterRef struct {
@@ -115,7 +113,10 @@ func (c *ClusterRef) validateExistingVPCState(ec2Svc ec2Service) error {
 }
 
 func NewCluster(cfg *config.Cluster, opts config.StackTemplateOptions, awsDebug bool) (*Cluster, error) {
-	cluster := NewClusterRef(cfg, awsDebug)
+	cluster, err := NewClusterRef(cfg, awsDebug)
+	if err != nil {
+		return nil, err
+	}
 	stackConfig, err := cluster.StackConfig(opts)
 	if err != nil {
 		return nil, err
@@ -136,11 +137,16 @@ func (c *Cluster) Assets() (cfnstack.Assets, error) {
 		return nil, fmt.Errorf("Error while rendering template : %v", err)
 	}
 
-	return cfnstack.NewAssetsBuilder(c.StackName(), c.StackConfig.S3URI, c.StackConfig.Region).
-		Add(c.UserDataControllerFileName(), c.UserDataController).
-		Add(c.UserDataEtcdFileName(), c.UserDataEtcd).
-		Add(STACK_TEMPLATE_FILENAME, stackTemplate).
-		Build(), nil
+	userdata, err := c.UserDataAssets()
+	if err != nil {
+		return nil, fmt.Errorf("Error while sizing userdata : %v", err)
+	}
+
+	builder := cfnstack.NewAssetsBuilder(c.StackName(), c.StackConfig.S3URI, c.StackConfig.Region)
+	for name, content := range userdata {
+		builder = builder.Add(name, content)
+	}
+	return builder.Add(STACK_TEMPLATE_FILENAME, stackTemplate).Build(), nil
 }
 
 func (c *Cluster) TemplateURL() (string, error) {
@@ -176,30 +182,13 @@ func (c *Cluster) RenderTemplateAsString() (string, error) {
 }
 
 func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
-	stackPolicyBody := `{
-  "Statement" : [
-    {
-      "Effect" : "Deny",
-      "Action" : "Update:*",
-      "Principal" : "*",
-      "Resource" : "LogicalResourceId/InstanceEtcd*"
-    },
-    {
-       "Effect" : "Allow",
-       "Principal" : "*",
-       "Action" : "Update:*",
-       "Resource" : "*"
-     }
-  ]
-}
-`
 	return cfnstack.NewProvisioner(
 		c.StackName(),
 		c.StackTags,
 		c.S3URI,
 		c.Region,
-		stackPolicyBody,
-		c.session)
+		c.EffectiveStackPolicy().Body(),
+		c.session).WithS3Options(c.S3)
 }
 
 func (c *Cluster) Validate() error {
@@ -239,9 +228,9 @@ func (c *Cluster) Create() error {
 		return fmt.Errorf("Error while rendering template : %v", err)
 	}
 
-	cloudConfigs := map[string]string{
-		"userdata-controller": c.UserDataController,
-		"userdata-worker":     c.UserDataWorker,
+	cloudConfigs, err := c.UserDataAssets()
+	if err != nil {
+		return fmt.Errorf("Error while sizing userdata : %v", err)
 	}
 
 	return c.stackProvisioner().CreateStackAndWait(cfSvc, s3Svc, stackTemplate, cloudConfigs)
@@ -336,10 +325,9 @@ func (c *Cluster) Update() (string, error) {
 		return "", err
 	}
 
-	cloudConfigs := map[string]string{
-		"userdata-controller": c.UserDataController,
-		"userdata-worker":     c.UserDataWorker,
-		"userdata-etcd":       c.UserDataEtcd,
+	cloudConfigs, err := c.UserDataAssets()
+	if err != nil {
+		return "", fmt.Errorf("Error while sizing userdata : %v", err)
 	}
 	updateOutput, err := c.stackProvisioner().UpdateStackAndWait(cfSvc, s3Svc, stackBody, cloudConfigs)
 
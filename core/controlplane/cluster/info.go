@@ -7,8 +7,8 @@ import (
 )
 
 type Info struct {
-	Name           string
-	ControllerHost string
+	Name           string `json:"name" yaml:"name"`
+	ControllerHost string `json:"controllerHost" yaml:"controllerHost"`
 }
 
 func (c *Info) String() string {
@@ -12,9 +12,9 @@ import (
 	"unicode/utf8"
 
 	"github.com/coreos/go-semver/semver"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/cfnresource"
 	"github.com/coreos/kube-aws/coreos/amiregistry"
-	"github.com/coreos/kube-aws/filereader/userdatatemplate"
 	"github.com/coreos/kube-aws/model"
 	"github.com/coreos/kube-aws/model/derived"
 	"github.com/coreos/kube-aws/netutil"
@@ -91,6 +91,7 @@ func NewDefaultCluster() *Cluster {
 			ReleaseChannel:              "stable",
 			K8sVer:                      k8sVer,
 			ContainerRuntime:            "docker",
+			UserDataFormat:              UserDataFormatCloudConfig,
 			Subnets:                     []model.Subnet{},
 			EIPAllocationIDs:            []string{},
 			MapPublicIPs:                true,
@@ -154,6 +155,7 @@ func NewDefaultCluster() *Cluster {
 		// for base cloudformation stack
 		TLSCADurationDays:   365 * 10,
 		TLSCertDurationDays: 365,
+		TLS:                 model.NewDefaultTLS(),
 		CreateRecordSet:     false,
 		RecordSetTTL:        300,
 		CustomSettings:      make(map[string]interface{}),
@@ -359,8 +361,11 @@ type DeploymentSettings struct {
 	InstanceCIDR        string            `yaml:"instanceCIDR,omitempty"`
 	K8sVer              string            `yaml:"kubernetesVersion,omitempty"`
 	ContainerRuntime    string            `yaml:"containerRuntime,omitempty"`
+	UserDataFormat      string            `yaml:"userDataFormat,omitempty"`
 	KMSKeyARN           string            `yaml:"kmsKeyArn,omitempty"`
 	StackTags           map[string]string `yaml:"stackTags,omitempty"`
+	S3                  model.S3          `yaml:"s3,omitempty"`
+	AWS                 model.AWS         `yaml:"aws,omitempty"`
 	Subnets             []model.Subnet    `yaml:"subnets,omitempty"`
 	EIPAllocationIDs    []string          `yaml:"eipAllocationIDs,omitempty"`
 	MapPublicIPs        bool              `yaml:"mapPublicIPs,omitempty"`
@@ -442,14 +447,21 @@ type Cluster struct {
 	ControllerSettings     `yaml:",inline"`
 	EtcdSettings           `yaml:",inline"`
 	FlannelSettings        `yaml:",inline"`
-	ServiceCIDR            string `yaml:"serviceCIDR,omitempty"`
-	CreateRecordSet        bool   `yaml:"createRecordSet,omitempty"`
-	RecordSetTTL           int    `yaml:"recordSetTTL,omitempty"`
-	TLSCADurationDays      int    `yaml:"tlsCADurationDays,omitempty"`
-	TLSCertDurationDays    int    `yaml:"tlsCertDurationDays,omitempty"`
-	HostedZoneID           string `yaml:"hostedZoneId,omitempty"`
+	ServiceCIDR            string    `yaml:"serviceCIDR,omitempty"`
+	CreateRecordSet        bool      `yaml:"createRecordSet,omitempty"`
+	RecordSetTTL           int       `yaml:"recordSetTTL,omitempty"`
+	TLSCADurationDays      int       `yaml:"tlsCADurationDays,omitempty"`
+	TLSCertDurationDays    int       `yaml:"tlsCertDurationDays,omitempty"`
+	TLS                    model.TLS `yaml:"tls,omitempty"`
+	HostedZoneID           string    `yaml:"hostedZoneId,omitempty"`
 	ProvidedEncryptService EncryptService
 	CustomSettings         map[string]interface{} `yaml:"customSettings,omitempty"`
+	// StackPolicy is applied to the control-plane stack. Defaults to the one allowing any update
+	StackPolicy *model.StackPolicy `yaml:"stackPolicy,omitempty"`
+	// ProtectEtcdNodes makes the default stack policy deny replacing and deleting etcd nodes
+	ProtectEtcdNodes bool `yaml:"protectEtcdNodes,omitempty"`
+	// EnableTerminationProtection prevents the root stack, and therefore the whole cluster, from being deleted
+	EnableTerminationProtection bool `yaml:"enableTerminationProtection,omitempty"`
 }
 
 type Experimental struct {
@@ -467,6 +479,7 @@ type Experimental struct {
 	NodeLabels               NodeLabels               `yaml:"nodeLabels"`
 	Plugins                  Plugins                  `yaml:"plugins"`
 	Taints                   []Taint                  `yaml:"taints"`
+	TLSBootstrap             TLSBootstrap             `yaml:"tlsBootstrap"`
 	model.UnknownKeys        `yaml:",inline"`
 }
 
@@ -718,6 +731,9 @@ type StackTemplateOptions struct {
 	S3URI                 string
 	PrettyPrint           bool
 	SkipWait              bool
+	// SkipAssetsEncryption renders userdata with raw TLS assets and auth tokens so that KMS isn't called
+	// e.g. while validating offline
+	SkipAssetsEncryption bool
 }
 
 func (c Cluster) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
@@ -731,12 +747,13 @@ func (c Cluster) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
 	// TODO: Check if new tests are needed to verify the auth token file is handled correctly
 
 	if c.ManageCertificates {
-		if c.AssetsEncryptionEnabled() {
+		if c.AssetsEncryptionEnabled() && !opts.SkipAssetsEncryption {
 			var compactAssets *CompactTLSAssets
 			var compactAuthTokens *CompactAuthTokens
 
 			compactAssets, err = ReadOrCreateCompactTLSAssets(opts.AssetsDir, KMSConfig{
 				Region:         stackConfig.Config.Region,
+				AWSOptions:     c.AWSConnOptions(),
 				KMSKeyARN:      c.KMSKeyARN,
 				EncryptService: c.ProvidedEncryptService,
 			})
@@ -744,8 +761,22 @@ func (c Cluster) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
 				return nil, err
 			}
 
+			// Read before auth tokens as the bootstrap token is added to tokens.csv if missing
+			if c.Experimental.TLSBootstrap.Enabled {
+				stackConfig.Config.TLSBootstrapConfig, err = ReadOrCreateCompactTLSBootstrapAssets(opts.AssetsDir, KMSConfig{
+					Region:         stackConfig.Config.Region,
+					AWSOptions:     c.AWSConnOptions(),
+					KMSKeyARN:      c.KMSKeyARN,
+					EncryptService: c.ProvidedEncryptService,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
+
 			compactAuthTokens, err = ReadOrCreateCompactAuthTokens(opts.AssetsDir, KMSConfig{
 				Region:         stackConfig.Config.Region,
+				AWSOptions:     c.AWSConnOptions(),
 				KMSKeyARN:      c.KMSKeyARN,
 				EncryptService: c.ProvidedEncryptService,
 			})
@@ -761,6 +792,12 @@ func (c Cluster) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
 				return nil, err
 			}
 
+			if c.Experimental.TLSBootstrap.Enabled {
+				if stackConfig.Config.TLSBootstrapConfig, err = ReadOrCreateUnencryptedCompactTLSBootstrapAssets(opts.AssetsDir); err != nil {
+					return nil, err
+				}
+			}
+
 			rawAuthTokens, err := ReadOrCreateUnecryptedCompactAuthTokens(opts.AssetsDir)
 			if err != nil {
 				return nil, err
@@ -771,10 +808,10 @@ func (c Cluster) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
 		}
 	}
 
-	if stackConfig.UserDataController, err = userdatatemplate.GetString(opts.ControllerTmplFile, stackConfig.Config); err != nil {
+	if stackConfig.UserDataController, err = c.RenderUserData(opts.ControllerTmplFile, stackConfig.Config); err != nil {
 		return nil, fmt.Errorf("failed to render controller cloud config: %v", err)
 	}
-	if stackConfig.UserDataEtcd, err = userdatatemplate.GetString(opts.EtcdTmplFile, stackConfig.Config); err != nil {
+	if stackConfig.UserDataEtcd, err = c.RenderUserData(opts.EtcdTmplFile, stackConfig.Config); err != nil {
 		return nil, fmt.Errorf("failed to render etcd cloud config: %v", err)
 	}
 
@@ -801,6 +838,9 @@ type Config struct {
 
 	// Encoded TLS assets
 	TLSConfig *CompactTLSAssets
+
+	// Encoded credentials for kubelet TLS bootstrapping
+	TLSBootstrapConfig *CompactTLSBootstrapAssets
 }
 
 // StackName returns the logical name of a CloudFormation stack resource in a root stack template
@@ -816,6 +856,10 @@ func (c Cluster) StackNameEnvVarName() string {
 }
 
 func (c Cluster) EtcdNodeEnvFileName() string {
+	// Ignition can't write to /var/run, which is a tmpfs mounted after Ignition runs
+	if c.IgnitionEnabled() {
+		return "/etc/kube-aws/etcd-node.env"
+	}
 	return "/var/run/coreos/etcd-node.env"
 }
 
@@ -847,6 +891,35 @@ func (c Config) InternetGatewayRef() string {
 	}
 }
 
+// EffectiveStackPolicy returns the stack policy applied to the control-plane stack.
+// Unless specified in cluster.yaml, it allows any update. With protectEtcdNodes, it denies replacing and deleting resources
+// which make up the identities and the data of etcd nodes, so that etcd members aren't lost by an update
+func (c Config) EffectiveStackPolicy() model.StackPolicy {
+	if c.StackPolicy != nil {
+		return *c.StackPolicy
+	}
+	if !c.ProtectEtcdNodes {
+		return model.NewAllowAllStackPolicy()
+	}
+
+	protected := []string{}
+	for _, n := range c.EtcdNodes {
+		protected = append(protected, n.LogicalName())
+		if !c.EtcdDataVolumeEphemeral {
+			protected = append(protected, n.EBSLogicalName())
+		}
+		if n.NetworkInterfaceManaged() {
+			protected = append(protected, n.NetworkInterfaceLogicalName())
+		}
+		if n.EIPManaged() {
+			if eip, err := n.EIPLogicalName(); err == nil {
+				protected = append(protected, eip)
+			}
+		}
+	}
+	return model.NewStackPolicyProtecting(protected)
+}
+
 // NestedStackName returns a sanitized name of this control-plane which is usable as a valid cloudformation nested stack name
 func (c Cluster) NestedStackName() string {
 	// Convert stack name into something valid as a cfn resource name or
@@ -860,6 +933,28 @@ func (c Cluster) valid() error {
 		return fmt.Errorf("clusterName(=%s) is malformed. It must consist only of alphanumeric characters, colons, or hyphens", c.ClusterName)
 	}
 
+	if c.StackPolicy != nil {
+		if err := c.StackPolicy.Valid(); err != nil {
+			return err
+		}
+	}
+
+	if err := c.TLS.Valid(); err != nil {
+		return err
+	}
+
+	if c.Experimental.TLSBootstrap.Enabled {
+		if !c.ManageCertificates {
+			return errors.New("`experimental.tlsBootstrap.enabled` requires `manageCertificates` to be true as the controller manager signs certificates of kubelets with the CA key managed by kube-aws")
+		}
+		if !c.Experimental.Plugins.Rbac.Enabled {
+			return errors.New("`experimental.tlsBootstrap.enabled` requires `experimental.plugins.rbac.enabled` to be true as the bootstrap token would otherwise be authorized to do anything with the API")
+		}
+		if err := c.validateTLSBootstrap(); err != nil {
+			return err
+		}
+	}
+
 	if c.CreateRecordSet {
 		if c.HostedZoneID == "" {
 			return errors.New("hostedZoneID must be specified when createRecordSet is true")
@@ -996,6 +1091,9 @@ func (c DeploymentSettings) Valid() (*DeploymentValidationResult, error) {
 	if c.KeyName == "" && len(c.SSHAuthorizedKeys) == 0 {
 		return nil, errors.New("Either keyName or sshAuthorizedKeys must be set")
 	}
+	if c.UserDataFormat != UserDataFormatCloudConfig && c.UserDataFormat != UserDataFormatIgnition {
+		return nil, fmt.Errorf("userDataFormat must be either %s or %s but was %s", UserDataFormatCloudConfig, UserDataFormatIgnition, c.UserDataFormat)
+	}
 	if c.ClusterName == "" {
 		return nil, errors.New("clusterName must be set")
 	}
@@ -1011,6 +1109,18 @@ func (c DeploymentSettings) Valid() (*DeploymentValidationResult, error) {
 		return nil, errors.New("region must be set")
 	}
 
+	if err := c.S3.Valid(); err != nil {
+		return nil, err
+	}
+
+	if err := c.AWS.Valid(); err != nil {
+		return nil, err
+	}
+
+	if err := c.Region.Endpoints.Valid(); err != nil {
+		return nil, err
+	}
+
 	_, vpcNet, err := net.ParseCIDR(c.VPCCIDR)
 	if err != nil {
 		return nil, fmt.Errorf("invalid vpcCIDR: %v", err)
@@ -1100,10 +1210,30 @@ func (c DeploymentSettings) Valid() (*DeploymentValidationResult, error) {
 	return &DeploymentValidationResult{vpcNet: vpcNet}, nil
 }
 
+// AWSConnOptions returns options of AWS sessions used to manage the cluster e.g. the role to assume
+func (c DeploymentSettings) AWSConnOptions() awsconn.Options {
+	o := awsconn.Options{
+		AssumeRoleARN: c.AWS.AssumeRoleARN,
+		ExternalID:    c.AWS.ExternalID,
+		SessionName:   c.AWS.SessionName,
+		MFASerial:     c.AWS.MFASerial,
+		Endpoints:     c.Region.Endpoints.ServiceEndpoints(),
+	}
+	if o.AssumeRoleARN != "" && o.SessionName == "" {
+		o.SessionName = fmt.Sprintf("kube-aws-%s", c.ClusterName)
+	}
+	return o
+}
+
 func (c DeploymentSettings) AssetsEncryptionEnabled() bool {
 	return c.ManageCertificates && c.Region.SupportsKMS()
 }
 
+// RawAssetsInUserData is true when TLS assets are written into userdata without being encrypted with KMS
+func (c DeploymentSettings) RawAssetsInUserData() bool {
+	return c.ManageCertificates && !c.AssetsEncryptionEnabled()
+}
+
 func (s DeploymentSettings) AllSubnets() []model.Subnet {
 	subnets := s.Subnets
 	return subnets
@@ -1235,6 +1365,14 @@ func (e EtcdSettings) Valid() error {
 		return fmt.Errorf("`etcd.kmsKeyArn` can only be specified when `etcdDataVolumeEncrypted` is enabled")
 	}
 
+	if err := model.ValidateCustomSystemdUnits(e.Etcd.CustomSystemdUnits); err != nil {
+		return fmt.Errorf("invalid etcd: %v", err)
+	}
+
+	if err := model.ValidateCustomFiles(e.Etcd.CustomFiles); err != nil {
+		return fmt.Errorf("invalid etcd: %v", err)
+	}
+
 	return nil
 }
 
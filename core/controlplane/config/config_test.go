@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/coreos/kube-aws/model"
@@ -157,6 +158,32 @@ func TestMinimalChinaConfig(t *testing.T) {
 	}
 }
 
+func TestAWSEndpointsConfig(t *testing.T) {
+	c, err := ClusterFromBytes([]byte(singleAzConfigYaml + `
+awsEndpoints:
+  s3: http://localhost:4572/
+  elb: http://localhost:4580
+`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	if c.Region.S3Endpoint() != "http://localhost:4572" {
+		t.Errorf("S3 endpoint should be overridden but was %s", c.Region.S3Endpoint())
+	}
+
+	expected := map[string]string{"s3": "http://localhost:4572/", "elasticloadbalancing": "http://localhost:4580"}
+	if actual := c.AWSConnOptions().Endpoints; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected endpoints: expected=%v, actual=%v", expected, actual)
+	}
+
+	for _, invalid := range []string{"awsEndpoints:\n  sqs: http://localhost:4576\n", "awsEndpoints:\n  s3: localhost:4572\n"} {
+		if _, err := ClusterFromBytes([]byte(singleAzConfigYaml + invalid)); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
 func TestKubernetesServiceIPInference(t *testing.T) {
 
 	// We sill assert that after parsing the network configuration,
@@ -831,3 +858,28 @@ func TestWithTrailingDot(t *testing.T) {
 		}
 	}
 }
+
+func TestEffectiveStackPolicy(t *testing.T) {
+	policyOf := func(yaml string) string {
+		c, err := ClusterFromBytes([]byte(singleAzConfigYaml + yaml))
+		if err != nil {
+			t.Fatalf("failed to parse config: %v", err)
+		}
+		cfg, err := c.Config()
+		if err != nil {
+			t.Fatalf("failed to create config: %v", err)
+		}
+		return cfg.EffectiveStackPolicy().Body()
+	}
+
+	if actual, expected := policyOf(""), model.NewAllowAllStackPolicy().Body(); actual != expected {
+		t.Errorf("expected the stack policy to allow any update by default: expected=%s actual=%s", expected, actual)
+	}
+
+	protected := policyOf("protectEtcdNodes: true\n")
+	for _, id := range []string{"Etcd0", "Etcd0EBS"} {
+		if !strings.Contains(protected, `"LogicalResourceId/`+id+`"`) {
+			t.Errorf("expected %s to be protected by the stack policy: %s", id, protected)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"github.com/coreos/kube-aws/coreos/userdatavalidation"
 	"github.com/coreos/kube-aws/filereader/jsontemplate"
-	"github.com/coreos/kube-aws/fingerprint"
 	"net/url"
 )
 
@@ -21,14 +20,51 @@ type CompressedStackConfig struct {
 	*StackConfig
 }
 
+// ControllerUserData is the userdata of controller nodes
+func (c *StackConfig) ControllerUserData() (UserData, error) {
+	s3dir, err := c.userDataControllerS3Directory()
+	if err != nil {
+		return UserData{}, fmt.Errorf("Error in ControllerUserData : %v", err)
+	}
+	return UserData{
+		Role:        "controller",
+		Format:      c.UserDataFormat,
+		Content:     c.UserDataController,
+		S3Dir:       s3dir,
+		AWSCliImage: c.AWSCliImage,
+		RawSecrets:  c.RawAssetsInUserData(),
+	}, nil
+}
+
+// EtcdUserData is the userdata of the etcd node at the index
+func (c *StackConfig) EtcdUserData(etcdIndex int) (UserData, error) {
+	s3dir, err := c.userDataEtcdS3Directory()
+	if err != nil {
+		return UserData{}, fmt.Errorf("Error in EtcdUserData : %v", err)
+	}
+	return UserData{
+		Role:        "etcd",
+		Format:      c.UserDataFormat,
+		Content:     c.UserDataEtcd,
+		S3Dir:       s3dir,
+		AWSCliImage: c.AWSCliImage,
+		Env: []string{
+			fmt.Sprintf("%s=%s", c.StackNameEnvVarName(), stackNameVariable),
+			fmt.Sprintf("%s=%d", c.EtcdIndexEnvVarName(), etcdIndex),
+		},
+		EnvFile:    c.EtcdNodeEnvFileName(),
+		RawSecrets: c.RawAssetsInUserData(),
+	}, nil
+}
+
 // UserDataControllerS3Prefix is the prefix prepended to all userdata-controller-<fingerprint> files uploaded to S3
 // Use this to author the IAM policy to provide controller nodes least required permissions for getting the files from S3
 func (c *StackConfig) UserDataControllerS3Prefix() (string, error) {
-	s3dir, err := c.userDataControllerS3Directory()
+	u, err := c.ControllerUserData()
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataControllerS3Prefix : %v", err)
 	}
-	return fmt.Sprintf("%s/userdata-controller", s3dir), nil
+	return u.S3Prefix(), nil
 }
 
 func (c *StackConfig) userDataControllerS3Directory() (string, error) {
@@ -42,26 +78,35 @@ func (c *StackConfig) userDataControllerS3Directory() (string, error) {
 // UserDataControllerS3URI is the URI to an userdata-controller-<fingerprint> file used to provision controller nodes
 // Use this to run download the file by running e.g. `aws cp *return value of UserDataControllerS3URI* ./`
 func (c *StackConfig) UserDataControllerS3URI() (string, error) {
-	s3dir, err := c.userDataControllerS3Directory()
+	u, err := c.ControllerUserData()
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataControllerS3URI : %v", err)
 	}
-	return fmt.Sprintf("s3://%s/%s", s3dir, c.UserDataControllerFileName()), nil
+	return u.S3URI(), nil
 }
 
 // UserDataControllerFileName is used to upload and download userdata-controller-<fingerprint> files
 func (c *StackConfig) UserDataControllerFileName() string {
-	return "userdata-controller-" + fingerprint.SHA256(c.UserDataController)
+	return UserData{Role: "controller", Content: c.UserDataController}.FileName()
+}
+
+// UserDataControllerExpression is the userdata of the launch configuration for controller nodes
+func (c *StackConfig) UserDataControllerExpression() (string, error) {
+	u, err := c.ControllerUserData()
+	if err != nil {
+		return "", err
+	}
+	return u.Expression()
 }
 
 // UserDataEtcdS3Prefix is the prefix prepended to all userdata-etcd-<fingerprint> files uploaded to S3
 // Use this to author the IAM policy to provide etcd nodes least required permissions for getting the files from S3
 func (c *StackConfig) UserDataEtcdS3Prefix() (string, error) {
-	s3dir, err := c.userDataEtcdS3Directory()
+	u, err := c.EtcdUserData(0)
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataEtcdS3Prefix : %v", err)
 	}
-	return fmt.Sprintf("%s/userdata-etcd", s3dir), nil
+	return u.S3Prefix(), nil
 }
 
 func (c *StackConfig) userDataEtcdS3Directory() (string, error) {
@@ -75,16 +120,60 @@ func (c *StackConfig) userDataEtcdS3Directory() (string, error) {
 // UserDataEtcdS3URI is the URI to an userdata-etcd-<fingerprint> file used to provision etcd nodes
 // Use this to run download the file by running e.g. `aws cp *return value of UserDataEtcdS3URI* ./`
 func (c *StackConfig) UserDataEtcdS3URI() (string, error) {
-	s3dir, err := c.userDataEtcdS3Directory()
+	u, err := c.EtcdUserData(0)
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataEtcdS3URI : %v", err)
 	}
-	return fmt.Sprintf("s3://%s/%s", s3dir, c.UserDataEtcdFileName()), nil
+	return u.S3URI(), nil
 }
 
 // UserDataEtcdFileName is used to upload and download userdata-etcd-<fingerprint> files
 func (c *StackConfig) UserDataEtcdFileName() string {
-	return "userdata-etcd-" + fingerprint.SHA256(c.UserDataEtcd)
+	return UserData{Role: "etcd", Content: c.UserDataEtcd}.FileName()
+}
+
+// UserDataEtcdExpression is the userdata of the launch configuration for the etcd node at the index
+func (c *StackConfig) UserDataEtcdExpression(etcdIndex int) (string, error) {
+	u, err := c.EtcdUserData(etcdIndex)
+	if err != nil {
+		return "", err
+	}
+	return u.Expression()
+}
+
+// userDataList returns the userdata of every launch configuration in the stack
+func (c *StackConfig) userDataList() ([]UserData, error) {
+	controller, err := c.ControllerUserData()
+	if err != nil {
+		return nil, err
+	}
+	list := []UserData{controller}
+	for i := range c.EtcdNodes {
+		etcd, err := c.EtcdUserData(i)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, etcd)
+	}
+	return list, nil
+}
+
+// UserDataSizes reports the size of userdata per role. Etcd nodes are reported once by the largest userdata among them
+func (c *StackConfig) UserDataSizes() ([]UserDataSize, error) {
+	list, err := c.userDataList()
+	if err != nil {
+		return nil, err
+	}
+	return ReportUserDataSizes(list)
+}
+
+// UserDataAssets returns userdata files which nodes fetch from S3 and therefore have to be uploaded, keyed by file name
+func (c *StackConfig) UserDataAssets() (map[string]string, error) {
+	list, err := c.userDataList()
+	if err != nil {
+		return nil, err
+	}
+	return UserDataToUpload(list)
 }
 
 func (c *StackConfig) ValidateUserData() error {
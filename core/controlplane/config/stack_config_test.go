@@ -1,8 +1,14 @@
 package config
 
 import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/coreos/kube-aws/coreos/ignition"
 	"github.com/coreos/kube-aws/model"
 	"github.com/coreos/kube-aws/test/helper"
+	"strings"
 	"testing"
 )
 
@@ -40,6 +46,49 @@ func TestRenderStackTemplate(t *testing.T) {
 	})
 }
 
+type failingEncryptService struct{}
+
+func (s failingEncryptService) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	return nil, fmt.Errorf("kms is unavailable")
+}
+
+func TestRenderStackTemplateWithoutAssetsEncryption(t *testing.T) {
+	cluster := newDefaultClusterWithDeps(failingEncryptService{})
+
+	cluster.Region = model.RegionForName("us-west-1")
+	cluster.Subnets = []model.Subnet{
+		model.NewPublicSubnet("us-west-1a", "10.0.1.0/16"),
+	}
+	cluster.AmiId = "ami-12345678"
+	cluster.SetDefaults()
+
+	helper.WithDummyCredentials(func(dir string) {
+		var stackTemplateOptions = StackTemplateOptions{
+			AssetsDir:             dir,
+			ControllerTmplFile:    "templates/cloud-config-controller",
+			EtcdTmplFile:          "templates/cloud-config-etcd",
+			StackTemplateTmplFile: "templates/stack-template.json",
+			SkipAssetsEncryption:  true,
+		}
+
+		stackConfig, err := cluster.StackConfig(stackTemplateOptions)
+		if err != nil {
+			t.Fatalf("expected the stack config to be generated without kms, but failed: %v", err)
+		}
+		if err := stackConfig.ValidateUserData(); err != nil {
+			t.Errorf("failed to validate user data: %v", err)
+		}
+
+		encrypted, err := filepath.Glob(filepath.Join(dir, "*.enc"))
+		if err != nil {
+			t.Fatalf("failed to list encrypted assets: %v", err)
+		}
+		if len(encrypted) > 0 {
+			t.Errorf("expected no encrypted assets to be written, but found %v", encrypted)
+		}
+	})
+}
+
 func TestValidateUserData(t *testing.T) {
 	cluster := newDefaultClusterWithDeps(&dummyEncryptService{})
 
@@ -68,3 +117,213 @@ func TestValidateUserData(t *testing.T) {
 		}
 	})
 }
+
+func TestValidateIgnitionUserData(t *testing.T) {
+	cluster := newDefaultClusterWithDeps(&dummyEncryptService{})
+
+	cluster.Region = model.RegionForName("us-west-1")
+	cluster.Subnets = []model.Subnet{
+		model.NewPublicSubnet("us-west-1a", "10.0.1.0/16"),
+		model.NewPublicSubnet("us-west-1b", "10.0.2.0/16"),
+	}
+	cluster.AmiId = "ami-12345678"
+	cluster.UserDataFormat = UserDataFormatIgnition
+	cluster.SetDefaults()
+
+	helper.WithDummyCredentials(func(dir string) {
+		var stackTemplateOptions = StackTemplateOptions{
+			AssetsDir:             dir,
+			ControllerTmplFile:    "templates/cloud-config-controller",
+			EtcdTmplFile:          "templates/cloud-config-etcd",
+			StackTemplateTmplFile: "templates/stack-template.json",
+			S3URI:                 "s3://mybucket/mydir",
+		}
+
+		stackConfig, err := cluster.StackConfig(stackTemplateOptions)
+		if err != nil {
+			t.Fatalf("failed to generate stack config : %v", err)
+		}
+
+		for name, userdata := range map[string]string{"controller": stackConfig.UserDataController, "etcd": stackConfig.UserDataEtcd} {
+			if !strings.HasPrefix(userdata, `{"ignition":{"version":"2.1.0"`) {
+				t.Errorf("expected %s userdata to be an ignition config, got %s", name, userdata)
+			}
+		}
+
+		if err := stackConfig.ValidateUserData(); err != nil {
+			t.Errorf("failed to validate user data: %v", err)
+		}
+
+		compressed, err := stackConfig.Compress()
+		if err != nil {
+			t.Fatalf("failed to compress : %v", err)
+		}
+		template, err := compressed.RenderStackTemplateAsString()
+		if err != nil {
+			t.Fatalf("failed to render stack template: %v", err)
+		}
+		if strings.Contains(template, "coreos-cloudinit") {
+			t.Errorf("expected userdata of launch configurations to be ignition configs, got %s", template)
+		}
+	})
+}
+
+func TestRenderCustomSystemdUnitsAndFiles(t *testing.T) {
+	for _, format := range []string{UserDataFormatCloudConfig, UserDataFormatIgnition} {
+		t.Run(format, func(t *testing.T) {
+			cluster := newDefaultClusterWithDeps(&dummyEncryptService{})
+
+			cluster.Region = model.RegionForName("us-west-1")
+			cluster.Subnets = []model.Subnet{
+				model.NewPublicSubnet("us-west-1a", "10.0.1.0/16"),
+			}
+			cluster.AmiId = "ami-12345678"
+			cluster.UserDataFormat = format
+			cluster.Controller.CustomSystemdUnits = []model.CustomSystemdUnit{
+				{
+					Name:    "log-shipper.service",
+					Command: "start",
+					Enable:  true,
+					Content: "[Unit]\nDescription=Ships logs\n\n[Service]\nExecStart=/usr/bin/true\n\n[Install]\nWantedBy=multi-user.target\n",
+				},
+				{
+					Name:    "docker.service",
+					DropIns: []model.CustomSystemdUnitDropIn{{Name: "90-custom.conf", Content: "[Service]\nEnvironment=FOO=bar\n"}},
+				},
+			}
+			cluster.Controller.CustomFiles = []model.CustomFile{
+				{Path: "/etc/sysctl.d/90-custom.conf", Permissions: 0600, Content: "  vm.max_map_count=262144\nnet.core.somaxconn=1024\n"},
+				// Values which would break YAML unless quoted
+				{Path: "/etc/kube-aws/env: #1", Owner: "root", Content: "FOO=bar\n"},
+			}
+			cluster.Etcd.CustomFiles = []model.CustomFile{
+				{Path: "/etc/motd", Owner: "root:root", Content: ""},
+			}
+			cluster.SetDefaults()
+
+			helper.WithDummyCredentials(func(dir string) {
+				var stackTemplateOptions = StackTemplateOptions{
+					AssetsDir:             dir,
+					ControllerTmplFile:    "templates/cloud-config-controller",
+					EtcdTmplFile:          "templates/cloud-config-etcd",
+					StackTemplateTmplFile: "templates/stack-template.json",
+					S3URI:                 "s3://mybucket/mydir",
+				}
+
+				stackConfig, err := cluster.StackConfig(stackTemplateOptions)
+				if err != nil {
+					t.Fatalf("failed to generate stack config : %v", err)
+				}
+
+				if err := stackConfig.ValidateUserData(); err != nil {
+					t.Errorf("failed to validate user data: %v", err)
+				}
+
+				sysctl := "vm.max_map_count=262144"
+				if format == UserDataFormatIgnition {
+					sysctl = ignition.EscapeDataURL(sysctl)
+				}
+				for _, expected := range []string{"log-shipper.service", "90-custom.conf", "Environment=FOO=bar", sysctl, `"/etc/kube-aws/env: #1"`} {
+					if !strings.Contains(stackConfig.UserDataController, expected) {
+						t.Errorf("expected controller userdata to contain %q: %s", expected, stackConfig.UserDataController)
+					}
+				}
+				if !strings.Contains(stackConfig.UserDataEtcd, "/etc/motd") {
+					t.Errorf("expected etcd userdata to contain the custom file: %s", stackConfig.UserDataEtcd)
+				}
+			})
+		})
+	}
+}
+
+func TestRenderTLSBootstrap(t *testing.T) {
+	for _, c := range []struct {
+		k8sVer          string
+		approvalByGroup bool
+	}{
+		{"v1.6.2_coreos.0", true},
+		{"v1.7.0_coreos.0", true},
+		{"v1.8.0_coreos.0", false},
+	} {
+		t.Run(c.k8sVer, func(t *testing.T) {
+			cluster := newDefaultClusterWithDeps(&dummyEncryptService{})
+
+			cluster.Region = model.RegionForName("us-west-1")
+			cluster.Subnets = []model.Subnet{
+				model.NewPublicSubnet("us-west-1a", "10.0.1.0/16"),
+			}
+			cluster.AmiId = "ami-12345678"
+			cluster.K8sVer = c.k8sVer
+			cluster.Experimental.TLSBootstrap.Enabled = true
+			cluster.Experimental.Plugins.Rbac.Enabled = true
+			cluster.SetDefaults()
+
+			if err := cluster.validateTLSBootstrap(); err != nil {
+				t.Fatalf("expected kubernetes %s to support tls bootstrapping: %v", c.k8sVer, err)
+			}
+
+			helper.WithTempDir(func(dir string) {
+				if err := genTLSAssets(t).WriteToDir(dir, true); err != nil {
+					t.Fatalf("failed to write tls assets: %v", err)
+				}
+				var stackTemplateOptions = StackTemplateOptions{
+					AssetsDir:             dir,
+					ControllerTmplFile:    "templates/cloud-config-controller",
+					EtcdTmplFile:          "templates/cloud-config-etcd",
+					StackTemplateTmplFile: "templates/stack-template.json",
+					S3URI:                 "s3://mybucket/mydir",
+				}
+
+				stackConfig, err := cluster.StackConfig(stackTemplateOptions)
+				if err != nil {
+					t.Fatalf("failed to generate stack config : %v", err)
+				}
+
+				if err := stackConfig.ValidateUserData(); err != nil {
+					t.Errorf("failed to validate user data: %v", err)
+				}
+
+				userdata := stackConfig.UserDataController
+				expected := []string{
+					"KUBELET_VERSION=" + c.k8sVer,
+					"/etc/kubernetes/ssl/ca-key.pem.enc",
+					"--cluster-signing-key-file=/etc/kubernetes/ssl/ca-key.pem",
+					"certificatesigningrequests/nodeclient",
+					"name: system:node\n",
+					"name: system:node-proxier\n",
+				}
+				unexpected := []string{
+					"certificates.k8s.io/v1alpha1",
+				}
+				approvalFlag := "--insecure-experimental-approve-all-kubelet-csrs-for-group=" + KubeletTLSBootstrapGroup
+				if c.approvalByGroup {
+					expected = append(expected, approvalFlag)
+				} else {
+					unexpected = append(unexpected, approvalFlag)
+				}
+				for _, e := range expected {
+					if !strings.Contains(userdata, e) {
+						t.Errorf("expected controller userdata to contain %q: %s", e, userdata)
+					}
+				}
+				for _, u := range unexpected {
+					if strings.Contains(userdata, u) {
+						t.Errorf("expected controller userdata not to contain %q: %s", u, userdata)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestTLSBootstrapRequiresKubernetes16(t *testing.T) {
+	for _, k8sVer := range []string{"v1.5.5_coreos.0", "1.4.9", "latest"} {
+		cluster := newDefaultClusterWithDeps(&dummyEncryptService{})
+		cluster.K8sVer = k8sVer
+		cluster.Experimental.TLSBootstrap.Enabled = true
+
+		if err := cluster.validateTLSBootstrap(); err == nil {
+			t.Errorf("expected tls bootstrapping to be rejected for kubernetes %s", k8sVer)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+package config
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/coreos/kube-aws/gzipcompressor"
+)
+
+const (
+	// KubeletTLSBootstrapGroup is the group kubelets authenticated with the bootstrap token belong to while requesting their certificates
+	KubeletTLSBootstrapGroup = "system:kubelet-bootstrap"
+
+	kubeletTLSBootstrapUser      = "kubelet-bootstrap"
+	kubeletTLSBootstrapUID       = "10001"
+	kubeletTLSBootstrapTokenFile = "kubelet-tls-bootstrap-token"
+)
+
+var (
+	// The controller manager approves certificate signing requests of kubelets automatically since Kubernetes 1.6
+	minTLSBootstrapK8sVersion = semver.Version{Major: 1, Minor: 6}
+	// --insecure-experimental-approve-all-kubelet-csrs-for-group is replaced with approvals authorized by RBAC in 1.7
+	// and removed in 1.8
+	maxKubeletCSRApprovalByGroupK8sVersion = semver.Version{Major: 1, Minor: 8}
+
+	k8sVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)`)
+)
+
+// TLSBootstrap makes kubelets on worker nodes obtain their own client certificates signed by the controller manager
+// instead of sharing worker.pem
+type TLSBootstrap struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// k8sVersion parses kubernetesVersion e.g. v1.6.2_coreos.0 ignoring the suffix
+func k8sVersion(version string) (*semver.Version, error) {
+	m := k8sVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return nil, fmt.Errorf("invalid kubernetes version \"%s\"", version)
+	}
+	v := semver.Version{}
+	for i, n := range []*int64{&v.Major, &v.Minor, &v.Patch} {
+		var err error
+		if *n, err = strconv.ParseInt(m[i+1], 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid kubernetes version \"%s\": %v", version, err)
+		}
+	}
+	return &v, nil
+}
+
+func (c DeploymentSettings) validateTLSBootstrap() error {
+	v, err := k8sVersion(c.K8sVer)
+	if err != nil {
+		return err
+	}
+	if v.LessThan(minTLSBootstrapK8sVersion) {
+		return fmt.Errorf("`experimental.tlsBootstrap.enabled` requires `kubernetesVersion` to be v%s or later as the controller manager can't approve certificates of kubelets before, but it is %s", minTLSBootstrapK8sVersion, c.K8sVer)
+	}
+	return nil
+}
+
+// KubeletCSRApprovalByGroup returns true if the controller manager approves certificate signing requests of kubelets in
+// the bootstrap group by a flag rather than by RBAC
+func (c DeploymentSettings) KubeletCSRApprovalByGroup() bool {
+	v, err := k8sVersion(c.K8sVer)
+	return err == nil && v.LessThan(maxKubeletCSRApprovalByGroupK8sVersion)
+}
+
+// Credentials for kubelet TLS bootstrapping: the CA key the controller manager signs certificates with and
+// the token kubelets request their certificates with
+type RawTLSBootstrapAssetsOnDisk struct {
+	CAKey RawCredentialOnDisk
+	Token RawCredentialOnDisk
+}
+
+// Encrypted credentials for kubelet TLS bootstrapping
+type EncryptedTLSBootstrapAssetsOnDisk struct {
+	CAKey EncryptedCredentialOnDisk
+	Token EncryptedCredentialOnDisk
+}
+
+// Encrypted -> gzip -> base64 encoded credentials for kubelet TLS bootstrapping
+type CompactTLSBootstrapAssets struct {
+	CAKey string
+	Token string
+}
+
+// NewTLSBootstrapTokenOnDisk generates a token for kubelets to request their certificates with and adds it to tokens.csv
+func NewTLSBootstrapTokenOnDisk(dir string) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, kubeletTLSBootstrapTokenFile), []byte(hex.EncodeToString(b)), 0600); err != nil {
+		return fmt.Errorf("error creating kubelet TLS bootstrap token file: %v", err)
+	}
+	return addTLSBootstrapTokenToAuthTokens(dir)
+}
+
+// addTLSBootstrapTokenToAuthTokens appends the bootstrap token to tokens.csv unless it is already there
+func addTLSBootstrapTokenToAuthTokens(dir string) error {
+	token, err := ioutil.ReadFile(filepath.Join(dir, kubeletTLSBootstrapTokenFile))
+	if err != nil {
+		return err
+	}
+	authTokensPath := filepath.Join(dir, "tokens.csv")
+	authTokens, err := ioutil.ReadFile(authTokensPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(authTokens))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", authTokensPath, err)
+	}
+	for _, r := range records {
+		if r[0] == string(token) {
+			return nil
+		}
+	}
+
+	buf := bytes.NewBuffer(authTokens)
+	if len(authTokens) > 0 && !bytes.HasSuffix(authTokens, []byte("\n")) {
+		buf.WriteString("\n")
+	}
+	w := csv.NewWriter(buf)
+	if err := w.Write([]string{string(token), kubeletTLSBootstrapUser, kubeletTLSBootstrapUID, KubeletTLSBootstrapGroup}); err != nil {
+		return err
+	}
+	w.Flush()
+	return ioutil.WriteFile(authTokensPath, buf.Bytes(), 0600)
+}
+
+// ReadOrCreateRawTLSBootstrapAssets reads the CA key and the bootstrap token, generating the token if missing so that
+// TLS bootstrapping can be enabled for an existing cluster. The CA key must be in the directory as ca-key.pem and match ca.pem
+func ReadOrCreateRawTLSBootstrapAssets(dirname string) (*RawTLSBootstrapAssetsOnDisk, error) {
+	tokenPath := filepath.Join(dirname, kubeletTLSBootstrapTokenFile)
+	if _, err := os.Stat(tokenPath); os.IsNotExist(err) {
+		if err := NewTLSBootstrapTokenOnDisk(dirname); err != nil {
+			return nil, err
+		}
+	}
+	if err := addTLSBootstrapTokenToAuthTokens(dirname); err != nil {
+		return nil, err
+	}
+
+	cas, err := readCACerts(dirname)
+	if err != nil {
+		return nil, err
+	}
+	caKeyPath := filepath.Join(dirname, "ca-key.pem")
+	if _, err := readCAKey(caKeyPath, cas[0]); err != nil {
+		return nil, fmt.Errorf("the controller manager needs the key of the CA in ca.pem to sign certificates of kubelets with TLS bootstrapping: %v", err)
+	}
+
+	caKey, err := RawCredentialFileFromPath(caKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	token, err := RawCredentialFileFromPath(tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(token.String()) == "" {
+		return nil, fmt.Errorf("%s is empty", tokenPath)
+	}
+	return &RawTLSBootstrapAssetsOnDisk{CAKey: *caKey, Token: *token}, nil
+}
+
+func ReadOrEncryptTLSBootstrapAssets(dirname string, encryptor CachedEncryptor) (*EncryptedTLSBootstrapAssetsOnDisk, error) {
+	if _, err := ReadOrCreateRawTLSBootstrapAssets(dirname); err != nil {
+		return nil, err
+	}
+
+	r := new(EncryptedTLSBootstrapAssetsOnDisk)
+	files := []struct {
+		name string
+		data *EncryptedCredentialOnDisk
+	}{
+		{"ca-key.pem", &r.CAKey},
+		{kubeletTLSBootstrapTokenFile, &r.Token},
+	}
+	for _, file := range files {
+		data, err := encryptor.EncryptedCredentialFromPath(filepath.Join(dirname, file.name))
+		if err != nil {
+			return nil, err
+		}
+		if err := data.Persist(); err != nil {
+			return nil, err
+		}
+		*file.data = *data
+	}
+	return r, nil
+}
+
+func ReadOrCreateEncryptedTLSBootstrapAssets(dirname string, kmsConfig KMSConfig) (*EncryptedTLSBootstrapAssetsOnDisk, error) {
+	encryptor, err := newCachedEncryptor(kmsConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadOrEncryptTLSBootstrapAssets(dirname, encryptor)
+}
+
+func (r *RawTLSBootstrapAssetsOnDisk) Compact() (*CompactTLSBootstrapAssets, error) {
+	return compactTLSBootstrapAssets(r.CAKey.content, r.Token.content)
+}
+
+func (r *EncryptedTLSBootstrapAssetsOnDisk) Compact() (*CompactTLSBootstrapAssets, error) {
+	return compactTLSBootstrapAssets(r.CAKey.content, r.Token.content)
+}
+
+func compactTLSBootstrapAssets(caKey []byte, token []byte) (*CompactTLSBootstrapAssets, error) {
+	compactCAKey, err := gzipcompressor.CompressData(caKey)
+	if err != nil {
+		return nil, err
+	}
+	compactToken, err := gzipcompressor.CompressData(token)
+	if err != nil {
+		return nil, err
+	}
+	return &CompactTLSBootstrapAssets{CAKey: compactCAKey, Token: compactToken}, nil
+}
+
+func ReadOrCreateCompactTLSBootstrapAssets(dirname string, kmsConfig KMSConfig) (*CompactTLSBootstrapAssets, error) {
+	encryptedAssets, err := ReadOrCreateEncryptedTLSBootstrapAssets(dirname, kmsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read/create TLS bootstrap assets: %v", err)
+	}
+
+	compactAssets, err := encryptedAssets.Compact()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress TLS bootstrap assets: %v", err)
+	}
+
+	return compactAssets, nil
+}
+
+func ReadOrCreateUnencryptedCompactTLSBootstrapAssets(dirname string) (*CompactTLSBootstrapAssets, error) {
+	rawAssets, err := ReadOrCreateRawTLSBootstrapAssets(dirname)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read/create TLS bootstrap assets: %v", err)
+	}
+
+	compactAssets, err := rawAssets.Compact()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compress TLS bootstrap assets: %v", err)
+	}
+
+	return compactAssets, nil
+}
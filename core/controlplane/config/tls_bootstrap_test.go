@@ -0,0 +1,83 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/coreos/kube-aws/test/helper"
+)
+
+func TestReadOrCreateRawTLSBootstrapAssets(t *testing.T) {
+	existingTokens := "admintoken,admin,10000,system:masters\n"
+
+	withTLSAssets := func(fn func(dir string)) {
+		helper.WithTempDir(func(dir string) {
+			if err := genTLSAssets(t).WriteToDir(dir, true); err != nil {
+				t.Fatalf("failed to write tls assets: %v", err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(dir, "tokens.csv"), []byte(existingTokens), 0600); err != nil {
+				t.Fatalf("failed to write tokens.csv: %v", err)
+			}
+			fn(dir)
+		})
+	}
+
+	t.Run("CreatesTokenAndAddsItToAuthTokens", func(t *testing.T) {
+		withTLSAssets(func(dir string) {
+			created, err := ReadOrCreateRawTLSBootstrapAssets(dir)
+			if err != nil {
+				t.Fatalf("failed to read or create tls bootstrap assets: %v", err)
+			}
+			token := created.Token.String()
+			if len(token) != 32 {
+				t.Errorf("expected a 32 characters long token but was %q", token)
+			}
+
+			read, err := ReadOrCreateRawTLSBootstrapAssets(dir)
+			if err != nil {
+				t.Fatalf("failed to read tls bootstrap assets: %v", err)
+			}
+			if read.Token.String() != token {
+				t.Errorf("expected the token to be unchanged once created but it changed from %q to %q", token, read.Token.String())
+			}
+
+			authTokens, err := ioutil.ReadFile(filepath.Join(dir, "tokens.csv"))
+			if err != nil {
+				t.Fatalf("failed to read tokens.csv: %v", err)
+			}
+			expected := existingTokens + token + ",kubelet-bootstrap,10001,system:kubelet-bootstrap\n"
+			if string(authTokens) != expected {
+				t.Errorf("unexpected tokens.csv: expected=%q actual=%q", expected, string(authTokens))
+			}
+			if err := validateAuthTokens(authTokens); err != nil {
+				t.Errorf("expected tokens.csv with the bootstrap token to be valid: %v", err)
+			}
+		})
+	})
+
+	t.Run("MissingCAKey", func(t *testing.T) {
+		withTLSAssets(func(dir string) {
+			if err := os.Remove(filepath.Join(dir, "ca-key.pem")); err != nil {
+				t.Fatalf("failed to remove ca-key.pem: %v", err)
+			}
+			if _, err := ReadOrCreateRawTLSBootstrapAssets(dir); err == nil {
+				t.Errorf("expected an error without ca-key.pem")
+			}
+		})
+	})
+
+	t.Run("MismatchedCAKey", func(t *testing.T) {
+		withTLSAssets(func(dir string) {
+			if err := ioutil.WriteFile(filepath.Join(dir, "ca-key.pem"), genTLSAssets(t).CAKey, 0600); err != nil {
+				t.Fatalf("failed to overwrite ca-key.pem: %v", err)
+			}
+			_, err := ReadOrCreateRawTLSBootstrapAssets(dir)
+			if err == nil || !strings.Contains(err.Error(), "TLS bootstrapping") {
+				t.Errorf("expected an error for the CA key not matching ca.pem but was: %v", err)
+			}
+		})
+	})
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/coreos/kube-aws/tlsutil"
+)
+
+// DefaultCertExpiryWindowDays is the number of days before expiry from which certificates are reported as expiring
+const DefaultCertExpiryWindowDays = 30
+
+// CertificateStatus describes a certificate in the TLS assets directory and the problems found in it
+type CertificateStatus struct {
+	File     string    `json:"file" yaml:"file"`
+	Subject  string    `json:"subject" yaml:"subject"`
+	SANs     []string  `json:"sans,omitempty" yaml:"sans,omitempty"`
+	Issuer   string    `json:"issuer" yaml:"issuer"`
+	Serial   string    `json:"serial" yaml:"serial"`
+	NotAfter time.Time `json:"notAfter" yaml:"notAfter"`
+	Problems []string  `json:"problems,omitempty" yaml:"problems,omitempty"`
+}
+
+// TLSAssetsReport is the result of checking every certificate in the TLS assets directory
+type TLSAssetsReport struct {
+	Dir              string               `json:"dir" yaml:"dir"`
+	ExpiryWindowDays int                  `json:"expiryWindowDays" yaml:"expiryWindowDays"`
+	Certificates     []*CertificateStatus `json:"certificates" yaml:"certificates"`
+}
+
+func (r *TLSAssetsReport) HasProblems() bool {
+	for _, c := range r.Certificates {
+		if len(c.Problems) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TLSAssetsReport) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FILE\tSUBJECT\tISSUER\tSERIAL\tNOT AFTER\tSANS")
+	for _, c := range r.Certificates {
+		sans := strings.Join(c.SANs, ",")
+		if sans == "" {
+			sans = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.File, c.Subject, c.Issuer, c.Serial, c.NotAfter.Format(time.RFC3339), sans)
+	}
+	w.Flush()
+	for _, c := range r.Certificates {
+		for _, p := range c.Problems {
+			fmt.Fprintf(buf, "PROBLEM: %s (%s): %s\n", c.File, c.Subject, p)
+		}
+	}
+	return buf.String()
+}
+
+// CheckTLSAssets inspects every certificate in the TLS assets directory. It reports certificates expiring within the number
+// of days from now, not signed by any CA in ca.pem, and the apiserver certificate not valid for externalDNSName or the IP of the
+// kubernetes service
+func (c *Cluster) CheckTLSAssets(dir string, expiryWindowDays int, now time.Time) (*TLSAssetsReport, error) {
+	report := &TLSAssetsReport{
+		Dir:              dir,
+		ExpiryWindowDays: expiryWindowDays,
+		Certificates:     []*CertificateStatus{},
+	}
+	expiryThreshold := now.AddDate(0, 0, expiryWindowDays)
+
+	cas, err := readCACerts(dir)
+	if os.IsNotExist(err) && !c.ManageCertificates {
+		return report, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceIP, err := c.kubernetesServiceIP()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Contains(data, []byte("-----BEGIN CERTIFICATE-----")) {
+			continue
+		}
+		certs, err := tlsutil.DecodeCertificatesPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+
+		file := filepath.Base(path)
+		for i, cert := range certs {
+			status := &CertificateStatus{
+				File:     file,
+				Subject:  formatName(cert.Subject),
+				SANs:     subjectAltNames(cert),
+				Issuer:   formatName(cert.Issuer),
+				Serial:   cert.SerialNumber.String(),
+				NotAfter: cert.NotAfter,
+				Problems: []string{},
+			}
+
+			if cert.NotAfter.Before(now) {
+				status.Problems = append(status.Problems, fmt.Sprintf("expired on %s", cert.NotAfter.Format("2006-01-02")))
+			} else if cert.NotAfter.Before(expiryThreshold) {
+				status.Problems = append(status.Problems, fmt.Sprintf("expires on %s, within %d days", cert.NotAfter.Format("2006-01-02"), expiryWindowDays))
+			}
+
+			// Certificates following the first one in a file are intermediate CAs
+			if file != "ca.pem" && i == 0 && !signedByAny(cert, cas) {
+				status.Problems = append(status.Problems, "not signed by any CA in ca.pem")
+			}
+
+			if file == "apiserver.pem" && i == 0 {
+				for _, name := range []string{c.ExternalDNSName, serviceIP.String()} {
+					if err := cert.VerifyHostname(name); err != nil {
+						status.Problems = append(status.Problems, fmt.Sprintf("not valid for %s", name))
+					}
+				}
+			}
+
+			report.Certificates = append(report.Certificates, status)
+		}
+	}
+	return report, nil
+}
+
+func signedByAny(cert *x509.Certificate, cas []*x509.Certificate) bool {
+	for _, ca := range cas {
+		if cert.CheckSignatureFrom(ca) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	return sans
+}
+
+// formatName formats the common name and organizations of a subject or an issuer like CN=kube-ca,O=kube-aws
+func formatName(n pkix.Name) string {
+	parts := []string{}
+	if n.CommonName != "" {
+		parts = append(parts, "CN="+n.CommonName)
+	}
+	for _, o := range n.Organization {
+		parts = append(parts, "O="+o)
+	}
+	return strings.Join(parts, ",")
+}
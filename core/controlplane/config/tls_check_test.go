@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/kube-aws/test/helper"
+)
+
+func problemsOf(report *TLSAssetsReport, file string) []string {
+	for _, c := range report.Certificates {
+		if c.File == file {
+			return c.Problems
+		}
+	}
+	return nil
+}
+
+func TestCheckTLSAssets(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+
+	helper.WithTempDir(func(dir string) {
+		if err := genTLSAssets(t).WriteToDir(dir, true); err != nil {
+			t.Fatalf("failed to write tls assets: %v", err)
+		}
+
+		t.Run("Valid", func(t *testing.T) {
+			report, err := cluster.CheckTLSAssets(dir, DefaultCertExpiryWindowDays, time.Now())
+			if err != nil {
+				t.Fatalf("failed to check tls assets: %v", err)
+			}
+			if len(report.Certificates) != 6 || report.HasProblems() {
+				t.Errorf("expected 6 certificates without problems, got:\n%s", report.String())
+			}
+			for _, c := range report.Certificates {
+				if c.File == "apiserver.pem" && (c.Subject != "CN=kube-apiserver,O=kube-aws" || c.Issuer != "CN=kube-ca,O=kube-aws") {
+					t.Errorf("unexpected apiserver certificate: %+v", c)
+				}
+			}
+		})
+
+		t.Run("Expiring", func(t *testing.T) {
+			report, err := cluster.CheckTLSAssets(dir, DefaultCertExpiryWindowDays, time.Now().AddDate(0, 0, 340))
+			if err != nil {
+				t.Fatalf("failed to check tls assets: %v", err)
+			}
+			if problems := problemsOf(report, "worker.pem"); len(problems) != 1 || !strings.Contains(problems[0], "within 30 days") {
+				t.Errorf("expected the worker certificate to be expiring, got %v", problems)
+			}
+			if problems := problemsOf(report, "ca.pem"); len(problems) != 0 {
+				t.Errorf("expected the CA not to be expiring, got %v", problems)
+			}
+		})
+
+		t.Run("MismatchedAPIServerNames", func(t *testing.T) {
+			c := *cluster
+			c.ExternalDNSName = "other.example.com"
+			c.ServiceCIDR = "10.4.0.0/24"
+			report, err := c.CheckTLSAssets(dir, DefaultCertExpiryWindowDays, time.Now())
+			if err != nil {
+				t.Fatalf("failed to check tls assets: %v", err)
+			}
+			problems := problemsOf(report, "apiserver.pem")
+			if len(problems) != 2 || problems[0] != "not valid for other.example.com" || problems[1] != "not valid for 10.4.0.1" {
+				t.Errorf("unexpected problems: %v", problems)
+			}
+		})
+
+		t.Run("NotSignedByCA", func(t *testing.T) {
+			other := genTLSAssets(t)
+			if err := ioutil.WriteFile(filepath.Join(dir, "admin.pem"), other.AdminCert, 0600); err != nil {
+				t.Fatalf("failed to write admin.pem: %v", err)
+			}
+			report, err := cluster.CheckTLSAssets(dir, DefaultCertExpiryWindowDays, time.Now())
+			if err != nil {
+				t.Fatalf("failed to check tls assets: %v", err)
+			}
+			if problems := problemsOf(report, "admin.pem"); len(problems) != 1 || problems[0] != "not signed by any CA in ca.pem" {
+				t.Errorf("unexpected problems: %v", problems)
+			}
+		})
+	})
+}
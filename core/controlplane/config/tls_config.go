@@ -1,7 +1,7 @@
 package config
 
 import (
-	"crypto/rsa"
+	"crypto"
 	"crypto/x509"
 	"fmt"
 	"net"
@@ -10,9 +10,8 @@ import (
 	"io/ioutil"
 	"path/filepath"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/gzipcompressor"
 	"github.com/coreos/kube-aws/model"
 	"github.com/coreos/kube-aws/netutil"
@@ -83,8 +82,8 @@ type CompactTLSAssets struct {
 	EtcdKey        string
 }
 
-func (c *Cluster) NewTLSCA() (*rsa.PrivateKey, *x509.Certificate, error) {
-	caKey, err := tlsutil.NewPrivateKey()
+func (c *Cluster) NewTLSCA() (crypto.Signer, *x509.Certificate, error) {
+	caKey, err := tlsutil.NewPrivateKeyWithAlgorithm(c.TLS.KeyAlgorithm)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -109,9 +108,15 @@ type CredentialsOptions struct {
 	GenerateCA bool
 	CaKeyPath  string
 	CaCertPath string
+	// VaultAddr enables signing certificates with the PKI secret backend of Vault instead of a local CA
+	VaultAddr      string
+	VaultToken     string
+	VaultPKIMount  string
+	VaultRole      string
+	VaultAdminRole string
 }
 
-func (c *Cluster) NewTLSAssetsOnDisk(dir string, renderCredentialsOpts CredentialsOptions, caKey *rsa.PrivateKey, caCert *x509.Certificate) (*RawTLSAssetsOnDisk, error) {
+func (c *Cluster) NewTLSAssetsOnDisk(dir string, renderCredentialsOpts CredentialsOptions, caKey crypto.Signer, caCert *x509.Certificate) (*RawTLSAssetsOnDisk, error) {
 	assets, err := c.NewTLSAssetsOnMemory(caKey, caCert)
 	if err != nil {
 		return nil, fmt.Errorf("Error generating default assets: %v", err)
@@ -122,26 +127,73 @@ func (c *Cluster) NewTLSAssetsOnDisk(dir string, renderCredentialsOpts Credentia
 	return ReadRawTLSAssets(dir)
 }
 
-func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certificate) (*RawTLSAssetsOnMemory, error) {
-	// Convert from days to time.Duration
-	certDuration := time.Duration(c.TLSCertDurationDays) * 24 * time.Hour
+// NewTLSAssetsOnDiskWithSigner writes TLS assets with certificates issued by the signer in the same layout as NewTLSAssetsOnDisk
+// except ca-key.pem
+func (c *Cluster) NewTLSAssetsOnDiskWithSigner(dir string, signer tlsutil.CertSigner) (*RawTLSAssetsOnDisk, error) {
+	assets, err := c.NewTLSAssetsWithSigner(signer)
+	if err != nil {
+		return nil, fmt.Errorf("Error generating default assets: %v", err)
+	}
+	if err := assets.WriteToDir(dir, false); err != nil {
+		return nil, fmt.Errorf("Error create assets: %v", err)
+	}
+	return ReadRawTLSAssets(dir)
+}
+
+// tlsKeys are the private keys of the certificates signed by the CA
+type tlsKeys struct {
+	apiServer, worker, admin, etcd, etcdClient crypto.Signer
+}
 
+func newTLSKeys(algorithm string) (*tlsKeys, error) {
 	// Generate keys for the various components.
-	keys := make([]*rsa.PrivateKey, 5)
+	keys := make([]crypto.Signer, 5)
 	var err error
 	for i := range keys {
-		if keys[i], err = tlsutil.NewPrivateKey(); err != nil {
+		if keys[i], err = tlsutil.NewPrivateKeyWithAlgorithm(algorithm); err != nil {
 			return nil, err
 		}
 	}
-	apiServerKey, workerKey, adminKey, etcdKey, etcdClientKey := keys[0], keys[1], keys[2], keys[3], keys[4]
+	return &tlsKeys{keys[0], keys[1], keys[2], keys[3], keys[4]}, nil
+}
 
-	//Compute kubernetesServiceIP from serviceCIDR
+// kubernetesServiceIP computes the IP of the kubernetes service from serviceCIDR
+func (c *Cluster) kubernetesServiceIP() (net.IP, error) {
 	_, serviceNet, err := net.ParseCIDR(c.ServiceCIDR)
 	if err != nil {
 		return nil, fmt.Errorf("invalid serviceCIDR: %v", err)
 	}
-	kubernetesServiceIPAddr := netutil.IncrementIP(serviceNet.IP)
+	return netutil.IncrementIP(serviceNet.IP), nil
+}
+
+func (c *Cluster) NewTLSAssetsOnMemory(caKey crypto.Signer, caCert *x509.Certificate) (*RawTLSAssetsOnMemory, error) {
+	keys, err := newTLSKeys(c.TLS.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return c.newTLSAssetsOnMemory(tlsutil.NewLocalCASigner(caCert, caKey), caKey, keys)
+}
+
+// NewTLSAssetsWithSigner generates TLS assets with certificates issued by the signer. The CA key is left empty as it
+// isn't available to kube-aws
+func (c *Cluster) NewTLSAssetsWithSigner(signer tlsutil.CertSigner) (*RawTLSAssetsOnMemory, error) {
+	keys, err := newTLSKeys(c.TLS.KeyAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return c.newTLSAssetsOnMemory(signer, nil, keys)
+}
+
+func (c *Cluster) newTLSAssetsOnMemory(signer tlsutil.CertSigner, caKey crypto.Signer, keys *tlsKeys) (*RawTLSAssetsOnMemory, error) {
+	// Convert from days to time.Duration
+	certDuration := time.Duration(c.TLSCertDurationDays) * 24 * time.Hour
+
+	apiServerKey, workerKey, adminKey, etcdKey, etcdClientKey := keys.apiServer, keys.worker, keys.admin, keys.etcd, keys.etcdClient
+
+	kubernetesServiceIPAddr, err := c.kubernetesServiceIP()
+	if err != nil {
+		return nil, err
+	}
 
 	apiServerConfig := tlsutil.ServerCertConfig{
 		CommonName: "kube-apiserver",
@@ -157,7 +209,7 @@ func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certi
 		},
 		Duration: certDuration,
 	}
-	apiServerCert, err := tlsutil.NewSignedServerCertificate(apiServerConfig, apiServerKey, caCert, caKey)
+	apiServerCert, err := signer.SignServerCertificate(apiServerConfig, apiServerKey)
 	if err != nil {
 		return nil, err
 	}
@@ -170,7 +222,7 @@ func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certi
 		Duration: tlsutil.Duration365d,
 	}
 
-	etcdCert, err := tlsutil.NewSignedServerCertificate(etcdConfig, etcdKey, caCert, caKey)
+	etcdCert, err := signer.SignServerCertificate(etcdConfig, etcdKey)
 	if err != nil {
 		return nil, err
 	}
@@ -183,7 +235,7 @@ func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certi
 		},
 		Duration: certDuration,
 	}
-	workerCert, err := tlsutil.NewSignedClientCertificate(workerConfig, workerKey, caCert, caKey)
+	workerCert, err := signer.SignClientCertificate(workerConfig, workerKey)
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +245,7 @@ func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certi
 		Duration:   certDuration,
 	}
 
-	etcdClientCert, err := tlsutil.NewSignedClientCertificate(etcdClientConfig, etcdClientKey, caCert, caKey)
+	etcdClientCert, err := signer.SignClientCertificate(etcdClientConfig, etcdClientKey)
 	if err != nil {
 		return nil, err
 	}
@@ -203,25 +255,50 @@ func (c *Cluster) NewTLSAssetsOnMemory(caKey *rsa.PrivateKey, caCert *x509.Certi
 		Organization: []string{"system:masters"},
 		Duration:     certDuration,
 	}
-	adminCert, err := tlsutil.NewSignedClientCertificate(adminConfig, adminKey, caCert, caKey)
+	adminCert, err := signer.SignClientCertificate(adminConfig, adminKey)
 	if err != nil {
 		return nil, err
 	}
 
-	return &RawTLSAssetsOnMemory{
-		CACert:         tlsutil.EncodeCertificatePEM(caCert),
+	// ca.pem contains the chain from the issuing CA to the root so that certificates issued by an intermediate CA are verified
+	cas, err := signer.CACertificates()
+	if err != nil {
+		return nil, err
+	}
+	caCertPEM := []byte{}
+	for _, ca := range cas {
+		caCertPEM = append(caCertPEM, tlsutil.EncodeCertificatePEM(ca)...)
+	}
+
+	assets := RawTLSAssetsOnMemory{
+		CACert:         caCertPEM,
 		APIServerCert:  tlsutil.EncodeCertificatePEM(apiServerCert),
 		WorkerCert:     tlsutil.EncodeCertificatePEM(workerCert),
 		AdminCert:      tlsutil.EncodeCertificatePEM(adminCert),
 		EtcdCert:       tlsutil.EncodeCertificatePEM(etcdCert),
 		EtcdClientCert: tlsutil.EncodeCertificatePEM(etcdClientCert),
-		CAKey:          tlsutil.EncodePrivateKeyPEM(caKey),
-		APIServerKey:   tlsutil.EncodePrivateKeyPEM(apiServerKey),
-		WorkerKey:      tlsutil.EncodePrivateKeyPEM(workerKey),
-		AdminKey:       tlsutil.EncodePrivateKeyPEM(adminKey),
-		EtcdKey:        tlsutil.EncodePrivateKeyPEM(etcdKey),
-		EtcdClientKey:  tlsutil.EncodePrivateKeyPEM(etcdClientKey),
-	}, nil
+	}
+	encodedKeys := []struct {
+		key crypto.Signer
+		out *[]byte
+	}{
+		{caKey, &assets.CAKey},
+		{apiServerKey, &assets.APIServerKey},
+		{workerKey, &assets.WorkerKey},
+		{adminKey, &assets.AdminKey},
+		{etcdKey, &assets.EtcdKey},
+		{etcdClientKey, &assets.EtcdClientKey},
+	}
+	for _, k := range encodedKeys {
+		// The CA key is nil when certificates are signed by an external signer
+		if k.key == nil {
+			continue
+		}
+		if *k.out, err = tlsutil.EncodePrivateKeyPEM(k.key); err != nil {
+			return nil, err
+		}
+	}
+	return &assets, nil
 }
 
 func ReadRawTLSAssets(dirname string) (*RawTLSAssetsOnDisk, error) {
@@ -422,30 +499,38 @@ func (r *EncryptedTLSAssetsOnDisk) Compact() (*CompactTLSAssets, error) {
 
 type KMSConfig struct {
 	Region         model.Region
+	AWSOptions     awsconn.Options
 	EncryptService EncryptService
 	KMSKeyARN      string
 }
 
-func ReadOrCreateEncryptedTLSAssets(tlsAssetsDir string, kmsConfig KMSConfig) (*EncryptedTLSAssetsOnDisk, error) {
+// newCachedEncryptor returns an encryptor with the encrypt service in the config, or KMS when it isn't given
+func newCachedEncryptor(kmsConfig KMSConfig) (CachedEncryptor, error) {
 	var kmsSvc EncryptService
 
 	// TODO Cleaner way to inject this dependency
 	if kmsConfig.EncryptService == nil {
-		awsConfig := aws.NewConfig().
-			WithRegion(kmsConfig.Region.String()).
-			WithCredentialsChainVerboseErrors(true)
-		kmsSvc = kms.New(session.New(awsConfig))
+		session, err := awsconn.NewSession(awsconn.Config{Region: kmsConfig.Region, Options: kmsConfig.AWSOptions})
+		if err != nil {
+			return CachedEncryptor{}, err
+		}
+		kmsSvc = kms.New(session)
 	} else {
 		kmsSvc = kmsConfig.EncryptService
 	}
 
-	encryptionSvc := bytesEncryptionService{
-		kmsKeyARN: kmsConfig.KMSKeyARN,
-		kmsSvc:    kmsSvc,
-	}
+	return CachedEncryptor{
+		bytesEncryptionService: bytesEncryptionService{
+			kmsKeyARN: kmsConfig.KMSKeyARN,
+			kmsSvc:    kmsSvc,
+		},
+	}, nil
+}
 
-	encryptor := CachedEncryptor{
-		bytesEncryptionService: encryptionSvc,
+func ReadOrCreateEncryptedTLSAssets(tlsAssetsDir string, kmsConfig KMSConfig) (*EncryptedTLSAssetsOnDisk, error) {
+	encryptor, err := newCachedEncryptor(kmsConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	readOrEncryptedTLSAssets, err := ReadOrEncryptTLSAssets(tlsAssetsDir, encryptor)
@@ -3,15 +3,26 @@ package config
 import (
 	"testing"
 
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"github.com/coreos/kube-aws/model"
 	"github.com/coreos/kube-aws/test/helper"
+	"github.com/coreos/kube-aws/tlsutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
+	"time"
 )
 
 func genTLSAssets(t *testing.T) *RawTLSAssetsOnMemory {
@@ -109,6 +120,290 @@ func TestTLSGeneration(t *testing.T) {
 	}
 }
 
+func TestTLSGenerationWithKeyAlgorithm(t *testing.T) {
+	for _, algorithm := range tlsutil.KeyAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml + fmt.Sprintf(`
+tls:
+  keyAlgorithm: %s
+`, algorithm)))
+			if err != nil {
+				t.Fatalf("failed generating config: %v", err)
+			}
+
+			caKey, caCert, err := cluster.NewTLSCA()
+			if err != nil {
+				t.Fatalf("failed generating tls ca: %v", err)
+			}
+			assets, err := cluster.NewTLSAssetsOnMemory(caKey, caCert)
+			if err != nil {
+				t.Fatalf("failed generating tls: %v", err)
+			}
+
+			for name, keyBytes := range map[string][]byte{"ca": assets.CAKey, "apiserver": assets.APIServerKey, "worker": assets.WorkerKey} {
+				key, err := tlsutil.DecodePrivateKeyPEM(keyBytes)
+				if err != nil {
+					t.Errorf("failed to parse key %s: %v", name, err)
+					continue
+				}
+				switch k := key.(type) {
+				case *rsa.PrivateKey:
+					if algorithm != fmt.Sprintf("rsa-%d", k.N.BitLen()) {
+						t.Errorf("unexpected %d-bit RSA key %s for %s", k.N.BitLen(), name, algorithm)
+					}
+				case *ecdsa.PrivateKey:
+					if algorithm != fmt.Sprintf("ecdsa-p%d", k.Curve.Params().BitSize) {
+						t.Errorf("unexpected %s ECDSA key %s for %s", k.Curve.Params().Name, name, algorithm)
+					}
+				default:
+					t.Errorf("unexpected key type %T of %s", key, name)
+				}
+			}
+
+			apiServerCert, err := tlsutil.DecodeCertificatePEM(assets.APIServerCert)
+			if err != nil {
+				t.Fatalf("failed to parse apiserver cert: %v", err)
+			}
+			if err := apiServerCert.CheckSignatureFrom(caCert); err != nil {
+				t.Errorf("could not verify apiserver certificate signature: %v", err)
+			}
+		})
+	}
+}
+
+// newIntermediateCA generates an intermediate CA signed by a new root CA
+func newIntermediateCA(t *testing.T, cluster *Cluster) (crypto.Signer, []*x509.Certificate) {
+	rootKey, rootCert, err := cluster.NewTLSCA()
+	if err != nil {
+		t.Fatalf("failed generating root ca: %v", err)
+	}
+	key, err := tlsutil.NewPrivateKeyWithAlgorithm(tlsutil.KeyAlgorithmECDSAP256)
+	if err != nil {
+		t.Fatalf("failed generating intermediate ca key: %v", err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: "kube-intermediate-ca", Organization: []string{"kube-aws"}},
+		NotBefore:             rootCert.NotBefore,
+		NotAfter:              rootCert.NotAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, rootCert, key.Public(), rootKey)
+	if err != nil {
+		t.Fatalf("failed signing intermediate ca: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed parsing intermediate ca: %v", err)
+	}
+	return key, []*x509.Certificate{cert, rootCert}
+}
+
+// vaultPKIStub serves the sign endpoint of the PKI secret backend of Vault mounted at pki, signing CSRs with the intermediate CA.
+// The admin role sets the organization to system:masters
+func vaultPKIStub(t *testing.T, caKey crypto.Signer, chain []*x509.Certificate) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "dummy-token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"errors":["permission denied"]}`)
+			return
+		}
+		var organization []string
+		switch r.URL.Path {
+		case "/v1/pki/sign/kube-aws":
+		case "/v1/pki/sign/kube-aws-admin":
+			organization = []string{"system:masters"}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"errors":["unknown role at %s"]}`, r.URL.Path)
+			return
+		}
+
+		var req struct {
+			CSR        string `json:"csr"`
+			CommonName string `json:"common_name"`
+			AltNames   string `json:"alt_names"`
+			IPSANs     string `json:"ip_sans"`
+			TTL        string `json:"ttl"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode sign request: %v", err)
+		}
+		block, _ := pem.Decode([]byte(req.CSR))
+		if block == nil {
+			t.Errorf("no csr in sign request")
+			return
+		}
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		if err != nil {
+			t.Errorf("failed to parse csr: %v", err)
+			return
+		}
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil {
+			t.Errorf("invalid ttl %q: %v", req.TTL, err)
+		}
+		tmpl := x509.Certificate{
+			SerialNumber: big.NewInt(time.Now().UnixNano()),
+			Subject:      pkix.Name{CommonName: req.CommonName, Organization: organization},
+			DNSNames:     csr.DNSNames,
+			IPAddresses:  csr.IPAddresses,
+			NotBefore:    chain[0].NotBefore,
+			NotAfter:     time.Now().Add(ttl),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, &tmpl, chain[0], csr.PublicKey, caKey)
+		if err != nil {
+			t.Errorf("failed to sign csr: %v", err)
+			return
+		}
+
+		resp := map[string]map[string]interface{}{
+			"data": {
+				"certificate": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+				"issuing_ca":  string(tlsutil.EncodeCertificatePEM(chain[0])),
+				"ca_chain":    []string{string(tlsutil.EncodeCertificatePEM(chain[0])), string(tlsutil.EncodeCertificatePEM(chain[1]))},
+			},
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Errorf("failed to encode sign response: %v", err)
+		}
+	}))
+}
+
+func TestTLSAssetsWithSigner(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+	intermediateKey, chain := newIntermediateCA(t, cluster)
+
+	verifyAssets := func(t *testing.T, dir string) {
+		if _, err := ReadRawTLSAssets(dir); err != nil {
+			t.Fatalf("failed to read tls assets: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "ca-key.pem")); !os.IsNotExist(err) {
+			t.Errorf("expected ca-key.pem not to be written: %v", err)
+		}
+
+		cas, err := readCACerts(dir)
+		if err != nil {
+			t.Fatalf("failed to read ca.pem: %v", err)
+		}
+		if len(cas) != 2 || !cas[0].Equal(chain[0]) || !cas[1].Equal(chain[1]) {
+			t.Fatalf("expected ca.pem to contain the intermediate and root CAs, got %d certificates", len(cas))
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(cas[1])
+		intermediates := x509.NewCertPool()
+		intermediates.AddCert(cas[0])
+		for _, name := range []string{"apiserver", "worker", "admin", "etcd", "etcd-client"} {
+			cert, err := readCert(filepath.Join(dir, name+".pem"))
+			if err != nil {
+				t.Fatalf("failed to read %s.pem: %v", name, err)
+			}
+			opts := x509.VerifyOptions{Roots: roots, Intermediates: intermediates, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}
+			if _, err := cert.Verify(opts); err != nil {
+				t.Errorf("failed to verify %s.pem with the CA chain: %v", name, err)
+			}
+		}
+		admin, err := readCert(filepath.Join(dir, "admin.pem"))
+		if err != nil {
+			t.Fatalf("failed to read admin.pem: %v", err)
+		}
+		if o := admin.Subject.Organization; len(o) == 0 || o[len(o)-1] != "system:masters" {
+			t.Errorf("expected the admin certificate to be in system:masters, got %v", admin.Subject.Organization)
+		}
+	}
+
+	t.Run("IntermediateCA", func(t *testing.T) {
+		signer, err := tlsutil.NewIntermediateCASigner(chain, intermediateKey)
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		helper.WithTempDir(func(dir string) {
+			if _, err := cluster.NewTLSAssetsOnDiskWithSigner(dir, signer); err != nil {
+				t.Fatalf("failed generating tls assets: %v", err)
+			}
+			verifyAssets(t, dir)
+
+			if _, err := NextCARotationStep(dir); err == nil {
+				t.Errorf("expected an error for rotating certificates issued by an intermediate CA")
+			}
+		})
+	})
+
+	t.Run("MismatchedIntermediateCAKey", func(t *testing.T) {
+		otherKey, _, err := cluster.NewTLSCA()
+		if err != nil {
+			t.Fatalf("failed generating tls ca: %v", err)
+		}
+		if _, err := tlsutil.NewIntermediateCASigner(chain, otherKey); err == nil {
+			t.Errorf("expected an error for the key not matching the intermediate CA")
+		}
+	})
+
+	t.Run("Vault", func(t *testing.T) {
+		server := vaultPKIStub(t, intermediateKey, chain)
+		defer server.Close()
+
+		signer, err := tlsutil.NewVaultSigner(tlsutil.VaultSignerConfig{
+			Addr:              server.URL,
+			Token:             "dummy-token",
+			Role:              "kube-aws",
+			OrganizationRoles: map[string]string{"system:masters": "kube-aws-admin"},
+		})
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		helper.WithTempDir(func(dir string) {
+			if _, err := cluster.NewTLSAssetsOnDiskWithSigner(dir, signer); err != nil {
+				t.Fatalf("failed generating tls assets: %v", err)
+			}
+			verifyAssets(t, dir)
+		})
+	})
+
+	t.Run("VaultWithoutAdminRole", func(t *testing.T) {
+		server := vaultPKIStub(t, intermediateKey, chain)
+		defer server.Close()
+
+		signer, err := tlsutil.NewVaultSigner(tlsutil.VaultSignerConfig{
+			Addr:  server.URL,
+			Token: "dummy-token",
+			Role:  "kube-aws",
+		})
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		_, err = cluster.NewTLSAssetsWithSigner(signer)
+		if err == nil || !strings.Contains(err.Error(), "lacks the organization system:masters") {
+			t.Errorf("expected an error for the admin certificate lacking system:masters, got %v", err)
+		}
+	})
+
+	t.Run("VaultPermissionDenied", func(t *testing.T) {
+		server := vaultPKIStub(t, intermediateKey, chain)
+		defer server.Close()
+
+		signer, err := tlsutil.NewVaultSigner(tlsutil.VaultSignerConfig{
+			Addr:  server.URL,
+			Token: "wrong-token",
+			Role:  "kube-aws",
+		})
+		if err != nil {
+			t.Fatalf("failed to create signer: %v", err)
+		}
+		_, err = cluster.NewTLSAssetsWithSigner(signer)
+		if err == nil || !strings.Contains(err.Error(), "returned 403: permission denied") {
+			t.Errorf("expected an error for the denied request, got %v", err)
+		}
+	})
+}
+
 func TestReadOrCreateCompactTLSAssets(t *testing.T) {
 	helper.WithDummyCredentials(func(dir string) {
 		kmsConfig := KMSConfig{
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/coreos/kube-aws/tlsutil"
+)
+
+// Steps of rotating TLS assets. Rotating the CA takes the three steps in order, each of which must be rolled out to
+// all the nodes before the next one begins, so that nodes trust both the old and the new CA while certificates are re-issued.
+// Re-issuing certificates from the existing CA takes TLSRotationReissue only
+const (
+	// TLSRotationAddCA generates a new CA and adds it to ca.pem in front of the existing one
+	TLSRotationAddCA = "add-ca"
+	// TLSRotationReissue re-issues the certificates signed by the first CA in ca.pem
+	TLSRotationReissue = "reissue"
+	// TLSRotationRemoveCA removes the old CA from ca.pem
+	TLSRotationRemoveCA = "remove-ca"
+)
+
+// CARotationSteps are the steps to rotate the CA in order
+var CARotationSteps = []string{TLSRotationAddCA, TLSRotationReissue, TLSRotationRemoveCA}
+
+// NextCARotationStep determines the step to rotate the CA from the TLS assets in the directory.
+// ca.pem containing the old CA after the new one indicates that a rotation is in progress
+func NextCARotationStep(dir string) (string, error) {
+	cas, err := readCACerts(dir)
+	if err != nil {
+		return "", err
+	}
+	if tlsutil.IsCertificateChain(cas) {
+		return "", fmt.Errorf("%s contains the chain of an intermediate CA. Re-issue certificates signed by an intermediate CA or Vault with `kube-aws render credentials` instead", filepath.Join(dir, "ca.pem"))
+	}
+	switch len(cas) {
+	case 1:
+		return TLSRotationAddCA, nil
+	case 2:
+		apiServerCert, err := readCert(filepath.Join(dir, "apiserver.pem"))
+		if err != nil {
+			return "", err
+		}
+		if apiServerCert.CheckSignatureFrom(cas[0]) == nil {
+			return TLSRotationRemoveCA, nil
+		}
+		return TLSRotationReissue, nil
+	default:
+		return "", fmt.Errorf("%s contains %d certificates: expected the current CA and optionally the one being rotated out", filepath.Join(dir, "ca.pem"), len(cas))
+	}
+}
+
+// ValidateRollingUpdate returns an error when rolling out new TLS assets to the nodes one by one would lose the
+// quorum of etcd or every controller serving the Kubernetes API
+func (c *Cluster) ValidateRollingUpdate() error {
+	if !c.WaitSignal.Enabled() {
+		return fmt.Errorf("`waitSignal.enabled` must be true so that each node is replaced only after the previous one becomes ready")
+	}
+	if c.EtcdCount < 3 {
+		return fmt.Errorf("etcdCount must be 3 or more to keep the quorum of etcd while one of etcd nodes is replaced but was %d", c.EtcdCount)
+	}
+	if c.ControllerRollingUpdateMinInstancesInService() < 1 {
+		return fmt.Errorf("`controller.autoScalingGroup.rollingUpdateMinInstancesInService` must be 1 or more to keep serving the Kubernetes API while controller nodes are replaced but was %d", c.ControllerRollingUpdateMinInstancesInService())
+	}
+	return nil
+}
+
+// RotateTLSAssets runs the rotation step against the TLS assets in the directory. The CA key is read from and
+// written to caKeyPath. Files to be overwritten are backed up into a sub-directory of the directory whose path is
+// returned, and the new assets are encrypted with KMS if enabled
+func (c *Cluster) RotateTLSAssets(dir string, step string, caKeyPath string) (string, error) {
+	if !c.ManageCertificates {
+		return "", fmt.Errorf("TLS assets are not managed by kube-aws as `manageCertificates` is false")
+	}
+
+	caPath := filepath.Join(dir, "ca.pem")
+	caBundle, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return "", err
+	}
+	cas, err := tlsutil.DecodeCertificatesPEM(caBundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %v", caPath, err)
+	}
+
+	// Preconditions are checked first so that a rejected step leaves no backup behind
+	switch step {
+	case TLSRotationAddCA:
+		if len(cas) != 1 {
+			return "", fmt.Errorf("%s already contains %d certificates", caPath, len(cas))
+		}
+	case TLSRotationReissue:
+	case TLSRotationRemoveCA:
+		if len(cas) != 2 {
+			return "", fmt.Errorf("%s contains %d certificates: expected the new CA and the old one", caPath, len(cas))
+		}
+	default:
+		return "", fmt.Errorf("unknown rotation step: %s", step)
+	}
+
+	backupDir, err := backupTLSAssets(dir, caKeyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to back up TLS assets: %v", err)
+	}
+
+	switch step {
+	case TLSRotationAddCA:
+		caKey, caCert, err := c.NewTLSCA()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate CA: %v", err)
+		}
+		caKeyPEM, err := tlsutil.EncodePrivateKeyPEM(caKey)
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(caKeyPath, caKeyPEM, 0600); err != nil {
+			return "", err
+		}
+		bundle := append(tlsutil.EncodeCertificatePEM(caCert), caBundle...)
+		if err := ioutil.WriteFile(caPath, bundle, 0600); err != nil {
+			return "", err
+		}
+	case TLSRotationReissue:
+		caKey, err := readCAKey(caKeyPath, cas[0])
+		if err != nil {
+			return "", err
+		}
+		keys, err := newTLSKeys(c.TLS.KeyAlgorithm)
+		if err != nil {
+			return "", err
+		}
+		// The apiserver key signs service account tokens. Tokens already issued would be rejected with a new key
+		apiServerKeyPath := filepath.Join(dir, "apiserver-key.pem")
+		apiServerKey, err := ioutil.ReadFile(apiServerKeyPath)
+		if err != nil {
+			return "", err
+		}
+		if keys.apiServer, err = tlsutil.DecodePrivateKeyPEM(apiServerKey); err != nil {
+			return "", fmt.Errorf("failed to parse %s: %v", apiServerKeyPath, err)
+		}
+		assets, err := c.newTLSAssetsOnMemory(tlsutil.NewLocalCASigner(cas[0], caKey), caKey, keys)
+		if err != nil {
+			return "", fmt.Errorf("failed to re-issue certificates: %v", err)
+		}
+		assets.CACert = caBundle
+		if err := assets.WriteToDir(dir, false); err != nil {
+			return "", err
+		}
+	case TLSRotationRemoveCA:
+		if err := ioutil.WriteFile(caPath, tlsutil.EncodeCertificatePEM(cas[0]), 0600); err != nil {
+			return "", err
+		}
+	}
+
+	if c.AssetsEncryptionEnabled() {
+		_, err := ReadOrCreateEncryptedTLSAssets(dir, KMSConfig{
+			Region:         c.Region,
+			AWSOptions:     c.AWSConnOptions(),
+			KMSKeyARN:      c.KMSKeyARN,
+			EncryptService: c.ProvidedEncryptService,
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt TLS assets: %v", err)
+		}
+	}
+
+	return backupDir, nil
+}
+
+func backupTLSAssets(dir string, caKeyPath string) (string, error) {
+	backupDir := filepath.Join(dir, "backup", time.Now().UTC().Format("20060102150405"))
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		return "", err
+	}
+	paths := []string{caKeyPath}
+	for _, name := range []string{"ca", "apiserver", "worker", "admin", "etcd", "etcd-client"} {
+		paths = append(paths, filepath.Join(dir, name+".pem"))
+		if name != "ca" {
+			paths = append(paths, filepath.Join(dir, name+"-key.pem"))
+		}
+	}
+	for _, p := range paths {
+		data, err := ioutil.ReadFile(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(filepath.Join(backupDir, filepath.Base(p)), data, 0600); err != nil {
+			return "", err
+		}
+	}
+	return backupDir, nil
+}
+
+func readCACerts(dir string) ([]*x509.Certificate, error) {
+	caPath := filepath.Join(dir, "ca.pem")
+	data, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	cas, err := tlsutil.DecodeCertificatesPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", caPath, err)
+	}
+	return cas, nil
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := tlsutil.DecodeCertificatesPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+	return certs[0], nil
+}
+
+// readCAKey reads the CA key and verifies that it is the key of the CA certificate
+func readCAKey(path string, caCert *x509.Certificate) (crypto.Signer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA key: %v", err)
+	}
+	caKey, err := tlsutil.DecodePrivateKeyPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA key %s: %v", path, err)
+	}
+	if err := tlsutil.VerifyKeyPair(caCert, caKey); err != nil {
+		return nil, fmt.Errorf("invalid CA key %s: %v", path, err)
+	}
+	return caKey, nil
+}
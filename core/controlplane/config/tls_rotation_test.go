@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreos/kube-aws/test/helper"
+	"github.com/coreos/kube-aws/tlsutil"
+)
+
+func readTestCerts(t *testing.T, path string) []*x509.Certificate {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", path, err)
+	}
+	certs, err := tlsutil.DecodeCertificatesPEM(data)
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", path, err)
+	}
+	return certs
+}
+
+func TestRotateTLSAssets(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+	cluster.ProvidedEncryptService = &dummyEncryptService{}
+
+	leaves := []string{"apiserver", "worker", "admin", "etcd", "etcd-client"}
+
+	helper.WithTempDir(func(dir string) {
+		if err := genTLSAssets(t).WriteToDir(dir, true); err != nil {
+			t.Fatalf("failed to write tls assets: %v", err)
+		}
+		caKeyPath := filepath.Join(dir, "ca-key.pem")
+		oldCA := readTestCerts(t, filepath.Join(dir, "ca.pem"))[0]
+		apiServerKey, _ := ioutil.ReadFile(filepath.Join(dir, "apiserver-key.pem"))
+		workerKey, _ := ioutil.ReadFile(filepath.Join(dir, "worker-key.pem"))
+
+		t.Run("Reissue", func(t *testing.T) {
+			oldWorker := readTestCerts(t, filepath.Join(dir, "worker.pem"))[0]
+			backupDir, err := cluster.RotateTLSAssets(dir, TLSRotationReissue, caKeyPath)
+			if err != nil {
+				t.Fatalf("failed to re-issue certificates: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "worker.pem.enc")); err != nil {
+				t.Errorf("expected the re-issued certificate to be encrypted: %v", err)
+			}
+			if backup := readTestCerts(t, filepath.Join(backupDir, "worker.pem"))[0]; !backup.Equal(oldWorker) {
+				t.Errorf("expected the old worker certificate to be backed up")
+			}
+			for _, name := range leaves {
+				cert := readTestCerts(t, filepath.Join(dir, name+".pem"))[0]
+				if err := cert.CheckSignatureFrom(oldCA); err != nil {
+					t.Errorf("expected %s to be signed by the existing CA: %v", name, err)
+				}
+			}
+			if readTestCerts(t, filepath.Join(dir, "worker.pem"))[0].Equal(oldWorker) {
+				t.Errorf("expected the worker certificate to be re-issued")
+			}
+			if key, _ := ioutil.ReadFile(filepath.Join(dir, "worker-key.pem")); bytes.Equal(key, workerKey) {
+				t.Errorf("expected the worker key to be regenerated")
+			}
+			if key, _ := ioutil.ReadFile(filepath.Join(dir, "apiserver-key.pem")); !bytes.Equal(key, apiServerKey) {
+				t.Errorf("expected the apiserver key signing service account tokens to be kept")
+			}
+		})
+
+		t.Run("CA", func(t *testing.T) {
+			for _, step := range CARotationSteps {
+				next, err := NextCARotationStep(dir)
+				if err != nil || next != step {
+					t.Fatalf("expected the next step to be %s, got %s, %v", step, next, err)
+				}
+				if _, err := cluster.RotateTLSAssets(dir, step, caKeyPath); err != nil {
+					t.Fatalf("failed to run the %s step: %v", step, err)
+				}
+
+				cas := readTestCerts(t, filepath.Join(dir, "ca.pem"))
+				switch step {
+				case TLSRotationAddCA:
+					if len(cas) != 2 || !cas[1].Equal(oldCA) {
+						t.Errorf("expected the new CA to be trusted along with the old one, got %d CAs", len(cas))
+					}
+					if err := readTestCerts(t, filepath.Join(dir, "apiserver.pem"))[0].CheckSignatureFrom(oldCA); err != nil {
+						t.Errorf("expected certificates to be kept until the new CA is trusted: %v", err)
+					}
+				case TLSRotationReissue:
+					for _, name := range leaves {
+						cert := readTestCerts(t, filepath.Join(dir, name+".pem"))[0]
+						if err := cert.CheckSignatureFrom(cas[0]); err != nil {
+							t.Errorf("expected %s to be signed by the new CA: %v", name, err)
+						}
+					}
+				case TLSRotationRemoveCA:
+					if len(cas) != 1 || cas[0].Equal(oldCA) {
+						t.Errorf("expected only the new CA to be trusted, got %d CAs", len(cas))
+					}
+				}
+			}
+		})
+
+		t.Run("MismatchedCAKey", func(t *testing.T) {
+			otherKey, _, err := cluster.NewTLSCA()
+			if err != nil {
+				t.Fatalf("failed generating tls ca: %v", err)
+			}
+			otherKeyPEM, err := tlsutil.EncodePrivateKeyPEM(otherKey)
+			if err != nil {
+				t.Fatalf("failed to encode ca key: %v", err)
+			}
+			otherKeyPath := filepath.Join(dir, "other-ca-key.pem")
+			if err := ioutil.WriteFile(otherKeyPath, otherKeyPEM, 0600); err != nil {
+				t.Fatalf("failed to write ca key: %v", err)
+			}
+			defer os.Remove(otherKeyPath)
+			if _, err := cluster.RotateTLSAssets(dir, TLSRotationReissue, otherKeyPath); err == nil {
+				t.Errorf("expected an error for the CA key not matching the CA certificate")
+			}
+		})
+
+		t.Run("RejectedStep", func(t *testing.T) {
+			if err := os.RemoveAll(filepath.Join(dir, "backup")); err != nil {
+				t.Fatalf("failed to remove backups: %v", err)
+			}
+			if _, err := cluster.RotateTLSAssets(dir, TLSRotationRemoveCA, caKeyPath); err == nil {
+				t.Errorf("expected an error for removing the only CA")
+			}
+			if _, err := cluster.RotateTLSAssets(dir, "unknown", caKeyPath); err == nil {
+				t.Errorf("expected an error for an unknown step")
+			}
+			if _, err := os.Stat(filepath.Join(dir, "backup")); !os.IsNotExist(err) {
+				t.Errorf("expected no backup for rejected steps: %v", err)
+			}
+		})
+	})
+}
+
+func TestValidateRollingUpdate(t *testing.T) {
+	cluster, err := ClusterFromBytes([]byte(singleAzConfigYaml))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+	if err := cluster.ValidateRollingUpdate(); err == nil {
+		t.Errorf("expected an error for the single etcd node")
+	}
+
+	ha, err := ClusterFromBytes([]byte(singleAzConfigYaml + "etcdCount: 3\ncontrollerCount: 2\n"))
+	if err != nil {
+		t.Fatalf("failed generating config: %v", err)
+	}
+	if err := ha.ValidateRollingUpdate(); err != nil {
+		t.Errorf("expected nodes to be replaceable one by one: %v", err)
+	}
+}
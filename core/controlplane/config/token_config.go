@@ -8,10 +8,6 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/kms"
-
 	"github.com/coreos/kube-aws/gzipcompressor"
 )
 
@@ -54,6 +50,8 @@ func NewAuthTokensOnDisk(dir string) (*RawAuthTokensOnDisk, error) {
 func validateAuthTokens(authTokens []byte) error {
 	if len(authTokens) > 0 {
 		csvReader := csv.NewReader(bytes.NewReader(authTokens))
+		// Groups are optional
+		csvReader.FieldsPerRecord = -1
 
 		records, err := csvReader.ReadAll()
 		if err != nil {
@@ -176,24 +174,9 @@ func ReadOrEncryptAuthTokens(dirname string, encryptor CachedEncryptor) (*Encryp
 }
 
 func ReadOrCreateEncryptedAuthTokens(dirname string, kmsConfig KMSConfig) (*EncryptedAuthTokensOnDisk, error) {
-	var kmsSvc EncryptService
-
-	awsConfig := aws.NewConfig().
-		WithRegion(kmsConfig.Region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	// TODO Cleaner way to inject this dependency
-	if kmsConfig.EncryptService == nil {
-		kmsSvc = kms.New(session.New(awsConfig))
-	} else {
-		kmsSvc = kmsConfig.EncryptService
-	}
-
-	encryptor := CachedEncryptor{
-		bytesEncryptionService: bytesEncryptionService{
-			kmsKeyARN: kmsConfig.KMSKeyARN,
-			kmsSvc:    kmsSvc,
-		},
+	encryptor, err := newCachedEncryptor(kmsConfig)
+	if err != nil {
+		return nil, err
 	}
 
 	return ReadOrEncryptAuthTokens(dirname, encryptor)
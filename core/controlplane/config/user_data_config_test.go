@@ -3,11 +3,11 @@ package config
 import (
 	"bytes"
 	"testing"
-	"text/template"
 
 	"fmt"
 	"github.com/aws/aws-sdk-go/service/kms"
 	"github.com/coreos/coreos-cloudinit/config/validate"
+	"github.com/coreos/kube-aws/filereader/texttemplate"
 	"github.com/coreos/kube-aws/test/helper"
 )
 
@@ -137,7 +137,7 @@ func TestCloudConfigTemplating(t *testing.T) {
 			Template: CloudConfigController,
 		},
 	} {
-		tmpl, err := template.New(cloudTemplate.Name).Parse(string(cloudTemplate.Template))
+		tmpl, err := texttemplate.Parse(cloudTemplate.Name, string(cloudTemplate.Template))
 		if err != nil {
 			t.Errorf("Error loading template %s : %v", cloudTemplate.Name, err)
 			continue
@@ -0,0 +1,310 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/kube-aws/coreos/ignition"
+	"github.com/coreos/kube-aws/filereader/userdatatemplate"
+	"github.com/coreos/kube-aws/fingerprint"
+	"github.com/coreos/kube-aws/gzipcompressor"
+	"github.com/coreos/kube-aws/model"
+)
+
+const (
+	// UserDataFormatCloudConfig makes nodes provisioned by coreos-cloudinit on every boot
+	UserDataFormatCloudConfig = "cloud-config"
+	// UserDataFormatIgnition makes nodes provisioned by Ignition on the first boot, with configs converted from cloud-configs
+	UserDataFormatIgnition = "ignition"
+)
+
+const (
+	// MaxUserDataSize is the limit EC2 puts on the size of userdata before it is base64-encoded
+	MaxUserDataSize = 16384
+
+	// UserDataDeliveryInline embeds the rendered userdata into launch configurations
+	UserDataDeliveryInline = "inline"
+	// UserDataDeliveryS3 makes nodes fetch the rendered userdata uploaded to S3
+	UserDataDeliveryS3 = "s3"
+)
+
+const stackNameVariable = "${AWS::StackName}"
+
+// maxStackNameLength is assumed for the stack name while sizing userdata, so that the delivery doesn't depend on it
+const maxStackNameLength = 128
+
+func (c DeploymentSettings) IgnitionEnabled() bool {
+	return c.UserDataFormat == UserDataFormatIgnition
+}
+
+// RenderUserData renders a cloud-config template and converts the result into the userdata format configured in cluster.yaml
+func (c DeploymentSettings) RenderUserData(tmplFile string, data interface{}) (string, error) {
+	cloudConfig, err := userdatatemplate.GetString(tmplFile, data)
+	if err != nil {
+		return "", err
+	}
+	if !c.IgnitionEnabled() {
+		return cloudConfig, nil
+	}
+
+	config, err := ignition.FromCloudConfig(cloudConfig)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert %s to ignition: %v", tmplFile, err)
+	}
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ignition config converted from %s: %v", tmplFile, err)
+	}
+	return string(bytes), nil
+}
+
+// UserData is the rendered userdata of nodes in a role along with how it is delivered to them.
+// It is embedded into launch configurations as long as it fits in MaxUserDataSize and contains no raw secrets, and
+// otherwise uploaded to S3 and fetched on boot by a stub
+type UserData struct {
+	// Role is one of controller, etcd and worker
+	Role string
+	// Format is either UserDataFormatCloudConfig or UserDataFormatIgnition
+	Format  string
+	Content string
+	// S3Dir is the bucket and the path without the scheme under which the userdata is uploaded
+	S3Dir       string
+	AWSCliImage model.Image
+	// Env is written to EnvFile before the userdata is applied. Values may refer to the stack name by ${AWS::StackName}
+	Env     []string
+	EnvFile string
+	// RawSecrets is true when the content contains secrets e.g. TLS keys which aren't encrypted with KMS. Such userdata is
+	// never embedded, because launch configurations are readable by anyone allowed to describe them
+	RawSecrets bool
+}
+
+// UserDataSize reports how large the userdata of launch configurations for a role is against MaxUserDataSize
+type UserDataSize struct {
+	Role   string
+	Format string
+	// Content is the size of the rendered cloud-config or Ignition config
+	Content int
+	// Inline is the size of the userdata embedding the content
+	Inline int
+	// Stub is the size of the userdata fetching the content from S3
+	Stub int
+	// RawSecrets forces the delivery via S3
+	RawSecrets bool
+}
+
+// Delivery is UserDataDeliveryInline when the content fits in userdata and contains no raw secrets, or
+// UserDataDeliveryS3 otherwise
+func (s UserDataSize) Delivery() string {
+	if s.Inline <= MaxUserDataSize && !s.RawSecrets {
+		return UserDataDeliveryInline
+	}
+	return UserDataDeliveryS3
+}
+
+// Size is the size of the userdata actually put into launch configurations
+func (s UserDataSize) Size() int {
+	if s.Delivery() == UserDataDeliveryInline {
+		return s.Inline
+	}
+	return s.Stub
+}
+
+// Headroom is how many bytes the userdata can grow before hitting MaxUserDataSize. Negative when it is already over
+func (s UserDataSize) Headroom() int {
+	return MaxUserDataSize - s.Size()
+}
+
+// FileName is used to upload and download userdata-<role>-<fingerprint> files
+func (u UserData) FileName() string {
+	return fmt.Sprintf("userdata-%s-%s", u.Role, fingerprint.SHA256(u.Content))
+}
+
+// S3Prefix is the prefix prepended to all userdata-<role>-<fingerprint> files uploaded to S3
+// Use this to author the IAM policy to provide nodes least required permissions for getting the files from S3
+func (u UserData) S3Prefix() string {
+	return fmt.Sprintf("%s/userdata-%s", u.S3Dir, u.Role)
+}
+
+// S3URI is the URI to the userdata-<role>-<fingerprint> file used to provision nodes
+func (u UserData) S3URI() string {
+	return fmt.Sprintf("s3://%s/%s", u.S3Dir, u.FileName())
+}
+
+// Size computes the sizes of the inline and S3-delivered userdata, assuming the longest stack name possible
+func (u UserData) Size() (UserDataSize, error) {
+	inline, err := u.inline()
+	if err != nil {
+		return UserDataSize{}, err
+	}
+	stub, err := u.stub()
+	if err != nil {
+		return UserDataSize{}, err
+	}
+	return UserDataSize{
+		Role:       u.Role,
+		Format:     u.Format,
+		Content:    len(u.Content),
+		Inline:     rawSize(inline),
+		Stub:       rawSize(stub),
+		RawSecrets: u.RawSecrets,
+	}, nil
+}
+
+// UploadedToS3 tells whether nodes fetch the userdata from S3URI, which requires it to be uploaded
+func (u UserData) UploadedToS3() (bool, error) {
+	size, err := u.Size()
+	if err != nil {
+		return false, err
+	}
+	return size.Delivery() == UserDataDeliveryS3, nil
+}
+
+// Expression returns the CloudFormation expression of the userdata for launch configurations
+func (u UserData) Expression() (string, error) {
+	size, err := u.Size()
+	if err != nil {
+		return "", err
+	}
+	var userdata string
+	switch size.Delivery() {
+	case UserDataDeliveryInline:
+		userdata, err = u.inline()
+	default:
+		if size.Stub > MaxUserDataSize {
+			return "", fmt.Errorf("userdata of %s nodes is %d bytes even when fetched from S3, exceeding the limit of %d bytes", u.Role, size.Stub, MaxUserDataSize)
+		}
+		userdata, err = u.stub()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Only ${AWS::StackName} is substituted by CloudFormation. Others like ones in shell scripts are kept as-is
+	sub := strings.Replace(strings.Replace(userdata, "${", "${!", -1), "${!AWS::StackName}", stackNameVariable, -1)
+	expr, err := json.Marshal(map[string]interface{}{
+		"Fn::Base64": map[string]string{"Fn::Sub": sub},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal userdata of %s nodes: %v", u.Role, err)
+	}
+	return string(expr), nil
+}
+
+func rawSize(userdata string) int {
+	return len(strings.Replace(userdata, stackNameVariable, strings.Repeat("x", maxStackNameLength), -1))
+}
+
+func (u UserData) ignitionEnabled() bool {
+	return u.Format == UserDataFormatIgnition
+}
+
+// inline returns the userdata embedding the content
+func (u UserData) inline() (string, error) {
+	if u.ignitionEnabled() {
+		var config ignition.Config
+		if err := json.Unmarshal([]byte(u.Content), &config); err != nil {
+			return "", fmt.Errorf("failed to parse ignition config of %s nodes: %v", u.Role, err)
+		}
+		config.Storage.Files = append(u.ignitionEnvFiles(), config.Storage.Files...)
+		return u.marshalIgnition(config)
+	}
+
+	compressed, err := gzipcompressor.CompressString(u.Content)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress userdata of %s nodes: %v", u.Role, err)
+	}
+	return u.script(
+		fmt.Sprintf("echo '%s' | base64 -d | gunzip > /var/run/coreos/$USERDATA_FILE", compressed),
+	), nil
+}
+
+// stub returns the userdata fetching the content from S3URI
+func (u UserData) stub() (string, error) {
+	if u.ignitionEnabled() {
+		return u.marshalIgnition(ignition.Stub(u.S3URI(), u.ignitionEnvFiles()...))
+	}
+
+	return u.script(
+		"REGION=$(curl -s http://169.254.169.254/latest/dynamic/instance-identity/document | jq -r '.region')",
+		"/usr/bin/rkt run \\",
+		"   --net=host \\",
+		"   --volume=dns,kind=host,source=/etc/resolv.conf,readOnly=true --mount volume=dns,target=/etc/resolv.conf  \\",
+		"   --volume=awsenv,kind=host,source=/var/run/coreos,readOnly=false --mount volume=awsenv,target=/var/run/coreos \\",
+		"   --trust-keys-from-https \\",
+		fmt.Sprintf("   %s%s --exec=aws -- s3 --region $REGION  cp %s /var/run/coreos/$USERDATA_FILE", u.AWSCliImage.Options(), u.AWSCliImage.RktRepo(), u.S3URI()),
+	), nil
+}
+
+// script returns a shell script which writes the env file, saves the cloud-config by running fetch and then runs coreos-cloudinit
+func (u UserData) script(fetch ...string) string {
+	lines := []string{"#!/bin/bash -xe"}
+	for _, e := range u.Env {
+		lines = append(lines, fmt.Sprintf("echo '%s' >> %s", e, u.EnvFile))
+	}
+	lines = append(lines,
+		" . /etc/environment",
+		"export COREOS_PRIVATE_IPV4 COREOS_PRIVATE_IPV6 COREOS_PUBLIC_IPV4 COREOS_PUBLIC_IPV6",
+		"USERDATA_FILE="+u.FileName(),
+	)
+	lines = append(lines, fetch...)
+	lines = append(lines, "exec /usr/bin/coreos-cloudinit --from-file /var/run/coreos/$USERDATA_FILE")
+	return strings.Join(lines, "\n")
+}
+
+// ignitionEnvFiles returns the env file to be written by Ignition, keeping ${AWS::StackName} unescaped for CloudFormation
+func (u UserData) ignitionEnvFiles() []ignition.File {
+	if len(u.Env) == 0 {
+		return []ignition.File{}
+	}
+	parts := strings.Split(strings.Join(u.Env, "\n")+"\n", stackNameVariable)
+	for i, p := range parts {
+		parts[i] = ignition.EscapeDataURL(p)
+	}
+	file := ignition.NewFile(u.EnvFile, 0644, "")
+	file.Contents.Source = "data:," + strings.Join(parts, stackNameVariable)
+	return []ignition.File{file}
+}
+
+func (u UserData) marshalIgnition(config ignition.Config) (string, error) {
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ignition config of %s nodes: %v", u.Role, err)
+	}
+	return string(bytes), nil
+}
+
+// ReportUserDataSizes reports the size of userdata per role, by the largest one when a role has several
+func ReportUserDataSizes(list []UserData) ([]UserDataSize, error) {
+	sizes := []UserDataSize{}
+	index := map[string]int{}
+	for _, u := range list {
+		size, err := u.Size()
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[u.Role]
+		if !ok {
+			index[u.Role] = len(sizes)
+			sizes = append(sizes, size)
+		} else if size.Size() > sizes[i].Size() {
+			sizes[i] = size
+		}
+	}
+	return sizes, nil
+}
+
+// UserDataToUpload returns contents of the userdata fetched from S3 by nodes, keyed by file name
+func UserDataToUpload(list []UserData) (map[string]string, error) {
+	files := map[string]string{}
+	for _, u := range list {
+		uploaded, err := u.UploadedToS3()
+		if err != nil {
+			return nil, err
+		}
+		if uploaded {
+			files[u.FileName()] = u.Content
+		}
+	}
+	return files, nil
+}
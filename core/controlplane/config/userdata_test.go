@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/coreos/kube-aws/coreos/ignition"
+	"github.com/coreos/kube-aws/model"
+)
+
+func testUserData(format string, content string) UserData {
+	return UserData{
+		Role:        "etcd",
+		Format:      format,
+		Content:     content,
+		S3Dir:       "mybucket/mydir/mystack",
+		AWSCliImage: model.Image{Repo: "quay.io/coreos/awscli", Tag: "master"},
+		Env:         []string{"KUBE_AWS_STACK_NAME=${AWS::StackName}", "KUBE_AWS_ETCD_INDEX=0"},
+		EnvFile:     "/var/run/coreos/etcd-node.env",
+	}
+}
+
+// sub returns the string substituted by Fn::Sub in the userdata expression
+func sub(t *testing.T, expr string) string {
+	var parsed struct {
+		Base64 struct {
+			Sub string `json:"Fn::Sub"`
+		} `json:"Fn::Base64"`
+	}
+	if err := json.Unmarshal([]byte(expr), &parsed); err != nil {
+		t.Fatalf("failed to parse userdata expression %s: %v", expr, err)
+	}
+	return parsed.Base64.Sub
+}
+
+func TestUserDataDelivery(t *testing.T) {
+	small := "#cloud-config\nwrite_files:\n  - path: /etc/motd\n    content: ${HOME}\n"
+	// Random content doesn't compress enough to be embedded
+	random := make([]byte, 2*MaxUserDataSize)
+	rand.New(rand.NewSource(1)).Read(random)
+	large := "#cloud-config\n# " + hex.EncodeToString(random) + "\n"
+
+	t.Run("InlineCloudConfig", func(t *testing.T) {
+		u := testUserData(UserDataFormatCloudConfig, small)
+		size, err := u.Size()
+		if err != nil {
+			t.Fatalf("failed to size userdata: %v", err)
+		}
+		if size.Delivery() != UserDataDeliveryInline || size.Content != len(small) || size.Headroom() != MaxUserDataSize-size.Inline {
+			t.Errorf("unexpected size: %+v", size)
+		}
+		expr, err := u.Expression()
+		if err != nil {
+			t.Fatalf("failed to render userdata: %v", err)
+		}
+		script := sub(t, expr)
+		if !strings.Contains(script, "echo 'KUBE_AWS_STACK_NAME=${AWS::StackName}' >> /var/run/coreos/etcd-node.env") {
+			t.Errorf("expected the stack name to be substituted by CloudFormation: %s", script)
+		}
+		if !strings.Contains(script, "| base64 -d | gunzip > /var/run/coreos/$USERDATA_FILE") || strings.Contains(script, "s3://") {
+			t.Errorf("expected the cloud-config to be embedded: %s", script)
+		}
+		if uploaded, err := u.UploadedToS3(); err != nil || uploaded {
+			t.Errorf("expected the userdata not to be uploaded, got %v, %v", uploaded, err)
+		}
+	})
+
+	t.Run("S3CloudConfig", func(t *testing.T) {
+		u := testUserData(UserDataFormatCloudConfig, large)
+		size, err := u.Size()
+		if err != nil {
+			t.Fatalf("failed to size userdata: %v", err)
+		}
+		if size.Delivery() != UserDataDeliveryS3 || size.Inline <= MaxUserDataSize || size.Size() != size.Stub {
+			t.Errorf("unexpected size: %+v", size)
+		}
+		expr, err := u.Expression()
+		if err != nil {
+			t.Fatalf("failed to render userdata: %v", err)
+		}
+		if script := sub(t, expr); !strings.Contains(script, "cp "+u.S3URI()+" /var/run/coreos/$USERDATA_FILE") {
+			t.Errorf("expected the cloud-config to be fetched from S3: %s", script)
+		}
+		files, err := UserDataToUpload([]UserData{u})
+		if err != nil || len(files) != 1 || files[u.FileName()] != large {
+			t.Errorf("expected the userdata to be uploaded, got %v, %v", files, err)
+		}
+	})
+
+	t.Run("S3CloudConfigWithRawSecrets", func(t *testing.T) {
+		u := testUserData(UserDataFormatCloudConfig, small)
+		u.RawSecrets = true
+		size, err := u.Size()
+		if err != nil {
+			t.Fatalf("failed to size userdata: %v", err)
+		}
+		if size.Delivery() != UserDataDeliveryS3 || size.Inline > MaxUserDataSize {
+			t.Errorf("expected the userdata to be delivered via S3 though it fits: %+v", size)
+		}
+		expr, err := u.Expression()
+		if err != nil {
+			t.Fatalf("failed to render userdata: %v", err)
+		}
+		if script := sub(t, expr); strings.Contains(script, "base64 -d") || !strings.Contains(script, u.S3URI()) {
+			t.Errorf("expected the cloud-config to be fetched from S3: %s", script)
+		}
+		files, err := UserDataToUpload([]UserData{u})
+		if err != nil || files[u.FileName()] != small {
+			t.Errorf("expected the userdata to be uploaded, got %v, %v", files, err)
+		}
+	})
+
+	t.Run("InlineIgnition", func(t *testing.T) {
+		content, _ := json.Marshal(ignition.Stub("s3://mybucket/config", ignition.NewFile("/etc/motd", 0644, "${HOME}")))
+		u := testUserData(UserDataFormatIgnition, string(content))
+		expr, err := u.Expression()
+		if err != nil {
+			t.Fatalf("failed to render userdata: %v", err)
+		}
+		config := sub(t, expr)
+		if !strings.Contains(config, "KUBE_AWS_STACK_NAME%3D${AWS::StackName}") {
+			t.Errorf("expected the env file to be embedded: %s", config)
+		}
+		if !strings.Contains(config, "/etc/motd") || strings.Contains(config, u.S3URI()) {
+			t.Errorf("expected the ignition config to be embedded: %s", config)
+		}
+	})
+
+	t.Run("Sizes", func(t *testing.T) {
+		second := testUserData(UserDataFormatCloudConfig, small)
+		second.Env = append(second.Env, "LONGER=1")
+		sizes, err := ReportUserDataSizes([]UserData{testUserData(UserDataFormatCloudConfig, small), second})
+		if err != nil {
+			t.Fatalf("failed to size userdata: %v", err)
+		}
+		expected, _ := second.Size()
+		if len(sizes) != 1 || sizes[0] != expected {
+			t.Errorf("expected the largest userdata of the role to be reported, got %+v", sizes)
+		}
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/cfnstack"
 	"github.com/coreos/kube-aws/core/nodepool/config"
 	"text/tabwriter"
@@ -49,19 +50,16 @@ func (c *Info) String() string {
 	return buf.String()
 }
 
-func NewClusterRef(cfg *config.ProvidedConfig, awsDebug bool) *ClusterRef {
-	awsConfig := aws.NewConfig().
-		WithRegion(cfg.Region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	if awsDebug {
-		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
+func NewClusterRef(cfg *config.ProvidedConfig, awsDebug bool) (*ClusterRef, error) {
+	session, err := awsconn.NewSession(awsconn.Config{Region: cfg.Region, Debug: awsDebug, Options: cfg.AWSConnOptions()})
+	if err != nil {
+		return nil, err
 	}
 
 	return &ClusterRef{
 		ProvidedConfig: *cfg,
-		session:        session.New(awsConfig),
-	}
+		session:        session,
+	}, nil
 }
 
 func NewCluster(provided *config.ProvidedConfig, opts config.StackTemplateOptions, awsDebug bool) (*Cluster, error) {
@@ -77,7 +75,10 @@ func NewCluster(provided *config.ProvidedConfig, opts config.StackTemplateOption
 	if err != nil {
 		return nil, err
 	}
-	ref := NewClusterRef(provided, awsDebug)
+	ref, err := NewClusterRef(provided, awsDebug)
+	if err != nil {
+		return nil, err
+	}
 	return &Cluster{
 		CompressedStackConfig: compressed,
 		ClusterRef:            ref,
@@ -90,10 +91,16 @@ func (c *Cluster) Assets() (cfnstack.Assets, error) {
 		return nil, fmt.Errorf("Error while rendering template : %v", err)
 	}
 
-	return cfnstack.NewAssetsBuilder(c.StackName(), c.StackConfig.S3URI, c.StackConfig.Region).
-		Add(c.UserDataWorkerFileName(), c.UserDataWorker).
-		Add(STACK_TEMPLATE_FILENAME, stackTemplate).
-		Build(), nil
+	userdata, err := c.UserDataAssets()
+	if err != nil {
+		return nil, fmt.Errorf("Error while sizing userdata : %v", err)
+	}
+
+	builder := cfnstack.NewAssetsBuilder(c.StackName(), c.StackConfig.S3URI, c.StackConfig.Region)
+	for name, content := range userdata {
+		builder = builder.Add(name, content)
+	}
+	return builder.Add(STACK_TEMPLATE_FILENAME, stackTemplate).Build(), nil
 }
 
 func (c *Cluster) TemplateURL() (string, error) {
@@ -109,18 +116,7 @@ func (c *Cluster) TemplateURL() (string, error) {
 }
 
 func (c *Cluster) stackProvisioner() *cfnstack.Provisioner {
-	stackPolicyBody := `{
-  "Statement" : [
-    {
-       "Effect" : "Allow",
-       "Principal" : "*",
-       "Action" : "Update:*",
-       "Resource" : "*"
-     }
-  ]
-}`
-
-	return cfnstack.NewProvisioner(c.StackName(), c.WorkerDeploymentSettings().StackTags(), c.S3URI, c.Region, stackPolicyBody, c.session())
+	return cfnstack.NewProvisioner(c.StackName(), c.WorkerDeploymentSettings().StackTags(), c.S3URI, c.Region, c.EffectiveStackPolicy().Body(), c.session()).WithS3Options(c.S3)
 }
 
 func (c *Cluster) session() *session.Session {
@@ -135,8 +131,9 @@ func (c *Cluster) Create() error {
 		return err
 	}
 
-	cloudConfigs := map[string]string{
-		"userdata-worker": c.UserDataWorker,
+	cloudConfigs, err := c.UserDataAssets()
+	if err != nil {
+		return err
 	}
 
 	return c.stackProvisioner().CreateStackAndWait(cfSvc, s3Svc, stackTemplate, cloudConfigs)
@@ -150,8 +147,9 @@ func (c *Cluster) Update() (string, error) {
 		return "", err
 	}
 
-	cloudConfigs := map[string]string{
-		"userdata-worker": c.UserDataWorker,
+	cloudConfigs, err := c.UserDataAssets()
+	if err != nil {
+		return "", err
 	}
 
 	updateOutput, err := c.stackProvisioner().UpdateStackAndWait(cfSvc, s3Svc, stackTemplate, cloudConfigs)
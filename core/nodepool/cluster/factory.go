@@ -10,6 +10,5 @@ func ClusterRefFromBytes(bytes []byte, main *controlplane.Config, awsDebug bool)
 	if err != nil {
 		return nil, err
 	}
-	c := NewClusterRef(provided, awsDebug)
-	return c, nil
+	return NewClusterRef(provided, awsDebug)
 }
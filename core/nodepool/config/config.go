@@ -11,7 +11,6 @@ import (
 	"github.com/coreos/kube-aws/cfnresource"
 	cfg "github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/coreos/amiregistry"
-	"github.com/coreos/kube-aws/filereader/userdatatemplate"
 	"github.com/coreos/kube-aws/model"
 	"github.com/coreos/kube-aws/model/derived"
 	"gopkg.in/yaml.v2"
@@ -25,8 +24,9 @@ type Ref struct {
 type ComputedConfig struct {
 	ProvidedConfig
 	// Fields computed from Cluster
-	AMI       string
-	TLSConfig *cfg.CompactTLSAssets
+	AMI                string
+	TLSConfig          *cfg.CompactTLSAssets
+	TLSBootstrapConfig *cfg.CompactTLSBootstrapAssets
 }
 
 type ProvidedConfig struct {
@@ -55,6 +55,8 @@ type StackTemplateOptions struct {
 	PrettyPrint           bool
 	S3URI                 string
 	SkipWait              bool
+	// SkipAssetsEncryption renders userdata with raw TLS assets so that KMS isn't called e.g. while validating offline
+	SkipAssetsEncryption bool
 }
 
 // NestedStackName returns a sanitized name of this node pool which is usable as a valid cloudformation nested stack name
@@ -64,6 +66,14 @@ func (c ProvidedConfig) NestedStackName() string {
 	return strings.Title(strings.Replace(c.StackName(), "-", "", -1))
 }
 
+// EffectiveStackPolicy returns the stack policy applied to the node pool stack, which allows any update by default
+func (c ProvidedConfig) EffectiveStackPolicy() model.StackPolicy {
+	if c.StackPolicy != nil {
+		return *c.StackPolicy
+	}
+	return model.NewAllowAllStackPolicy()
+}
+
 func (c ProvidedConfig) StackConfig(opts StackTemplateOptions) (*StackConfig, error) {
 	var err error
 	stackConfig := StackConfig{}
@@ -73,21 +83,45 @@ func (c ProvidedConfig) StackConfig(opts StackTemplateOptions) (*StackConfig, er
 	}
 
 	if stackConfig.ManageCertificates {
-		if stackConfig.ComputedConfig.AssetsEncryptionEnabled() {
+		if stackConfig.ComputedConfig.AssetsEncryptionEnabled() && !opts.SkipAssetsEncryption {
 			compactAssets, _ := cfg.ReadOrCreateCompactTLSAssets(opts.AssetsDir, cfg.KMSConfig{
 				Region:         stackConfig.ComputedConfig.Region,
+				AWSOptions:     c.AWSConnOptions(),
 				KMSKeyARN:      c.KMSKeyARN,
 				EncryptService: c.providedEncryptService,
 			})
 
 			stackConfig.ComputedConfig.TLSConfig = compactAssets
+
+			if c.TLSBootstrap.Enabled {
+				stackConfig.ComputedConfig.TLSBootstrapConfig, err = cfg.ReadOrCreateCompactTLSBootstrapAssets(opts.AssetsDir, cfg.KMSConfig{
+					Region:         stackConfig.ComputedConfig.Region,
+					AWSOptions:     c.AWSConnOptions(),
+					KMSKeyARN:      c.KMSKeyARN,
+					EncryptService: c.providedEncryptService,
+				})
+				if err != nil {
+					return nil, err
+				}
+			}
 		} else {
 			rawAssets, _ := cfg.ReadOrCreateUnecryptedCompactTLSAssets(opts.AssetsDir)
 			stackConfig.ComputedConfig.TLSConfig = rawAssets
+
+			if c.TLSBootstrap.Enabled {
+				if stackConfig.ComputedConfig.TLSBootstrapConfig, err = cfg.ReadOrCreateUnencryptedCompactTLSBootstrapAssets(opts.AssetsDir); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		// Only controller nodes sign certificates. Worker nodes request them with the token
+		if stackConfig.ComputedConfig.TLSBootstrapConfig != nil {
+			stackConfig.ComputedConfig.TLSBootstrapConfig.CAKey = ""
 		}
 	}
 
-	if stackConfig.UserDataWorker, err = userdatatemplate.GetString(opts.WorkerTmplFile, stackConfig.ComputedConfig); err != nil {
+	if stackConfig.UserDataWorker, err = c.RenderUserData(opts.WorkerTmplFile, stackConfig.ComputedConfig); err != nil {
 		return nil, fmt.Errorf("failed to render worker cloud config: %v", err)
 	}
 
@@ -144,6 +178,8 @@ func (c *ProvidedConfig) Load(main *cfg.Config) error {
 
 	// Inherit parameters from the control plane stack
 	c.KubeClusterSettings = main.KubeClusterSettings
+	// Controllers sign certificates of kubelets only when TLS bootstrapping is enabled for the whole cluster
+	c.TLSBootstrap = main.Experimental.TLSBootstrap
 
 	// Validate whole the inputs including inherited ones
 	if err := c.valid(); err != nil {
@@ -101,15 +101,23 @@ func (c DeploymentSettings) WithDefaultsFrom(main cfg.DeploymentSettings) Deploy
 		c.SSHAuthorizedKeys = main.SSHAuthorizedKeys
 	}
 
+	if c.UserDataFormat == "" {
+		c.UserDataFormat = main.UserDataFormat
+	}
+
 	// And assuming that no one wants to differentiate these settings among control plane and node pools, we forbid customization of:
 	c.ManageCertificates = main.ManageCertificates
 	// And believing it is impossible to mix different values, we also forbid customization of:
 	// * Region
 	// * ContainerRuntime
 	// * KMSKeyARN
+	// * S3
+	// * AWS
 	c.Region = main.Region
 	c.ContainerRuntime = main.ContainerRuntime
 	c.KMSKeyARN = main.KMSKeyARN
+	c.S3 = main.S3
+	c.AWS = main.AWS
 
 	return c
 }
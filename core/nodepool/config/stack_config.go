@@ -2,9 +2,9 @@ package config
 
 import (
 	"fmt"
+	cfg "github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/coreos/userdatavalidation"
 	"github.com/coreos/kube-aws/filereader/jsontemplate"
-	"github.com/coreos/kube-aws/fingerprint"
 	"net/url"
 )
 
@@ -18,14 +18,34 @@ type CompressedStackConfig struct {
 	*StackConfig
 }
 
+// WorkerUserData is the userdata of worker nodes in the node pool
+func (c *StackConfig) WorkerUserData() (cfg.UserData, error) {
+	s3dir, err := c.userDataWorkerS3Directory()
+	if err != nil {
+		return cfg.UserData{}, fmt.Errorf("Error in WorkerUserData : %v", err)
+	}
+	return cfg.UserData{
+		Role:        "worker",
+		Format:      c.UserDataFormat,
+		Content:     c.UserDataWorker,
+		S3Dir:       s3dir,
+		AWSCliImage: c.AWSCliImage,
+		Env: []string{
+			fmt.Sprintf("%s=${AWS::StackName}", c.StackNameEnvVarName()),
+		},
+		EnvFile:    c.StackNameEnvFileName(),
+		RawSecrets: c.RawAssetsInUserData(),
+	}, nil
+}
+
 // UserDataWorkerS3Prefix is the prefix prepended to all user-data-worker-<fingerprint> files uploaded to S3
 // Use this to author the IAM policy to provide worker nodes least required permissions for getting the files from S3
 func (c *StackConfig) UserDataWorkerS3Prefix() (string, error) {
-	s3dir, err := c.userDataWorkerS3Directory()
+	u, err := c.WorkerUserData()
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataWorkerS3Prefix : %v", err)
 	}
-	return fmt.Sprintf("%s/userdata-worker", s3dir), nil
+	return u.S3Prefix(), nil
 }
 
 func (c *StackConfig) userDataWorkerS3Directory() (string, error) {
@@ -39,16 +59,43 @@ func (c *StackConfig) userDataWorkerS3Directory() (string, error) {
 // UserDataWorkerS3URI is the URI to an userdata-worker-<fingerprint> file used to provision worker nodes
 // Use this to run download the file by running e.g. `aws cp *return value of UserDataWorkerS3URI* ./`
 func (c *StackConfig) UserDataWorkerS3URI() (string, error) {
-	s3dir, err := c.userDataWorkerS3Directory()
+	u, err := c.WorkerUserData()
 	if err != nil {
 		return "", fmt.Errorf("Error in UserDataWorkerS3URI : %v", err)
 	}
-	return fmt.Sprintf("s3://%s/%s", s3dir, c.UserDataWorkerFileName()), nil
+	return u.S3URI(), nil
 }
 
 // UserDataWorkerFileName is used to upload and download userdata-worker-<fingerprint> files
 func (c *StackConfig) UserDataWorkerFileName() string {
-	return "userdata-worker-" + fingerprint.SHA256(c.UserDataWorker)
+	return cfg.UserData{Role: "worker", Content: c.UserDataWorker}.FileName()
+}
+
+// UserDataWorkerExpression is the userdata of the launch configuration or the spot fleet for worker nodes
+func (c *StackConfig) UserDataWorkerExpression() (string, error) {
+	u, err := c.WorkerUserData()
+	if err != nil {
+		return "", err
+	}
+	return u.Expression()
+}
+
+// UserDataSizes reports the size of userdata of worker nodes
+func (c *StackConfig) UserDataSizes() ([]cfg.UserDataSize, error) {
+	u, err := c.WorkerUserData()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ReportUserDataSizes([]cfg.UserData{u})
+}
+
+// UserDataAssets returns the userdata file of worker nodes keyed by the file name, if they fetch it from S3
+func (c *StackConfig) UserDataAssets() (map[string]string, error) {
+	u, err := c.WorkerUserData()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.UserDataToUpload([]cfg.UserData{u})
 }
 
 func (c *StackConfig) ValidateUserData() error {
@@ -60,18 +107,8 @@ func (c *StackConfig) ValidateUserData() error {
 }
 
 func (c *StackConfig) Compress() (*CompressedStackConfig, error) {
-	//var err error
-	//var compressedWorkerUserData string
-	//
-	//if compressedWorkerUserData, err = gzipcompressor.CompressString(c.UserDataWorker); err != nil {
-	//	return nil, err
-	//}
-
 	var stackConfig CompressedStackConfig
 	stackConfig.StackConfig = &(*c)
-	//stackConfig.UserDataWorker = compressedWorkerUserData
-	stackConfig.UserDataWorker = c.UserDataWorker
-
 	return &stackConfig, nil
 }
 
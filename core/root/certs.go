@@ -0,0 +1,100 @@
+package root
+
+import (
+	"fmt"
+	"time"
+
+	controlplane_cfg "github.com/coreos/kube-aws/core/controlplane/config"
+	"github.com/coreos/kube-aws/core/root/config"
+)
+
+type CertsRotationOptions struct {
+	// CA rotates the CA as well as the certificates signed by it
+	CA        bool
+	CaKeyPath string
+	// RenderOnly rewrites TLS assets for the next rotation step without updating the cluster
+	RenderOnly bool
+	// Force skips checking that nodes can be replaced without losing the quorum of etcd or the Kubernetes API
+	Force bool
+}
+
+var rotationStepDescriptions = map[string]string{
+	controlplane_cfg.TLSRotationAddCA:    "Adding a new CA to ca.pem",
+	controlplane_cfg.TLSRotationReissue:  "Re-issuing certificates",
+	controlplane_cfg.TLSRotationRemoveCA: "Removing the old CA from ca.pem",
+}
+
+// RotateCerts re-issues the TLS assets of the cluster and rolls them out to the nodes with an update per rotation step.
+// Etcd nodes, controller nodes and then node pools are replaced one by one as they are in any update
+func RotateCerts(configPath string, opts options, awsDebug bool, rotation CertsRotationOptions) error {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return err
+	}
+	if !rotation.RenderOnly && !rotation.Force {
+		if err := cfg.Cluster.ValidateRollingUpdate(); err != nil {
+			return fmt.Errorf("%v. Pass --force to rotate anyway", err)
+		}
+	}
+
+	next, err := controlplane_cfg.NextCARotationStep(opts.AssetsDir)
+	if err != nil {
+		return err
+	}
+	steps := []string{controlplane_cfg.TLSRotationReissue}
+	if rotation.CA {
+		for i, s := range controlplane_cfg.CARotationSteps {
+			if s == next {
+				steps = controlplane_cfg.CARotationSteps[i:]
+			}
+		}
+		if next != controlplane_cfg.TLSRotationAddCA {
+			fmt.Printf("Resuming the CA rotation in progress from the %s step\n", next)
+		}
+	} else if next != controlplane_cfg.TLSRotationAddCA {
+		return fmt.Errorf("a CA rotation is in progress. Finish it with --ca")
+	}
+	if rotation.RenderOnly {
+		steps = steps[:1]
+	}
+
+	for _, step := range steps {
+		fmt.Printf("-> %s\n", rotationStepDescriptions[step])
+		backupDir, err := cfg.Cluster.RotateTLSAssets(opts.AssetsDir, step, rotation.CaKeyPath)
+		if err != nil {
+			return fmt.Errorf("failed to rotate TLS assets: %v", err)
+		}
+		fmt.Printf("-> Backed up previous TLS assets to %s\n", backupDir)
+
+		if rotation.RenderOnly {
+			return nil
+		}
+
+		// TLS assets are read while loading the cluster
+		cluster, err := ClusterFromFile(configPath, opts, awsDebug)
+		if err != nil {
+			return err
+		}
+		if _, err := cluster.ValidateStack(); err != nil {
+			return err
+		}
+		fmt.Printf("-> Rolling out to nodes\n")
+		report, err := cluster.Update()
+		if err != nil {
+			return fmt.Errorf("failed to roll out the %s step: %v. Finish rolling it out with `kube-aws update` before rotating again", step, err)
+		}
+		if report != "" {
+			fmt.Printf("Update stack: %s\n", report)
+		}
+	}
+	return nil
+}
+
+// CheckCerts inspects the certificates in the TLS assets directory of the cluster without calling AWS APIs
+func CheckCerts(configPath string, opts options, expiryWindowDays int) (*controlplane_cfg.TLSAssetsReport, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Cluster.CheckTLSAssets(opts.AssetsDir, expiryWindowDays, time.Now())
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/awsconn"
+	"github.com/coreos/kube-aws/cfnlint"
 	"github.com/coreos/kube-aws/cfnstack"
 	controlplane "github.com/coreos/kube-aws/core/controlplane/cluster"
 	controlplane_cfg "github.com/coreos/kube-aws/core/controlplane/config"
@@ -14,6 +16,8 @@ import (
 	"github.com/coreos/kube-aws/core/root/config"
 	"github.com/coreos/kube-aws/core/root/defaults"
 	"github.com/coreos/kube-aws/filereader/jsontemplate"
+	"github.com/coreos/kube-aws/fingerprint"
+	"github.com/coreos/kube-aws/model"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -96,13 +100,21 @@ func (c clusterImpl) EstimateCost() ([]string, error) {
 type Cluster interface {
 	Assets() (cfnstack.Assets, error)
 	Create() error
+	Drift() (*DriftReport, error)
 	Export() error
 	EstimateCost() ([]string, error)
 	Info() (*Info, error)
+	LintTemplates() ([]cfnlint.Problem, error)
+	Plan(changeSetName string) (*Plan, error)
+	Resume() (*cfnstack.ResumedOperation, error)
+	Revisions() ([]*Revision, error)
+	Rollback(to int) (*Revision, error)
 	Update() (string, error)
+	UpdateWithChangeSet(changeSetName string) (string, error)
 	ValidateStack() (string, error)
 	ValidateTemplates() error
 	ValidateUserData() error
+	UserDataSizes() ([]UserDataSize, error)
 }
 
 func ClusterFromFile(configPath string, opts options, awsDebug bool) (Cluster, error) {
@@ -110,7 +122,17 @@ func ClusterFromFile(configPath string, opts options, awsDebug bool) (Cluster, e
 	if err != nil {
 		return nil, err
 	}
-	return ClusterFromConfig(cfg, opts, awsDebug)
+	c, err := ClusterFromConfig(cfg, opts, awsDebug)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	impl := c.(clusterImpl)
+	impl.configHash = fingerprint.SHA256(string(data))
+	return impl, nil
 }
 
 func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster, error) {
@@ -122,6 +144,7 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 		PrettyPrint:           opts.PrettyPrint,
 		S3URI:                 opts.S3URI,
 		SkipWait:              opts.SkipWait,
+		SkipAssetsEncryption:  opts.SkipAssetsEncryption,
 	}
 	cp, err := controlplane.NewCluster(cfg.Cluster, cpOpts, awsDebug)
 	if err != nil {
@@ -136,6 +159,7 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 			PrettyPrint:           opts.PrettyPrint,
 			S3URI:                 opts.S3URI,
 			SkipWait:              opts.SkipWait,
+			SkipAssetsEncryption:  opts.SkipAssetsEncryption,
 		}
 		np, err := nodepool.NewCluster(c, npOpts, awsDebug)
 		if err != nil {
@@ -143,17 +167,9 @@ func ClusterFromConfig(cfg *config.Config, opts options, awsDebug bool) (Cluster
 		}
 		nodePools = append(nodePools, np)
 	}
-	awsConfig := aws.NewConfig().
-		WithRegion(cfg.Region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	if awsDebug {
-		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
-	}
-
-	session, err := session.NewSession(awsConfig)
+	session, err := awsconn.NewSession(awsconn.Config{Region: cfg.Region, Debug: awsDebug, Options: cfg.AWSConnOptions()})
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+		return nil, err
 	}
 	return clusterImpl{
 		opts:         opts,
@@ -168,17 +184,55 @@ type clusterImpl struct {
 	nodePools    []*nodepool.Cluster
 	opts         options
 	session      *session.Session
+	// configHash is the SHA-256 hash of cluster.yaml recorded in revisions
+	configHash string
 }
 
 func (c clusterImpl) Create() error {
 	cfSvc := cloudformation.New(c.session)
 
-	stackTemplateURL, err := c.prepareTemplateWithAssets()
+	assets, stackTemplateURL, err := c.prepareTemplateWithAssets()
+	if err != nil {
+		return err
+	}
+
+	timeout, err := c.createTimeout()
 	if err != nil {
 		return err
 	}
+	ctx, cancel := c.contextWithTimeout(timeout)
+	defer cancel()
+
+	if err := c.stackProvisioner().WithContext(ctx).CreateStackAtURLAndWait(cfSvc, stackTemplateURL); err != nil {
+		return c.resumable(err)
+	}
+
+	if err := c.protect(cfSvc); err != nil {
+		return err
+	}
+
+	return c.recordRevisionWithWarning(assets)
+}
+
+// recordRevisionWithWarning records a revision of the assets which have just been deployed successfully.
+// Failing to record it doesn't fail the deployment itself but only prevents rolling back to it
+func (c clusterImpl) recordRevisionWithWarning(assets cfnstack.Assets) error {
+	revision, err := c.recordRevision(assets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to record the revision of this deployment. You won't be able to roll back to it: %v\n", err)
+		return nil
+	}
+	fmt.Printf("Recorded revision %d\n", revision.Number)
+	return nil
+}
 
-	return c.stackProvisioner().CreateStackAtURLAndWait(cfSvc, stackTemplateURL)
+// resumable gives the S3 URI to the error, if kube-aws stopped waiting for the stack operation, so that the command
+// suggested to resume waiting records a revision too
+func (c clusterImpl) resumable(err error) error {
+	if interrupted, ok := err.(*cfnstack.WaitInterruptedError); ok {
+		interrupted.S3URI = c.opts.S3URI
+	}
+	return err
 }
 
 func (c clusterImpl) Info() (*Info, error) {
@@ -186,28 +240,28 @@ func (c clusterImpl) Info() (*Info, error) {
 	return describer.Info()
 }
 
-func (c clusterImpl) prepareTemplateWithAssets() (string, error) {
+func (c clusterImpl) prepareTemplateWithAssets() (cfnstack.Assets, string, error) {
 	assets, err := c.Assets()
 
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	s3Svc := s3.New(c.session)
 	err = c.stackProvisioner().UploadAssets(s3Svc, assets)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 
 	asset, err := assets.FindAssetByStackAndFileName(c.stackName(), REMOTE_STACK_TEMPLATE_FILENAME)
 
 	if err != nil {
-		return "", fmt.Errorf("failed to prepare template with assets: %v", err)
+		return nil, "", fmt.Errorf("failed to prepare template with assets: %v", err)
 	}
 
 	url := asset.URL()
 
-	return url, nil
+	return assets, url, nil
 }
 
 func (c clusterImpl) Assets() (cfnstack.Assets, error) {
@@ -215,10 +269,7 @@ func (c clusterImpl) Assets() (cfnstack.Assets, error) {
 	if err != nil {
 		return nil, fmt.Errorf("Error while rendering template : %v", err)
 	}
-	s3URI := fmt.Sprintf("%s/kube-aws/clusters/%s/exported/stacks",
-		strings.TrimSuffix(c.opts.S3URI, "/"),
-		c.controlPlane.ClusterName,
-	)
+	s3URI := clusterAssetsS3URI(c.opts.S3URI, c.controlPlane.ClusterName)
 
 	assets := cfnstack.NewAssetsBuilder(c.stackName(), s3URI, c.controlPlane.Region).Add(REMOTE_STACK_TEMPLATE_FILENAME, stackTemplate).Build()
 
@@ -257,23 +308,16 @@ func (c clusterImpl) renderTemplateAsString() (string, error) {
 }
 
 func (c clusterImpl) stackProvisioner() *cfnstack.Provisioner {
-	stackPolicyBody := `{
-  "Statement" : [
-    {
-       "Effect" : "Allow",
-       "Principal" : "*",
-       "Action" : "Update:*",
-       "Resource" : "*"
-     }
-  ]
-}`
+	// The root stack contains only nested stacks, which are protected by their own stack policies.
+	// Templates uploaded by the provisioner e.g. on validation go along with the other assets so that destroy and gc
+	// delete them
 	return cfnstack.NewProvisioner(
 		c.stackName(),
 		c.tags(),
-		c.opts.S3URI,
+		clusterAssetsS3URI(c.opts.S3URI, c.controlPlane.ClusterName),
 		c.controlPlane.Region,
-		stackPolicyBody,
-		c.session)
+		model.NewAllowAllStackPolicy().Body(),
+		c.session).WithS3Options(c.controlPlane.S3)
 }
 
 func (c clusterImpl) stackName() string {
@@ -287,12 +331,22 @@ func (c clusterImpl) tags() map[string]string {
 func (c clusterImpl) Update() (string, error) {
 	cfSvc := cloudformation.New(c.session)
 
-	templateUrl, err := c.prepareTemplateWithAssets()
+	assets, templateUrl, err := c.prepareTemplateWithAssets()
 	if err != nil {
 		return "", err
 	}
 
-	return c.stackProvisioner().UpdateStackAtURLAndWait(cfSvc, templateUrl)
+	ctx, cancel := c.contextWithTimeout(0)
+	defer cancel()
+
+	report, err := c.updateWithStackPolicies(cfSvc, func() (string, error) {
+		return c.stackProvisioner().WithContext(ctx).UpdateStackAtURLAndWait(cfSvc, templateUrl)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return report, c.recordRevisionWithWarning(assets)
 }
 
 func (c clusterImpl) ValidateUserData() error {
@@ -323,6 +377,62 @@ func (c clusterImpl) ValidateTemplates() error {
 	return nil
 }
 
+// LintTemplates renders the root, control-plane and node-pool stack templates and lints them without calling AWS APIs
+func (c clusterImpl) LintTemplates() ([]cfnlint.Problem, error) {
+	rootTemplate, err := c.renderTemplateAsString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render template: %v", err)
+	}
+	templates := []cfnlint.Template{{Name: c.stackName(), Body: []byte(rootTemplate)}}
+
+	cpTemplate, err := c.controlPlane.RenderStackTemplateAsString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render control plane template: %v", err)
+	}
+	templates = append(templates, cfnlint.Template{Name: c.controlPlane.NestedStackName(), Body: []byte(cpTemplate)})
+
+	for i, p := range c.nodePools {
+		npTemplate, err := p.RenderStackTemplateAsString()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render node pool #%d template: %v", i, err)
+		}
+		templates = append(templates, cfnlint.Template{Name: p.NestedStackName(), Body: []byte(npTemplate)})
+	}
+
+	return cfnlint.Lint(templates), nil
+}
+
+// UserDataSize is the size of userdata of nodes in a role provisioned by a nested stack
+type UserDataSize struct {
+	controlplane_cfg.UserDataSize
+	Stack string
+}
+
+// UserDataSizes reports how large userdata is against the limit per role in the control plane and every node pool
+func (c clusterImpl) UserDataSizes() ([]UserDataSize, error) {
+	sizes := []UserDataSize{}
+
+	cpSizes, err := c.controlPlane.UserDataSizes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to size control plane userdata: %v", err)
+	}
+	for _, s := range cpSizes {
+		sizes = append(sizes, UserDataSize{UserDataSize: s, Stack: c.controlPlane.NestedStackName()})
+	}
+
+	for i, p := range c.nodePools {
+		npSizes, err := p.UserDataSizes()
+		if err != nil {
+			return nil, fmt.Errorf("failed to size node pool #%d userdata: %v", i, err)
+		}
+		for _, s := range npSizes {
+			sizes = append(sizes, UserDataSize{UserDataSize: s, Stack: p.NestedStackName()})
+		}
+	}
+
+	return sizes, nil
+}
+
 func (c clusterImpl) ValidateStack() (string, error) {
 	reports := []string{}
 
@@ -70,19 +70,24 @@ func ConfigFromBytes(data []byte) (*Config, error) {
 			return nil, fmt.Errorf("invalid node pool at index %d: %v", i, err)
 		}
 
-		if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
+		validations := []unknownKeyValidation{
 			{np, fmt.Sprintf("worker.nodePools[%d]", i)},
 			{np.AutoScalingGroup, fmt.Sprintf("worker.nodePools[%d].autoScalingGroup", i)},
 			{np.ClusterAutoscaler, fmt.Sprintf("worker.nodePools[%d].clusterAutoscaler", i)},
 			{np.SpotFleet, fmt.Sprintf("worker.nodePools[%d].spotFleet", i)},
-		}); err != nil {
+		}
+		if np.StackPolicy != nil {
+			validations = append(validations, unknownKeyValidation{np.StackPolicy, fmt.Sprintf("worker.nodePools[%d].stackPolicy", i)})
+		}
+		validations = append(validations, customizationValidations(fmt.Sprintf("worker.nodePools[%d]", i), np.CustomSystemdUnits, np.CustomFiles)...)
+		if err := failFastWhenUnknownKeysFound(validations); err != nil {
 			return nil, err
 		}
 	}
 
 	cfg := &Config{Cluster: cpCluser, NodePools: nodePools}
 
-	if err := failFastWhenUnknownKeysFound([]unknownKeyValidation{
+	validations := []unknownKeyValidation{
 		{c, ""},
 		{c.WorkerConfig, "worker"},
 		{c.Etcd, "etcd"},
@@ -90,13 +95,37 @@ func ConfigFromBytes(data []byte) (*Config, error) {
 		{c.Controller.AutoScalingGroup, "controller.autoScalingGroup"},
 		{c.Controller.ClusterAutoscaler, "controller.ClusterAutoscaler"},
 		{c.Experimental, "experimental"},
-	}); err != nil {
+		{c.S3, "s3"},
+		{c.AWS, "aws"},
+		{c.TLS, "tls"},
+	}
+	if c.StackPolicy != nil {
+		validations = append(validations, unknownKeyValidation{c.StackPolicy, "stackPolicy"})
+	}
+	validations = append(validations, customizationValidations("controller", c.Controller.CustomSystemdUnits, c.Controller.CustomFiles)...)
+	validations = append(validations, customizationValidations("etcd", c.Etcd.CustomSystemdUnits, c.Etcd.CustomFiles)...)
+	if err := failFastWhenUnknownKeysFound(validations); err != nil {
 		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// customizationValidations validates keys of custom systemd units, their drop-ins and custom files under the key path
+func customizationValidations(keyPath string, units []model.CustomSystemdUnit, files []model.CustomFile) []unknownKeyValidation {
+	validations := []unknownKeyValidation{}
+	for i, u := range units {
+		validations = append(validations, unknownKeyValidation{u, fmt.Sprintf("%s.customSystemdUnits[%d]", keyPath, i)})
+		for j, d := range u.DropIns {
+			validations = append(validations, unknownKeyValidation{d, fmt.Sprintf("%s.customSystemdUnits[%d].dropIns[%d]", keyPath, i, j)})
+		}
+	}
+	for i, f := range files {
+		validations = append(validations, unknownKeyValidation{f, fmt.Sprintf("%s.customFiles[%d]", keyPath, i)})
+	}
+	return validations
+}
+
 func failFastWhenUnknownKeysFound(vs []unknownKeyValidation) error {
 	for _, v := range vs {
 		if err := v.unknownKeysSupport.FailWhenUnknownKeysFound(v.keyPath); err != nil {
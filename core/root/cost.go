@@ -0,0 +1,301 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	controlplane_cfg "github.com/coreos/kube-aws/core/controlplane/config"
+	nodepool_cfg "github.com/coreos/kube-aws/core/nodepool/config"
+	"github.com/coreos/kube-aws/core/root/config"
+	"github.com/coreos/kube-aws/model"
+	"github.com/coreos/kube-aws/pricing"
+)
+
+// CostItem is the monthly cost of identical resources of a cluster component
+type CostItem struct {
+	// Component is either controller, etcd, network or the name of a node pool
+	Component string  `json:"component"`
+	Resource  string  `json:"resource"`
+	Quantity  int     `json:"quantity"`
+	Detail    string  `json:"detail"`
+	Monthly   float64 `json:"monthly"`
+}
+
+// CostEstimate is the monthly cost of a cluster computed offline from cluster.yaml and a price table.
+// It doesn't include data transfer, data processed by ELBs and NAT gateways, S3 and CloudWatch
+type CostEstimate struct {
+	Region            string     `json:"region"`
+	PriceTableVersion string     `json:"priceTableVersion"`
+	Currency          string     `json:"currency"`
+	Items             []CostItem `json:"items"`
+	Total             float64    `json:"total"`
+}
+
+func (e *CostEstimate) String() string {
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "COMPONENT\tRESOURCE\tQUANTITY\tDETAIL\tMONTHLY")
+	for _, i := range e.Items {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", i.Component, i.Resource, i.Quantity, i.Detail, formatMoney(i.Monthly))
+	}
+	fmt.Fprintf(w, "TOTAL\t\t\t\t%s\n", formatMoney(e.Total))
+	w.Flush()
+	fmt.Fprintf(buf, "\nPrices in %s for %s from the price table %s, assuming %d hours per month. Spot instances are costed at their maximum prices.\n", e.Currency, e.Region, e.PriceTableVersion, pricing.HoursPerMonth)
+	fmt.Fprintln(buf, "Data transfer, data processed by load balancers and NAT gateways, S3 and CloudWatch aren't included.")
+	return buf.String()
+}
+
+func formatMoney(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// EstimateMonthlyCost computes the monthly cost of the cluster configured by cluster.yaml without calling AWS APIs
+func EstimateMonthlyCost(cfg *config.Config, table pricing.Table) (*CostEstimate, error) {
+	return estimateMonthlyCost(cfg.Cluster, cfg.NodePools, table)
+}
+
+type costEstimator struct {
+	prices   pricing.RegionPrices
+	estimate *CostEstimate
+}
+
+func estimateMonthlyCost(cp *controlplane_cfg.Cluster, nodePools []*nodepool_cfg.ProvidedConfig, table pricing.Table) (*CostEstimate, error) {
+	prices, err := table.Region(cp.Region.Name)
+	if err != nil {
+		return nil, err
+	}
+	e := &costEstimator{
+		prices: prices,
+		estimate: &CostEstimate{
+			Region:            cp.Region.Name,
+			PriceTableVersion: table.Version,
+			Currency:          table.Currency,
+			Items:             []CostItem{},
+		},
+	}
+
+	controllers := cp.MinControllerCount()
+	if err := e.instances("controller", controllers, cp.ControllerInstanceType); err != nil {
+		return nil, err
+	}
+	if err := e.volumes("controller", "root volumes", controllers, cp.ControllerRootVolumeType, cp.ControllerRootVolumeSize, cp.ControllerRootVolumeIOPS); err != nil {
+		return nil, err
+	}
+
+	if err := e.instances("etcd", cp.EtcdCount, cp.EtcdInstanceType); err != nil {
+		return nil, err
+	}
+	if err := e.volumes("etcd", "root volumes", cp.EtcdCount, cp.EtcdRootVolumeType, cp.EtcdRootVolumeSize, cp.EtcdRootVolumeIOPS); err != nil {
+		return nil, err
+	}
+	if !cp.EtcdDataVolumeEphemeral {
+		if err := e.volumes("etcd", "data volumes", cp.EtcdCount, cp.EtcdDataVolumeType, cp.EtcdDataVolumeSize, cp.EtcdDataVolumeIOPS); err != nil {
+			return nil, err
+		}
+	}
+	if cp.Etcd.NodeShouldHaveEIP() {
+		e.elasticIPs("etcd", cp.EtcdCount)
+	}
+
+	e.add(CostItem{
+		Component: "network",
+		Resource:  "load balancers",
+		Quantity:  1,
+		Detail:    fmt.Sprintf("API server, %s/h", formatHourly(prices.LoadBalancerHourly)),
+		Monthly:   prices.LoadBalancerHourly * pricing.HoursPerMonth,
+	})
+	natGateways, natEIPs := 0, 0
+	for _, ngw := range cp.NATGateways() {
+		if ngw.ManageNATGateway() {
+			natGateways++
+		}
+		if ngw.ManageEIP() {
+			natEIPs++
+		}
+	}
+	if natGateways > 0 {
+		e.add(CostItem{
+			Component: "network",
+			Resource:  "NAT gateways",
+			Quantity:  natGateways,
+			Detail:    fmt.Sprintf("%s/h", formatHourly(prices.NATGatewayHourly)),
+			Monthly:   float64(natGateways) * prices.NATGatewayHourly * pricing.HoursPerMonth,
+		})
+	}
+	e.elasticIPs("network", natEIPs)
+
+	for _, p := range nodePools {
+		if err := e.nodePool(p); err != nil {
+			return nil, fmt.Errorf("failed to estimate cost of node pool %s: %v", p.NodePoolName, err)
+		}
+	}
+
+	for _, i := range e.estimate.Items {
+		e.estimate.Total += i.Monthly
+	}
+	e.estimate.Total = math.Floor(e.estimate.Total*100+0.5) / 100
+	return e.estimate, nil
+}
+
+func (e *costEstimator) nodePool(p *nodepool_cfg.ProvidedConfig) error {
+	name := p.NodePoolName
+	var err error
+	if p.SpotFleet.Enabled() {
+		err = e.spotFleet(name, p.SpotFleet)
+	} else {
+		err = e.autoScalingGroup(name, p)
+	}
+	if err != nil {
+		return err
+	}
+	e.nodePoolLoadBalancers(name, p)
+	return nil
+}
+
+// autoScalingGroup costs instances of a node pool at its minimum size
+func (e *costEstimator) autoScalingGroup(name string, p *nodepool_cfg.ProvidedConfig) error {
+	count := p.MinCount()
+	if p.SpotPrice != "" {
+		price, err := strconv.ParseFloat(p.SpotPrice, 64)
+		if err != nil {
+			return fmt.Errorf("invalid spot price %q: %v", p.SpotPrice, err)
+		}
+		e.add(CostItem{
+			Component: name,
+			Resource:  "spot instances",
+			Quantity:  count,
+			Detail:    fmt.Sprintf("%s, at most %s/h", p.InstanceType, formatHourly(price)),
+			Monthly:   float64(count) * price * pricing.HoursPerMonth,
+		})
+	} else if err := e.instances(name, count, p.InstanceType); err != nil {
+		return err
+	}
+	return e.volumes(name, "root volumes", count, p.RootVolumeType, p.RootVolumeSize, p.RootVolumeIOPS)
+}
+
+// spotFleet costs a spot fleet as if it fulfilled the target capacity only with instances of the launch specification
+// whose price per unit of weighted capacity is the lowest, as the default lowestPrice allocation strategy does
+func (e *costEstimator) spotFleet(name string, fleet model.SpotFleet) error {
+	var cheapest *model.LaunchSpecification
+	var cheapestPrice, cheapestPricePerUnit float64
+	for i, spec := range fleet.LaunchSpecifications {
+		if spec.WeightedCapacity <= 0 {
+			return fmt.Errorf("invalid weighted capacity %d of %s", spec.WeightedCapacity, spec.InstanceType)
+		}
+		price, err := strconv.ParseFloat(spec.SpotPrice, 64)
+		if err != nil {
+			return fmt.Errorf("invalid spot price %q of %s: %v", spec.SpotPrice, spec.InstanceType, err)
+		}
+		pricePerUnit := price / float64(spec.WeightedCapacity)
+		if cheapest == nil || pricePerUnit < cheapestPricePerUnit {
+			cheapest = &fleet.LaunchSpecifications[i]
+			cheapestPrice, cheapestPricePerUnit = price, pricePerUnit
+		}
+	}
+	if cheapest == nil {
+		return fmt.Errorf("spot fleet has no launch specifications")
+	}
+
+	spec := *cheapest
+	count := (fleet.TargetCapacity + spec.WeightedCapacity - 1) / spec.WeightedCapacity
+	e.add(CostItem{
+		Component: name,
+		Resource:  "spot fleet instances",
+		Quantity:  count,
+		Detail: fmt.Sprintf("%s weighing %d for the target capacity %d, at most %s/h",
+			spec.InstanceType, spec.WeightedCapacity, fleet.TargetCapacity, formatHourly(cheapestPrice)),
+		Monthly: float64(count) * cheapestPrice * pricing.HoursPerMonth,
+	})
+	return e.volumes(name, "root volumes", count, spec.RootVolumeType, spec.RootVolumeSize, spec.RootVolumeIOPS)
+}
+
+// nodePoolLoadBalancers adds load balancers which node pools are attached to. Classic ELBs are costed per name, while load
+// balancers behind target groups aren't as which ones they are can't be known offline
+func (e *costEstimator) nodePoolLoadBalancers(name string, p *nodepool_cfg.ProvidedConfig) {
+	if p.LoadBalancer.Enabled && len(p.LoadBalancer.Names) > 0 {
+		count := len(p.LoadBalancer.Names)
+		e.add(CostItem{
+			Component: name,
+			Resource:  "load balancers",
+			Quantity:  count,
+			Detail:    fmt.Sprintf("%s, %s/h", strings.Join(p.LoadBalancer.Names, " "), formatHourly(e.prices.LoadBalancerHourly)),
+			Monthly:   float64(count) * e.prices.LoadBalancerHourly * pricing.HoursPerMonth,
+		})
+	}
+	if p.TargetGroup.Enabled && len(p.TargetGroup.Arns) > 0 {
+		e.add(CostItem{
+			Component: name,
+			Resource:  "target groups",
+			Quantity:  len(p.TargetGroup.Arns),
+			Detail:    "load balancers of target groups aren't costed",
+		})
+	}
+}
+
+func (e *costEstimator) instances(component string, count int, instanceType string) error {
+	if count == 0 {
+		return nil
+	}
+	price, err := e.prices.Instance(instanceType)
+	if err != nil {
+		return err
+	}
+	e.add(CostItem{
+		Component: component,
+		Resource:  "instances",
+		Quantity:  count,
+		Detail:    fmt.Sprintf("%s, %s/h", instanceType, formatHourly(price)),
+		Monthly:   float64(count) * price * pricing.HoursPerMonth,
+	})
+	return nil
+}
+
+func (e *costEstimator) volumes(component string, resource string, count int, volumeType string, sizeGB int, iops int) error {
+	if count == 0 {
+		return nil
+	}
+	price, err := e.prices.Volume(volumeType, sizeGB, iops)
+	if err != nil {
+		return err
+	}
+	detail := fmt.Sprintf("%dGiB %s", sizeGB, volumeType)
+	if volumeType == "io1" {
+		detail = fmt.Sprintf("%s, %d IOPS", detail, iops)
+	}
+	e.add(CostItem{
+		Component: component,
+		Resource:  resource,
+		Quantity:  count,
+		Detail:    detail,
+		Monthly:   float64(count) * price,
+	})
+	return nil
+}
+
+// elasticIPs adds EIPs, which are free while associated with running instances and NAT gateways as kube-aws does
+func (e *costEstimator) elasticIPs(component string, count int) {
+	if count == 0 {
+		return
+	}
+	e.add(CostItem{
+		Component: component,
+		Resource:  "elastic IPs",
+		Quantity:  count,
+		Detail:    fmt.Sprintf("free while associated, %s/h otherwise", formatHourly(e.prices.ElasticIPHourly)),
+	})
+}
+
+// add adds the item with its cost rounded to cents
+func (e *costEstimator) add(i CostItem) {
+	i.Monthly = math.Floor(i.Monthly*100+0.5) / 100
+	e.estimate.Items = append(e.estimate.Items, i)
+}
+
+func formatHourly(v float64) string {
+	return "$" + strconv.FormatFloat(v, 'f', -1, 64)
+}
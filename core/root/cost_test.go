@@ -0,0 +1,162 @@
+package root
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/coreos/kube-aws/core/root/config"
+	"github.com/coreos/kube-aws/pricing"
+)
+
+const costTestConfigYaml = `clusterName: test-cluster-name
+externalDNSName: test.staging.core-os.net
+keyName: test-key-name
+region: us-west-1
+kmsKeyArn: "arn:aws:kms:us-west-1:xxxxxxxxx:key/xxxxxxxxxxxxxxxxxxx"
+amiId: ami-12345678
+controllerCount: 2
+controllerInstanceType: m4.large
+etcdCount: 3
+etcdDataVolumeType: io1
+etcdDataVolumeSize: 100
+etcdDataVolumeIOPS: 1000
+subnets:
+- name: private1
+  availabilityZone: us-west-1a
+  instanceCIDR: "10.0.1.0/24"
+  private: true
+- name: private2
+  availabilityZone: us-west-1b
+  instanceCIDR: "10.0.2.0/24"
+  private: true
+- name: public1
+  availabilityZone: us-west-1a
+  instanceCIDR: "10.0.3.0/24"
+- name: public2
+  availabilityZone: us-west-1b
+  instanceCIDR: "10.0.4.0/24"
+controller:
+  subnets:
+  - name: private1
+  - name: private2
+  loadBalancer:
+    subnets:
+    - name: public1
+    - name: public2
+etcd:
+  subnets:
+  - name: private1
+  - name: private2
+worker:
+  nodePools:
+  - name: pool1
+    count: 2
+    subnets:
+    - name: public1
+    loadBalancer:
+      enabled: true
+      names:
+      - lb1
+      - lb2
+  - name: pool2
+    subnets:
+    - name: public2
+    targetGroup:
+      enabled: true
+      arns:
+      - arn:aws:elasticloadbalancing:us-west-1:xxxxxxxxxxxx:targetgroup/tg1/xxxxxxxxxxxxxxxx
+    spotFleet:
+      targetCapacity: 5
+      unitRootVolumeSize: 30
+      launchSpecifications:
+      - weightedCapacity: 1
+        instanceType: c4.large
+        spotPrice: "0.05"
+      - weightedCapacity: 2
+        instanceType: c4.xlarge
+        spotPrice: "0.08"
+`
+
+var costTestTable = pricing.Table{
+	Version:  "test",
+	Currency: "USD",
+	Regions: map[string]pricing.RegionPrices{
+		"us-west-1": {
+			InstanceHourly: map[string]float64{
+				"m4.large":  0.1,
+				"t2.medium": 0.05,
+			},
+			Volumes: map[string]pricing.VolumePrices{
+				"gp2": {GBMonthly: 0.1},
+				"io1": {GBMonthly: 0.125, IOPSMonthly: 0.065},
+			},
+			LoadBalancerHourly: 0.025,
+			NATGatewayHourly:   0.045,
+			ElasticIPHourly:    0.005,
+		},
+	},
+}
+
+func TestEstimateMonthlyCost(t *testing.T) {
+	cfg, err := config.ConfigFromBytes([]byte(costTestConfigYaml))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	estimate, err := EstimateMonthlyCost(cfg, costTestTable)
+	if err != nil {
+		t.Fatalf("failed to estimate cost: %v", err)
+	}
+
+	expected := []CostItem{
+		{Component: "controller", Resource: "instances", Quantity: 2, Monthly: 146},
+		{Component: "controller", Resource: "root volumes", Quantity: 2, Monthly: 6},
+		{Component: "etcd", Resource: "instances", Quantity: 3, Monthly: 109.5},
+		{Component: "etcd", Resource: "root volumes", Quantity: 3, Monthly: 9},
+		{Component: "etcd", Resource: "data volumes", Quantity: 3, Monthly: 232.5},
+		{Component: "etcd", Resource: "elastic IPs", Quantity: 3, Monthly: 0},
+		{Component: "network", Resource: "load balancers", Quantity: 1, Monthly: 18.25},
+		{Component: "network", Resource: "NAT gateways", Quantity: 2, Monthly: 65.7},
+		{Component: "network", Resource: "elastic IPs", Quantity: 2, Monthly: 0},
+		{Component: "pool1", Resource: "instances", Quantity: 2, Monthly: 73},
+		{Component: "pool1", Resource: "root volumes", Quantity: 2, Monthly: 6},
+		{Component: "pool1", Resource: "load balancers", Quantity: 2, Monthly: 36.5},
+		// c4.xlarge is cheaper per unit of weighted capacity
+		{Component: "pool2", Resource: "spot fleet instances", Quantity: 3, Monthly: 175.2},
+		{Component: "pool2", Resource: "root volumes", Quantity: 3, Monthly: 18},
+		{Component: "pool2", Resource: "target groups", Quantity: 1, Monthly: 0},
+	}
+	if len(estimate.Items) != len(expected) {
+		t.Fatalf("expected %d items, got %d: %+v", len(expected), len(estimate.Items), estimate.Items)
+	}
+	for i, e := range expected {
+		a := estimate.Items[i]
+		if a.Component != e.Component || a.Resource != e.Resource || a.Quantity != e.Quantity || math.Abs(a.Monthly-e.Monthly) > 0.001 {
+			t.Errorf("expected item #%d to be %+v, got %+v", i, e, a)
+		}
+	}
+	if math.Abs(estimate.Total-895.65) > 0.001 {
+		t.Errorf("unexpected total: %f", estimate.Total)
+	}
+	if !strings.Contains(estimate.Items[12].Detail, "c4.xlarge") {
+		t.Errorf("unexpected spot fleet detail: %s", estimate.Items[12].Detail)
+	}
+	if out := estimate.String(); !strings.Contains(out, "TOTAL") || !strings.Contains(out, "895.65") {
+		t.Errorf("unexpected output: %s", out)
+	}
+
+	t.Run("UnknownInstanceType", func(t *testing.T) {
+		cfg.ControllerInstanceType = "x1.32xlarge"
+		if _, err := EstimateMonthlyCost(cfg, costTestTable); err == nil || !strings.Contains(err.Error(), "x1.32xlarge") {
+			t.Errorf("expected an error for the instance type missing in the price table, got %v", err)
+		}
+	})
+
+	t.Run("UnknownRegion", func(t *testing.T) {
+		cfg.Region.Name = "eu-west-2"
+		if _, err := EstimateMonthlyCost(cfg, costTestTable); err == nil {
+			t.Error("expected an error for the region missing in the price table")
+		}
+	})
+}
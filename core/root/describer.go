@@ -5,16 +5,22 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/core/controlplane/cluster"
+	controlplane_cfg "github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root/config"
+	"github.com/coreos/kube-aws/core/root/defaults"
+	"os"
+	"time"
 )
 
 type Info struct {
-	ControlPlane *cluster.Info
-}
-
-func (i *Info) String() string {
-	return i.ControlPlane.String()
+	ControlPlane *cluster.Info           `json:"controlPlane" yaml:"controlPlane"`
+	Controllers  *AutoScalingGroupStatus `json:"controllers,omitempty" yaml:"controllers,omitempty"`
+	Etcd         []*EtcdInstanceStatus   `json:"etcd" yaml:"etcd"`
+	NodePools    []*NodePoolStatus       `json:"nodePools" yaml:"nodePools"`
+	// Certificates are read from the local TLS assets directory rather than the running cluster
+	Certificates []*controlplane_cfg.CertificateStatus `json:"certificates,omitempty" yaml:"certificates,omitempty"`
 }
 
 type ClusterDescriber interface {
@@ -25,6 +31,8 @@ type clusterDescriberImpl struct {
 	session     *session.Session
 	clusterName string
 	stackName   string
+	// controlPlaneConfig is used to check the certificates in the TLS assets directory when set
+	controlPlaneConfig *controlplane_cfg.Cluster
 }
 
 func ClusterDescriberFromFile(configPath string) (ClusterDescriber, error) {
@@ -32,16 +40,14 @@ func ClusterDescriberFromFile(configPath string) (ClusterDescriber, error) {
 	if err != nil {
 		return nil, err
 	}
-	awsConfig := aws.NewConfig().
-		WithRegion(config.Region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	session, err := session.NewSession(awsConfig)
+	session, err := awsconn.NewSession(awsconn.Config{Region: config.Region, Options: config.AWSConnOptions()})
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+		return nil, err
 	}
 
-	return NewClusterDescriber(config.ClusterName, config.ClusterName, session), nil
+	describer := NewClusterDescriber(config.ClusterName, config.ClusterName, session).(clusterDescriberImpl)
+	describer.controlPlaneConfig = config.Cluster
+	return describer, nil
 }
 
 func NewClusterDescriber(clusterName string, stackName string, session *session.Session) ClusterDescriber {
@@ -55,19 +61,14 @@ func NewClusterDescriber(clusterName string, stackName string, session *session.
 func (c clusterDescriberImpl) Info() (*Info, error) {
 	cfSvc := cloudformation.New(c.session)
 
-	var cpStackName string
-	{
-		resp, err := cfSvc.DescribeStackResource(
-			&cloudformation.DescribeStackResourceInput{
-				LogicalResourceId: aws.String("Controlplane"),
-				StackName:         aws.String(c.stackName),
-			},
-		)
-		if err != nil {
-			errmsg := "unable to get nested stack for control-plane:\n" + err.Error()
-			return nil, fmt.Errorf(errmsg)
-		}
-		cpStackName = *resp.StackResourceDetail.PhysicalResourceId
+	resources, err := listStackResources(cfSvc, c.stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	cpStackName := physicalResourceID(resources, "Controlplane")
+	if cpStackName == "" {
+		return nil, fmt.Errorf("unable to get nested stack for control-plane in stack %s", c.stackName)
 	}
 
 	var info Info
@@ -88,9 +89,38 @@ func (c clusterDescriberImpl) Info() (*Info, error) {
 		cpDescriber := cluster.NewClusterDescriber(c.clusterName, cpStackName, c.session)
 
 		cpInfo, err := cpDescriber.Info()
+		if err != nil {
+			return nil, err
+		}
 
 		info.ControlPlane = cpInfo
 	}
 
+	if err := c.controlPlaneStatus(&info, cpStackName); err != nil {
+		return nil, err
+	}
+
+	info.NodePools = []*NodePoolStatus{}
+	for _, r := range resources {
+		name := aws.StringValue(r.LogicalResourceId)
+		if aws.StringValue(r.ResourceType) != "AWS::CloudFormation::Stack" || name == "Controlplane" || aws.StringValue(r.PhysicalResourceId) == "" {
+			continue
+		}
+		p, err := c.nodePoolStatus(name, aws.StringValue(r.PhysicalResourceId))
+		if err != nil {
+			return nil, err
+		}
+		info.NodePools = append(info.NodePools, p)
+	}
+
+	if c.controlPlaneConfig != nil {
+		report, err := c.controlPlaneConfig.CheckTLSAssets(defaults.AssetsDir, controlplane_cfg.DefaultCertExpiryWindowDays, time.Now())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "WARNING: failed to check certificates: %v\n", err)
+		} else {
+			info.Certificates = report.Certificates
+		}
+	}
+
 	return &info, nil
 }
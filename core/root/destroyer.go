@@ -1,23 +1,40 @@
 package root
 
 import (
+	"context"
 	"fmt"
-	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/awsconn"
 	"github.com/coreos/kube-aws/cfnstack"
 	"github.com/coreos/kube-aws/core/root/config"
+	"time"
 )
 
 type DestroyOptions struct {
 	AwsDebug bool
+	// Wait makes Destroy block until the root stack and its nested stacks are deleted
+	Wait bool
+	// DeleteAssets makes Destroy delete every file uploaded under S3URI for the cluster once the stacks are deleted.
+	// Requires Wait
+	DeleteAssets bool
+	S3URI        string
+	// Context is used to stop waiting for the deletion e.g. on an interrupt
+	Context context.Context
+	// Timeout is how long to wait for the deletion when Wait is set. Zero means no timeout
+	Timeout time.Duration
 }
 
 type ClusterDestroyer interface {
 	Destroy() error
+	RetainStuckResourcesAndDestroy(*cfnstack.DeletionFailedError) error
 }
 
 type clusterDestroyerImpl struct {
-	underlying *cfnstack.Destroyer
+	underlying  *cfnstack.Destroyer
+	clusterName string
+	opts        DestroyOptions
+	session     *session.Session
 }
 
 func ClusterDestroyerFromFile(configPath string, opts DestroyOptions) (ClusterDestroyer, error) {
@@ -29,25 +46,61 @@ func ClusterDestroyerFromFile(configPath string, opts DestroyOptions) (ClusterDe
 	region := cfg.Region
 	stackName := cfg.RootStackName()
 
-	awsConfig := aws.NewConfig().
-		WithRegion(region.String()).
-		WithCredentialsChainVerboseErrors(true)
-
-	if opts.AwsDebug {
-		awsConfig = awsConfig.WithLogLevel(aws.LogDebug)
-	}
-
-	session, err := session.NewSession(awsConfig)
+	session, err := awsconn.NewSession(awsconn.Config{Region: region, Debug: opts.AwsDebug, Options: cfg.AWSConnOptions()})
 	if err != nil {
-		return nil, fmt.Errorf("failed to establish aws session: %v", err)
+		return nil, err
 	}
 
 	cfnDestroyer := cfnstack.NewDestroyer(stackName, session)
 	return clusterDestroyerImpl{
-		underlying: cfnDestroyer,
+		underlying:  cfnDestroyer,
+		clusterName: cfg.ClusterName,
+		opts:        opts,
+		session:     session,
 	}, nil
 }
 
 func (d clusterDestroyerImpl) Destroy() error {
-	return d.underlying.Destroy()
+	if !d.opts.Wait {
+		return d.underlying.Destroy()
+	}
+	ctx, cancel := d.contextWithTimeout()
+	defer cancel()
+	if err := d.underlying.WithContext(ctx).DestroyAndWait(); err != nil {
+		return err
+	}
+	return d.deleteAssets()
+}
+
+func (d clusterDestroyerImpl) contextWithTimeout() (context.Context, context.CancelFunc) {
+	ctx := d.opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if d.opts.Timeout == 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d.opts.Timeout)
+}
+
+func (d clusterDestroyerImpl) RetainStuckResourcesAndDestroy(failure *cfnstack.DeletionFailedError) error {
+	ctx, cancel := d.contextWithTimeout()
+	defer cancel()
+	if err := d.underlying.WithContext(ctx).RetainStuckResourcesAndDestroy(failure); err != nil {
+		return err
+	}
+	return d.deleteAssets()
+}
+
+func (d clusterDestroyerImpl) deleteAssets() error {
+	if !d.opts.DeleteAssets {
+		return nil
+	}
+	s3URI := clusterS3URI(d.opts.S3URI, d.clusterName)
+	n, err := cfnstack.DeleteAllObjects(s3.New(d.session), s3URI)
+	if err != nil {
+		return fmt.Errorf("failed to delete assets in %s: %v", s3URI, err)
+	}
+	fmt.Printf("Deleted %d file(s) from %s\n", n, s3URI)
+	return nil
 }
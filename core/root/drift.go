@@ -0,0 +1,453 @@
+package root
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/coreos/kube-aws/cfnstack"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	// DriftSourceTemplate is the source of a drift between the template rendered from cluster.yaml and the deployed one
+	DriftSourceTemplate = "template"
+	// DriftSourceLive is the source of a drift between the deployed template and the running resource, which is usually
+	// caused by a change made outside of CloudFormation e.g. in the AWS console
+	DriftSourceLive = "live"
+
+	maxDriftValueLength = 60
+)
+
+var etcdLaunchConfigurationLogicalName = regexp.MustCompile(`^Etcd[0-9]+LC$`)
+
+// Drift is a difference found in a resource which is going to be reverted by the next `kube-aws update`
+type Drift struct {
+	StackName    string `json:"stackName" yaml:"stackName"`
+	LogicalID    string `json:"logicalId" yaml:"logicalId"`
+	ResourceType string `json:"resourceType" yaml:"resourceType"`
+	// Property is the path to the differing value in the resource e.g. Properties.MaxSize.
+	// Empty when the whole resource is missing on either side
+	Property string `json:"property" yaml:"property"`
+	Source   string `json:"source" yaml:"source"`
+	Expected string `json:"expected" yaml:"expected"`
+	Actual   string `json:"actual" yaml:"actual"`
+	// ConfigKey is the cluster.yaml key which produces the resource, if known
+	ConfigKey string `json:"configKey" yaml:"configKey"`
+}
+
+type DriftReport struct {
+	Drifts []Drift `json:"drifts" yaml:"drifts"`
+}
+
+func (r *DriftReport) HasDrifts() bool {
+	return len(r.Drifts) > 0
+}
+
+func (r *DriftReport) String() string {
+	if !r.HasDrifts() {
+		return "No drift found\n"
+	}
+
+	buf := new(bytes.Buffer)
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STACK\tRESOURCE\tPROPERTY\tSOURCE\tEXPECTED\tACTUAL\tCLUSTER.YAML")
+	for _, d := range r.Drifts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.StackName,
+			d.LogicalID,
+			stringOrDash(d.Property),
+			d.Source,
+			truncateDriftValue(d.Expected),
+			truncateDriftValue(d.Actual),
+			stringOrDash(d.ConfigKey),
+		)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// configKeyFunc returns the cluster.yaml key which produces the property of the resource
+type configKeyFunc func(logicalID string, property string) string
+
+// Drift compares the templates rendered from cluster.yaml with the ones deployed to the root, control-plane and
+// node pool stacks, and key properties of running resources with the deployed templates
+func (c clusterImpl) Drift() (*DriftReport, error) {
+	cfSvc := cloudformation.New(c.session)
+	asSvc := autoscaling.New(c.session)
+	ec2Svc := ec2.New(c.session)
+
+	report := &DriftReport{Drifts: []Drift{}}
+
+	rootTemplate, err := c.renderTemplateAsString()
+	if err != nil {
+		return nil, fmt.Errorf("failed to render the root stack template: %v", err)
+	}
+	drifts, err := templateDrifts(cfSvc, c.stackName(), c.stackName(), rootTemplate, c.rootConfigKey)
+	if err != nil {
+		return nil, err
+	}
+	report.Drifts = append(report.Drifts, drifts...)
+
+	ids, err := c.nestedStackIDs(cfSvc)
+	if err != nil {
+		return nil, err
+	}
+
+	type nestedStackWithConfigKey struct {
+		stack     NestedStack
+		template  func() (string, error)
+		configKey configKeyFunc
+	}
+	stacks := []nestedStackWithConfigKey{
+		{c.templateParams().ControlPlane(), c.controlPlane.RenderStackTemplateAsString, c.controlPlaneConfigKey},
+	}
+	for i, np := range c.nodePools {
+		stacks = append(stacks, nestedStackWithConfigKey{c.templateParams().NodePools()[i], np.RenderStackTemplateAsString, nodePoolConfigKey(i)})
+	}
+
+	for _, s := range stacks {
+		stackID, ok := ids[s.stack.Name()]
+		if !ok {
+			// The nested stack doesn't exist yet. It is reported as a drift in the root stack
+			continue
+		}
+
+		rendered, err := s.template()
+		if err != nil {
+			return nil, fmt.Errorf("failed to render the template of stack %s: %v", s.stack.Name(), err)
+		}
+
+		drifts, err := templateDrifts(cfSvc, stackID, s.stack.Name(), rendered, s.configKey)
+		if err != nil {
+			return nil, err
+		}
+		report.Drifts = append(report.Drifts, drifts...)
+
+		drifts, err = liveDrifts(cfSvc, asSvc, ec2Svc, stackID, s.stack.Name(), s.configKey)
+		if err != nil {
+			return nil, err
+		}
+		report.Drifts = append(report.Drifts, drifts...)
+	}
+
+	return report, nil
+}
+
+// templateDrifts compares the template rendered from cluster.yaml with the one deployed to the stack
+func templateDrifts(cfSvc *cloudformation.CloudFormation, stackID string, name string, rendered string, configKey configKeyFunc) ([]Drift, error) {
+	deployed, err := cfnstack.GetTemplateBody(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs, err := cfnstack.DiffTemplates(rendered, deployed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare templates of stack %s: %v", name, err)
+	}
+
+	drifts := []Drift{}
+	for _, d := range diffs {
+		logicalID := d.LogicalID
+		if d.Section != "Resources" {
+			logicalID = strings.TrimSpace(d.Section + " " + d.LogicalID)
+		}
+		drifts = append(drifts, Drift{
+			StackName:    name,
+			LogicalID:    logicalID,
+			ResourceType: d.ResourceType,
+			Property:     d.Path,
+			Source:       DriftSourceTemplate,
+			Expected:     formatDriftValue(d.Expected),
+			Actual:       formatDriftValue(d.Actual),
+			ConfigKey:    configKey(d.LogicalID, d.Path),
+		})
+	}
+	return drifts, nil
+}
+
+// liveDrifts compares ASG sizes and launch configurations, launch configuration instance types and security groups and
+// spot fleet target capacities with the template deployed to the stack.
+// Properties referring to anything other than resources and parameters of the stack are not compared
+func liveDrifts(cfSvc *cloudformation.CloudFormation, asSvc *autoscaling.AutoScaling, ec2Svc *ec2.EC2, stackID string, name string, configKey configKeyFunc) ([]Drift, error) {
+	deployed, err := cfnstack.GetTemplateBody(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+	var template struct {
+		Resources map[string]struct {
+			Type       string
+			Properties map[string]interface{}
+		}
+	}
+	if err := json.Unmarshal([]byte(deployed), &template); err != nil {
+		return nil, fmt.Errorf("failed to parse the template of stack %s: %v", name, err)
+	}
+
+	resources, err := listStackResources(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+	parameters, err := stackParameters(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+	r := refResolver{resources: resources, parameters: parameters}
+
+	logicalIDs := []string{}
+	for id := range template.Resources {
+		logicalIDs = append(logicalIDs, id)
+	}
+	sort.Strings(logicalIDs)
+
+	drifts := []Drift{}
+	add := func(logicalID string, resourceType string, property string, expected string, actual string) {
+		if expected == actual {
+			return
+		}
+		drifts = append(drifts, Drift{
+			StackName:    name,
+			LogicalID:    logicalID,
+			ResourceType: resourceType,
+			Property:     property,
+			Source:       DriftSourceLive,
+			Expected:     expected,
+			Actual:       actual,
+			ConfigKey:    configKey(logicalID, property),
+		})
+	}
+
+	for _, id := range logicalIDs {
+		res := template.Resources[id]
+		physicalID := physicalResourceID(resources, id)
+		if physicalID == "" {
+			continue
+		}
+
+		switch res.Type {
+		case "AWS::AutoScaling::AutoScalingGroup":
+			resp, err := asSvc.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+				AutoScalingGroupNames: []*string{aws.String(physicalID)},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe autoscaling group %s: %v", physicalID, err)
+			}
+			if len(resp.AutoScalingGroups) == 0 {
+				add(id, res.Type, "", physicalID, "<none>")
+				continue
+			}
+			g := resp.AutoScalingGroups[0]
+			if v, ok := r.resolve(res.Properties["MinSize"]); ok {
+				add(id, res.Type, "Properties.MinSize", v, strconv.FormatInt(aws.Int64Value(g.MinSize), 10))
+			}
+			if v, ok := r.resolve(res.Properties["MaxSize"]); ok {
+				add(id, res.Type, "Properties.MaxSize", v, strconv.FormatInt(aws.Int64Value(g.MaxSize), 10))
+			}
+			if v, ok := r.resolve(res.Properties["LaunchConfigurationName"]); ok {
+				add(id, res.Type, "Properties.LaunchConfigurationName", v, aws.StringValue(g.LaunchConfigurationName))
+			}
+		case "AWS::AutoScaling::LaunchConfiguration":
+			resp, err := asSvc.DescribeLaunchConfigurations(&autoscaling.DescribeLaunchConfigurationsInput{
+				LaunchConfigurationNames: []*string{aws.String(physicalID)},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe launch configuration %s: %v", physicalID, err)
+			}
+			if len(resp.LaunchConfigurations) == 0 {
+				add(id, res.Type, "", physicalID, "<none>")
+				continue
+			}
+			lc := resp.LaunchConfigurations[0]
+			if v, ok := r.resolve(res.Properties["InstanceType"]); ok {
+				add(id, res.Type, "Properties.InstanceType", v, aws.StringValue(lc.InstanceType))
+			}
+			if v, ok := r.resolveSet(res.Properties["SecurityGroups"]); ok {
+				add(id, res.Type, "Properties.SecurityGroups", v, sortedJoin(aws.StringValueSlice(lc.SecurityGroups)))
+			}
+		case "AWS::EC2::SpotFleet":
+			config, _ := res.Properties["SpotFleetRequestConfigData"].(map[string]interface{})
+			v, ok := r.resolve(config["TargetCapacity"])
+			if !ok {
+				continue
+			}
+			status, err := describeSpotFleet(ec2Svc, physicalID)
+			if err != nil {
+				return nil, err
+			}
+			add(id, res.Type, "Properties.SpotFleetRequestConfigData.TargetCapacity", v, strconv.FormatInt(status.TargetCapacity, 10))
+		}
+	}
+	return drifts, nil
+}
+
+func stackParameters(cfSvc *cloudformation.CloudFormation, stackID string) (map[string]string, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing stack %s: %v", stackID, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return nil, fmt.Errorf("could not find a stack with name %s", stackID)
+	}
+	parameters := map[string]string{}
+	for _, p := range resp.Stacks[0].Parameters {
+		parameters[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+	}
+	return parameters, nil
+}
+
+// refResolver resolves literals and Refs in a template to the values CloudFormation has passed to resources
+type refResolver struct {
+	resources  []*cloudformation.StackResourceSummary
+	parameters map[string]string
+}
+
+func (r refResolver) resolve(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), true
+	case map[string]interface{}:
+		name, ok := t["Ref"].(string)
+		if !ok || len(t) != 1 {
+			return "", false
+		}
+		if p, ok := r.parameters[name]; ok {
+			return p, true
+		}
+		if id := physicalResourceID(r.resources, name); id != "" {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// resolveSet resolves a list of values into a sorted, comma-separated string so that it can be compared regardless of order
+func (r refResolver) resolveSet(v interface{}) (string, bool) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return "", false
+	}
+	values := []string{}
+	for _, item := range items {
+		s, ok := r.resolve(item)
+		if !ok {
+			return "", false
+		}
+		values = append(values, s)
+	}
+	return sortedJoin(values), true
+}
+
+func sortedJoin(values []string) string {
+	sorted := append([]string{}, values...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+func formatDriftValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "<none>"
+	case string:
+		return t
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func truncateDriftValue(v string) string {
+	if len(v) <= maxDriftValueLength {
+		return v
+	}
+	return v[:maxDriftValueLength-3] + "..."
+}
+
+func (c clusterImpl) rootConfigKey(logicalID string, property string) string {
+	if logicalID == c.controlPlane.NestedStackName() {
+		return "(top-level keys)"
+	}
+	for i, np := range c.nodePools {
+		if logicalID == np.NestedStackName() {
+			return fmt.Sprintf("worker.nodePools[%d]", i)
+		}
+	}
+	return ""
+}
+
+func (c clusterImpl) controlPlaneConfigKey(logicalID string, property string) string {
+	cp := c.controlPlane
+	controller := cp.Controller.LogicalName()
+
+	switch {
+	case logicalID == controller:
+		if property == "Properties.MinSize" || property == "Properties.MaxSize" {
+			return "controllerCount, controller.autoScalingGroup"
+		}
+		return "controller"
+	case logicalID == controller+"LC":
+		return launchConfigurationConfigKey(property, "controllerInstanceType", "controllerRootVolume*", "controller")
+	case etcdLaunchConfigurationLogicalName.MatchString(logicalID):
+		return launchConfigurationConfigKey(property, "etcdInstanceType", "etcdRootVolume*, etcdDataVolume*", "etcd")
+	case strings.HasPrefix(logicalID, cp.Etcd.LogicalName()):
+		return "etcd"
+	case strings.HasSuffix(logicalID, "ElbAPIServer"):
+		return "externalDNSName, controller.loadBalancer"
+	case logicalID == cp.VPCLogicalName() || logicalID == cp.InternetGatewayLogicalName() || logicalID == "VPCGatewayAttachment":
+		return "vpcId, vpcCIDR, internetGatewayId"
+	case strings.HasPrefix(logicalID, "NatGateway"):
+		return "subnets[].natGateway"
+	}
+
+	for i, s := range cp.Subnets {
+		if s.Name != "" && strings.HasPrefix(logicalID, s.LogicalName()) {
+			return fmt.Sprintf("subnets[%d]", i)
+		}
+	}
+	return ""
+}
+
+func nodePoolConfigKey(index int) configKeyFunc {
+	prefix := fmt.Sprintf("worker.nodePools[%d]", index)
+	return func(logicalID string, property string) string {
+		switch logicalID {
+		case workersLogicalName:
+			switch {
+			case property == "Properties.MinSize" || property == "Properties.MaxSize":
+				return prefix + ".count, " + prefix + ".autoScalingGroup"
+			case strings.HasPrefix(property, "Properties.SpotFleetRequestConfigData"):
+				return prefix + ".spotFleet"
+			}
+			return prefix
+		case workersLogicalName + "LC":
+			if property == "Properties.SecurityGroups" {
+				return prefix + ".securityGroupIds"
+			}
+			return launchConfigurationConfigKey(property, prefix+".instanceType", prefix+".rootVolume*", prefix)
+		}
+		return prefix
+	}
+}
+
+func launchConfigurationConfigKey(property string, instanceTypeKey string, volumeKey string, defaultKey string) string {
+	switch {
+	case property == "Properties.InstanceType":
+		return instanceTypeKey
+	case strings.HasPrefix(property, "Properties.BlockDeviceMappings"):
+		return volumeKey
+	}
+	return defaultKey
+}
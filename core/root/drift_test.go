@@ -0,0 +1,85 @@
+package root
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"strings"
+	"testing"
+)
+
+func TestRefResolver(t *testing.T) {
+	r := refResolver{
+		resources: []*cloudformation.StackResourceSummary{
+			{LogicalResourceId: aws.String("SecurityGroupWorker"), PhysicalResourceId: aws.String("sg-1111")},
+		},
+		parameters: map[string]string{"ControlPlaneStackName": "mycluster-Controlplane"},
+	}
+
+	cases := []struct {
+		value    interface{}
+		expected string
+		ok       bool
+	}{
+		{"t2.medium", "t2.medium", true},
+		{float64(3), "3", true},
+		{map[string]interface{}{"Ref": "SecurityGroupWorker"}, "sg-1111", true},
+		{map[string]interface{}{"Ref": "ControlPlaneStackName"}, "mycluster-Controlplane", true},
+		{map[string]interface{}{"Ref": "Unknown"}, "", false},
+		{map[string]interface{}{"Fn::ImportValue": "foo"}, "", false},
+	}
+	for _, c := range cases {
+		v, ok := r.resolve(c.value)
+		if v != c.expected || ok != c.ok {
+			t.Errorf("unexpected result for %v: expected=(%q, %v), actual=(%q, %v)", c.value, c.expected, c.ok, v, ok)
+		}
+	}
+
+	v, ok := r.resolveSet([]interface{}{"sg-2222", map[string]interface{}{"Ref": "SecurityGroupWorker"}})
+	if !ok || v != "sg-1111,sg-2222" {
+		t.Errorf("unexpected result: %q, %v", v, ok)
+	}
+	if _, ok := r.resolveSet([]interface{}{map[string]interface{}{"Fn::ImportValue": "foo"}}); ok {
+		t.Errorf("expected a list containing an unresolvable value not to be resolved")
+	}
+}
+
+func TestNodePoolConfigKey(t *testing.T) {
+	key := nodePoolConfigKey(1)
+
+	cases := map[string]string{
+		"Workers Properties.MaxSize":          "worker.nodePools[1].count, worker.nodePools[1].autoScalingGroup",
+		"WorkersLC Properties.InstanceType":   "worker.nodePools[1].instanceType",
+		"WorkersLC Properties.SecurityGroups": "worker.nodePools[1].securityGroupIds",
+		"IAMRoleWorker Properties.Policies":   "worker.nodePools[1]",
+	}
+	for input, expected := range cases {
+		args := strings.SplitN(input, " ", 2)
+		if actual := key(args[0], args[1]); actual != expected {
+			t.Errorf("unexpected config key for %s: expected=%s, actual=%s", input, expected, actual)
+		}
+	}
+}
+
+func TestDriftReportString(t *testing.T) {
+	r := &DriftReport{Drifts: []Drift{}}
+	if r.String() != "No drift found\n" {
+		t.Errorf("unexpected report: %s", r.String())
+	}
+
+	r.Drifts = append(r.Drifts, Drift{
+		StackName: "pool1",
+		LogicalID: "Workers",
+		Property:  "Properties.MaxSize",
+		Source:    DriftSourceLive,
+		Expected:  "3",
+		Actual:    "5",
+		ConfigKey: "worker.nodePools[0].autoScalingGroup",
+	})
+	lines := strings.Split(strings.TrimSpace(r.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected a header and a drift but got: %s", r.String())
+	}
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "pool1 Workers Properties.MaxSize live 3 5 worker.nodePools[0].autoScalingGroup" {
+		t.Errorf("unexpected line: %s", lines[1])
+	}
+}
@@ -0,0 +1,203 @@
+package root
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/awsconn"
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/core/root/config"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var revisionObjectKey = regexp.MustCompile(`/revisions/([0-9]+)/`)
+
+type GCOptions struct {
+	AwsDebug bool
+	S3URI    string
+	// Keep is the number of the most recent revisions of the cluster, and of each unreferenced asset, to be kept
+	Keep   int
+	DryRun bool
+}
+
+type AssetsCollector interface {
+	Collect() ([]string, error)
+}
+
+type assetsCollectorImpl struct {
+	stackName   string
+	clusterName string
+	opts        GCOptions
+	session     *session.Session
+}
+
+func AssetsCollectorFromFile(configPath string, opts GCOptions) (AssetsCollector, error) {
+	cfg, err := config.ConfigFromFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := awsconn.NewSession(awsconn.Config{Region: cfg.Region, Debug: opts.AwsDebug, Options: cfg.AWSConnOptions()})
+	if err != nil {
+		return nil, err
+	}
+
+	return assetsCollectorImpl{
+		stackName:   cfg.RootStackName(),
+		clusterName: cfg.ClusterName,
+		opts:        opts,
+		session:     session,
+	}, nil
+}
+
+// Collect deletes assets uploaded to S3 which are no longer referenced from templates of the live stacks and returns
+// their keys. Nothing is deleted when DryRun is set
+func (c assetsCollectorImpl) Collect() ([]string, error) {
+	cfSvc := cloudformation.New(c.session)
+	s3Svc := s3.New(c.session)
+
+	templates, err := liveTemplates(cfSvc, c.stackName)
+	if err != nil {
+		return nil, err
+	}
+
+	s3URI := clusterAssetsS3URI(c.opts.S3URI, c.clusterName)
+	objects, err := cfnstack.ListObjects(s3Svc, s3URI)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions, err := listRevisions(s3Svc, c.opts.S3URI, c.clusterName)
+	if err != nil {
+		return nil, err
+	}
+	kept, references, err := keptRevisions(revisions, c.opts.Keep, s3URI)
+	if err != nil {
+		return nil, err
+	}
+
+	stale := append(
+		cfnstack.StaleObjects(objects, append(templates, references...), c.opts.Keep),
+		staleRevisionObjects(objects, kept)...,
+	)
+
+	keys := []string{}
+	for _, o := range stale {
+		keys = append(keys, aws.StringValue(o.Key))
+	}
+
+	if c.opts.DryRun || len(stale) == 0 {
+		return keys, nil
+	}
+
+	uri, err := cfnstack.S3URIFromString(s3URI)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfnstack.DeleteObjects(s3Svc, uri.Bucket(), stale); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// keptRevisions returns numbers of the most recent revisions which must be kept so that the cluster can be rolled back to
+// them, along with keys of the userdata files referenced from them. At least the latest revision is always kept
+func keptRevisions(revisions []*Revision, keep int, assetsS3URI string) (map[int]bool, []string, error) {
+	uri, err := cfnstack.S3URIFromString(assetsS3URI)
+	if err != nil {
+		return nil, nil, err
+	}
+	prefix := strings.Join(uri.PathComponents(), "/")
+
+	if keep < 1 {
+		keep = 1
+	}
+	start := len(revisions) - keep
+	if start < 0 {
+		start = 0
+	}
+
+	kept := map[int]bool{}
+	references := []string{}
+	for _, r := range revisions[start:] {
+		kept[r.Number] = true
+		for name, fingerprint := range r.UserDataFingerprints {
+			references = append(references, path.Join(prefix, fmt.Sprintf("%s-%s", name, fingerprint)))
+		}
+	}
+	return kept, references, nil
+}
+
+// staleRevisionObjects returns manifests and template copies of revisions which are not kept
+func staleRevisionObjects(objects []*s3.Object, kept map[int]bool) []*s3.Object {
+	stale := []*s3.Object{}
+	for _, o := range objects {
+		m := revisionObjectKey.FindStringSubmatch(aws.StringValue(o.Key))
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil || kept[n] {
+			continue
+		}
+		stale = append(stale, o)
+	}
+	return stale
+}
+
+// liveTemplates returns the current templates of the root stack and all of its nested stacks
+func liveTemplates(cfSvc *cloudformation.CloudFormation, stackName string) ([]string, error) {
+	templates := []string{}
+	stacks := []string{stackName}
+
+	for len(stacks) > 0 {
+		s := stacks[0]
+		stacks = stacks[1:]
+
+		t, err := cfSvc.GetTemplate(&cloudformation.GetTemplateInput{
+			StackName: aws.String(s),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error getting template of stack %s: %v", s, err)
+		}
+		templates = append(templates, aws.StringValue(t.TemplateBody))
+
+		var nextToken *string
+		for {
+			resp, err := cfSvc.ListStackResources(&cloudformation.ListStackResourcesInput{
+				StackName: aws.String(s),
+				NextToken: nextToken,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list resources of stack %s: %v", s, err)
+			}
+			for _, r := range resp.StackResourceSummaries {
+				if aws.StringValue(r.ResourceType) == "AWS::CloudFormation::Stack" && aws.StringValue(r.PhysicalResourceId) != "" {
+					stacks = append(stacks, aws.StringValue(r.PhysicalResourceId))
+				}
+			}
+			if aws.StringValue(resp.NextToken) == "" {
+				break
+			}
+			nextToken = resp.NextToken
+		}
+	}
+
+	return templates, nil
+}
+
+// clusterS3URI returns the S3 URI under which every file kube-aws uploads for the cluster is stored
+func clusterS3URI(s3URI string, clusterName string) string {
+	return fmt.Sprintf("%s/kube-aws/clusters/%s", strings.TrimSuffix(s3URI, "/"), clusterName)
+}
+
+// clusterAssetsS3URI returns the S3 URI under which stack templates and userdata of the cluster are uploaded
+func clusterAssetsS3URI(s3URI string, clusterName string) string {
+	return fmt.Sprintf("%s/exported/stacks", clusterS3URI(s3URI, clusterName))
+}
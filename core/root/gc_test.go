@@ -0,0 +1,60 @@
+package root
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	controlplane "github.com/coreos/kube-aws/core/controlplane/cluster"
+	controlplane_cfg "github.com/coreos/kube-aws/core/controlplane/config"
+	"github.com/coreos/kube-aws/model"
+)
+
+type recordingS3ObjectPutter struct {
+	keys []string
+}
+
+func (s *recordingS3ObjectPutter) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	s.keys = append(s.keys, aws.StringValue(input.Key))
+	return &s3.PutObjectOutput{}, nil
+}
+
+type dummyStackCreator struct{}
+
+func (s dummyStackCreator) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	return &cloudformation.CreateStackOutput{StackId: aws.String("arn:aws:cloudformation:us-west-1:123456789012:stack/mycluster/1")}, nil
+}
+
+func TestStackProvisionerUploadsTemplatesAlongWithClusterAssets(t *testing.T) {
+	c := clusterImpl{
+		controlPlane: &controlplane.Cluster{
+			ClusterRef: &controlplane.ClusterRef{
+				Cluster: &controlplane_cfg.Cluster{
+					DeploymentSettings: controlplane_cfg.DeploymentSettings{
+						ClusterName: "mycluster",
+						Region:      model.RegionForName("us-west-1"),
+					},
+				},
+			},
+		},
+		opts: options{S3URI: "s3://mybucket/mydir"},
+	}
+
+	s3Svc := &recordingS3ObjectPutter{}
+	if _, err := c.stackProvisioner().CreateStack(dummyStackCreator{}, s3Svc, "{}", map[string]string{"userdata-worker": "#cloud-config"}); err != nil {
+		t.Fatalf("failed to create stack: %v", err)
+	}
+
+	// Keys listed by destroy and gc
+	prefix := strings.TrimPrefix(clusterAssetsS3URI("s3://mybucket/mydir", "mycluster"), "s3://mybucket/") + "/"
+	if len(s3Svc.keys) != 2 {
+		t.Errorf("expected 2 uploads but there were %d: %v", len(s3Svc.keys), s3Svc.keys)
+	}
+	for _, k := range s3Svc.keys {
+		if !strings.HasPrefix(k, prefix) {
+			t.Errorf("expected %s to be uploaded under %s", k, prefix)
+		}
+	}
+}
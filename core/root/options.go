@@ -1,6 +1,10 @@
 package root
 
-import "github.com/coreos/kube-aws/core/root/defaults"
+import (
+	"context"
+	"github.com/coreos/kube-aws/core/root/defaults"
+	"time"
+)
 
 type options struct {
 	AssetsDir                         string
@@ -13,6 +17,16 @@ type options struct {
 	S3URI                             string
 	SkipWait                          bool
 	PrettyPrint                       bool
+	// Context is used to stop waiting for stack operations e.g. on an interrupt
+	Context context.Context
+	// Timeout is how long to wait for a stack operation. `kube-aws up` defaults to the sum of createTimeouts in cluster.yaml
+	// while others wait indefinitely by default
+	Timeout time.Duration
+	// AllowReplace is the list of logical IDs of resources in nested stacks which are temporarily allowed by stack policies
+	// to be replaced or deleted during an update
+	AllowReplace []string
+	// SkipAssetsEncryption renders stacks with raw TLS assets and auth tokens so that KMS isn't called
+	SkipAssetsEncryption bool
 }
 
 func NewOptions(s3URI string, prettyPrint bool, skipWait bool) options {
@@ -0,0 +1,367 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/model"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+)
+
+const PLAN_MANIFEST_FILENAME = "manifest.json"
+
+// Plan is the set of changes which are going to be made to the root stack and its nested stacks by `kube-aws update`
+type Plan struct {
+	// ChangeSetName is the name of the change set created for the root stack, which can be executed later via
+	// `kube-aws update --change-set <name>`. Empty when there is no change, as the change set is deleted
+	ChangeSetName string
+	ChangeSets    []*cfnstack.ChangeSet
+}
+
+func (p *Plan) Replacements() []cfnstack.ResourceChange {
+	replacements := []cfnstack.ResourceChange{}
+	for _, cs := range p.ChangeSets {
+		replacements = append(replacements, cs.Replacements()...)
+	}
+	return replacements
+}
+
+func (p *Plan) HasReplacements() bool {
+	return len(p.Replacements()) > 0
+}
+
+func (p *Plan) String() string {
+	reports := []string{}
+	for _, cs := range p.ChangeSets {
+		reports = append(reports, cs.String())
+	}
+	return strings.Join(reports, "\n")
+}
+
+func NewChangeSetName() string {
+	return fmt.Sprintf("kube-aws-plan-%s", time.Now().UTC().Format("20060102150405"))
+}
+
+func (c clusterImpl) Plan(changeSetName string) (*Plan, error) {
+	cfSvc := cloudformation.New(c.session)
+
+	assets, templateURL, err := c.prepareTemplateWithAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{
+		ChangeSetName: changeSetName,
+		ChangeSets:    []*cfnstack.ChangeSet{},
+	}
+
+	rootProvisioner := c.stackProvisioner().WithContext(c.context())
+	rootChangeSet, err := rootProvisioner.CreateChangeSetAtURLAndWait(cfSvc, changeSetName, templateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	plan.ChangeSets = append(plan.ChangeSets, rootChangeSet)
+
+	// Nested stacks are updated only through the root stack, so nothing changes in them either
+	if !rootChangeSet.HasChanges() {
+		plan.ChangeSetName = ""
+		if err := rootProvisioner.DeleteChangeSet(cfSvc, changeSetName); err != nil {
+			return nil, err
+		}
+		return plan, nil
+	}
+
+	nestedChangeSets, err := c.planNestedStacks(cfSvc, changeSetName)
+	if err != nil {
+		if deleteErr := rootProvisioner.DeleteChangeSet(cfSvc, changeSetName); deleteErr != nil {
+			return nil, fmt.Errorf("%v, and then %v", err, deleteErr)
+		}
+		return nil, err
+	}
+	plan.ChangeSets = append(plan.ChangeSets, nestedChangeSets...)
+
+	manifestAssets, err := newPlanManifestAssets(changeSetName, assets, c.opts.S3URI, c.controlPlane.ClusterName, c.configHash)
+	if err == nil {
+		err = c.stackProvisioner().UploadAssets(s3.New(c.session), manifestAssets)
+	}
+	if err != nil {
+		if deleteErr := rootProvisioner.DeleteChangeSet(cfSvc, changeSetName); deleteErr != nil {
+			return nil, fmt.Errorf("failed to upload the manifest of change set %s: %v, and then %v", changeSetName, err, deleteErr)
+		}
+		return nil, fmt.Errorf("failed to upload the manifest of change set %s: %v", changeSetName, err)
+	}
+
+	return plan, nil
+}
+
+// planManifest is the set of assets uploaded by `kube-aws plan` for a change set. It is recorded as a revision once
+// the change set is executed, as assets rendered at that time may differ from the ones the change set was created from
+type planManifest struct {
+	// ConfigHash is the SHA-256 hash of cluster.yaml the change set was created from
+	ConfigHash string         `json:"configHash"`
+	Assets     []plannedAsset `json:"assets"`
+}
+
+type plannedAsset struct {
+	StackName string `json:"stackName"`
+	Filename  string `json:"filename"`
+	// Content is omitted for userdata files, as revisions refer to them only by the fingerprints in their names
+	Content string `json:"content,omitempty"`
+}
+
+func plansS3URI(s3URI string, clusterName string) string {
+	return fmt.Sprintf("%s/plans", clusterAssetsS3URI(s3URI, clusterName))
+}
+
+// newPlanManifestAssets returns the manifest of the assets uploaded for the change set, placed under the directory
+// for the change set
+func newPlanManifestAssets(changeSetName string, assets cfnstack.Assets, s3URI string, clusterName string, configHash string) (cfnstack.Assets, error) {
+	manifest := planManifest{
+		ConfigHash: configHash,
+		Assets:     []plannedAsset{},
+	}
+	var region model.Region
+	for id, a := range assets.AsMap() {
+		planned := plannedAsset{
+			StackName: id.StackName(),
+			Filename:  id.Filename(),
+		}
+		if !fingerprintedFileName.MatchString(id.Filename()) {
+			planned.Content = a.Content
+		}
+		manifest.Assets = append(manifest.Assets, planned)
+		region = a.Region
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal plan manifest: %v", err)
+	}
+
+	return cfnstack.NewAssetsBuilder(changeSetName, plansS3URI(s3URI, clusterName), region).
+		Add(PLAN_MANIFEST_FILENAME, string(data)).
+		Build(), nil
+}
+
+// assets returns the assets in the manifest, located where they were uploaded by `kube-aws plan`
+func (m *planManifest) assets(s3URI string, clusterName string, region model.Region) cfnstack.Assets {
+	builders := map[string]cfnstack.AssetsBuilder{}
+	for _, a := range m.Assets {
+		builder, ok := builders[a.StackName]
+		if !ok {
+			builder = cfnstack.NewAssetsBuilder(a.StackName, clusterAssetsS3URI(s3URI, clusterName), region)
+			builders[a.StackName] = builder
+		}
+		builder.Add(a.Filename, a.Content)
+	}
+
+	var assets cfnstack.Assets
+	for _, b := range builders {
+		if assets == nil {
+			assets = b.Build()
+			continue
+		}
+		assets = assets.Merge(b.Build())
+	}
+	return assets
+}
+
+func loadPlanManifest(s3Svc revisionsS3Service, s3URI string, clusterName string, changeSetName string) (*planManifest, error) {
+	uri, err := cfnstack.S3URIFromString(fmt.Sprintf("%s/%s/%s", plansS3URI(s3URI, clusterName), changeSetName, PLAN_MANIFEST_FILENAME))
+	if err != nil {
+		return nil, err
+	}
+	key := strings.Join(uri.PathComponents(), "/")
+	resp, err := s3Svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(uri.Bucket()),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get plan manifest %s: %v", key, err)
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan manifest %s: %v", key, err)
+	}
+	var m planManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse plan manifest %s: %v", key, err)
+	}
+	if len(m.Assets) == 0 {
+		return nil, fmt.Errorf("plan manifest %s contains no assets", key)
+	}
+	return &m, nil
+}
+
+func (c clusterImpl) planNestedStacks(cfSvc *cloudformation.CloudFormation, changeSetName string) ([]*cfnstack.ChangeSet, error) {
+	nestedStackIDs, err := c.nestedStackIDs(cfSvc)
+	if err != nil {
+		return nil, err
+	}
+	values := nestedStackParameterValues(nestedStackIDs[c.templateParams().ControlPlane().Name()])
+
+	changeSets := []*cfnstack.ChangeSet{}
+	nestedStacks := append([]NestedStack{c.templateParams().ControlPlane()}, c.templateParams().NodePools()...)
+	for _, s := range nestedStacks {
+		stackID, ok := nestedStackIDs[s.Name()]
+		if !ok {
+			// The nested stack doesn't exist yet. It is shown as an addition in the change set for the root stack
+			continue
+		}
+
+		changeSet, err := c.createNestedChangeSet(cfSvc, stackID, s, changeSetName, values)
+		if err != nil {
+			return nil, err
+		}
+		changeSet.StackName = s.Name()
+		changeSets = append(changeSets, changeSet)
+	}
+	return changeSets, nil
+}
+
+// createNestedChangeSet creates a change set for the nested stack only to describe changes in it.
+// The change set is deleted immediately afterwards because nested stacks are updated through the root stack
+func (c clusterImpl) createNestedChangeSet(cfSvc *cloudformation.CloudFormation, stackID string, s NestedStack, changeSetName string, values map[string]string) (*cfnstack.ChangeSet, error) {
+	templateURL, err := s.TemplateURL()
+	if err != nil {
+		return nil, err
+	}
+
+	parameters, err := nestedStackParameters(cfSvc, stackID, templateURL, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan changes for nested stack %s: %v", s.Name(), err)
+	}
+
+	provisioner := cfnstack.NewProvisioner(stackID, s.Tags(), c.opts.S3URI, c.controlPlane.Region, "", c.session).WithContext(c.context())
+
+	changeSet, err := provisioner.CreateChangeSetAtURLAndWait(cfSvc, changeSetName, templateURL, parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to plan changes for nested stack %s: %v", s.Name(), err)
+	}
+
+	if err := provisioner.DeleteChangeSet(cfSvc, changeSetName); err != nil {
+		return nil, err
+	}
+
+	return changeSet, nil
+}
+
+// nestedStackIDs returns a map from logical IDs of nested stacks in the root stack to their stack IDs
+func (c clusterImpl) nestedStackIDs(cfSvc *cloudformation.CloudFormation) (map[string]string, error) {
+	resp, err := cfSvc.DescribeStackResources(&cloudformation.DescribeStackResourcesInput{
+		StackName: aws.String(c.stackName()),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe resources of stack %s: %v", c.stackName(), err)
+	}
+
+	ids := map[string]string{}
+	for _, r := range resp.StackResources {
+		if aws.StringValue(r.ResourceType) == "AWS::CloudFormation::Stack" && aws.StringValue(r.PhysicalResourceId) != "" {
+			ids[aws.StringValue(r.LogicalResourceId)] = aws.StringValue(r.PhysicalResourceId)
+		}
+	}
+	return ids, nil
+}
+
+// nestedStackParameterValues returns the values the root stack template passes to parameters of nested stacks
+func nestedStackParameterValues(controlPlaneStackID string) map[string]string {
+	// Stack IDs are in the form of arn:aws:cloudformation:<region>:<account>:stack/<name>/<id>
+	parts := strings.Split(controlPlaneStackID, "/")
+	if len(parts) < 2 {
+		return map[string]string{}
+	}
+	return map[string]string{
+		"ControlPlaneStackName": parts[1],
+	}
+}
+
+// nestedStackParameters returns parameters declared in the template for the nested stack.
+// Parameters of nested stacks are passed from the root stack and therefore can't be known until the root stack is updated.
+// Ones the stack already has reuse the values currently in use, and new ones are given the values from the root stack
+// template, or left to their defaults
+func nestedStackParameters(cfSvc *cloudformation.CloudFormation, stackID string, templateURL string, values map[string]string) ([]*cloudformation.Parameter, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing stack %s: %v", stackID, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return nil, fmt.Errorf("could not find a stack with name %s", stackID)
+	}
+	previous := map[string]bool{}
+	for _, p := range resp.Stacks[0].Parameters {
+		previous[aws.StringValue(p.ParameterKey)] = true
+	}
+
+	tmpl, err := cfSvc.ValidateTemplate(&cloudformation.ValidateTemplateInput{
+		TemplateURL: aws.String(templateURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read parameters from template %s: %v", templateURL, err)
+	}
+
+	parameters := []*cloudformation.Parameter{}
+	for _, p := range tmpl.Parameters {
+		key := aws.StringValue(p.ParameterKey)
+		if previous[key] {
+			parameters = append(parameters, &cloudformation.Parameter{
+				ParameterKey:     p.ParameterKey,
+				UsePreviousValue: aws.Bool(true),
+			})
+			continue
+		}
+		if v, ok := values[key]; ok {
+			parameters = append(parameters, &cloudformation.Parameter{
+				ParameterKey:   p.ParameterKey,
+				ParameterValue: aws.String(v),
+			})
+			continue
+		}
+		if p.DefaultValue == nil {
+			return nil, fmt.Errorf("value of parameter %s new to stack %s is unknown until the root stack is updated", key, stackID)
+		}
+	}
+	return parameters, nil
+}
+
+func (c clusterImpl) UpdateWithChangeSet(changeSetName string) (string, error) {
+	cfSvc := cloudformation.New(c.session)
+
+	ctx, cancel := c.contextWithTimeout(0)
+	defer cancel()
+
+	report, err := c.updateWithStackPolicies(cfSvc, func() (string, error) {
+		return c.stackProvisioner().WithContext(ctx).ExecuteChangeSetAndWait(cfSvc, changeSetName)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	s3Svc := s3.New(c.session)
+	manifest, err := loadPlanManifest(s3Svc, c.opts.S3URI, c.controlPlane.ClusterName, changeSetName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to record the revision of this deployment. You won't be able to roll back to it: %v\n", err)
+		return report, nil
+	}
+
+	// The revision is recorded with the hash of cluster.yaml the change set was created from
+	planned := c
+	planned.configHash = manifest.ConfigHash
+	if err := planned.recordRevisionWithWarning(manifest.assets(c.opts.S3URI, c.controlPlane.ClusterName, c.controlPlane.Region)); err != nil {
+		return report, err
+	}
+
+	if _, err := cfnstack.DeleteAllObjects(s3Svc, fmt.Sprintf("%s/%s", plansS3URI(c.opts.S3URI, c.controlPlane.ClusterName), changeSetName)); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to delete the manifest of change set %s: %v\n", changeSetName, err)
+	}
+	return report, nil
+}
@@ -0,0 +1,159 @@
+package root
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/model"
+	"github.com/coreos/kube-aws/test/fakeaws"
+)
+
+func TestNestedStackParameters(t *testing.T) {
+	b := fakeaws.New("us-west-1")
+	b.S3.AddBucket("mybucket")
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-west-1").WithCredentials(credentials.AnonymousCredentials)))
+	b.Install(sess)
+	cfSvc := cloudformation.New(sess)
+
+	resource := `"Resources": {"Role": {"Type": "AWS::IAM::Role", "Properties": {}}}`
+	created, err := cfSvc.CreateStack(&cloudformation.CreateStackInput{
+		StackName:    aws.String("mycluster-Pool1"),
+		TemplateBody: aws.String(`{"Parameters": {"Legacy": {"Type": "String"}}, ` + resource + `}`),
+		Parameters:   []*cloudformation.Parameter{{ParameterKey: aws.String("Legacy"), ParameterValue: aws.String("foo")}},
+		Capabilities: []*string{aws.String(cloudformation.CapabilityCapabilityIam)},
+	})
+	if err != nil {
+		t.Fatalf("failed to create stack: %v", err)
+	}
+
+	template := `{"Parameters": {
+  "Legacy": {"Type": "String"},
+  "ControlPlaneStackName": {"Type": "String"},
+  "WithDefault": {"Type": "String", "Default": "bar"}
+}, ` + resource + `}`
+	if _, err := s3.New(sess).PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("mybucket"),
+		Key:    aws.String("pool1.json"),
+		Body:   strings.NewReader(template),
+	}); err != nil {
+		t.Fatalf("failed to upload template: %v", err)
+	}
+	templateURL := "https://s3-us-west-1.amazonaws.com/mybucket/pool1.json"
+
+	values := nestedStackParameterValues("arn:aws:cloudformation:us-west-1:123456789012:stack/mycluster-Controlplane-1234/5678")
+	parameters, err := nestedStackParameters(cfSvc, aws.StringValue(created.StackId), templateURL, values)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual := map[string]string{}
+	for _, p := range parameters {
+		if aws.BoolValue(p.UsePreviousValue) {
+			actual[aws.StringValue(p.ParameterKey)] = "(previous)"
+		} else {
+			actual[aws.StringValue(p.ParameterKey)] = aws.StringValue(p.ParameterValue)
+		}
+	}
+	expected := map[string]string{
+		"Legacy":                "(previous)",
+		"ControlPlaneStackName": "mycluster-Controlplane-1234",
+	}
+	if len(actual) != len(expected) {
+		t.Errorf("unexpected parameters: expected %v, got %v", expected, actual)
+	}
+	for k, v := range expected {
+		if actual[k] != v {
+			t.Errorf("unexpected value of parameter %s: expected %s, got %s", k, v, actual[k])
+		}
+	}
+
+	if _, err := cfSvc.CreateChangeSet(&cloudformation.CreateChangeSetInput{
+		StackName:     created.StackId,
+		ChangeSetName: aws.String("plan1"),
+		TemplateURL:   aws.String(templateURL),
+		Parameters:    parameters,
+		Capabilities:  []*string{aws.String(cloudformation.CapabilityCapabilityIam)},
+	}); err != nil {
+		t.Errorf("expected a change set to be created with the parameters, but failed: %v", err)
+	}
+
+	if _, err := nestedStackParameters(cfSvc, aws.StringValue(created.StackId), templateURL, map[string]string{}); err == nil || !strings.Contains(err.Error(), "ControlPlaneStackName") {
+		t.Errorf("expected an error for the parameter whose value is unknown, but got %v", err)
+	}
+}
+
+func TestPlanManifest(t *testing.T) {
+	b := fakeaws.New("us-west-1")
+	b.S3.AddBucket("mybucket")
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-west-1").WithCredentials(credentials.AnonymousCredentials)))
+	b.Install(sess)
+	s3Svc := s3.New(sess)
+
+	s3URI := "s3://mybucket/mydir"
+	assetsURI := clusterAssetsS3URI(s3URI, "mycluster")
+	region := model.RegionForName("us-west-1")
+	userdata := "userdata-controller-" + strings.Repeat("a", 64)
+
+	assets := cfnstack.NewAssetsBuilder("control-plane", assetsURI, region).
+		Add(userdata, "#cloud-config").
+		Add("stack.json", `{"Resources":{}}`).
+		Build().
+		Merge(cfnstack.NewAssetsBuilder("mycluster", assetsURI, region).Add("stack.json", `{"Resources":{"Controlplane":{}}}`).Build())
+
+	manifestAssets, err := newPlanManifestAssets("plan1", assets, s3URI, "mycluster", "confighash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, a := range manifestAssets.AsMap() {
+		if a.Key != "mydir/kube-aws/clusters/mycluster/exported/stacks/plans/plan1/manifest.json" {
+			t.Errorf("unexpected manifest key: %s", a.Key)
+		}
+		if _, err := s3Svc.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(a.Bucket),
+			Key:    aws.String(a.Key),
+			Body:   strings.NewReader(a.Content),
+		}); err != nil {
+			t.Fatalf("failed to upload manifest: %v", err)
+		}
+	}
+
+	manifest, err := loadPlanManifest(s3Svc, s3URI, "mycluster", "plan1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest.ConfigHash != "confighash" {
+		t.Errorf("unexpected config hash: %s", manifest.ConfigHash)
+	}
+
+	planned := manifest.assets(s3URI, "mycluster", region).AsMap()
+	if len(planned) != len(assets.AsMap()) {
+		t.Errorf("expected %d assets, got %d", len(assets.AsMap()), len(planned))
+	}
+	for id, a := range assets.AsMap() {
+		p, ok := planned[id]
+		if !ok {
+			t.Errorf("asset %s/%s missing in the plan manifest", id.StackName(), id.Filename())
+			continue
+		}
+		if p.URL() != a.URL() {
+			t.Errorf("unexpected url of %s/%s: expected %s, got %s", id.StackName(), id.Filename(), a.URL(), p.URL())
+		}
+		if id.Filename() == userdata {
+			if p.Content != "" {
+				t.Errorf("expected the content of userdata to be omitted, got %s", p.Content)
+			}
+		} else if p.Content != a.Content {
+			t.Errorf("unexpected content of %s/%s: expected %s, got %s", id.StackName(), id.Filename(), a.Content, p.Content)
+		}
+	}
+
+	if _, err := loadPlanManifest(s3Svc, s3URI, "mycluster", "plan2"); err == nil {
+		t.Error("expected an error for a change set without a manifest, but got none")
+	}
+}
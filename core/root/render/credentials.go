@@ -1,14 +1,14 @@
 package render
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
+	"crypto"
 	"fmt"
 	"github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root/defaults"
 	"github.com/coreos/kube-aws/tlsutil"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 )
 
 type CredentialsRenderer interface {
@@ -28,49 +28,126 @@ func NewCredentialsRenderer(c *config.Cluster) CredentialsRenderer {
 func (r credentialsRendererImpl) RenderFiles(renderCredentialsOpts config.CredentialsOptions) error {
 	cluster := r.c
 	fmt.Printf("Generating TLS credentials...\n")
-	var caKey *rsa.PrivateKey
-	var caCert *x509.Certificate
+
+	dir := defaults.AssetsDir
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	if cluster.Experimental.TLSBootstrap.Enabled && renderCredentialsOpts.VaultAddr != "" {
+		return fmt.Errorf("kubelet TLS bootstrapping can't be enabled when certificates are signed by Vault as the controller manager needs the CA key")
+	}
+
 	if renderCredentialsOpts.GenerateCA {
-		var err error
-		caKey, caCert, err = cluster.NewTLSCA()
+		if renderCredentialsOpts.VaultAddr != "" {
+			return fmt.Errorf("a CA can't be generated when certificates are signed by Vault")
+		}
+		caKey, caCert, err := cluster.NewTLSCA()
 		if err != nil {
 			return fmt.Errorf("failed generating cluster CA: %v", err)
 		}
 		fmt.Printf("-> Generating new TLS CA\n")
+
+		fmt.Printf("-> Generating new TLS assets\n")
+		if _, err := cluster.NewTLSAssetsOnDisk(dir, renderCredentialsOpts, caKey, caCert); err != nil {
+			return err
+		}
 	} else {
-		fmt.Printf("-> Parsing existing TLS CA\n")
-		if caKeyBytes, err := ioutil.ReadFile(renderCredentialsOpts.CaKeyPath); err != nil {
-			return fmt.Errorf("failed reading ca key file %s : %v", renderCredentialsOpts.CaKeyPath, err)
-		} else {
-			if caKey, err = tlsutil.DecodePrivateKeyPEM(caKeyBytes); err != nil {
-				return fmt.Errorf("failed parsing ca key: %v", err)
-			}
+		signer, err := newCertSigner(renderCredentialsOpts)
+		if err != nil {
+			return err
 		}
-		if caCertBytes, err := ioutil.ReadFile(renderCredentialsOpts.CaCertPath); err != nil {
-			return fmt.Errorf("failed reading ca cert file %s : %v", renderCredentialsOpts.CaCertPath, err)
-		} else {
-			if caCert, err = tlsutil.DecodeCertificatePEM(caCertBytes); err != nil {
-				return fmt.Errorf("failed parsing ca cert: %v", err)
-			}
+
+		fmt.Printf("-> Generating new TLS assets\n")
+		if _, err := cluster.NewTLSAssetsOnDiskWithSigner(dir, signer); err != nil {
+			return err
 		}
 	}
 
-	dir := defaults.AssetsDir
-	if err := os.MkdirAll(dir, 0700); err != nil {
+	fmt.Printf("-> Generating auth token file\n")
+	_, err := config.NewAuthTokensOnDisk(dir)
+	if err != nil {
 		return err
 	}
 
-	fmt.Printf("-> Generating new TLS assets\n")
-	_, err := cluster.NewTLSAssetsOnDisk(dir, renderCredentialsOpts, caKey, caCert)
+	if cluster.Experimental.TLSBootstrap.Enabled {
+		if !renderCredentialsOpts.GenerateCA {
+			// The controller manager signs certificates of kubelets with the CA key deployed from the assets directory
+			fmt.Printf("-> Copying the CA key for kubelet TLS bootstrapping\n")
+			if err := copyCAKey(renderCredentialsOpts.CaKeyPath, filepath.Join(dir, "ca-key.pem")); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("-> Generating kubelet TLS bootstrap token\n")
+		if err := config.NewTLSBootstrapTokenOnDisk(dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyCAKey(src string, dst string) error {
+	srcAbs, err := filepath.Abs(src)
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("-> Generating auth token file\n")
-	_, err = config.NewAuthTokensOnDisk(dir)
+	dstAbs, err := filepath.Abs(dst)
 	if err != nil {
 		return err
 	}
+	if srcAbs == dstAbs {
+		return nil
+	}
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		return fmt.Errorf("failed reading ca key file %s : %v", src, err)
+	}
+	return ioutil.WriteFile(dst, data, 0600)
+}
 
-	return nil
+// newCertSigner returns the signer of Vault if its address is given, or of the existing CA otherwise.
+// The CA is an intermediate CA when the CA certificate file contains its chain up to the root
+func newCertSigner(renderCredentialsOpts config.CredentialsOptions) (tlsutil.CertSigner, error) {
+	if renderCredentialsOpts.VaultAddr != "" {
+		fmt.Printf("-> Signing certificates with Vault at %s\n", renderCredentialsOpts.VaultAddr)
+		organizationRoles := map[string]string{}
+		if renderCredentialsOpts.VaultAdminRole != "" {
+			organizationRoles["system:masters"] = renderCredentialsOpts.VaultAdminRole
+		}
+		return tlsutil.NewVaultSigner(tlsutil.VaultSignerConfig{
+			Addr:              renderCredentialsOpts.VaultAddr,
+			Token:             renderCredentialsOpts.VaultToken,
+			Mount:             renderCredentialsOpts.VaultPKIMount,
+			Role:              renderCredentialsOpts.VaultRole,
+			OrganizationRoles: organizationRoles,
+		})
+	}
+
+	fmt.Printf("-> Parsing existing TLS CA\n")
+	var caKey crypto.Signer
+	if caKeyBytes, err := ioutil.ReadFile(renderCredentialsOpts.CaKeyPath); err != nil {
+		return nil, fmt.Errorf("failed reading ca key file %s : %v", renderCredentialsOpts.CaKeyPath, err)
+	} else {
+		if caKey, err = tlsutil.DecodePrivateKeyPEM(caKeyBytes); err != nil {
+			return nil, fmt.Errorf("failed parsing ca key: %v", err)
+		}
+	}
+	caCertBytes, err := ioutil.ReadFile(renderCredentialsOpts.CaCertPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading ca cert file %s : %v", renderCredentialsOpts.CaCertPath, err)
+	}
+	caCerts, err := tlsutil.DecodeCertificatesPEM(caCertBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing ca cert: %v", err)
+	}
+	if !tlsutil.IsCertificateChain(caCerts) {
+		return tlsutil.NewLocalCASigner(caCerts[0], caKey), nil
+	}
+	fmt.Printf("-> Using intermediate CA %s\n", caCerts[0].Subject.CommonName)
+	signer, err := tlsutil.NewIntermediateCASigner(caCerts, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid intermediate CA: %v", err)
+	}
+	return signer, nil
 }
@@ -0,0 +1,40 @@
+package root
+
+import (
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/cfnstack"
+)
+
+// Resume waits for the operation in progress on the root stack, if any, which has been started by a previous kube-aws
+// run e.g. killed halfway. A revision is recorded after a successful creation or update only when the S3 URI is given
+func (c clusterImpl) Resume() (*cfnstack.ResumedOperation, error) {
+	cfSvc := cloudformation.New(c.session)
+
+	ctx, cancel := c.contextWithTimeout(0)
+	defer cancel()
+
+	op, err := c.stackProvisioner().WithContext(ctx).ResumeWaiting(cfSvc)
+	if err != nil {
+		return op, c.resumable(err)
+	}
+
+	if op.Operation != cfnstack.OperationCreate && op.Operation != cfnstack.OperationUpdate {
+		return op, nil
+	}
+
+	if err := c.protect(cfSvc); err != nil {
+		return op, err
+	}
+
+	if c.opts.S3URI == "" {
+		return op, nil
+	}
+
+	// Assets are rendered only to be recorded. They are identical to what has been deployed as long as cluster.yaml and
+	// the templates are unchanged since the interrupted run
+	assets, err := c.Assets()
+	if err != nil {
+		return op, err
+	}
+	return op, c.recordRevisionWithWarning(assets)
+}
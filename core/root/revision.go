@@ -0,0 +1,285 @@
+package root
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/coreos/kube-aws/cfnstack"
+	controlplane "github.com/coreos/kube-aws/core/controlplane/cluster"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const REVISION_MANIFEST_FILENAME = "manifest.json"
+
+// Revision is a manifest of what was deployed by a successful `kube-aws up`, `update` or `rollback`.
+// Stack templates are copied per revision so that the cluster can be rolled back to them even after they are
+// overwritten by later updates. Userdata files are never overwritten as their names contain fingerprints
+type Revision struct {
+	Number         int       `json:"revision"`
+	CreatedAt      time.Time `json:"createdAt"`
+	KubeAwsVersion string    `json:"kubeAwsVersion"`
+	// ConfigHash is the SHA-256 hash of cluster.yaml the revision was deployed from
+	ConfigHash           string            `json:"configHash"`
+	RootStackTemplateURL string            `json:"rootStackTemplateURL"`
+	TemplateURLs         map[string]string `json:"templateURLs"`
+	UserDataFingerprints map[string]string `json:"userDataFingerprints"`
+	// RollbackOf is the number of the revision this revision has been rolled back to, if any
+	RollbackOf int `json:"rollbackOf,omitempty"`
+}
+
+func (r *Revision) String() string {
+	s := fmt.Sprintf("%d\t%s\t%s\t%s", r.Number, r.CreatedAt.UTC().Format(time.RFC3339), r.KubeAwsVersion, r.ConfigHash)
+	if r.RollbackOf != 0 {
+		s = fmt.Sprintf("%s\t(rollback to %d)", s, r.RollbackOf)
+	}
+	return s
+}
+
+var revisionManifestKey = regexp.MustCompile(`/revisions/([0-9]+)/` + regexp.QuoteMeta(REVISION_MANIFEST_FILENAME) + `$`)
+var fingerprintedFileName = regexp.MustCompile(`^(.+)-([0-9a-f]{64})$`)
+
+type revisionsS3Service interface {
+	cfnstack.S3ObjectPutterService
+	cfnstack.S3ObjectsService
+	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+}
+
+func revisionsS3URI(s3URI string, clusterName string) string {
+	return fmt.Sprintf("%s/revisions", clusterAssetsS3URI(s3URI, clusterName))
+}
+
+// listRevisions returns all the revisions of the cluster, oldest first
+func listRevisions(s3Svc revisionsS3Service, s3URI string, clusterName string) ([]*Revision, error) {
+	uri := revisionsS3URI(s3URI, clusterName)
+	objects, err := cfnstack.ListObjects(s3Svc, uri)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := cfnstack.S3URIFromString(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []*Revision{}
+	for _, o := range objects {
+		if !revisionManifestKey.MatchString(aws.StringValue(o.Key)) {
+			continue
+		}
+		resp, err := s3Svc.GetObject(&s3.GetObjectInput{
+			Bucket: aws.String(parsed.Bucket()),
+			Key:    o.Key,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get revision manifest %s: %v", aws.StringValue(o.Key), err)
+		}
+		data, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read revision manifest %s: %v", aws.StringValue(o.Key), err)
+		}
+		var r Revision
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("failed to parse revision manifest %s: %v", aws.StringValue(o.Key), err)
+		}
+		revisions = append(revisions, &r)
+	}
+
+	sort.Sort(revisionsByNumber(revisions))
+
+	return revisions, nil
+}
+
+// newRevisionAssets returns copies of the stack templates in the assets, placed under the directory for the revision
+// along with the manifest of the revision.
+// The root stack template is modified to refer to copies of nested stack templates instead of the originals, which
+// are overwritten on every update
+func newRevisionAssets(number int, assets cfnstack.Assets, rootStackName string, s3URI string, clusterName string, configHash string) (cfnstack.Assets, *Revision, error) {
+	revision := &Revision{
+		Number:               number,
+		CreatedAt:            time.Now().UTC(),
+		KubeAwsVersion:       controlplane.VERSION,
+		ConfigHash:           configHash,
+		TemplateURLs:         map[string]string{},
+		UserDataFingerprints: map[string]string{},
+	}
+
+	revisionsURI := revisionsS3URI(s3URI, clusterName)
+	revisionURI := fmt.Sprintf("%s/%d", revisionsURI, number)
+
+	var rootTemplate *cfnstack.Asset
+	// URLs to the original nested stack templates and their copies for the revision
+	replacements := []string{}
+	builders := map[string]cfnstack.AssetsBuilder{}
+
+	for id, a := range assets.AsMap() {
+		stackName := id.StackName()
+		filename := id.Filename()
+
+		if m := fingerprintedFileName.FindStringSubmatch(filename); m != nil {
+			revision.UserDataFingerprints[path.Join(stackName, m[1])] = m[2]
+			continue
+		}
+		if filename != REMOTE_STACK_TEMPLATE_FILENAME {
+			continue
+		}
+
+		builder, ok := builders[stackName]
+		if !ok {
+			builder = cfnstack.NewAssetsBuilder(stackName, revisionURI, a.Region)
+			builders[stackName] = builder
+		}
+
+		if stackName == rootStackName {
+			asset := a
+			rootTemplate = &asset
+			continue
+		}
+
+		copied := builder.Add(filename, a.Content).Build()
+		copiedAsset, err := copied.FindAssetByStackAndFileName(stackName, filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		revision.TemplateURLs[stackName] = copiedAsset.URL()
+		replacements = append(replacements, a.URL(), copiedAsset.URL())
+	}
+
+	if rootTemplate == nil {
+		return nil, nil, fmt.Errorf("[bug] root stack template not found in assets")
+	}
+
+	rootContent := strings.NewReplacer(replacements...).Replace(rootTemplate.Content)
+	copied := builders[rootStackName].Add(REMOTE_STACK_TEMPLATE_FILENAME, rootContent).Build()
+	copiedRoot, err := copied.FindAssetByStackAndFileName(rootStackName, REMOTE_STACK_TEMPLATE_FILENAME)
+	if err != nil {
+		return nil, nil, err
+	}
+	revision.RootStackTemplateURL = copiedRoot.URL()
+	revision.TemplateURLs[rootStackName] = copiedRoot.URL()
+
+	manifest, err := json.MarshalIndent(revision, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal revision manifest: %v", err)
+	}
+
+	revisionAssets := cfnstack.NewAssetsBuilder(strconv.Itoa(number), revisionsURI, rootTemplate.Region).
+		Add(REVISION_MANIFEST_FILENAME, string(manifest)).
+		Build()
+	for _, b := range builders {
+		revisionAssets = revisionAssets.Merge(b.Build())
+	}
+
+	return revisionAssets, revision, nil
+}
+
+func nextRevisionNumber(revisions []*Revision) int {
+	if len(revisions) == 0 {
+		return 1
+	}
+	return revisions[len(revisions)-1].Number + 1
+}
+
+// recordRevision uploads a new revision for the assets which have just been deployed successfully
+func (c clusterImpl) recordRevision(assets cfnstack.Assets) (*Revision, error) {
+	s3Svc := s3.New(c.session)
+
+	revisions, err := listRevisions(s3Svc, c.opts.S3URI, c.controlPlane.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	revisionAssets, revision, err := newRevisionAssets(nextRevisionNumber(revisions), assets, c.stackName(), c.opts.S3URI, c.controlPlane.ClusterName, c.configHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.stackProvisioner().UploadAssets(s3Svc, revisionAssets); err != nil {
+		return nil, fmt.Errorf("failed to upload revision %d: %v", revision.Number, err)
+	}
+
+	return revision, nil
+}
+
+func (c clusterImpl) Revisions() ([]*Revision, error) {
+	return listRevisions(s3.New(c.session), c.opts.S3URI, c.controlPlane.ClusterName)
+}
+
+// Rollback updates the root stack to use templates of the revision numbered `to`, or the one before the latest
+// revision when `to` is zero. Another revision is recorded to mark the rollback
+func (c clusterImpl) Rollback(to int) (*Revision, error) {
+	s3Svc := s3.New(c.session)
+	cfSvc := cloudformation.New(c.session)
+
+	revisions, err := listRevisions(s3Svc, c.opts.S3URI, c.controlPlane.ClusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := findRollbackTarget(revisions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := c.contextWithTimeout(0)
+	defer cancel()
+
+	_, err = c.updateWithStackPolicies(cfSvc, func() (string, error) {
+		return c.stackProvisioner().WithContext(ctx).UpdateStackAtURLAndWait(cfSvc, target.RootStackTemplateURL)
+	})
+	if err != nil {
+		if _, ok := err.(*cfnstack.WaitInterruptedError); ok {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to roll back to revision %d: %v", target.Number, err)
+	}
+
+	rollback := *target
+	rollback.Number = nextRevisionNumber(revisions)
+	rollback.CreatedAt = time.Now().UTC()
+	rollback.RollbackOf = target.Number
+
+	manifest, err := json.MarshalIndent(rollback, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal revision manifest: %v", err)
+	}
+	manifestAssets := cfnstack.NewAssetsBuilder(strconv.Itoa(rollback.Number), revisionsS3URI(c.opts.S3URI, c.controlPlane.ClusterName), c.controlPlane.Region).
+		Add(REVISION_MANIFEST_FILENAME, string(manifest)).
+		Build()
+	if err := c.stackProvisioner().UploadAssets(s3Svc, manifestAssets); err != nil {
+		return nil, fmt.Errorf("failed to upload revision %d: %v", rollback.Number, err)
+	}
+
+	return &rollback, nil
+}
+
+func findRollbackTarget(revisions []*Revision, to int) (*Revision, error) {
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("no revision has been recorded for this cluster yet")
+	}
+	if to == 0 {
+		if len(revisions) < 2 {
+			return nil, fmt.Errorf("there is no revision before the latest revision %d", revisions[0].Number)
+		}
+		return revisions[len(revisions)-2], nil
+	}
+	for _, r := range revisions {
+		if r.Number == to {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("revision %d not found", to)
+}
+
+type revisionsByNumber []*Revision
+
+func (s revisionsByNumber) Len() int           { return len(s) }
+func (s revisionsByNumber) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s revisionsByNumber) Less(i, j int) bool { return s[i].Number < s[j].Number }
@@ -0,0 +1,83 @@
+package root
+
+import (
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/model"
+	"strings"
+	"testing"
+)
+
+func TestNewRevisionAssets(t *testing.T) {
+	s3URI := "s3://mybucket/mydir"
+	assetsURI := clusterAssetsS3URI(s3URI, "mycluster")
+	region := model.RegionForName("us-west-1")
+	fingerprint := strings.Repeat("a", 64)
+
+	cp := cfnstack.NewAssetsBuilder("control-plane", assetsURI, region).
+		Add("userdata-controller-"+fingerprint, "#cloud-config").
+		Add("stack.json", `{"Resources":{}}`).
+		Build()
+	cpTemplate, err := cp.FindAssetByStackAndFileName("control-plane", "stack.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rootTemplate := `{"Resources":{"Controlplane":{"Properties":{"TemplateURL":"` + cpTemplate.URL() + `"}}}}`
+	assets := cfnstack.NewAssetsBuilder("mycluster", assetsURI, region).
+		Add("stack.json", rootTemplate).
+		Build().
+		Merge(cp)
+
+	revisionAssets, revision, err := newRevisionAssets(3, assets, "mycluster", s3URI, "mycluster", "confighash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	revisionURL := "https://s3.amazonaws.com/mybucket/mydir/kube-aws/clusters/mycluster/exported/stacks/revisions/3"
+
+	if revision.RootStackTemplateURL != revisionURL+"/mycluster/stack.json" {
+		t.Errorf("unexpected root stack template url: %s", revision.RootStackTemplateURL)
+	}
+	if revision.TemplateURLs["control-plane"] != revisionURL+"/control-plane/stack.json" {
+		t.Errorf("unexpected control plane template url: %s", revision.TemplateURLs["control-plane"])
+	}
+	if revision.UserDataFingerprints["control-plane/userdata-controller"] != fingerprint {
+		t.Errorf("unexpected userdata fingerprints: %v", revision.UserDataFingerprints)
+	}
+
+	copiedRoot, err := revisionAssets.FindAssetByStackAndFileName("mycluster", "stack.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(copiedRoot.Content, revisionURL+"/control-plane/stack.json") || strings.Contains(copiedRoot.Content, cpTemplate.URL()) {
+		t.Errorf("expected the copied root stack template to refer to the copied nested stack template, but was: %s", copiedRoot.Content)
+	}
+
+	manifest, err := revisionAssets.FindAssetByStackAndFileName("3", REVISION_MANIFEST_FILENAME)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest.Key != "mydir/kube-aws/clusters/mycluster/exported/stacks/revisions/3/manifest.json" {
+		t.Errorf("unexpected manifest key: %s", manifest.Key)
+	}
+	if !revisionManifestKey.MatchString(manifest.Key) {
+		t.Errorf("manifest key doesn't match the pattern used to list revisions: %s", manifest.Key)
+	}
+}
+
+func TestFindRollbackTarget(t *testing.T) {
+	revisions := []*Revision{{Number: 1}, {Number: 2}, {Number: 4}}
+
+	if r, err := findRollbackTarget(revisions, 0); err != nil || r.Number != 2 {
+		t.Errorf("expected the revision before the latest one, but got: %v, %v", r, err)
+	}
+	if r, err := findRollbackTarget(revisions, 1); err != nil || r.Number != 1 {
+		t.Errorf("expected revision 1, but got: %v, %v", r, err)
+	}
+	if _, err := findRollbackTarget(revisions, 3); err == nil {
+		t.Error("expected an error for a missing revision, but got none")
+	}
+	if _, err := findRollbackTarget(revisions[:1], 0); err == nil {
+		t.Error("expected an error when there is only one revision, but got none")
+	}
+}
@@ -0,0 +1,90 @@
+package root
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/cfnstack"
+	"os"
+	"strings"
+)
+
+// applyStackPolicies sets the stack policies configured in cluster.yaml to the nested stacks which exist.
+// Nested stacks are created by the root stack, which can't pass stack policies to them
+func (c clusterImpl) applyStackPolicies(cfSvc *cloudformation.CloudFormation, allowReplace []string) error {
+	ids, err := c.nestedStackIDs(cfSvc)
+	if err != nil {
+		return err
+	}
+
+	nestedStacks := append([]NestedStack{c.templateParams().ControlPlane()}, c.templateParams().NodePools()...)
+	for _, s := range nestedStacks {
+		stackID, ok := ids[s.Name()]
+		if !ok {
+			continue
+		}
+		policy, err := s.StackPolicy().AllowingUpdatesTo(allowReplace)
+		if err != nil {
+			return fmt.Errorf("failed to allow replacing resources in stack %s: %v", s.Name(), err)
+		}
+		_, err = cfSvc.SetStackPolicy(&cloudformation.SetStackPolicyInput{
+			StackName:       aws.String(stackID),
+			StackPolicyBody: aws.String(policy.Body()),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set stack policy of stack %s: %v", s.Name(), err)
+		}
+	}
+	return nil
+}
+
+// applyTerminationProtection updates termination protection of the root stack only when it differs from cluster.yaml, so
+// that clusters never enabling it don't require the permission to update it
+func (c clusterImpl) applyTerminationProtection(cfSvc *cloudformation.CloudFormation) error {
+	enabled, err := cfnstack.TerminationProtectionEnabled(cfSvc, c.stackName())
+	if err != nil {
+		return err
+	}
+	if enabled == c.controlPlane.EnableTerminationProtection {
+		return nil
+	}
+	return cfnstack.UpdateTerminationProtection(cfSvc, c.stackName(), c.controlPlane.EnableTerminationProtection)
+}
+
+// protect applies termination protection and stack policies to the stacks which have just been created or updated
+func (c clusterImpl) protect(cfSvc *cloudformation.CloudFormation) error {
+	if err := c.applyTerminationProtection(cfSvc); err != nil {
+		return err
+	}
+	return c.applyStackPolicies(cfSvc, nil)
+}
+
+// updateWithStackPolicies runs the update while stack policies are temporarily overridden to allow replacing resources
+// specified via options, and then restores the stack policies. Policies of nested stacks added by the update are applied
+// afterwards as well
+func (c clusterImpl) updateWithStackPolicies(cfSvc *cloudformation.CloudFormation, update func() (string, error)) (string, error) {
+	if err := c.applyTerminationProtection(cfSvc); err != nil {
+		return "", err
+	}
+	if err := c.applyStackPolicies(cfSvc, c.opts.AllowReplace); err != nil {
+		return "", err
+	}
+
+	report, err := update()
+	// Restoring stack policies before the update finishes would deny replacements the update is going to make
+	_, interrupted := err.(*cfnstack.WaitInterruptedError)
+	if interrupted || (err == nil && c.opts.SkipWait) {
+		if len(c.opts.AllowReplace) > 0 {
+			fmt.Fprintf(os.Stderr, "WARNING: stack policies still allow replacing %s until the update finishes and kube-aws wait or update is run\n", strings.Join(c.opts.AllowReplace, ", "))
+		}
+		return report, c.resumable(err)
+	}
+
+	if protectErr := c.applyStackPolicies(cfSvc, nil); protectErr != nil {
+		if err != nil {
+			return report, fmt.Errorf("%v, and then failed to restore stack policies: %v", err, protectErr)
+		}
+		return report, protectErr
+	}
+	return report, err
+}
@@ -0,0 +1,361 @@
+package root
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	controllersLogicalName = "Controllers"
+	workersLogicalName     = "Workers"
+)
+
+var etcdResourceLogicalName = regexp.MustCompile(`^Etcd([0-9]+)(ENI|EIP|EBS)?$`)
+
+type InstanceStatus struct {
+	InstanceID       string `json:"instanceId" yaml:"instanceId"`
+	AvailabilityZone string `json:"availabilityZone" yaml:"availabilityZone"`
+	LifecycleState   string `json:"lifecycleState" yaml:"lifecycleState"`
+	HealthStatus     string `json:"healthStatus" yaml:"healthStatus"`
+}
+
+type AutoScalingGroupStatus struct {
+	Name            string `json:"name" yaml:"name"`
+	DesiredCapacity int64  `json:"desiredCapacity" yaml:"desiredCapacity"`
+	// ActualCapacity is the number of instances which are in service and healthy
+	ActualCapacity int64            `json:"actualCapacity" yaml:"actualCapacity"`
+	Instances      []InstanceStatus `json:"instances" yaml:"instances"`
+}
+
+type SpotFleetStatus struct {
+	ID                string  `json:"id" yaml:"id"`
+	State             string  `json:"state" yaml:"state"`
+	TargetCapacity    int64   `json:"targetCapacity" yaml:"targetCapacity"`
+	FulfilledCapacity float64 `json:"fulfilledCapacity" yaml:"fulfilledCapacity"`
+}
+
+type NodePoolStatus struct {
+	Name             string                  `json:"name" yaml:"name"`
+	StackName        string                  `json:"stackName" yaml:"stackName"`
+	StackStatus      string                  `json:"stackStatus" yaml:"stackStatus"`
+	AutoScalingGroup *AutoScalingGroupStatus `json:"autoScalingGroup,omitempty" yaml:"autoScalingGroup,omitempty"`
+	SpotFleet        *SpotFleetStatus        `json:"spotFleet,omitempty" yaml:"spotFleet,omitempty"`
+}
+
+type EtcdInstanceStatus struct {
+	Index              int    `json:"index" yaml:"index"`
+	NetworkInterfaceID string `json:"networkInterfaceId,omitempty" yaml:"networkInterfaceId,omitempty"`
+	ElasticIP          string `json:"elasticIp,omitempty" yaml:"elasticIp,omitempty"`
+	VolumeID           string `json:"volumeId,omitempty" yaml:"volumeId,omitempty"`
+	// AutoScalingGroup is the single-instance ASG which keeps the etcd instance running
+	AutoScalingGroup *AutoScalingGroupStatus `json:"autoScalingGroup,omitempty" yaml:"autoScalingGroup,omitempty"`
+}
+
+func (s *AutoScalingGroupStatus) Capacity() string {
+	if s == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d", s.ActualCapacity, s.DesiredCapacity)
+}
+
+func (s *NodePoolStatus) Capacity() string {
+	if s.SpotFleet != nil {
+		return fmt.Sprintf("%g/%d", s.SpotFleet.FulfilledCapacity, s.SpotFleet.TargetCapacity)
+	}
+	return s.AutoScalingGroup.Capacity()
+}
+
+func stringOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (i *Info) String() string {
+	buf := new(bytes.Buffer)
+	buf.WriteString(i.ControlPlane.String())
+
+	w := new(tabwriter.Writer)
+	w.Init(buf, 0, 8, 2, ' ', 0)
+
+	if i.Controllers != nil {
+		fmt.Fprintf(w, "\nControllers:\t%s\n", i.Controllers.Capacity())
+		fmt.Fprintln(w, "INSTANCE\tZONE\tLIFECYCLE\tHEALTH")
+		for _, inst := range i.Controllers.Instances {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", inst.InstanceID, inst.AvailabilityZone, inst.LifecycleState, inst.HealthStatus)
+		}
+	}
+
+	if len(i.Etcd) > 0 {
+		fmt.Fprintln(w, "\nEtcd:")
+		fmt.Fprintln(w, "INDEX\tINSTANCE\tENI\tEIP\tVOLUME")
+		for _, e := range i.Etcd {
+			instance := "-"
+			if e.AutoScalingGroup != nil && len(e.AutoScalingGroup.Instances) > 0 {
+				inst := e.AutoScalingGroup.Instances[0]
+				instance = fmt.Sprintf("%s (%s)", inst.InstanceID, inst.HealthStatus)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", e.Index, instance, stringOrDash(e.NetworkInterfaceID), stringOrDash(e.ElasticIP), stringOrDash(e.VolumeID))
+		}
+	}
+
+	if len(i.NodePools) > 0 {
+		fmt.Fprintln(w, "\nNode Pools:")
+		fmt.Fprintln(w, "NAME\tSTACK STATUS\tCAPACITY")
+		for _, p := range i.NodePools {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", p.Name, p.StackStatus, p.Capacity())
+		}
+	}
+
+	if len(i.Certificates) > 0 {
+		fmt.Fprintln(w, "\nCertificates:")
+		fmt.Fprintln(w, "FILE\tSUBJECT\tNOT AFTER\tPROBLEMS")
+		for _, c := range i.Certificates {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.File, c.Subject, c.NotAfter.Format(time.RFC3339), stringOrDash(strings.Join(c.Problems, "; ")))
+		}
+	}
+
+	w.Flush()
+	return buf.String()
+}
+
+// listStackResources returns summaries of all the resources in the stack
+func listStackResources(cfSvc *cloudformation.CloudFormation, stackName string) ([]*cloudformation.StackResourceSummary, error) {
+	resources := []*cloudformation.StackResourceSummary{}
+	var nextToken *string
+	for {
+		resp, err := cfSvc.ListStackResources(&cloudformation.ListStackResourcesInput{
+			StackName: aws.String(stackName),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list resources of stack %s: %v", stackName, err)
+		}
+		resources = append(resources, resp.StackResourceSummaries...)
+		if aws.StringValue(resp.NextToken) == "" {
+			return resources, nil
+		}
+		nextToken = resp.NextToken
+	}
+}
+
+func physicalResourceID(resources []*cloudformation.StackResourceSummary, logicalName string) string {
+	for _, r := range resources {
+		if aws.StringValue(r.LogicalResourceId) == logicalName {
+			return aws.StringValue(r.PhysicalResourceId)
+		}
+	}
+	return ""
+}
+
+// etcdInstancesFromResources groups the etcd ASGs, ENIs, EIPs and EBS volumes in the control-plane stack by etcd index.
+// AutoScalingGroup of each returned instance is populated only with the ASG name
+func etcdInstancesFromResources(resources []*cloudformation.StackResourceSummary) []*EtcdInstanceStatus {
+	instances := map[int]*EtcdInstanceStatus{}
+	for _, r := range resources {
+		m := etcdResourceLogicalName.FindStringSubmatch(aws.StringValue(r.LogicalResourceId))
+		if m == nil {
+			continue
+		}
+		index, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		e, ok := instances[index]
+		if !ok {
+			e = &EtcdInstanceStatus{Index: index}
+			instances[index] = e
+		}
+		id := aws.StringValue(r.PhysicalResourceId)
+		switch m[2] {
+		case "ENI":
+			e.NetworkInterfaceID = id
+		case "EIP":
+			e.ElasticIP = id
+		case "EBS":
+			e.VolumeID = id
+		default:
+			if id != "" {
+				e.AutoScalingGroup = &AutoScalingGroupStatus{Name: id}
+			}
+		}
+	}
+
+	result := []*EtcdInstanceStatus{}
+	for _, e := range instances {
+		result = append(result, e)
+	}
+	sort.Sort(etcdInstancesByIndex(result))
+	return result
+}
+
+type etcdInstancesByIndex []*EtcdInstanceStatus
+
+func (s etcdInstancesByIndex) Len() int           { return len(s) }
+func (s etcdInstancesByIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s etcdInstancesByIndex) Less(i, j int) bool { return s[i].Index < s[j].Index }
+
+// describeAutoScalingGroups returns statuses of the ASGs keyed by their names
+func describeAutoScalingGroups(asSvc *autoscaling.AutoScaling, names []string) (map[string]*AutoScalingGroupStatus, error) {
+	statuses := map[string]*AutoScalingGroupStatus{}
+	if len(names) == 0 {
+		return statuses, nil
+	}
+
+	err := asSvc.DescribeAutoScalingGroupsPages(
+		&autoscaling.DescribeAutoScalingGroupsInput{AutoScalingGroupNames: aws.StringSlice(names)},
+		func(page *autoscaling.DescribeAutoScalingGroupsOutput, lastPage bool) bool {
+			for _, g := range page.AutoScalingGroups {
+				statuses[aws.StringValue(g.AutoScalingGroupName)] = autoScalingGroupStatus(g)
+			}
+			return true
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe autoscaling groups %v: %v", names, err)
+	}
+	return statuses, nil
+}
+
+func autoScalingGroupStatus(g *autoscaling.Group) *AutoScalingGroupStatus {
+	s := &AutoScalingGroupStatus{
+		Name:            aws.StringValue(g.AutoScalingGroupName),
+		DesiredCapacity: aws.Int64Value(g.DesiredCapacity),
+		Instances:       []InstanceStatus{},
+	}
+	for _, i := range g.Instances {
+		inst := InstanceStatus{
+			InstanceID:       aws.StringValue(i.InstanceId),
+			AvailabilityZone: aws.StringValue(i.AvailabilityZone),
+			LifecycleState:   aws.StringValue(i.LifecycleState),
+			HealthStatus:     aws.StringValue(i.HealthStatus),
+		}
+		if inst.LifecycleState == autoscaling.LifecycleStateInService && inst.HealthStatus == "Healthy" {
+			s.ActualCapacity++
+		}
+		s.Instances = append(s.Instances, inst)
+	}
+	return s
+}
+
+func describeSpotFleet(ec2Svc *ec2.EC2, id string) (*SpotFleetStatus, error) {
+	resp, err := ec2Svc.DescribeSpotFleetRequests(&ec2.DescribeSpotFleetRequestsInput{
+		SpotFleetRequestIds: []*string{aws.String(id)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe spot fleet request %s: %v", id, err)
+	}
+	if len(resp.SpotFleetRequestConfigs) == 0 {
+		return nil, fmt.Errorf("could not find a spot fleet request with id %s", id)
+	}
+	c := resp.SpotFleetRequestConfigs[0]
+	return &SpotFleetStatus{
+		ID:                id,
+		State:             aws.StringValue(c.SpotFleetRequestState),
+		TargetCapacity:    aws.Int64Value(c.SpotFleetRequestConfig.TargetCapacity),
+		FulfilledCapacity: aws.Float64Value(c.SpotFleetRequestConfig.FulfilledCapacity),
+	}, nil
+}
+
+func stackStatus(cfSvc *cloudformation.CloudFormation, stackID string) (string, error) {
+	resp, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{
+		StackName: aws.String(stackID),
+	})
+	if err != nil {
+		return "", fmt.Errorf("error describing stack %s: %v", stackID, err)
+	}
+	if len(resp.Stacks) == 0 {
+		return "", fmt.Errorf("could not find a stack with name %s", stackID)
+	}
+	return aws.StringValue(resp.Stacks[0].StackStatus), nil
+}
+
+// controlPlaneStatus populates the statuses of controller and etcd instances in the control-plane stack
+func (c clusterDescriberImpl) controlPlaneStatus(info *Info, cpStackID string) error {
+	cfSvc := cloudformation.New(c.session)
+	asSvc := autoscaling.New(c.session)
+
+	resources, err := listStackResources(cfSvc, cpStackID)
+	if err != nil {
+		return err
+	}
+
+	etcd := etcdInstancesFromResources(resources)
+	names := []string{}
+	controllersASG := physicalResourceID(resources, controllersLogicalName)
+	if controllersASG != "" {
+		names = append(names, controllersASG)
+	}
+	for _, e := range etcd {
+		if e.AutoScalingGroup != nil {
+			names = append(names, e.AutoScalingGroup.Name)
+		}
+	}
+
+	statuses, err := describeAutoScalingGroups(asSvc, names)
+	if err != nil {
+		return err
+	}
+
+	info.Controllers = statuses[controllersASG]
+	for _, e := range etcd {
+		if e.AutoScalingGroup != nil {
+			if s, ok := statuses[e.AutoScalingGroup.Name]; ok {
+				e.AutoScalingGroup = s
+			}
+		}
+	}
+	info.Etcd = etcd
+	return nil
+}
+
+func (c clusterDescriberImpl) nodePoolStatus(name string, stackID string) (*NodePoolStatus, error) {
+	cfSvc := cloudformation.New(c.session)
+
+	status, err := stackStatus(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+	p := &NodePoolStatus{
+		Name:        name,
+		StackName:   stackID,
+		StackStatus: status,
+	}
+
+	resources, err := listStackResources(cfSvc, stackID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range resources {
+		if aws.StringValue(r.LogicalResourceId) != workersLogicalName || aws.StringValue(r.PhysicalResourceId) == "" {
+			continue
+		}
+		id := aws.StringValue(r.PhysicalResourceId)
+		switch aws.StringValue(r.ResourceType) {
+		case "AWS::AutoScaling::AutoScalingGroup":
+			statuses, err := describeAutoScalingGroups(autoscaling.New(c.session), []string{id})
+			if err != nil {
+				return nil, err
+			}
+			p.AutoScalingGroup = statuses[id]
+		case "AWS::EC2::SpotFleet":
+			s, err := describeSpotFleet(ec2.New(c.session), id)
+			if err != nil {
+				return nil, err
+			}
+			p.SpotFleet = s
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,69 @@
+package root
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"testing"
+)
+
+func TestEtcdInstancesFromResources(t *testing.T) {
+	resource := func(logicalName, physicalID string) *cloudformation.StackResourceSummary {
+		return &cloudformation.StackResourceSummary{
+			LogicalResourceId:  aws.String(logicalName),
+			PhysicalResourceId: aws.String(physicalID),
+		}
+	}
+	resources := []*cloudformation.StackResourceSummary{
+		resource("Etcd1", "etcd1-asg"),
+		resource("Etcd1EBS", "vol-1"),
+		resource("Etcd0", "etcd0-asg"),
+		resource("Etcd0LC", "etcd0-lc"),
+		resource("Etcd0ENI", "eni-0"),
+		resource("Etcd0EIP", "1.2.3.4"),
+		resource("Etcd0EBS", "vol-0"),
+		resource("Etcd0InternalRecordSet", "etcd0.internal"),
+		resource("Controllers", "controllers-asg"),
+	}
+
+	etcd := etcdInstancesFromResources(resources)
+
+	if len(etcd) != 2 {
+		t.Fatalf("expected 2 etcd instances, but got %d: %+v", len(etcd), etcd)
+	}
+	e0 := etcd[0]
+	if e0.Index != 0 || e0.NetworkInterfaceID != "eni-0" || e0.ElasticIP != "1.2.3.4" || e0.VolumeID != "vol-0" || e0.AutoScalingGroup.Name != "etcd0-asg" {
+		t.Errorf("unexpected etcd instance: %+v", e0)
+	}
+	e1 := etcd[1]
+	if e1.Index != 1 || e1.NetworkInterfaceID != "" || e1.VolumeID != "vol-1" || e1.AutoScalingGroup.Name != "etcd1-asg" {
+		t.Errorf("unexpected etcd instance: %+v", e1)
+	}
+}
+
+func TestAutoScalingGroupStatus(t *testing.T) {
+	instance := func(id, lifecycleState, health string) *autoscaling.Instance {
+		return &autoscaling.Instance{
+			InstanceId:       aws.String(id),
+			AvailabilityZone: aws.String("us-west-1a"),
+			LifecycleState:   aws.String(lifecycleState),
+			HealthStatus:     aws.String(health),
+		}
+	}
+	s := autoScalingGroupStatus(&autoscaling.Group{
+		AutoScalingGroupName: aws.String("controllers"),
+		DesiredCapacity:      aws.Int64(3),
+		Instances: []*autoscaling.Instance{
+			instance("i-1", "InService", "Healthy"),
+			instance("i-2", "Pending", "Healthy"),
+			instance("i-3", "InService", "Unhealthy"),
+		},
+	})
+
+	if s.Capacity() != "1/3" {
+		t.Errorf("expected only in-service and healthy instances to be counted, but got: %s", s.Capacity())
+	}
+	if len(s.Instances) != 3 || s.Instances[2].HealthStatus != "Unhealthy" {
+		t.Errorf("unexpected instances: %+v", s.Instances)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	controlplane "github.com/coreos/kube-aws/core/controlplane/cluster"
 	nodepool "github.com/coreos/kube-aws/core/nodepool/cluster"
+	"github.com/coreos/kube-aws/model"
 )
 
 type TemplateParams struct {
@@ -24,6 +25,7 @@ type NestedStack interface {
 	Name() string
 	Tags() map[string]string
 	TemplateURL() (string, error)
+	StackPolicy() model.StackPolicy
 }
 
 type controlPlane struct {
@@ -38,6 +40,10 @@ func (p controlPlane) Tags() map[string]string {
 	return p.controlPlane.StackTags
 }
 
+func (p controlPlane) StackPolicy() model.StackPolicy {
+	return p.controlPlane.EffectiveStackPolicy()
+}
+
 func (p controlPlane) TemplateURL() (string, error) {
 	u, err := p.controlPlane.TemplateURL()
 
@@ -60,6 +66,10 @@ func (p nodePool) Tags() map[string]string {
 	return p.nodePool.StackTags
 }
 
+func (p nodePool) StackPolicy() model.StackPolicy {
+	return p.nodePool.EffectiveStackPolicy()
+}
+
 func (p nodePool) TemplateURL() (string, error) {
 	u, err := p.nodePool.TemplateURL()
 
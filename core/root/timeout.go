@@ -0,0 +1,78 @@
+package root
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// createTimeoutMargin is added to the creation timeouts of ASGs to cover the time taken to create every other resource
+// e.g. VPC, subnets, ELBs and nested stacks themselves
+const createTimeoutMargin = 10 * time.Minute
+
+var iso8601Duration = regexp.MustCompile(`^PT(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+)S)?$`)
+
+// parseISO8601Duration parses a duration like PT1H30M accepted by CloudFormation for CreationPolicy timeouts
+func parseISO8601Duration(s string) (time.Duration, error) {
+	m := iso8601Duration.FindStringSubmatch(s)
+	if m == nil || s == "PT" {
+		return 0, fmt.Errorf("invalid duration %q: must be in the ISO 8601 format like PT15M", s)
+	}
+	d := time.Duration(0)
+	units := []time.Duration{time.Hour, time.Minute, time.Second}
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %v", s, err)
+		}
+		d += time.Duration(n) * unit
+	}
+	return d, nil
+}
+
+// createTimeout returns how long `kube-aws up` waits for the root stack to be created when no timeout is specified.
+// Etcd nodes, controllers and node pools are created one after another, each waiting up to their createTimeout
+func (c clusterImpl) createTimeout() (time.Duration, error) {
+	controller, err := parseISO8601Duration(c.controlPlane.ControllerCreateTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid controllerCreateTimeout: %v", err)
+	}
+
+	worker := time.Duration(0)
+	for _, np := range c.nodePools {
+		d, err := parseISO8601Duration(np.CreateTimeout)
+		if err != nil {
+			return 0, fmt.Errorf("invalid createTimeout of node pool %s: %v", np.NodePoolName, err)
+		}
+		if d > worker {
+			worker = d
+		}
+	}
+
+	return 2*controller + worker + createTimeoutMargin, nil
+}
+
+func (c clusterImpl) context() context.Context {
+	if c.opts.Context == nil {
+		return context.Background()
+	}
+	return c.opts.Context
+}
+
+// contextWithTimeout returns the context to wait for stack operations with, which is done after the timeout specified
+// via options, or the default timeout if any
+func (c clusterImpl) contextWithTimeout(defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	timeout := c.opts.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
+	}
+	if timeout == 0 {
+		return context.WithCancel(c.context())
+	}
+	return context.WithTimeout(c.context(), timeout)
+}
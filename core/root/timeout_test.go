@@ -0,0 +1,32 @@
+package root
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseISO8601Duration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"PT15M":    15 * time.Minute,
+		"PT1H30M":  90 * time.Minute,
+		"PT1H":     time.Hour,
+		"PT90S":    90 * time.Second,
+		"PT1H2M3S": time.Hour + 2*time.Minute + 3*time.Second,
+	}
+	for s, expected := range valid {
+		actual, err := parseISO8601Duration(s)
+		if err != nil {
+			t.Errorf("unexpected error parsing %s: %v", s, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("unexpected duration for %s: expected %v, but was %v", s, expected, actual)
+		}
+	}
+
+	for _, s := range []string{"", "PT", "15M", "P1D", "PT15m"} {
+		if _, err := parseISO8601Duration(s); err == nil {
+			t.Errorf("expected an error parsing %q, but got none", s)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package amiregistry
 
 import (
 	"fmt"
+	"github.com/coreos/kube-aws/awsconn"
 	"log"
 	"net/http"
+	"time"
 )
 
 const MaxRetryCount = 2
@@ -14,11 +16,14 @@ type reliableHttp interface {
 
 type reliableHttpImpl struct {
 	underlyingGet func(url string) (resp *http.Response, err error)
+	// sleep waits between retries. Retries are made immediately when nil
+	sleep func(time.Duration)
 }
 
 func newHttp() reliableHttpImpl {
 	return reliableHttpImpl{
 		underlyingGet: http.Get,
+		sleep:         time.Sleep,
 	}
 }
 
@@ -40,6 +45,11 @@ func (i reliableHttpImpl) Get(url string) (resp *http.Response, err error) {
 		if e != nil {
 			log.Printf("GET %s failed due to \"%v\". retrying %d/%d", url, e, c, MaxRetryCount)
 		}
+
+		// Backs off the same way as AWS API calls do
+		if i.sleep != nil && c <= MaxRetryCount {
+			i.sleep(awsconn.RetryDelay(c-1, r != nil && r.StatusCode == 504))
+		}
 	}
 
 	return r, fmt.Errorf("max retry count exceeded: %v", e)
@@ -0,0 +1,460 @@
+package ignition
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cloudconfig "github.com/coreos/coreos-cloudinit/config"
+)
+
+const (
+	// metadataTemplatesDir keeps files containing placeholders like $private_ipv4 to substitute instance metadata for on every boot
+	metadataTemplatesDir     = "/etc/kube-aws/metadata-templates"
+	substituteMetadataScript = "/opt/bin/substitute-metadata-placeholders"
+	substituteMetadataUnit   = "substitute-metadata-placeholders.service"
+	flannelOptionsFile       = "/etc/flannel/options.env"
+)
+
+// metadataPlaceholders maps placeholders coreos-cloudinit substitutes to variables coreos-metadata writes on EC2
+var metadataPlaceholders = []struct {
+	placeholder string
+	variable    string
+}{
+	{"$private_ipv4", "COREOS_EC2_IPV4_LOCAL"},
+	{"$public_ipv4", "COREOS_EC2_IPV4_PUBLIC"},
+}
+
+var unsupportedPlaceholder = regexp.MustCompile(`\\\$(private|public)_ipv[46]|\$(private|public)_ipv6`)
+
+// Stub returns a config which makes Ignition fetch the config at source and append it to the stub.
+// files are written in addition, e.g. ones containing values known only to CloudFormation
+func Stub(source string, files ...File) Config {
+	return Config{
+		Ignition: Ignition{
+			Version: Version,
+			Config:  IgnitionConfig{Append: []ConfigReference{{Source: source}}},
+		},
+		Storage: Storage{Files: files},
+	}
+}
+
+// NewFile returns a root-owned file embedding the content
+func NewFile(path string, mode int, content string) File {
+	return File{
+		Filesystem: "root",
+		Path:       path,
+		Mode:       mode,
+		Contents:   FileContents{Source: DataURL(content)},
+	}
+}
+
+// DataURL returns a data URL embedding the content
+func DataURL(content string) string {
+	return "data:," + EscapeDataURL(content)
+}
+
+// EscapeDataURL percent-encodes all the characters in s except unreserved ones
+func EscapeDataURL(s string) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '.' || c == '_' || c == '~' {
+			buf = append(buf, c)
+		} else {
+			buf = append(buf, fmt.Sprintf("%%%02X", c)...)
+		}
+	}
+	return string(buf)
+}
+
+type converter struct {
+	config Config
+	// services are started on boot and therefore ordered after the substitution of instance metadata
+	services []string
+	// templated are paths to files containing metadata placeholders
+	templated []string
+}
+
+// FromCloudConfig converts a cloud-config into an Ignition config which provisions a node equivalently.
+// Placeholders like $private_ipv4 in files are substituted on every boot by a systemd unit as coreos-cloudinit does
+func FromCloudConfig(userdata string) (*Config, error) {
+	if !cloudconfig.IsCloudConfig(userdata) {
+		return nil, fmt.Errorf("userdata isn't a cloud-config")
+	}
+	cc, err := cloudconfig.NewCloudConfig(userdata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse cloud-config: %v", err)
+	}
+	if err := checkSupported(cc); err != nil {
+		return nil, err
+	}
+
+	c := &converter{config: Config{Ignition: Ignition{Version: Version}}}
+	for _, u := range cc.CoreOS.Units {
+		if err := c.unit(u); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.update(cc.CoreOS.Update); err != nil {
+		return nil, err
+	}
+	if err := c.flannel(cc.CoreOS.Flannel); err != nil {
+		return nil, err
+	}
+	for _, f := range cc.WriteFiles {
+		if err := c.file(f); err != nil {
+			return nil, err
+		}
+	}
+	if len(cc.SSHAuthorizedKeys) > 0 {
+		c.config.Passwd.Users = []User{{Name: "core", SSHAuthorizedKeys: cc.SSHAuthorizedKeys}}
+	}
+	c.substituteMetadata()
+
+	return &c.config, nil
+}
+
+func checkSupported(cc *cloudconfig.CloudConfig) error {
+	sections := []struct {
+		name  string
+		value interface{}
+	}{
+		{"hostname", cc.Hostname},
+		{"users", cc.Users},
+		{"manage_etc_hosts", cc.ManageEtcHosts},
+		{"coreos.etcd", cc.CoreOS.Etcd},
+		{"coreos.etcd2", cc.CoreOS.Etcd2},
+		{"coreos.fleet", cc.CoreOS.Fleet},
+		{"coreos.locksmith", cc.CoreOS.Locksmith},
+		{"coreos.oem", cc.CoreOS.OEM},
+	}
+	for _, s := range sections {
+		if !reflect.DeepEqual(s.value, reflect.Zero(reflect.TypeOf(s.value)).Interface()) {
+			return fmt.Errorf("%s in cloud-config can't be converted to ignition", s.name)
+		}
+	}
+	return nil
+}
+
+func (c *converter) unit(u cloudconfig.Unit) error {
+	if err := checkPlaceholders("unit "+u.Name, u.Content, false); err != nil {
+		return err
+	}
+	unit := Unit{
+		Name:     u.Name,
+		Enabled:  u.Enable,
+		Mask:     u.Mask,
+		Contents: u.Content,
+	}
+	for _, d := range u.DropIns {
+		if err := checkPlaceholders(fmt.Sprintf("drop-in %s of unit %s", d.Name, u.Name), d.Content, false); err != nil {
+			return err
+		}
+		unit.Dropins = append(unit.Dropins, Dropin{Name: d.Name, Contents: d.Content})
+	}
+	c.addUnit(unit)
+
+	// Ignition can't start units but the multi-user target can pull in ones coreos-cloudinit would have started
+	starts := u.Command == "start" || u.Command == "restart" || u.Command == "reload-or-restart"
+	if starts && (!u.Enable || u.Content != "" && !hasInstallSection(u.Content)) {
+		target := "/usr/lib/systemd/system/" + u.Name
+		if u.Content != "" {
+			target = "/etc/systemd/system/" + u.Name
+		}
+		c.addLink(Link{
+			Filesystem: "root",
+			Path:       "/etc/systemd/system/multi-user.target.wants/" + u.Name,
+			Target:     target,
+		})
+	}
+	if (starts || u.Enable) && !u.Mask && strings.HasSuffix(u.Name, ".service") && !contains(c.services, u.Name) {
+		c.services = append(c.services, u.Name)
+	}
+	return nil
+}
+
+// addUnit merges the unit into the one of the same name if any, as coreos-cloudinit applies every entry in order.
+// Contents are replaced, drop-ins are added or replaced by name, and the unit stays enabled or masked once it is
+func (c *converter) addUnit(unit Unit) {
+	for i, existing := range c.config.Systemd.Units {
+		if existing.Name != unit.Name {
+			continue
+		}
+		if unit.Contents != "" {
+			existing.Contents = unit.Contents
+		}
+		existing.Enabled = existing.Enabled || unit.Enabled
+		existing.Mask = existing.Mask || unit.Mask
+	dropins:
+		for _, d := range unit.Dropins {
+			for j, e := range existing.Dropins {
+				if e.Name == d.Name {
+					existing.Dropins[j] = d
+					continue dropins
+				}
+			}
+			existing.Dropins = append(existing.Dropins, d)
+		}
+		c.config.Systemd.Units[i] = existing
+		return
+	}
+	c.config.Systemd.Units = append(c.config.Systemd.Units, unit)
+}
+
+func (c *converter) addLink(link Link) {
+	for i, existing := range c.config.Storage.Links {
+		if existing.Path == link.Path {
+			c.config.Storage.Links[i] = link
+			return
+		}
+	}
+	c.config.Storage.Links = append(c.config.Storage.Links, link)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+func hasInstallSection(contents string) bool {
+	for _, l := range strings.Split(contents, "\n") {
+		if strings.TrimSpace(l) == "[Install]" {
+			return true
+		}
+	}
+	return false
+}
+
+// update configures update_engine and masks locksmithd when reboots are disabled as coreos-cloudinit does
+func (c *converter) update(u cloudconfig.Update) error {
+	vars := envVars(u)
+	if len(vars) == 0 {
+		return nil
+	}
+	if u.RebootStrategy == "off" {
+		c.config.Systemd.Units = append(c.config.Systemd.Units, Unit{Name: "locksmithd.service", Mask: true})
+	}
+	return c.file(cloudconfig.File{
+		Path:               "/etc/coreos/update.conf",
+		RawFilePermissions: "0644",
+		Content:            strings.Join(vars, "\n") + "\n",
+	})
+}
+
+// flannel writes options for flanneld to a persistent file, which is linked to the runtime file coreos-cloudinit would write
+func (c *converter) flannel(f cloudconfig.Flannel) error {
+	vars := envVars(f)
+	if len(vars) == 0 {
+		return nil
+	}
+	dropin := Dropin{
+		Name: "20-options-env.conf",
+		Contents: fmt.Sprintf(`[Service]
+ExecStartPre=/usr/bin/mkdir -p /run/flannel
+ExecStartPre=/usr/bin/ln -sf %s /run/flannel/options.env
+`, flannelOptionsFile),
+	}
+	found := false
+	for i, u := range c.config.Systemd.Units {
+		if u.Name == "flanneld.service" {
+			c.config.Systemd.Units[i].Dropins = append(u.Dropins, dropin)
+			found = true
+		}
+	}
+	if !found {
+		c.config.Systemd.Units = append(c.config.Systemd.Units, Unit{Name: "flanneld.service", Dropins: []Dropin{dropin}})
+	}
+	// flanneld reads the options which may contain metadata placeholders
+	ordered := false
+	for _, s := range c.services {
+		ordered = ordered || s == "flanneld.service"
+	}
+	if !ordered {
+		c.services = append(c.services, "flanneld.service")
+	}
+	return c.file(cloudconfig.File{
+		Path:               flannelOptionsFile,
+		RawFilePermissions: "0644",
+		Content:            strings.Join(vars, "\n") + "\n",
+	})
+}
+
+func envVars(section interface{}) []string {
+	t := reflect.TypeOf(section)
+	v := reflect.ValueOf(section)
+	vars := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		if val := v.Field(i).Interface(); !cloudconfig.IsZero(val) {
+			vars = append(vars, fmt.Sprintf("%s=%v", t.Field(i).Tag.Get("env"), val))
+		}
+	}
+	return vars
+}
+
+func (c *converter) file(f cloudconfig.File) error {
+	if !path.IsAbs(f.Path) {
+		return fmt.Errorf("path of file %s must be absolute", f.Path)
+	}
+	mode := 0644
+	if f.RawFilePermissions != "" {
+		m, err := strconv.ParseInt(f.RawFilePermissions, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid permissions %s of file %s: %v", f.RawFilePermissions, f.Path, err)
+		}
+		mode = int(m)
+	}
+	uid, gid, err := parseOwner(f.Owner)
+	if err != nil {
+		return fmt.Errorf("invalid owner of file %s: %v", f.Path, err)
+	}
+	if _, err := cloudconfig.DecodeContent(f.Content, f.Encoding); err != nil {
+		return fmt.Errorf("failed to decode content of file %s: %v", f.Path, err)
+	}
+
+	file := File{
+		Filesystem: "root",
+		Path:       f.Path,
+		Mode:       mode,
+		User:       NodeUser{ID: uid},
+		Group:      NodeGroup{ID: gid},
+	}
+	switch f.Encoding {
+	case "":
+		file.Contents.Source = DataURL(f.Content)
+	case "b64", "base64":
+		file.Contents.Source = "data:;base64," + strings.Join(strings.Fields(f.Content), "")
+	case "gz", "gzip":
+		file.Contents.Source = "data:;base64," + base64.StdEncoding.EncodeToString([]byte(f.Content))
+		file.Contents.Compression = "gzip"
+	default:
+		file.Contents.Source = "data:;base64," + strings.Join(strings.Fields(f.Content), "")
+		file.Contents.Compression = "gzip"
+	}
+
+	// A file written more than once ends up with the last content as coreos-cloudinit writes files in order
+	c.removeFile(f.Path)
+
+	// Encoded contents aren't substituted by coreos-cloudinit either
+	if f.Encoding == "" {
+		if err := checkPlaceholders("file "+f.Path, f.Content, true); err != nil {
+			return err
+		}
+		if hasMetadataPlaceholders(f.Content) {
+			c.templated = append(c.templated, f.Path)
+			file.Path = metadataTemplatesDir + f.Path
+		}
+	}
+	c.config.Storage.Files = append(c.config.Storage.Files, file)
+	return nil
+}
+
+func (c *converter) removeFile(p string) {
+	files := []File{}
+	for _, f := range c.config.Storage.Files {
+		if f.Path != p && f.Path != metadataTemplatesDir+p {
+			files = append(files, f)
+		}
+	}
+	c.config.Storage.Files = files
+	templated := []string{}
+	for _, t := range c.templated {
+		if t != p {
+			templated = append(templated, t)
+		}
+	}
+	c.templated = templated
+}
+
+// parseOwner accepts only owners resolvable without /etc/passwd of the node as ignition accepts only IDs
+func parseOwner(owner string) (int, int, error) {
+	if owner == "" {
+		return 0, 0, nil
+	}
+	parts := strings.SplitN(owner, ":", 2)
+	ids := []int{0, 0}
+	for i, p := range parts {
+		if p == "root" {
+			continue
+		}
+		id, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, 0, fmt.Errorf("%s isn't root or a numeric ID", p)
+		}
+		ids[i] = id
+	}
+	if len(parts) == 1 {
+		ids[1] = ids[0]
+	}
+	return ids[0], ids[1], nil
+}
+
+func hasMetadataPlaceholders(content string) bool {
+	for _, p := range metadataPlaceholders {
+		if strings.Contains(content, p.placeholder) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPlaceholders(name string, content string, substitutable bool) error {
+	if m := unsupportedPlaceholder.FindString(content); m != "" {
+		return fmt.Errorf("%s contains %s, which can't be converted to ignition", name, m)
+	}
+	if !substitutable && hasMetadataPlaceholders(content) {
+		return fmt.Errorf("%s contains metadata placeholders, which are substituted only in files when converted to ignition", name)
+	}
+	return nil
+}
+
+// substituteMetadata copies files containing metadata placeholders into place while substituting values coreos-metadata fetched on every boot,
+// before any service started by the config
+func (c *converter) substituteMetadata() {
+	if len(c.templated) == 0 {
+		return
+	}
+
+	sed := []string{}
+	for _, p := range metadataPlaceholders {
+		sed = append(sed, fmt.Sprintf(`-e "s/[$]%s/${%s}/g"`, strings.TrimPrefix(p.placeholder, "$"), p.variable))
+	}
+	script := fmt.Sprintf(`#!/bin/bash -e
+. /run/metadata/coreos
+for path in %s; do
+  cp -p %s$path $path
+  sed -i %s $path
+done
+`, strings.Join(c.templated, " "), metadataTemplatesDir, strings.Join(sed, " "))
+	c.config.Storage.Files = append(c.config.Storage.Files, NewFile(substituteMetadataScript, 0700, script))
+
+	before := ""
+	if len(c.services) > 0 {
+		before = fmt.Sprintf("Before=%s\n", strings.Join(c.services, " "))
+	}
+	c.config.Systemd.Units = append(c.config.Systemd.Units, Unit{
+		Name:    substituteMetadataUnit,
+		Enabled: true,
+		Contents: fmt.Sprintf(`[Unit]
+Description=Substitutes instance metadata for placeholders in files
+Requires=coreos-metadata.service
+After=coreos-metadata.service
+%s
+[Service]
+Type=oneshot
+RemainAfterExit=yes
+ExecStart=%s
+
+[Install]
+WantedBy=multi-user.target
+`, before, substituteMetadataScript),
+	})
+}
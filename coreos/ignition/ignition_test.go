@@ -0,0 +1,285 @@
+package ignition
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const cloudConfig = `#cloud-config
+coreos:
+  update:
+    reboot-strategy: "off"
+  flannel:
+    interface: $private_ipv4
+  units:
+    - name: etcd2.service
+      drop-ins:
+        - name: 20-etcd2-aws-cluster.conf
+          content: |
+            [Service]
+            EnvironmentFile=-/etc/etcd-environment
+      enable: true
+      command: start
+    - name: format-etcd2-volume.service
+      enable: true
+      content: |
+        [Unit]
+        Before=var-lib-etcd2.mount
+
+        [Service]
+        Type=oneshot
+        ExecStart=/opt/bin/ext4-format-volume-once \
+          /dev/xvdf
+
+        [Install]
+        RequiredBy=var-lib-etcd2.mount
+    - name: cfn-signal.service
+      command: start
+      content: |
+        [Service]
+        Type=oneshot
+        ExecStart=/opt/bin/cfn-signal
+ssh_authorized_keys:
+  - ssh-rsa AAAA
+write_files:
+  - path: /etc/environment
+    permissions: 0644
+    content: |
+      COREOS_PRIVATE_IPV4=$private_ipv4
+  - path: /opt/bin/ext4-format-volume-once
+    permissions: 0700
+    owner: root:root
+    content: |
+      #!/bin/bash -e
+      mkfs.ext4 $1
+  - path: /etc/kubernetes/ssl/ca.pem
+    encoding: gzip+base64
+    content: H4sIAAAAAAACA8tIzcnJBwCGphA2BQAAAA==
+`
+
+func convert(t *testing.T, userdata string) Config {
+	c, err := FromCloudConfig(userdata)
+	if err != nil {
+		t.Fatalf("failed to convert cloud-config: %v", err)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("failed to marshal ignition config: %v", err)
+	}
+	problems, err := Validate(data)
+	if err != nil {
+		t.Fatalf("failed to validate ignition config: %v", err)
+	}
+	if len(problems) > 0 {
+		t.Errorf("expected the converted config to be valid, got %v", problems)
+	}
+	return *c
+}
+
+func findFile(c Config, path string) *File {
+	for _, f := range c.Storage.Files {
+		if f.Path == path {
+			return &f
+		}
+	}
+	return nil
+}
+
+func findUnit(c Config, name string) *Unit {
+	for _, u := range c.Systemd.Units {
+		if u.Name == name {
+			return &u
+		}
+	}
+	return nil
+}
+
+func TestFromCloudConfig(t *testing.T) {
+	c := convert(t, cloudConfig)
+
+	if c.Ignition.Version != Version {
+		t.Errorf("unexpected version: %s", c.Ignition.Version)
+	}
+
+	etcd := findUnit(c, "etcd2.service")
+	if etcd == nil || !etcd.Enabled || len(etcd.Dropins) != 1 || etcd.Dropins[0].Name != "20-etcd2-aws-cluster.conf" {
+		t.Errorf("unexpected etcd2.service: %+v", etcd)
+	}
+	format := findUnit(c, "format-etcd2-volume.service")
+	if format == nil || !format.Enabled || !strings.Contains(format.Contents, "/dev/xvdf") {
+		t.Errorf("unexpected format-etcd2-volume.service: %+v", format)
+	}
+	if locksmithd := findUnit(c, "locksmithd.service"); locksmithd == nil || !locksmithd.Mask {
+		t.Errorf("expected locksmithd to be masked for the reboot strategy off, got %+v", locksmithd)
+	}
+
+	// cfn-signal.service has no [Install] section to enable it with
+	links := c.Storage.Links
+	if len(links) != 1 || links[0].Path != "/etc/systemd/system/multi-user.target.wants/cfn-signal.service" || links[0].Target != "/etc/systemd/system/cfn-signal.service" {
+		t.Errorf("unexpected links: %+v", links)
+	}
+
+	script := findFile(c, "/opt/bin/ext4-format-volume-once")
+	if script == nil || script.Mode != 0700 || script.User.ID != 0 || script.Group.ID != 0 {
+		t.Errorf("unexpected format script: %+v", script)
+	}
+	ca := findFile(c, "/etc/kubernetes/ssl/ca.pem")
+	if ca == nil || ca.Contents.Compression != "gzip" || ca.Contents.Source != "data:;base64,H4sIAAAAAAACA8tIzcnJBwCGphA2BQAAAA==" {
+		t.Errorf("unexpected compressed file: %+v", ca)
+	}
+	if update := findFile(c, "/etc/coreos/update.conf"); update == nil || update.Contents.Source != DataURL("REBOOT_STRATEGY=off\n") {
+		t.Errorf("unexpected update.conf: %+v", update)
+	}
+
+	// Files containing metadata placeholders are substituted on boot
+	if findFile(c, "/etc/environment") != nil || findFile(c, metadataTemplatesDir+"/etc/environment") == nil {
+		t.Errorf("expected /etc/environment to be written as a template, got %+v", c.Storage.Files)
+	}
+	if findFile(c, metadataTemplatesDir+flannelOptionsFile) == nil {
+		t.Errorf("expected flannel options to be written as a template, got %+v", c.Storage.Files)
+	}
+	flanneld := findUnit(c, "flanneld.service")
+	if flanneld == nil || len(flanneld.Dropins) != 1 || !strings.Contains(flanneld.Dropins[0].Contents, flannelOptionsFile) {
+		t.Errorf("unexpected flanneld.service: %+v", flanneld)
+	}
+	substitution := findUnit(c, substituteMetadataUnit)
+	if substitution == nil || !strings.Contains(substitution.Contents, "Before=etcd2.service format-etcd2-volume.service cfn-signal.service flanneld.service\n") {
+		t.Errorf("unexpected substitution unit: %+v", substitution)
+	}
+	substitutionScript := findFile(c, substituteMetadataScript)
+	if substitutionScript == nil || !strings.Contains(substitutionScript.Contents.Source, EscapeDataURL("/etc/flannel/options.env /etc/environment")) {
+		t.Errorf("unexpected substitution script: %+v", substitutionScript)
+	}
+
+	if len(c.Passwd.Users) != 1 || c.Passwd.Users[0].Name != "core" || c.Passwd.Users[0].SSHAuthorizedKeys[0] != "ssh-rsa AAAA" {
+		t.Errorf("unexpected users: %+v", c.Passwd.Users)
+	}
+
+	t.Run("PlaceholderInUnit", func(t *testing.T) {
+		userdata := strings.Replace(cloudConfig, "ExecStart=/opt/bin/cfn-signal", "ExecStart=/opt/bin/cfn-signal $private_ipv4", 1)
+		if _, err := FromCloudConfig(userdata); err == nil || !strings.Contains(err.Error(), "cfn-signal.service") {
+			t.Errorf("expected an error for the placeholder in the unit, got %v", err)
+		}
+	})
+
+	t.Run("UnsupportedSection", func(t *testing.T) {
+		userdata := strings.Replace(cloudConfig, "coreos:\n", "hostname: myhost\ncoreos:\n", 1)
+		if _, err := FromCloudConfig(userdata); err == nil || !strings.Contains(err.Error(), "hostname") {
+			t.Errorf("expected an error for the hostname, got %v", err)
+		}
+	})
+
+	t.Run("DuplicateUnitsAndFiles", func(t *testing.T) {
+		userdata := strings.Replace(cloudConfig, "ssh_authorized_keys:\n", `    - name: etcd2.service
+      drop-ins:
+        - name: 90-custom.conf
+          content: |
+            [Service]
+            Nice=-5
+ssh_authorized_keys:
+`, 1) + `  - path: /opt/bin/ext4-format-volume-once
+    permissions: 0755
+    content: |
+      #!/bin/bash -e
+      mkfs.ext4 -F $1
+`
+		c := convert(t, userdata)
+		etcd := findUnit(c, "etcd2.service")
+		if etcd == nil || !etcd.Enabled || len(etcd.Dropins) != 2 || etcd.Dropins[1].Name != "90-custom.conf" {
+			t.Errorf("expected the drop-in to be added to etcd2.service, got %+v", etcd)
+		}
+		script := findFile(c, "/opt/bin/ext4-format-volume-once")
+		if script == nil || script.Mode != 0755 || !strings.Contains(script.Contents.Source, EscapeDataURL("mkfs.ext4 -F")) {
+			t.Errorf("expected the later file to replace the earlier one, got %+v", script)
+		}
+		count := 0
+		for _, f := range c.Storage.Files {
+			if f.Path == "/opt/bin/ext4-format-volume-once" {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("expected the file to be written once, got %d", count)
+		}
+	})
+
+	t.Run("NonRootOwner", func(t *testing.T) {
+		userdata := strings.Replace(cloudConfig, "owner: root:root", "owner: core:core", 1)
+		if _, err := FromCloudConfig(userdata); err == nil || !strings.Contains(err.Error(), "owner") {
+			t.Errorf("expected an error for the owner, got %v", err)
+		}
+	})
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		context  string
+		config   string
+		expected string
+	}{
+		{
+			context:  "UnsupportedVersion",
+			config:   `{"ignition": {"version": "2.0.0"}}`,
+			expected: "ignition.version: unsupported version",
+		},
+		{
+			context:  "UnknownKey",
+			config:   `{"ignition": {"version": "2.1.0"}, "systemd": {"units": [{"name": "a.service", "enable": true}]}}`,
+			expected: "systemd.units[0].enable: unknown key",
+		},
+		{
+			context:  "RelativePath",
+			config:   `{"ignition": {"version": "2.1.0"}, "storage": {"files": [{"filesystem": "root", "path": "etc/hosts", "mode": 420}]}}`,
+			expected: "storage.files[0].path",
+		},
+		{
+			context:  "InvalidMode",
+			config:   `{"ignition": {"version": "2.1.0"}, "storage": {"files": [{"filesystem": "root", "path": "/etc/hosts", "mode": 65535}]}}`,
+			expected: "storage.files[0].mode",
+		},
+		{
+			context:  "InvalidCompressedData",
+			config:   `{"ignition": {"version": "2.1.0"}, "storage": {"files": [{"filesystem": "root", "path": "/etc/hosts", "mode": 420, "contents": {"compression": "gzip", "source": "data:,hosts"}}]}}`,
+			expected: "invalid gzip data",
+		},
+		{
+			context:  "UnsupportedScheme",
+			config:   `{"ignition": {"version": "2.1.0", "config": {"append": [{"source": "ftp://example.com/config"}]}}}`,
+			expected: `ignition.config.append[0].source: unsupported URL scheme "ftp"`,
+		},
+		{
+			context:  "InvalidUnitContents",
+			config:   `{"ignition": {"version": "2.1.0"}, "systemd": {"units": [{"name": "a.service", "contents": "ExecStart=/bin/true"}]}}`,
+			expected: "line 1: assignment outside of any section",
+		},
+		{
+			context:  "InvalidDropinName",
+			config:   `{"ignition": {"version": "2.1.0"}, "systemd": {"units": [{"name": "a.service", "dropins": [{"name": "10-a", "contents": "[Service]"}]}]}}`,
+			expected: "must end with .conf",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.context, func(t *testing.T) {
+			problems, err := Validate([]byte(c.config))
+			if err != nil {
+				t.Fatalf("failed to validate: %v", err)
+			}
+			found := false
+			for _, p := range problems {
+				found = found || strings.Contains(p, c.expected)
+			}
+			if !found {
+				t.Errorf("expected a problem containing %q, got %v", c.expected, problems)
+			}
+		})
+	}
+
+	t.Run("Stub", func(t *testing.T) {
+		data, _ := json.Marshal(Stub("s3://mybucket/userdata-etcd-0123", NewFile("/etc/kube-aws/etcd-node.env", 0644, "KUBE_AWS_ETCD_INDEX=0\n")))
+		if problems, err := Validate(data); err != nil || len(problems) > 0 {
+			t.Errorf("expected the stub to be valid, got %v, %v", problems, err)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package ignition
+
+// Version is the version of the Ignition config specification kube-aws renders
+const Version = "2.1.0"
+
+// Config is the subset of the Ignition config specification 2.1.0 kube-aws renders
+type Config struct {
+	Ignition Ignition `json:"ignition"`
+	Storage  Storage  `json:"storage,omitempty"`
+	Systemd  Systemd  `json:"systemd,omitempty"`
+	Passwd   Passwd   `json:"passwd,omitempty"`
+}
+
+type Ignition struct {
+	Version string         `json:"version"`
+	Config  IgnitionConfig `json:"config,omitempty"`
+}
+
+// IgnitionConfig references other configs Ignition fetches and merges into the one containing the references
+type IgnitionConfig struct {
+	Append  []ConfigReference `json:"append,omitempty"`
+	Replace *ConfigReference  `json:"replace,omitempty"`
+}
+
+type ConfigReference struct {
+	Source string `json:"source"`
+}
+
+type Storage struct {
+	Files []File `json:"files,omitempty"`
+	Links []Link `json:"links,omitempty"`
+}
+
+type File struct {
+	Filesystem string       `json:"filesystem"`
+	Path       string       `json:"path"`
+	Contents   FileContents `json:"contents"`
+	Mode       int          `json:"mode"`
+	User       NodeUser     `json:"user"`
+	Group      NodeGroup    `json:"group"`
+}
+
+type FileContents struct {
+	Compression string `json:"compression,omitempty"`
+	Source      string `json:"source"`
+}
+
+type NodeUser struct {
+	ID int `json:"id"`
+}
+
+type NodeGroup struct {
+	ID int `json:"id"`
+}
+
+type Link struct {
+	Filesystem string `json:"filesystem"`
+	Path       string `json:"path"`
+	Target     string `json:"target"`
+}
+
+type Systemd struct {
+	Units []Unit `json:"units,omitempty"`
+}
+
+type Unit struct {
+	Name     string   `json:"name"`
+	Enabled  bool     `json:"enabled,omitempty"`
+	Mask     bool     `json:"mask,omitempty"`
+	Contents string   `json:"contents,omitempty"`
+	Dropins  []Dropin `json:"dropins,omitempty"`
+}
+
+type Dropin struct {
+	Name     string `json:"name"`
+	Contents string `json:"contents"`
+}
+
+type Passwd struct {
+	Users []User `json:"users,omitempty"`
+}
+
+type User struct {
+	Name              string   `json:"name"`
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+}
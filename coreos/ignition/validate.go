@@ -0,0 +1,283 @@
+package ignition
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var unitSuffixes = []string{".service", ".socket", ".device", ".mount", ".automount", ".swap", ".target", ".path", ".timer", ".snapshot", ".slice", ".scope"}
+
+var sourceSchemes = map[string]bool{"data": true, "http": true, "https": true, "s3": true, "tftp": true, "oem": true}
+
+// IsIgnitionConfig tells whether the userdata is an Ignition config rather than a cloud-config or a script
+func IsIgnitionConfig(userdata string) bool {
+	return strings.HasPrefix(strings.TrimSpace(userdata), "{")
+}
+
+// Validate checks the config against the subset of the Ignition config specification kube-aws renders, without fetching referenced configs.
+// It returns problems found in the config, or an error when the config isn't JSON at all
+func Validate(data []byte) ([]string, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse ignition config: %v", err)
+	}
+
+	v := &validator{problems: []string{}}
+	v.keys("", raw, reflect.TypeOf(Config{}))
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		// e.g. a mode written as a string
+		v.problems = append(v.problems, err.Error())
+		return v.problems, nil
+	}
+	v.config(c)
+	return v.problems, nil
+}
+
+type validator struct {
+	problems []string
+}
+
+func (v *validator) errorf(path string, format string, args ...interface{}) {
+	v.problems = append(v.problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// keys reports keys which aren't part of the specification, which Ignition would reject
+func (v *validator) keys(p string, value interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return
+		}
+		fields := map[string]reflect.Type{}
+		for i := 0; i < t.NumField(); i++ {
+			fields[strings.Split(t.Field(i).Tag.Get("json"), ",")[0]] = t.Field(i).Type
+		}
+		keys := []string{}
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if p != "" {
+				child = p + "." + k
+			}
+			ft, ok := fields[k]
+			if !ok {
+				v.errorf(child, "unknown key")
+				continue
+			}
+			v.keys(child, obj[k], ft)
+		}
+	case reflect.Slice:
+		arr, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, e := range arr {
+			v.keys(fmt.Sprintf("%s[%d]", p, i), e, t.Elem())
+		}
+	}
+}
+
+func (v *validator) config(c Config) {
+	if c.Ignition.Version != Version {
+		v.errorf("ignition.version", "unsupported version %q, expected %s", c.Ignition.Version, Version)
+	}
+	for i, r := range c.Ignition.Config.Append {
+		v.source(fmt.Sprintf("ignition.config.append[%d].source", i), r.Source, "")
+	}
+	if r := c.Ignition.Config.Replace; r != nil {
+		v.source("ignition.config.replace.source", r.Source, "")
+	}
+
+	paths := map[string]bool{}
+	for i, f := range c.Storage.Files {
+		p := fmt.Sprintf("storage.files[%d]", i)
+		v.node(p, f.Filesystem, f.Path, paths)
+		if f.Mode < 0 || f.Mode > 07777 {
+			v.errorf(p+".mode", "%#o is out of the range of file modes", f.Mode)
+		}
+		if f.User.ID < 0 || f.Group.ID < 0 {
+			v.errorf(p, "negative user or group ID")
+		}
+		if f.Contents.Compression != "" && f.Contents.Compression != "gzip" {
+			v.errorf(p+".contents.compression", "unsupported compression %q", f.Contents.Compression)
+		}
+		if f.Contents.Source != "" {
+			v.source(p+".contents.source", f.Contents.Source, f.Contents.Compression)
+		}
+	}
+	for i, l := range c.Storage.Links {
+		p := fmt.Sprintf("storage.links[%d]", i)
+		v.node(p, l.Filesystem, l.Path, paths)
+		if l.Target == "" {
+			v.errorf(p+".target", "missing target")
+		}
+	}
+
+	units := map[string]bool{}
+	for i, u := range c.Systemd.Units {
+		p := fmt.Sprintf("systemd.units[%d]", i)
+		if !hasUnitSuffix(u.Name) {
+			v.errorf(p+".name", "invalid unit name %q", u.Name)
+		}
+		if units[u.Name] {
+			v.errorf(p+".name", "duplicate unit %s", u.Name)
+		}
+		units[u.Name] = true
+		if err := checkUnitContents(u.Contents); err != nil {
+			v.errorf(p+".contents", "invalid unit %s: %v", u.Name, err)
+		}
+		dropins := map[string]bool{}
+		for j, d := range u.Dropins {
+			dp := fmt.Sprintf("%s.dropins[%d]", p, j)
+			if !strings.HasSuffix(d.Name, ".conf") {
+				v.errorf(dp+".name", "drop-in name %q must end with .conf", d.Name)
+			}
+			if dropins[d.Name] {
+				v.errorf(dp+".name", "duplicate drop-in %s", d.Name)
+			}
+			dropins[d.Name] = true
+			if err := checkUnitContents(d.Contents); err != nil {
+				v.errorf(dp+".contents", "invalid drop-in %s of unit %s: %v", d.Name, u.Name, err)
+			}
+		}
+	}
+
+	for i, u := range c.Passwd.Users {
+		if u.Name == "" {
+			v.errorf(fmt.Sprintf("passwd.users[%d].name", i), "missing user name")
+		}
+	}
+}
+
+// node validates a file or a link, which are written to the root filesystem as kube-aws doesn't define other filesystems
+func (v *validator) node(p string, filesystem string, nodePath string, paths map[string]bool) {
+	if filesystem != "root" {
+		v.errorf(p+".filesystem", "undefined filesystem %q", filesystem)
+	}
+	if !path.IsAbs(nodePath) || path.Clean(nodePath) != nodePath {
+		v.errorf(p+".path", "path %q must be absolute and clean", nodePath)
+	}
+	if paths[nodePath] {
+		v.errorf(p+".path", "duplicate path %s", nodePath)
+	}
+	paths[nodePath] = true
+}
+
+func (v *validator) source(p string, source string, compression string) {
+	u, err := url.Parse(source)
+	if err != nil {
+		v.errorf(p, "invalid URL: %v", err)
+		return
+	}
+	if !sourceSchemes[u.Scheme] {
+		v.errorf(p, "unsupported URL scheme %q", u.Scheme)
+		return
+	}
+	if u.Scheme == "s3" && (u.Host == "" || u.Path == "") {
+		v.errorf(p, "s3 URL %s must have a bucket and a key", source)
+		return
+	}
+	if u.Scheme != "data" {
+		return
+	}
+	data, err := decodeDataURL(source)
+	if err != nil {
+		v.errorf(p, "invalid data URL: %v", err)
+		return
+	}
+	if compression == "gzip" {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+		}
+		if err != nil {
+			v.errorf(p, "invalid gzip data: %v", err)
+		}
+	}
+}
+
+func decodeDataURL(source string) ([]byte, error) {
+	i := strings.Index(source, ",")
+	if i < 0 {
+		return nil, fmt.Errorf("missing comma")
+	}
+	header, data := source[len("data:"):i], source[i+1:]
+	if strings.HasSuffix(header, ";base64") {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	return unescapeDataURL(data)
+}
+
+func unescapeDataURL(s string) ([]byte, error) {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' {
+			buf = append(buf, s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return nil, fmt.Errorf("truncated escape at %d", i)
+		}
+		b, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid escape at %d: %v", i, err)
+		}
+		buf = append(buf, byte(b))
+		i += 2
+	}
+	return buf, nil
+}
+
+func hasUnitSuffix(name string) bool {
+	for _, s := range unitSuffixes {
+		if strings.HasSuffix(name, s) && len(name) > len(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnitContents parses contents as a systemd unit file as Ignition does before writing it
+func checkUnitContents(contents string) error {
+	section := ""
+	lines := strings.Split(contents, "\n")
+	for i := 0; i < len(lines); i++ {
+		num := i + 1
+		line := strings.TrimSpace(lines[i])
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimSuffix(line, `\`) + " " + strings.TrimSpace(lines[i])
+		}
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+		case line[0] == '[':
+			if len(line) < 3 || !strings.HasSuffix(line, "]") {
+				return fmt.Errorf("line %d: invalid section header %s", num, line)
+			}
+			section = line
+		case section == "":
+			return fmt.Errorf("line %d: assignment outside of any section", num)
+		case strings.Index(line, "=") <= 0:
+			return fmt.Errorf("line %d: expected an assignment like Key=Value, got %s", num, line)
+		}
+	}
+	return nil
+}
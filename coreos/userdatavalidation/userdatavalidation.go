@@ -3,6 +3,7 @@ package userdatavalidation
 import (
 	"fmt"
 	"github.com/coreos/coreos-cloudinit/config/validate"
+	"github.com/coreos/kube-aws/coreos/ignition"
 	"strings"
 )
 
@@ -15,6 +16,18 @@ func Execute(entries []Entry) error {
 	errors := []string{}
 
 	for _, userData := range entries {
+		if ignition.IsIgnitionConfig(userData.Content) {
+			problems, err := ignition.Validate([]byte(userData.Content))
+			if err != nil {
+				errors = append(errors, fmt.Sprintf("ignition config %s could not be parsed: %v", userData.Name, err))
+				continue
+			}
+			for _, p := range problems {
+				errors = append(errors, fmt.Sprintf("%s: %s", userData.Name, p))
+			}
+			continue
+		}
+
 		report, err := validate.Validate([]byte(userData.Content))
 
 		if err != nil {
@@ -35,7 +48,7 @@ func Execute(entries []Entry) error {
 
 	if len(errors) > 0 {
 		reportString := strings.Join(errors, "\n")
-		return fmt.Errorf("cloud-config validation errors:\n%s\n", reportString)
+		return fmt.Errorf("userdata validation errors:\n%s\n", reportString)
 	}
 
 	return nil
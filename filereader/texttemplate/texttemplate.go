@@ -5,6 +5,8 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
@@ -13,12 +15,7 @@ func GetBytesBuffer(filename string, data interface{}) (*bytes.Buffer, error) {
 	if err != nil {
 		return nil, err
 	}
-	funcMap := template.FuncMap{
-		"sha1":  func(v string) string { return fmt.Sprintf("%x", sha1.Sum([]byte(v))) },
-		"minus": func(a, b int) int { return a - b },
-	}
-
-	tmpl, err := template.New(filename).Funcs(funcMap).Parse(string(raw))
+	tmpl, err := Parse(filename, string(raw))
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +26,29 @@ func GetBytesBuffer(filename string, data interface{}) (*bytes.Buffer, error) {
 	return &buff, nil
 }
 
+// Parse parses text as a template with the functions available to templates rendered by kube-aws
+func Parse(name string, text string) (*template.Template, error) {
+	funcMap := template.FuncMap{
+		"sha1":   func(v string) string { return fmt.Sprintf("%x", sha1.Sum([]byte(v))) },
+		"minus":  func(a, b int) int { return a - b },
+		"indent": indent,
+		// quote makes a value a YAML double-quoted scalar
+		"quote": strconv.Quote,
+	}
+	return template.New(name).Funcs(funcMap).Parse(text)
+}
+
+// indent prefixes every non-empty line of s with n spaces, e.g. to embed s into a YAML block scalar
+func indent(n int, s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = strings.Repeat(" ", n) + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
 func GetString(filename string, data interface{}) (string, error) {
 	buf, err := GetBytesBuffer(filename, data)
 
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var roleSessionNamePattern = regexp.MustCompile(`^[\w+=,.@-]{2,64}$`)
+
+// AWS configures how kube-aws accesses AWS to manage the cluster
+type AWS struct {
+	// AssumeRoleARN is the ARN of the IAM role assumed by every AWS API call made by kube-aws for the cluster
+	// e.g. to manage a cluster in another account
+	AssumeRoleARN string `yaml:"assumeRoleArn,omitempty"`
+	// ExternalID is passed to STS AssumeRole when the role requires it in its trust policy
+	ExternalID string `yaml:"externalId,omitempty"`
+	// SessionName identifies the session of the assumed role in CloudTrail. Defaults to kube-aws-<cluster name>
+	SessionName string `yaml:"sessionName,omitempty"`
+	// MFASerial is the serial number or the ARN of the MFA device required by the role. kube-aws prompts for a token code
+	// when the credentials are issued
+	MFASerial   string `yaml:"mfaSerial,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+func (a AWS) Valid() error {
+	if a.AssumeRoleARN == "" {
+		if a.ExternalID != "" || a.SessionName != "" || a.MFASerial != "" {
+			return fmt.Errorf("`aws.externalId`, `aws.sessionName` and `aws.mfaSerial` can be specified only with `aws.assumeRoleArn`")
+		}
+		return nil
+	}
+	if !strings.HasPrefix(a.AssumeRoleARN, "arn:") || !strings.Contains(a.AssumeRoleARN, ":role/") {
+		return fmt.Errorf("`aws.assumeRoleArn` must be the ARN of an IAM role e.g. arn:aws:iam::123456789012:role/kube-aws but was %q", a.AssumeRoleARN)
+	}
+	if a.SessionName != "" && !roleSessionNamePattern.MatchString(a.SessionName) {
+		return fmt.Errorf("`aws.sessionName` must consist of 2 to 64 alphanumeric characters or any of +=,.@_- but was %q", a.SessionName)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// awsEndpointServiceIDs maps keys of `awsEndpoints` to service IDs known to aws-sdk-go
+var awsEndpointServiceIDs = map[string]string{
+	"autoscaling":    "autoscaling",
+	"cloudformation": "cloudformation",
+	"ec2":            "ec2",
+	"elb":            "elasticloadbalancing",
+	"kms":            "kms",
+	"route53":        "route53",
+	"s3":             "s3",
+	"sts":            "sts",
+}
+
+// AWSEndpoints overrides endpoints of AWS services used by kube-aws e.g. with VPC endpoints or a local AWS emulator.
+// Keys are short names of services e.g. cloudformation, s3, ec2, kms, route53 and elb and values are URLs
+type AWSEndpoints map[string]string
+
+func (e AWSEndpoints) Valid() error {
+	for _, service := range e.services() {
+		if _, ok := awsEndpointServiceIDs[service]; !ok {
+			return fmt.Errorf("unknown service `awsEndpoints.%s`: must be one of %s", service, strings.Join(knownAWSEndpointServices(), ", "))
+		}
+		u, err := url.Parse(e[service])
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("`awsEndpoints.%s` must be an http or https URL but was %q", service, e[service])
+		}
+	}
+	return nil
+}
+
+// ServiceEndpoints returns the endpoints keyed by service IDs known to aws-sdk-go e.g. elasticloadbalancing for elb
+func (e AWSEndpoints) ServiceEndpoints() map[string]string {
+	m := map[string]string{}
+	for service, u := range e {
+		if id, ok := awsEndpointServiceIDs[service]; ok {
+			m[id] = u
+		}
+	}
+	return m
+}
+
+func (e AWSEndpoints) services() []string {
+	services := []string{}
+	for s := range e {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	return services
+}
+
+func knownAWSEndpointServices() []string {
+	services := []string{}
+	for s := range awsEndpointServiceIDs {
+		services = append(services, s)
+	}
+	sort.Strings(services)
+	return services
+}
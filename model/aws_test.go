@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestAWSValid(t *testing.T) {
+	valid := []AWS{
+		{},
+		{AssumeRoleARN: "arn:aws:iam::123456789012:role/kube-aws"},
+		{
+			AssumeRoleARN: "arn:aws:iam::123456789012:role/kube-aws",
+			ExternalID:    "myexternalid",
+			SessionName:   "ci@example.com",
+			MFASerial:     "arn:aws:iam::123456789012:mfa/ci",
+		},
+	}
+	for _, a := range valid {
+		if err := a.Valid(); err != nil {
+			t.Errorf("expected %+v to be valid but was not: %v", a, err)
+		}
+	}
+
+	invalid := []AWS{
+		{ExternalID: "myexternalid"},
+		{MFASerial: "arn:aws:iam::123456789012:mfa/ci"},
+		{AssumeRoleARN: "kube-aws"},
+		{AssumeRoleARN: "arn:aws:iam::123456789012:user/ci"},
+		{AssumeRoleARN: "arn:aws:iam::123456789012:role/kube-aws", SessionName: "my session"},
+	}
+	for _, a := range invalid {
+		if err := a.Valid(); err == nil {
+			t.Errorf("expected an error for %+v", a)
+		}
+	}
+}
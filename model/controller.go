@@ -4,11 +4,13 @@ import "errors"
 
 // TODO Merge this with NodePoolConfig
 type Controller struct {
-	AutoScalingGroup   AutoScalingGroup  `yaml:"autoScalingGroup,omitempty"`
-	ClusterAutoscaler  ClusterAutoscaler `yaml:"clusterAutoscaler,omitempty"`
-	LoadBalancer       ControllerElb     `yaml:"loadBalancer,omitempty"`
-	ManagedIamRoleName string            `yaml:"managedIamRoleName,omitempty"`
-	Subnets            []Subnet          `yaml:"subnets,omitempty"`
+	AutoScalingGroup   AutoScalingGroup    `yaml:"autoScalingGroup,omitempty"`
+	ClusterAutoscaler  ClusterAutoscaler   `yaml:"clusterAutoscaler,omitempty"`
+	CustomFiles        []CustomFile        `yaml:"customFiles,omitempty"`
+	CustomSystemdUnits []CustomSystemdUnit `yaml:"customSystemdUnits,omitempty"`
+	LoadBalancer       ControllerElb       `yaml:"loadBalancer,omitempty"`
+	ManagedIamRoleName string              `yaml:"managedIamRoleName,omitempty"`
+	Subnets            []Subnet            `yaml:"subnets,omitempty"`
 	UnknownKeys        `yaml:",inline"`
 }
 
@@ -33,6 +35,14 @@ func (c Controller) Validate() error {
 			"allowing so for a group of controller nodes spreading over 2 or more availability zones " +
 			"results in unreliability while scaling nodes out.")
 	}
+
+	if err := ValidateCustomSystemdUnits(c.CustomSystemdUnits); err != nil {
+		return err
+	}
+
+	if err := ValidateCustomFiles(c.CustomFiles); err != nil {
+		return err
+	}
 	return nil
 }
 
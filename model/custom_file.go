@@ -0,0 +1,73 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+)
+
+var fileOwnerPattern = regexp.MustCompile(`^[a-z0-9_][a-z0-9_-]*(:[a-z0-9_][a-z0-9_-]*)?$`)
+
+// CustomFile is a file added to the cloud-config of nodes from cluster.yaml.
+// A file at the same path as one in the cloud-config template replaces it
+type CustomFile struct {
+	Path string `yaml:"path"`
+	// Permissions defaults to 0644
+	Permissions FileMode `yaml:"permissions,omitempty"`
+	Owner       string   `yaml:"owner,omitempty"`
+	Content     string   `yaml:"content"`
+	UnknownKeys `yaml:",inline"`
+}
+
+// FileMode is a file mode always read in the octal notation like chmod so that both `permissions: 644` and
+// `permissions: 0644` mean rw-r--r--
+type FileMode uint32
+
+func (m *FileMode) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 07777 {
+		return fmt.Errorf("invalid file mode \"%s\": it must be in the octal notation like 0644", s)
+	}
+	*m = FileMode(mode)
+	return nil
+}
+
+// PermissionsString is the octal notation of the permissions as written in cloud-configs
+func (f CustomFile) PermissionsString() string {
+	if f.Permissions == 0 {
+		return "0644"
+	}
+	return fmt.Sprintf("%04o", f.Permissions)
+}
+
+func (f CustomFile) Validate() error {
+	if !path.IsAbs(f.Path) || path.Clean(f.Path) != f.Path {
+		return fmt.Errorf("invalid path \"%s\" of custom file: it must be absolute and clean", f.Path)
+	}
+	if f.Permissions > 07777 {
+		return fmt.Errorf("invalid permissions %#o of custom file %s", f.Permissions, f.Path)
+	}
+	if f.Owner != "" && !fileOwnerPattern.MatchString(f.Owner) {
+		return fmt.Errorf("invalid owner \"%s\" of custom file %s: it must be like user or user:group", f.Owner, f.Path)
+	}
+	return nil
+}
+
+func ValidateCustomFiles(files []CustomFile) error {
+	paths := map[string]bool{}
+	for _, f := range files {
+		if err := f.Validate(); err != nil {
+			return err
+		}
+		if paths[f.Path] {
+			return fmt.Errorf("duplicate custom file %s", f.Path)
+		}
+		paths[f.Path] = true
+	}
+	return nil
+}
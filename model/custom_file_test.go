@@ -0,0 +1,43 @@
+package model
+
+import (
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestCustomFilePermissions(t *testing.T) {
+	valid := []struct {
+		yaml     string
+		expected string
+	}{
+		{"path: /etc/motd", "0644"},
+		{"path: /etc/motd\npermissions: 0600", "0600"},
+		{"path: /etc/motd\npermissions: 644", "0644"},
+		{"path: /etc/motd\npermissions: \"0755\"", "0755"},
+		{"path: /etc/motd\npermissions: 4755", "4755"},
+	}
+	for _, c := range valid {
+		f := CustomFile{}
+		if err := yaml.Unmarshal([]byte(c.yaml), &f); err != nil {
+			t.Errorf("failed to parse %q: %v", c.yaml, err)
+			continue
+		}
+		if f.PermissionsString() != c.expected {
+			t.Errorf("expected permissions %s for %q but was %s", c.expected, c.yaml, f.PermissionsString())
+		}
+	}
+
+	invalid := []string{
+		"path: /etc/motd\npermissions: 0689",
+		"path: /etc/motd\npermissions: 17777",
+		"path: /etc/motd\npermissions: rw-r--r--",
+		"path: /etc/motd\npermissions: -644",
+	}
+	for _, c := range invalid {
+		f := CustomFile{}
+		if err := yaml.Unmarshal([]byte(c), &f); err == nil {
+			t.Errorf("expected %q to fail but permissions were parsed as %s", c, f.PermissionsString())
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+var unitSuffixes = []string{".service", ".socket", ".device", ".mount", ".automount", ".swap", ".target", ".path", ".timer", ".slice", ".scope"}
+
+var unitCommands = map[string]bool{
+	"start":                 true,
+	"stop":                  true,
+	"restart":               true,
+	"reload":                true,
+	"try-restart":           true,
+	"reload-or-restart":     true,
+	"reload-or-try-restart": true,
+}
+
+// CustomSystemdUnit is a systemd unit added to the cloud-config of nodes from cluster.yaml.
+// A unit named after one defined in the cloud-config template replaces its content or adds drop-ins to it
+type CustomSystemdUnit struct {
+	Name        string                    `yaml:"name"`
+	Command     string                    `yaml:"command,omitempty"`
+	Enable      bool                      `yaml:"enable,omitempty"`
+	Runtime     bool                      `yaml:"runtime,omitempty"`
+	Content     string                    `yaml:"content,omitempty"`
+	DropIns     []CustomSystemdUnitDropIn `yaml:"dropIns,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+type CustomSystemdUnitDropIn struct {
+	Name        string `yaml:"name"`
+	Content     string `yaml:"content"`
+	UnknownKeys `yaml:",inline"`
+}
+
+func (u CustomSystemdUnit) Validate() error {
+	valid := false
+	for _, s := range unitSuffixes {
+		valid = valid || strings.HasSuffix(u.Name, s) && len(u.Name) > len(s)
+	}
+	if !valid || strings.Contains(u.Name, "/") {
+		return fmt.Errorf("invalid name \"%s\" of custom systemd unit: it must end with a unit type like .service or .timer", u.Name)
+	}
+	if u.Command != "" && !unitCommands[u.Command] {
+		return fmt.Errorf("invalid command \"%s\" of custom systemd unit %s", u.Command, u.Name)
+	}
+	if u.Content == "" && len(u.DropIns) == 0 && u.Command == "" && !u.Enable {
+		return fmt.Errorf("custom systemd unit %s must have either content, drop-ins, command or enable", u.Name)
+	}
+	names := map[string]bool{}
+	for _, d := range u.DropIns {
+		if !strings.HasSuffix(d.Name, ".conf") || strings.Contains(d.Name, "/") {
+			return fmt.Errorf("invalid name \"%s\" of drop-in for custom systemd unit %s: it must end with .conf", d.Name, u.Name)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("duplicate drop-in %s for custom systemd unit %s", d.Name, u.Name)
+		}
+		names[d.Name] = true
+	}
+	return nil
+}
+
+func ValidateCustomSystemdUnits(units []CustomSystemdUnit) error {
+	names := map[string]bool{}
+	for _, u := range units {
+		if err := u.Validate(); err != nil {
+			return err
+		}
+		if names[u.Name] {
+			return fmt.Errorf("duplicate custom systemd unit %s", u.Name)
+		}
+		names[u.Name] = true
+	}
+	return nil
+}
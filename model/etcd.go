@@ -5,10 +5,12 @@ import (
 )
 
 type Etcd struct {
-	Subnets     []Subnet    `yaml:"subnets,omitempty"`
-	Nodes       []EtcdNode  `yaml:"nodes,omitempty"`
-	Cluster     EtcdCluster `yaml:",inline"`
-	UnknownKeys `yaml:",inline"`
+	Subnets            []Subnet            `yaml:"subnets,omitempty"`
+	Nodes              []EtcdNode          `yaml:"nodes,omitempty"`
+	CustomFiles        []CustomFile        `yaml:"customFiles,omitempty"`
+	CustomSystemdUnits []CustomSystemdUnit `yaml:"customSystemdUnits,omitempty"`
+	Cluster            EtcdCluster         `yaml:",inline"`
+	UnknownKeys        `yaml:",inline"`
 }
 
 func (i Etcd) LogicalName() string {
@@ -10,6 +10,7 @@ type NodePoolConfig struct {
 	SpotFleet          SpotFleet         `yaml:"spotFleet,omitempty"`
 	Count              *int              `yaml:"count,omitempty"`
 	CreateTimeout      string            `yaml:"createTimeout,omitempty"`
+	StackPolicy        *StackPolicy      `yaml:"stackPolicy,omitempty"`
 	InstanceType       string            `yaml:"instanceType,omitempty"`
 	ManagedIamRoleName string            `yaml:"managedIamRoleName,omitempty"`
 	RootVolume         `yaml:",inline"`
@@ -17,6 +18,8 @@ type NodePoolConfig struct {
 	SecurityGroupIds   []string               `yaml:"securityGroupIds,omitempty"`
 	Tenancy            string                 `yaml:"tenancy,omitempty"`
 	CustomSettings     map[string]interface{} `yaml:"customSettings,omitempty"`
+	CustomFiles        []CustomFile           `yaml:"customFiles,omitempty"`
+	CustomSystemdUnits []CustomSystemdUnit    `yaml:"customSystemdUnits,omitempty"`
 	VolumeMounts       []VolumeMount          `yaml:"volumeMounts,omitempty"`
 	UnknownKeys        `yaml:",inline"`
 }
@@ -93,6 +96,12 @@ func (c NodePoolConfig) Valid() error {
 		return err
 	}
 
+	if c.StackPolicy != nil {
+		if err := c.StackPolicy.Valid(); err != nil {
+			return err
+		}
+	}
+
 	if c.Tenancy != "default" && c.SpotFleet.Enabled() {
 		return fmt.Errorf("selected worker tenancy (%s) is incompatible with spot fleet", c.Tenancy)
 	}
@@ -113,6 +122,14 @@ func (c NodePoolConfig) Valid() error {
 		return err
 	}
 
+	if err := ValidateCustomSystemdUnits(c.CustomSystemdUnits); err != nil {
+		return err
+	}
+
+	if err := ValidateCustomFiles(c.CustomFiles); err != nil {
+		return err
+	}
+
 	if c.InstanceType == "t2.micro" || c.InstanceType == "t2.nano" {
 		fmt.Println(`WARNING: instance types "t2.nano" and "t2.micro" are not recommended. See https://github.com/coreos/kube-aws/issues/258 for more information`)
 	}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"strings"
+)
+
+// Partition is a group of AWS regions sharing a DNS suffix, an ARN prefix and a set of supported services
+type Partition struct {
+	// ID is the partition in ARNs e.g. aws-cn in arn:aws-cn:s3:::mybucket
+	ID string
+	// RegionPrefixes are prefixes of names of regions in the partition. Empty for the default partition
+	RegionPrefixes []string
+	// DNSSuffix is the domain name of endpoints and service principals in the partition
+	DNSSuffix string
+	// S3EndpointTemplate is the URL of the S3 endpoint in which {region} is replaced with the region name
+	S3EndpointTemplate string
+	// SupportsKMS is true when KMS is available in the partition
+	SupportsKMS bool
+}
+
+// Partitions are known AWS partitions. The last one is the default partition to which a region not matching any
+// prefix belongs
+var Partitions = []Partition{
+	{
+		ID:                 "aws-cn",
+		RegionPrefixes:     []string{"cn-"},
+		DNSSuffix:          "amazonaws.com.cn",
+		S3EndpointTemplate: "https://s3.{region}.amazonaws.com.cn",
+		SupportsKMS:        false,
+	},
+	{
+		ID:                 "aws-us-gov",
+		RegionPrefixes:     []string{"us-gov-"},
+		DNSSuffix:          "amazonaws.com",
+		S3EndpointTemplate: "https://s3-{region}.amazonaws.com",
+		SupportsKMS:        true,
+	},
+	{
+		ID:                 "aws",
+		DNSSuffix:          "amazonaws.com",
+		S3EndpointTemplate: "https://s3.amazonaws.com",
+		SupportsKMS:        true,
+	},
+}
+
+// PartitionForRegion returns the partition the region belongs to
+func PartitionForRegion(name string) Partition {
+	for _, p := range Partitions {
+		for _, prefix := range p.RegionPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return p
+			}
+		}
+	}
+	return Partitions[len(Partitions)-1]
+}
+
+// S3Endpoint returns the URL of the S3 endpoint for the region in the partition
+func (p Partition) S3Endpoint(region string) string {
+	return strings.Replace(p.S3EndpointTemplate, "{region}", region, -1)
+}
@@ -7,6 +7,8 @@ import (
 
 type Region struct {
 	Name string `yaml:"region,omitempty"`
+	// Endpoints overrides endpoints of AWS services in the region
+	Endpoints AWSEndpoints `yaml:"awsEndpoints,omitempty"`
 }
 
 func RegionForName(name string) Region {
@@ -32,32 +34,33 @@ func (r Region) PublicComputeDomainName() string {
 }
 
 func (r Region) PublicDomainName() string {
-	if r.IsChina() {
-		return "amazonaws.com.cn"
-	}
-	return "amazonaws.com"
+	return r.partition().DNSSuffix
 }
 
 func (r Region) String() string {
 	return r.Name
 }
 
+// S3Endpoint returns the URL of the S3 endpoint to which assets are uploaded, and from which CloudFormation and nodes
+// download them
 func (r Region) S3Endpoint() string {
-	if r.IsChina() {
-		return fmt.Sprintf("https://s3.%s.amazonaws.com.cn", r.Name)
+	if e, ok := r.Endpoints["s3"]; ok {
+		return strings.TrimSuffix(e, "/")
 	}
-	return "https://s3.amazonaws.com"
+	return r.partition().S3Endpoint(r.Name)
 }
 
+// Partition returns the ID of the partition e.g. aws-cn which is used in ARNs
 func (r Region) Partition() string {
-	if r.IsChina() {
-		return "aws-cn"
-	}
-	return "aws"
+	return r.partition().ID
+}
+
+func (r Region) partition() Partition {
+	return PartitionForRegion(r.Name)
 }
 
 func (r Region) IsChina() bool {
-	return strings.HasPrefix(r.Name, "cn-")
+	return r.Partition() == "aws-cn"
 }
 
 func (r Region) IsEmpty() bool {
@@ -65,5 +68,5 @@ func (r Region) IsEmpty() bool {
 }
 
 func (r Region) SupportsKMS() bool {
-	return !r.IsChina()
+	return r.partition().SupportsKMS
 }
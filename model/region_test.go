@@ -0,0 +1,63 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestRegionPartitions(t *testing.T) {
+	cases := []struct {
+		region           string
+		partition        string
+		publicDomainName string
+		s3Endpoint       string
+		supportsKMS      bool
+	}{
+		{"us-east-1", "aws", "amazonaws.com", "https://s3.amazonaws.com", true},
+		{"ap-northeast-1", "aws", "amazonaws.com", "https://s3.amazonaws.com", true},
+		{"cn-north-1", "aws-cn", "amazonaws.com.cn", "https://s3.cn-north-1.amazonaws.com.cn", false},
+		{"us-gov-west-1", "aws-us-gov", "amazonaws.com", "https://s3-us-gov-west-1.amazonaws.com", true},
+	}
+	for _, c := range cases {
+		r := RegionForName(c.region)
+		if r.Partition() != c.partition {
+			t.Errorf("unexpected partition for %s: expected=%s, actual=%s", c.region, c.partition, r.Partition())
+		}
+		if r.PublicDomainName() != c.publicDomainName {
+			t.Errorf("unexpected public domain name for %s: expected=%s, actual=%s", c.region, c.publicDomainName, r.PublicDomainName())
+		}
+		if r.S3Endpoint() != c.s3Endpoint {
+			t.Errorf("unexpected s3 endpoint for %s: expected=%s, actual=%s", c.region, c.s3Endpoint, r.S3Endpoint())
+		}
+		if r.SupportsKMS() != c.supportsKMS {
+			t.Errorf("unexpected KMS support for %s: expected=%v, actual=%v", c.region, c.supportsKMS, r.SupportsKMS())
+		}
+	}
+}
+
+func TestRegionS3EndpointOverride(t *testing.T) {
+	r := Region{Name: "us-gov-west-1", Endpoints: AWSEndpoints{"s3": "https://bucket.vpce-1a2b3c4d.s3.us-gov-west-1.vpce.amazonaws.com/"}}
+	if r.S3Endpoint() != "https://bucket.vpce-1a2b3c4d.s3.us-gov-west-1.vpce.amazonaws.com" {
+		t.Errorf("unexpected s3 endpoint: %s", r.S3Endpoint())
+	}
+}
+
+func TestAWSEndpointsValid(t *testing.T) {
+	valid := AWSEndpoints{
+		"cloudformation": "http://localhost:4581",
+		"elb":            "https://elasticloadbalancing.example.com",
+	}
+	if err := valid.Valid(); err != nil {
+		t.Errorf("expected %v to be valid but was not: %v", valid, err)
+	}
+
+	invalid := []AWSEndpoints{
+		{"sqs": "http://localhost:4576"},
+		{"s3": "localhost:4572"},
+		{"ec2": "ftp://localhost"},
+	}
+	for _, e := range invalid {
+		if err := e.Valid(); err == nil {
+			t.Errorf("expected an error for %v", e)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+const (
+	S3ServerSideEncryptionAES256 = "AES256"
+	S3ServerSideEncryptionKMS    = "aws:kms"
+)
+
+// S3 configures how kube-aws uploads stack templates and userdata to S3
+type S3 struct {
+	// ServerSideEncryption is either AES256 or aws:kms. Objects are uploaded without requesting encryption if omitted
+	ServerSideEncryption string `yaml:"serverSideEncryption,omitempty"`
+	// KMSKeyARN is the KMS key used to encrypt objects when ServerSideEncryption is aws:kms.
+	// The AWS managed key for S3 is used if omitted
+	KMSKeyARN string `yaml:"kmsKeyArn,omitempty"`
+	// Tags are added to every uploaded object
+	Tags        map[string]string `yaml:"tags,omitempty"`
+	UnknownKeys `yaml:",inline"`
+}
+
+func (s S3) Valid() error {
+	switch s.ServerSideEncryption {
+	case "", S3ServerSideEncryptionAES256, S3ServerSideEncryptionKMS:
+	default:
+		return fmt.Errorf("`s3.serverSideEncryption` must be either %s or %s but was %q", S3ServerSideEncryptionAES256, S3ServerSideEncryptionKMS, s.ServerSideEncryption)
+	}
+	if s.KMSKeyARN != "" && s.ServerSideEncryption != S3ServerSideEncryptionKMS {
+		return fmt.Errorf("`s3.kmsKeyArn` can be specified only when `s3.serverSideEncryption` is %s", S3ServerSideEncryptionKMS)
+	}
+	if len(s.Tags) > 10 {
+		return fmt.Errorf("`s3.tags` must not contain more than 10 tags but contained %d", len(s.Tags))
+	}
+	return nil
+}
+
+// Tagging returns tags in the URL-encoded form accepted by the x-amz-tagging header, or an empty string if there's no tag
+func (s S3) Tagging() string {
+	keys := []string{}
+	for k := range s.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", url.QueryEscape(k), url.QueryEscape(s.Tags[k])))
+	}
+	return strings.Join(pairs, "&")
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestS3Valid(t *testing.T) {
+	valid := []S3{
+		{},
+		{ServerSideEncryption: "AES256"},
+		{ServerSideEncryption: "aws:kms", KMSKeyARN: "arn:aws:kms:us-west-1:123456789012:key/mykey"},
+	}
+	for _, s := range valid {
+		if err := s.Valid(); err != nil {
+			t.Errorf("expected %+v to be valid but was not: %v", s, err)
+		}
+	}
+
+	invalid := []S3{
+		{ServerSideEncryption: "kms"},
+		{ServerSideEncryption: "AES256", KMSKeyARN: "arn:aws:kms:us-west-1:123456789012:key/mykey"},
+	}
+	for _, s := range invalid {
+		if err := s.Valid(); err == nil {
+			t.Errorf("expected an error for %+v", s)
+		}
+	}
+}
+
+func TestS3Tagging(t *testing.T) {
+	s := S3{Tags: map[string]string{"b": "x&y", "a": "1"}}
+	if s.Tagging() != "a=1&b=x%26y" {
+		t.Errorf("unexpected tagging: %s", s.Tagging())
+	}
+	if (S3{}).Tagging() != "" {
+		t.Errorf("tagging should be empty when there's no tag")
+	}
+}
@@ -83,7 +83,7 @@ func (f SpotFleet) WithDefaults() SpotFleet {
 
 func (f SpotFleet) IAMFleetRoleRef() string {
 	if f.IAMFleetRoleARN == "" {
-		return `{"Fn::Join":["", [ "arn:aws:iam::", {"Ref":"AWS::AccountId"}, ":role/aws-ec2-spot-fleet-role" ]]}`
+		return `{"Fn::Join":["", [ "arn:", {"Ref":"AWS::Partition"}, ":iam::", {"Ref":"AWS::AccountId"}, ":role/aws-ec2-spot-fleet-role" ]]}`
 	} else {
 		return f.IAMFleetRoleARN
 	}
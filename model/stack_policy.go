@@ -0,0 +1,162 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const stackPolicyResourcePrefix = "LogicalResourceId/"
+
+// StackPolicy is a CloudFormation stack policy which prevents resources in a stack from being unintentionally updated,
+// replaced or deleted. See http://docs.aws.amazon.com/AWSCloudFormation/latest/UserGuide/protect-stack-resources.html
+type StackPolicy struct {
+	Statements  []StackPolicyStatement `yaml:"statements,omitempty" json:"Statement"`
+	UnknownKeys `yaml:",inline" json:"-"`
+}
+
+type StackPolicyStatement struct {
+	Effect string   `yaml:"effect" json:"Effect"`
+	Action []string `yaml:"action" json:"Action"`
+	// Principal defaults to "*", the only value CloudFormation accepts
+	Principal   string                         `yaml:"principal,omitempty" json:"Principal"`
+	Resource    []string                       `yaml:"resource,omitempty" json:"Resource,omitempty"`
+	NotResource []string                       `yaml:"notResource,omitempty" json:"NotResource,omitempty"`
+	Condition   map[string]map[string][]string `yaml:"condition,omitempty" json:"Condition,omitempty"`
+}
+
+// NewAllowAllStackPolicy returns a stack policy which allows any update to any resource
+func NewAllowAllStackPolicy() StackPolicy {
+	return StackPolicy{
+		Statements: []StackPolicyStatement{
+			{Effect: "Allow", Action: []string{"Update:*"}, Resource: []string{"*"}},
+		},
+	}
+}
+
+// NewStackPolicyProtecting returns a stack policy which denies replacing and deleting the resources while allowing any
+// other update
+func NewStackPolicyProtecting(logicalIDs []string) StackPolicy {
+	if len(logicalIDs) == 0 {
+		return NewAllowAllStackPolicy()
+	}
+	resources := []string{}
+	for _, id := range logicalIDs {
+		resources = append(resources, stackPolicyResourcePrefix+id)
+	}
+	return StackPolicy{
+		Statements: append(
+			[]StackPolicyStatement{
+				{Effect: "Deny", Action: []string{"Update:Replace", "Update:Delete"}, Resource: resources},
+			},
+			NewAllowAllStackPolicy().Statements...,
+		),
+	}
+}
+
+func (p StackPolicy) Valid() error {
+	if len(p.Statements) == 0 {
+		return fmt.Errorf("`stackPolicy.statements` must contain at least one statement")
+	}
+	for i, s := range p.Statements {
+		if s.Effect != "Allow" && s.Effect != "Deny" {
+			return fmt.Errorf("`stackPolicy.statements[%d].effect` must be either Allow or Deny but was %q", i, s.Effect)
+		}
+		if len(s.Action) == 0 {
+			return fmt.Errorf("`stackPolicy.statements[%d].action` must not be empty", i)
+		}
+		if (len(s.Resource) == 0) == (len(s.NotResource) == 0) {
+			return fmt.Errorf("`stackPolicy.statements[%d]` must have exactly one of `resource` or `notResource`", i)
+		}
+		if s.Principal != "" && s.Principal != "*" {
+			return fmt.Errorf("`stackPolicy.statements[%d].principal` must be \"*\" if specified but was %q", i, s.Principal)
+		}
+	}
+	return nil
+}
+
+// Body returns the JSON document of the stack policy to be passed to CloudFormation
+func (p StackPolicy) Body() string {
+	statements := []StackPolicyStatement{}
+	for _, s := range p.Statements {
+		if s.Principal == "" {
+			s.Principal = "*"
+		}
+		statements = append(statements, s)
+	}
+	p.Statements = statements
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		// Never happens as the stack policy consists only of strings
+		panic(fmt.Sprintf("[bug] failed to marshal stack policy: %v", err))
+	}
+	return string(data)
+}
+
+// AllowingUpdatesTo returns a copy of the stack policy which allows any update to the resources.
+// Deny statements are modified so that they no longer cover the resources. An error is returned when a deny statement
+// covers any of them with a wildcard, which can't be narrowed down
+func (p StackPolicy) AllowingUpdatesTo(logicalIDs []string) (StackPolicy, error) {
+	if len(logicalIDs) == 0 {
+		return p, nil
+	}
+
+	allowed := []string{}
+	for _, id := range logicalIDs {
+		allowed = append(allowed, stackPolicyResourcePrefix+id)
+	}
+
+	statements := []StackPolicyStatement{}
+	for _, s := range p.Statements {
+		if s.Effect != "Deny" {
+			statements = append(statements, s)
+			continue
+		}
+
+		if len(s.NotResource) > 0 {
+			s.NotResource = append(append([]string{}, s.NotResource...), allowed...)
+			statements = append(statements, s)
+			continue
+		}
+
+		resources := []string{}
+		for _, r := range s.Resource {
+			if !containsString(allowed, r) {
+				for _, a := range allowed {
+					if r != a && stackPolicyResourceMatches(r, a) {
+						return StackPolicy{}, fmt.Errorf("%s can't be allowed to be updated because it is denied by the wildcard resource %q in the stack policy. List logical IDs explicitly in the stack policy instead", strings.TrimPrefix(a, stackPolicyResourcePrefix), r)
+					}
+				}
+				resources = append(resources, r)
+			}
+		}
+		if len(resources) > 0 {
+			s.Resource = resources
+			statements = append(statements, s)
+		}
+	}
+
+	statements = append(statements, StackPolicyStatement{Effect: "Allow", Action: []string{"Update:*"}, Resource: allowed})
+
+	return StackPolicy{Statements: statements}, nil
+}
+
+func stackPolicyResourceMatches(pattern string, resource string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(resource, strings.TrimSuffix(pattern, "*"))
+	}
+	return pattern == resource
+}
+
+func containsString(strs []string, s string) bool {
+	for _, x := range strs {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
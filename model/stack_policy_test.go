@@ -0,0 +1,85 @@
+package model
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStackPolicyBody(t *testing.T) {
+	p := NewStackPolicyProtecting([]string{"Etcd0"})
+
+	var doc map[string][]map[string]interface{}
+	if err := json.Unmarshal([]byte(p.Body()), &doc); err != nil {
+		t.Fatalf("stack policy body should be valid json but was not: %v", err)
+	}
+
+	statements := doc["Statement"]
+	if len(statements) != 2 {
+		t.Fatalf("stack policy should have 2 statements but had %d: %s", len(statements), p.Body())
+	}
+	if statements[0]["Effect"] != "Deny" || statements[0]["Principal"] != "*" {
+		t.Errorf("unexpected first statement: %+v", statements[0])
+	}
+	if !reflect.DeepEqual(statements[0]["Resource"], []interface{}{"LogicalResourceId/Etcd0"}) {
+		t.Errorf("unexpected resources in the first statement: %+v", statements[0]["Resource"])
+	}
+}
+
+func TestStackPolicyValid(t *testing.T) {
+	if err := NewStackPolicyProtecting([]string{"Etcd0"}).Valid(); err != nil {
+		t.Errorf("expected the stack policy to be valid but was not: %v", err)
+	}
+
+	invalid := []StackPolicy{
+		{},
+		{Statements: []StackPolicyStatement{{Effect: "Permit", Action: []string{"Update:*"}, Resource: []string{"*"}}}},
+		{Statements: []StackPolicyStatement{{Effect: "Allow", Resource: []string{"*"}}}},
+		{Statements: []StackPolicyStatement{{Effect: "Allow", Action: []string{"Update:*"}}}},
+		{Statements: []StackPolicyStatement{{Effect: "Allow", Action: []string{"Update:*"}, Resource: []string{"*"}, Principal: "me"}}},
+	}
+	for _, p := range invalid {
+		if err := p.Valid(); err == nil {
+			t.Errorf("expected an error for the invalid stack policy %+v", p)
+		}
+	}
+}
+
+func TestStackPolicyAllowingUpdatesTo(t *testing.T) {
+	p := NewStackPolicyProtecting([]string{"Etcd0", "Etcd0EBS"})
+
+	allowed, err := p.AllowingUpdatesTo([]string{"Etcd0EBS"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deny := allowed.Statements[0]
+	if deny.Effect != "Deny" || !reflect.DeepEqual(deny.Resource, []string{"LogicalResourceId/Etcd0"}) {
+		t.Errorf("the deny statement should no longer cover Etcd0EBS: %+v", deny)
+	}
+	last := allowed.Statements[len(allowed.Statements)-1]
+	if last.Effect != "Allow" || !reflect.DeepEqual(last.Resource, []string{"LogicalResourceId/Etcd0EBS"}) {
+		t.Errorf("the last statement should allow updating Etcd0EBS: %+v", last)
+	}
+	if !reflect.DeepEqual(p.Statements[0].Resource, []string{"LogicalResourceId/Etcd0", "LogicalResourceId/Etcd0EBS"}) {
+		t.Errorf("the original stack policy should not be modified: %+v", p.Statements[0])
+	}
+
+	notResource := StackPolicy{Statements: []StackPolicyStatement{
+		{Effect: "Deny", Action: []string{"Update:Delete"}, NotResource: []string{"LogicalResourceId/Workers"}},
+	}}
+	allowed, err = notResource.AllowingUpdatesTo([]string{"Etcd0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(allowed.Statements[0].NotResource, []string{"LogicalResourceId/Workers", "LogicalResourceId/Etcd0"}) {
+		t.Errorf("Etcd0 should be excluded from the deny statement: %+v", allowed.Statements[0])
+	}
+
+	wildcard := StackPolicy{Statements: []StackPolicyStatement{
+		{Effect: "Deny", Action: []string{"Update:Replace"}, Resource: []string{"LogicalResourceId/Etcd*"}},
+	}}
+	if _, err := wildcard.AllowingUpdatesTo([]string{"Etcd0"}); err == nil {
+		t.Errorf("expected an error for a resource denied by a wildcard")
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/coreos/kube-aws/tlsutil"
+)
+
+// TLS configures the TLS assets generated by kube-aws render credentials and kube-aws certs rotate
+type TLS struct {
+	// KeyAlgorithm is the algorithm of the private keys of the CA and the certificates signed by it
+	KeyAlgorithm string `yaml:"keyAlgorithm,omitempty"`
+	UnknownKeys  `yaml:",inline"`
+}
+
+func NewDefaultTLS() TLS {
+	return TLS{KeyAlgorithm: tlsutil.DefaultKeyAlgorithm}
+}
+
+func (t TLS) Valid() error {
+	for _, a := range tlsutil.KeyAlgorithms {
+		if t.KeyAlgorithm == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("`tls.keyAlgorithm` must be one of %v but was %q", tlsutil.KeyAlgorithms, t.KeyAlgorithm)
+}
@@ -0,0 +1,387 @@
+package pricing
+
+// Default is the bundled price table. Update Version along with prices
+var Default = Table{
+	Version:  "2017-10-01",
+	Currency: "USD",
+	Regions: map[string]RegionPrices{
+		"us-east-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0058,
+				"t2.micro":    0.0116,
+				"t2.small":    0.0232,
+				"t2.medium":   0.0464,
+				"t2.large":    0.0928,
+				"t2.xlarge":   0.1856,
+				"t2.2xlarge":  0.3712,
+				"m3.medium":   0.067,
+				"m3.large":    0.134,
+				"m3.xlarge":   0.268,
+				"m3.2xlarge":  0.536,
+				"m4.large":    0.1,
+				"m4.xlarge":   0.2,
+				"m4.2xlarge":  0.4,
+				"m4.4xlarge":  0.8,
+				"m4.10xlarge": 2.0,
+				"m4.16xlarge": 3.2,
+				"c4.large":    0.1,
+				"c4.xlarge":   0.2,
+				"c4.2xlarge":  0.4,
+				"c4.4xlarge":  0.8,
+				"c4.8xlarge":  1.6,
+				"r4.large":    0.133,
+				"r4.xlarge":   0.266,
+				"r4.2xlarge":  0.532,
+				"r4.4xlarge":  1.064,
+				"r4.8xlarge":  2.128,
+				"r4.16xlarge": 4.256,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.1},
+				"io1":      {GBMonthly: 0.125, IOPSMonthly: 0.065},
+				"sc1":      {GBMonthly: 0.025},
+				"st1":      {GBMonthly: 0.045},
+				"standard": {GBMonthly: 0.05},
+			},
+			LoadBalancerHourly: 0.025,
+			NATGatewayHourly:   0.045,
+			ElasticIPHourly:    0.005,
+		},
+		"us-east-2": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0058,
+				"t2.micro":    0.0116,
+				"t2.small":    0.0232,
+				"t2.medium":   0.0464,
+				"t2.large":    0.0928,
+				"t2.xlarge":   0.1856,
+				"t2.2xlarge":  0.3712,
+				"m3.medium":   0.067,
+				"m3.large":    0.134,
+				"m3.xlarge":   0.268,
+				"m3.2xlarge":  0.536,
+				"m4.large":    0.1,
+				"m4.xlarge":   0.2,
+				"m4.2xlarge":  0.4,
+				"m4.4xlarge":  0.8,
+				"m4.10xlarge": 2.0,
+				"m4.16xlarge": 3.2,
+				"c4.large":    0.1,
+				"c4.xlarge":   0.2,
+				"c4.2xlarge":  0.4,
+				"c4.4xlarge":  0.8,
+				"c4.8xlarge":  1.6,
+				"r4.large":    0.133,
+				"r4.xlarge":   0.266,
+				"r4.2xlarge":  0.532,
+				"r4.4xlarge":  1.064,
+				"r4.8xlarge":  2.128,
+				"r4.16xlarge": 4.256,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.1},
+				"io1":      {GBMonthly: 0.125, IOPSMonthly: 0.065},
+				"sc1":      {GBMonthly: 0.025},
+				"st1":      {GBMonthly: 0.045},
+				"standard": {GBMonthly: 0.05},
+			},
+			LoadBalancerHourly: 0.025,
+			NATGatewayHourly:   0.045,
+			ElasticIPHourly:    0.005,
+		},
+		"us-west-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0069,
+				"t2.micro":    0.0138,
+				"t2.small":    0.0276,
+				"t2.medium":   0.0552,
+				"t2.large":    0.1104,
+				"t2.xlarge":   0.2208,
+				"t2.2xlarge":  0.4416,
+				"m3.medium":   0.077,
+				"m3.large":    0.154,
+				"m3.xlarge":   0.308,
+				"m3.2xlarge":  0.616,
+				"m4.large":    0.117,
+				"m4.xlarge":   0.234,
+				"m4.2xlarge":  0.468,
+				"m4.4xlarge":  0.936,
+				"m4.10xlarge": 2.34,
+				"m4.16xlarge": 3.744,
+				"c4.large":    0.124,
+				"c4.xlarge":   0.248,
+				"c4.2xlarge":  0.496,
+				"c4.4xlarge":  0.992,
+				"c4.8xlarge":  1.984,
+				"r4.large":    0.148,
+				"r4.xlarge":   0.296,
+				"r4.2xlarge":  0.592,
+				"r4.4xlarge":  1.184,
+				"r4.8xlarge":  2.368,
+				"r4.16xlarge": 4.736,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.12},
+				"io1":      {GBMonthly: 0.138, IOPSMonthly: 0.072},
+				"sc1":      {GBMonthly: 0.03},
+				"st1":      {GBMonthly: 0.054},
+				"standard": {GBMonthly: 0.08},
+			},
+			LoadBalancerHourly: 0.028,
+			NATGatewayHourly:   0.048,
+			ElasticIPHourly:    0.005,
+		},
+		"us-west-2": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0058,
+				"t2.micro":    0.0116,
+				"t2.small":    0.0232,
+				"t2.medium":   0.0464,
+				"t2.large":    0.0928,
+				"t2.xlarge":   0.1856,
+				"t2.2xlarge":  0.3712,
+				"m3.medium":   0.067,
+				"m3.large":    0.134,
+				"m3.xlarge":   0.268,
+				"m3.2xlarge":  0.536,
+				"m4.large":    0.1,
+				"m4.xlarge":   0.2,
+				"m4.2xlarge":  0.4,
+				"m4.4xlarge":  0.8,
+				"m4.10xlarge": 2.0,
+				"m4.16xlarge": 3.2,
+				"c4.large":    0.1,
+				"c4.xlarge":   0.2,
+				"c4.2xlarge":  0.4,
+				"c4.4xlarge":  0.8,
+				"c4.8xlarge":  1.6,
+				"r4.large":    0.133,
+				"r4.xlarge":   0.266,
+				"r4.2xlarge":  0.532,
+				"r4.4xlarge":  1.064,
+				"r4.8xlarge":  2.128,
+				"r4.16xlarge": 4.256,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.1},
+				"io1":      {GBMonthly: 0.125, IOPSMonthly: 0.065},
+				"sc1":      {GBMonthly: 0.025},
+				"st1":      {GBMonthly: 0.045},
+				"standard": {GBMonthly: 0.05},
+			},
+			LoadBalancerHourly: 0.025,
+			NATGatewayHourly:   0.045,
+			ElasticIPHourly:    0.005,
+		},
+		"eu-west-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0063,
+				"t2.micro":    0.0125,
+				"t2.small":    0.025,
+				"t2.medium":   0.05,
+				"t2.large":    0.1,
+				"t2.xlarge":   0.2,
+				"t2.2xlarge":  0.4,
+				"m3.medium":   0.073,
+				"m3.large":    0.146,
+				"m3.xlarge":   0.292,
+				"m3.2xlarge":  0.584,
+				"m4.large":    0.111,
+				"m4.xlarge":   0.222,
+				"m4.2xlarge":  0.444,
+				"m4.4xlarge":  0.888,
+				"m4.10xlarge": 2.22,
+				"m4.16xlarge": 3.552,
+				"c4.large":    0.113,
+				"c4.xlarge":   0.226,
+				"c4.2xlarge":  0.452,
+				"c4.4xlarge":  0.904,
+				"c4.8xlarge":  1.808,
+				"r4.large":    0.148,
+				"r4.xlarge":   0.296,
+				"r4.2xlarge":  0.592,
+				"r4.4xlarge":  1.184,
+				"r4.8xlarge":  2.368,
+				"r4.16xlarge": 4.736,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.11},
+				"io1":      {GBMonthly: 0.138, IOPSMonthly: 0.072},
+				"sc1":      {GBMonthly: 0.028},
+				"st1":      {GBMonthly: 0.05},
+				"standard": {GBMonthly: 0.055},
+			},
+			LoadBalancerHourly: 0.028,
+			NATGatewayHourly:   0.048,
+			ElasticIPHourly:    0.005,
+		},
+		"eu-central-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0067,
+				"t2.micro":    0.0134,
+				"t2.small":    0.0268,
+				"t2.medium":   0.0536,
+				"t2.large":    0.1072,
+				"t2.xlarge":   0.2144,
+				"t2.2xlarge":  0.4288,
+				"m3.medium":   0.079,
+				"m3.large":    0.158,
+				"m3.xlarge":   0.316,
+				"m3.2xlarge":  0.632,
+				"m4.large":    0.12,
+				"m4.xlarge":   0.24,
+				"m4.2xlarge":  0.48,
+				"m4.4xlarge":  0.96,
+				"m4.10xlarge": 2.4,
+				"m4.16xlarge": 3.84,
+				"c4.large":    0.114,
+				"c4.xlarge":   0.228,
+				"c4.2xlarge":  0.456,
+				"c4.4xlarge":  0.912,
+				"c4.8xlarge":  1.824,
+				"r4.large":    0.16,
+				"r4.xlarge":   0.32,
+				"r4.2xlarge":  0.64,
+				"r4.4xlarge":  1.28,
+				"r4.8xlarge":  2.56,
+				"r4.16xlarge": 5.12,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.119},
+				"io1":      {GBMonthly: 0.149, IOPSMonthly: 0.078},
+				"sc1":      {GBMonthly: 0.03},
+				"st1":      {GBMonthly: 0.054},
+				"standard": {GBMonthly: 0.059},
+			},
+			LoadBalancerHourly: 0.03,
+			NATGatewayHourly:   0.052,
+			ElasticIPHourly:    0.005,
+		},
+		"ap-northeast-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0076,
+				"t2.micro":    0.0152,
+				"t2.small":    0.0304,
+				"t2.medium":   0.0608,
+				"t2.large":    0.1216,
+				"t2.xlarge":   0.2432,
+				"t2.2xlarge":  0.4864,
+				"m3.medium":   0.096,
+				"m3.large":    0.192,
+				"m3.xlarge":   0.384,
+				"m3.2xlarge":  0.768,
+				"m4.large":    0.129,
+				"m4.xlarge":   0.258,
+				"m4.2xlarge":  0.516,
+				"m4.4xlarge":  1.032,
+				"m4.10xlarge": 2.58,
+				"m4.16xlarge": 4.128,
+				"c4.large":    0.126,
+				"c4.xlarge":   0.252,
+				"c4.2xlarge":  0.504,
+				"c4.4xlarge":  1.008,
+				"c4.8xlarge":  2.016,
+				"r4.large":    0.16,
+				"r4.xlarge":   0.32,
+				"r4.2xlarge":  0.64,
+				"r4.4xlarge":  1.28,
+				"r4.8xlarge":  2.56,
+				"r4.16xlarge": 5.12,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.12},
+				"io1":      {GBMonthly: 0.142, IOPSMonthly: 0.074},
+				"sc1":      {GBMonthly: 0.03},
+				"st1":      {GBMonthly: 0.054},
+				"standard": {GBMonthly: 0.08},
+			},
+			LoadBalancerHourly: 0.027,
+			NATGatewayHourly:   0.062,
+			ElasticIPHourly:    0.005,
+		},
+		"ap-southeast-1": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0073,
+				"t2.micro":    0.0146,
+				"t2.small":    0.0292,
+				"t2.medium":   0.0584,
+				"t2.large":    0.1168,
+				"t2.xlarge":   0.2336,
+				"t2.2xlarge":  0.4672,
+				"m3.medium":   0.098,
+				"m3.large":    0.196,
+				"m3.xlarge":   0.392,
+				"m3.2xlarge":  0.784,
+				"m4.large":    0.125,
+				"m4.xlarge":   0.25,
+				"m4.2xlarge":  0.5,
+				"m4.4xlarge":  1.0,
+				"m4.10xlarge": 2.5,
+				"m4.16xlarge": 4.0,
+				"c4.large":    0.115,
+				"c4.xlarge":   0.23,
+				"c4.2xlarge":  0.46,
+				"c4.4xlarge":  0.92,
+				"c4.8xlarge":  1.84,
+				"r4.large":    0.16,
+				"r4.xlarge":   0.32,
+				"r4.2xlarge":  0.64,
+				"r4.4xlarge":  1.28,
+				"r4.8xlarge":  2.56,
+				"r4.16xlarge": 5.12,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.12},
+				"io1":      {GBMonthly: 0.138, IOPSMonthly: 0.072},
+				"sc1":      {GBMonthly: 0.03},
+				"st1":      {GBMonthly: 0.054},
+				"standard": {GBMonthly: 0.08},
+			},
+			LoadBalancerHourly: 0.028,
+			NATGatewayHourly:   0.059,
+			ElasticIPHourly:    0.005,
+		},
+		"ap-southeast-2": {
+			InstanceHourly: map[string]float64{
+				"t2.nano":     0.0073,
+				"t2.micro":    0.0146,
+				"t2.small":    0.0292,
+				"t2.medium":   0.0584,
+				"t2.large":    0.1168,
+				"t2.xlarge":   0.2336,
+				"t2.2xlarge":  0.4672,
+				"m3.medium":   0.093,
+				"m3.large":    0.186,
+				"m3.xlarge":   0.372,
+				"m3.2xlarge":  0.744,
+				"m4.large":    0.125,
+				"m4.xlarge":   0.25,
+				"m4.2xlarge":  0.5,
+				"m4.4xlarge":  1.0,
+				"m4.10xlarge": 2.5,
+				"m4.16xlarge": 4.0,
+				"c4.large":    0.13,
+				"c4.xlarge":   0.26,
+				"c4.2xlarge":  0.52,
+				"c4.4xlarge":  1.04,
+				"c4.8xlarge":  2.08,
+				"r4.large":    0.16,
+				"r4.xlarge":   0.32,
+				"r4.2xlarge":  0.64,
+				"r4.4xlarge":  1.28,
+				"r4.8xlarge":  2.56,
+				"r4.16xlarge": 5.12,
+			},
+			Volumes: map[string]VolumePrices{
+				"gp2":      {GBMonthly: 0.12},
+				"io1":      {GBMonthly: 0.138, IOPSMonthly: 0.072},
+				"sc1":      {GBMonthly: 0.03},
+				"st1":      {GBMonthly: 0.054},
+				"standard": {GBMonthly: 0.08},
+			},
+			LoadBalancerHourly: 0.028,
+			NATGatewayHourly:   0.059,
+			ElasticIPHourly:    0.005,
+		},
+	},
+}
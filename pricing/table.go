@@ -0,0 +1,85 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// HoursPerMonth is the number of hours AWS uses to convert hourly prices to monthly ones
+const HoursPerMonth = 730
+
+// Table is a snapshot of on-demand AWS prices per region
+type Table struct {
+	// Version identifies the snapshot e.g. the date prices were taken from AWS price lists
+	Version  string                  `json:"version"`
+	Currency string                  `json:"currency"`
+	Regions  map[string]RegionPrices `json:"regions"`
+}
+
+// RegionPrices are prices of resources kube-aws creates in a region
+type RegionPrices struct {
+	// InstanceHourly is the hourly on-demand price of a Linux instance per instance type
+	InstanceHourly map[string]float64 `json:"instanceHourly"`
+	// Volumes are monthly prices of EBS volumes per volume type
+	Volumes map[string]VolumePrices `json:"volumes"`
+	// LoadBalancerHourly is the hourly price of a classic ELB excluding the data it processes
+	LoadBalancerHourly float64 `json:"loadBalancerHourly"`
+	// NATGatewayHourly is the hourly price of a NAT gateway excluding the data it processes
+	NATGatewayHourly float64 `json:"natGatewayHourly"`
+	// ElasticIPHourly is charged only while an EIP isn't associated with a running instance
+	ElasticIPHourly float64 `json:"elasticIpHourly"`
+}
+
+// VolumePrices are monthly prices of an EBS volume type
+type VolumePrices struct {
+	GBMonthly float64 `json:"gbMonthly"`
+	// IOPSMonthly is the price of provisioned IOPS, which is zero for volume types without provisioned IOPS
+	IOPSMonthly float64 `json:"iopsMonthly"`
+}
+
+// TableFromFile reads a price table from a JSON file, which has the same structure as Table
+func TableFromFile(path string) (Table, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Table{}, fmt.Errorf("failed to read price table %s: %v", path, err)
+	}
+	var t Table
+	if err := json.Unmarshal(data, &t); err != nil {
+		return Table{}, fmt.Errorf("failed to parse price table %s: %v", path, err)
+	}
+	if t.Version == "" {
+		return Table{}, fmt.Errorf("price table %s has no version", path)
+	}
+	if t.Currency == "" {
+		t.Currency = "USD"
+	}
+	return t, nil
+}
+
+// Region returns prices in the region
+func (t Table) Region(name string) (RegionPrices, error) {
+	p, ok := t.Regions[name]
+	if !ok {
+		return RegionPrices{}, fmt.Errorf("no prices for the region %s in the price table %s", name, t.Version)
+	}
+	return p, nil
+}
+
+// Instance returns the hourly price of an instance of the type
+func (p RegionPrices) Instance(instanceType string) (float64, error) {
+	price, ok := p.InstanceHourly[instanceType]
+	if !ok {
+		return 0, fmt.Errorf("no price for the instance type %s in the price table", instanceType)
+	}
+	return price, nil
+}
+
+// Volume returns the monthly price of an EBS volume
+func (p RegionPrices) Volume(volumeType string, sizeGB int, iops int) (float64, error) {
+	price, ok := p.Volumes[volumeType]
+	if !ok {
+		return 0, fmt.Errorf("no price for the volume type %s in the price table", volumeType)
+	}
+	return float64(sizeGB)*price.GBMonthly + float64(iops)*price.IOPSMonthly, nil
+}
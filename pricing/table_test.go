@@ -0,0 +1,73 @@
+package pricing
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	for name, region := range Default.Regions {
+		for _, typ := range []string{"t2.medium", "m4.large", "c4.large", "c4.xlarge"} {
+			if _, err := region.Instance(typ); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+		for _, typ := range []string{"gp2", "io1", "standard"} {
+			if _, err := region.Volume(typ, 30, 0); err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+}
+
+func TestTableFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-aws-pricing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "prices.json")
+	body := `{
+  "version": "2017-11-01",
+  "regions": {
+    "us-west-1": {
+      "instanceHourly": {"m4.large": 0.117},
+      "volumes": {"io1": {"gbMonthly": 0.138, "iopsMonthly": 0.072}}
+    }
+  }
+}`
+	if err := ioutil.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := TableFromFile(path)
+	if err != nil {
+		t.Fatalf("failed to read price table: %v", err)
+	}
+	if table.Version != "2017-11-01" || table.Currency != "USD" {
+		t.Errorf("unexpected version or currency: %+v", table)
+	}
+	region, err := table.Region("us-west-1")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	if price, err := region.Volume("io1", 100, 1000); err != nil || price != 13.8+72 {
+		t.Errorf("unexpected volume price: %v %v", price, err)
+	}
+	if _, err := region.Instance("t2.medium"); err == nil {
+		t.Error("expected an error for the instance type missing in the table")
+	}
+	if _, err := table.Region("eu-west-1"); err == nil {
+		t.Error("expected an error for the region missing in the table")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"regions": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TableFromFile(path); err == nil {
+		t.Error("expected an error for a price table without version")
+	}
+}
@@ -0,0 +1,85 @@
+package fakeaws
+
+import (
+	"fmt"
+	"hash/fnv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+)
+
+// AutoScaling describes autoscaling groups and launch configurations created by CloudFormation. Every group has as
+// many healthy instances as its desired capacity
+type AutoScaling struct {
+	b *Backend
+}
+
+func (a *AutoScaling) DescribeAutoScalingGroups(input *autoscaling.DescribeAutoScalingGroupsInput) (*autoscaling.DescribeAutoScalingGroupsOutput, error) {
+	a.b.mu.Lock()
+	defer a.b.mu.Unlock()
+
+	names := aws.StringValueSlice(input.AutoScalingGroupNames)
+	out := &autoscaling.DescribeAutoScalingGroupsOutput{AutoScalingGroups: []*autoscaling.Group{}}
+	for _, r := range a.b.CloudFormation.liveResources("AWS::AutoScaling::AutoScalingGroup") {
+		if len(names) > 0 && !contains(names, r.physicalID) {
+			continue
+		}
+		min := intProperty(r.properties, "MinSize")
+		max := intProperty(r.properties, "MaxSize")
+		desired := min
+		if _, ok := r.properties["DesiredCapacity"]; ok {
+			desired = intProperty(r.properties, "DesiredCapacity")
+		}
+		zones := stringsProperty(r.properties, "AvailabilityZones")
+		if len(zones) == 0 {
+			zones = []string{a.b.Region + "a"}
+		}
+		lc, _ := r.properties["LaunchConfigurationName"].(string)
+		h := fnv.New32a()
+		h.Write([]byte(r.physicalID))
+		instances := []*autoscaling.Instance{}
+		for i := int64(0); i < desired; i++ {
+			instances = append(instances, &autoscaling.Instance{
+				InstanceId:              aws.String(fmt.Sprintf("i-%08x%04d", h.Sum32(), i)),
+				AvailabilityZone:        aws.String(zones[int(i)%len(zones)]),
+				LifecycleState:          aws.String(autoscaling.LifecycleStateInService),
+				HealthStatus:            aws.String("Healthy"),
+				LaunchConfigurationName: stringOrNil(lc),
+			})
+		}
+		out.AutoScalingGroups = append(out.AutoScalingGroups, &autoscaling.Group{
+			AutoScalingGroupName:    aws.String(r.physicalID),
+			MinSize:                 aws.Int64(min),
+			MaxSize:                 aws.Int64(max),
+			DesiredCapacity:         aws.Int64(desired),
+			LaunchConfigurationName: stringOrNil(lc),
+			AvailabilityZones:       aws.StringSlice(zones),
+			Instances:               instances,
+			CreatedTime:             aws.Time(r.updated),
+		})
+	}
+	return out, nil
+}
+
+func (a *AutoScaling) DescribeLaunchConfigurations(input *autoscaling.DescribeLaunchConfigurationsInput) (*autoscaling.DescribeLaunchConfigurationsOutput, error) {
+	a.b.mu.Lock()
+	defer a.b.mu.Unlock()
+
+	names := aws.StringValueSlice(input.LaunchConfigurationNames)
+	out := &autoscaling.DescribeLaunchConfigurationsOutput{LaunchConfigurations: []*autoscaling.LaunchConfiguration{}}
+	for _, r := range a.b.CloudFormation.liveResources("AWS::AutoScaling::LaunchConfiguration") {
+		if len(names) > 0 && !contains(names, r.physicalID) {
+			continue
+		}
+		instanceType, _ := r.properties["InstanceType"].(string)
+		imageID, _ := r.properties["ImageId"].(string)
+		out.LaunchConfigurations = append(out.LaunchConfigurations, &autoscaling.LaunchConfiguration{
+			LaunchConfigurationName: aws.String(r.physicalID),
+			InstanceType:            aws.String(instanceType),
+			ImageId:                 aws.String(imageID),
+			SecurityGroups:          aws.StringSlice(stringsProperty(r.properties, "SecurityGroups")),
+			CreatedTime:             aws.Time(r.updated),
+		})
+	}
+	return out, nil
+}
@@ -0,0 +1,225 @@
+// Package fakeaws is an in-memory fake of the AWS APIs used by kube-aws. It lets tests drive kube-aws end to end e.g.
+// create, update and destroy a cluster, and then assert on stack events, stored objects and what is left behind,
+// without access to AWS.
+//
+// Each service is a struct whose methods have the same signatures as the ones of the aws-sdk-go clients so that it can
+// be passed where kube-aws accepts an interface e.g. cfnstack.CRUDService. Sessions created via awsconn are served by
+// the backend while it is activated, so that code creating clients from sessions works unmodified
+package fakeaws
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/coreos/kube-aws/awsconn"
+)
+
+const DefaultAccountID = "123456789012"
+
+// Backend holds the state of all the fake services. Services share the state e.g. CloudFormation reads templates
+// uploaded to S3 and EC2 reports resources created by CloudFormation
+type Backend struct {
+	CloudFormation *CloudFormation
+	S3             *S3
+	EC2            *EC2
+	ELB            *ELB
+	AutoScaling    *AutoScaling
+	Route53        *Route53
+	KMS            *KMS
+
+	Region    string
+	AccountID string
+
+	mu       sync.Mutex
+	lastTime time.Time
+	lastID   int
+}
+
+// New returns an empty backend for the region
+func New(region string) *Backend {
+	b := &Backend{
+		Region:    region,
+		AccountID: DefaultAccountID,
+	}
+	b.CloudFormation = newCloudFormation(b)
+	b.S3 = newS3(b)
+	b.EC2 = newEC2(b)
+	b.ELB = &ELB{b: b}
+	b.AutoScaling = &AutoScaling{b: b}
+	b.Route53 = newRoute53(b)
+	b.KMS = &KMS{b: b}
+	return b
+}
+
+// Activate makes every session created via awsconn served by the backend until the returned function is called
+func (b *Backend) Activate() (deactivate func()) {
+	awsconn.SetSessionHook(b.Install)
+	return func() {
+		awsconn.SetSessionHook(nil)
+	}
+}
+
+// Install makes AWS API calls made via clients created from the session served by the backend.
+// Nothing is signed nor sent over the network
+func (b *Backend) Install(s *session.Session) {
+	s.Handlers.Validate.PushFrontNamed(request.NamedHandler{Name: "fakeaws.Intercept", Fn: b.intercept})
+}
+
+// intercept replaces the handlers of the request which build, sign, send and unmarshal it with the one serving it from
+// the backend. Parameters are still validated as usual
+func (b *Backend) intercept(r *request.Request) {
+	for _, l := range []*request.HandlerList{
+		&r.Handlers.Build,
+		&r.Handlers.Sign,
+		&r.Handlers.Send,
+		&r.Handlers.UnmarshalMeta,
+		&r.Handlers.ValidateResponse,
+		&r.Handlers.Unmarshal,
+		&r.Handlers.UnmarshalError,
+	} {
+		l.Clear()
+	}
+	r.Handlers.Send.PushBackNamed(request.NamedHandler{Name: "fakeaws.Serve", Fn: b.serve})
+}
+
+func (b *Backend) services() map[string]interface{} {
+	return map[string]interface{}{
+		"cloudformation":       b.CloudFormation,
+		"s3":                   b.S3,
+		"ec2":                  b.EC2,
+		"elasticloadbalancing": b.ELB,
+		"autoscaling":          b.AutoScaling,
+		"route53":              b.Route53,
+		"kms":                  b.KMS,
+	}
+}
+
+// serve calls the method of the fake service named after the operation, and then sets its output or error to the request
+func (b *Backend) serve(r *request.Request) {
+	svc, ok := b.services()[r.ClientInfo.ServiceName]
+	if !ok {
+		b.fail(r, newError(http.StatusNotImplemented, "NotImplemented", "service %s is not implemented by fakeaws", r.ClientInfo.ServiceName))
+		return
+	}
+	m := reflect.ValueOf(svc).MethodByName(r.Operation.Name)
+	// Requests for APIs newer than the vendored aws-sdk-go have their own output types. They're served by the method
+	// suffixed with "Latest" if any, and the output is copied to the request field by field
+	if latest := reflect.ValueOf(svc).MethodByName(r.Operation.Name + "Latest"); latest.IsValid() && m.IsValid() && m.Type().Out(0) != reflect.TypeOf(r.Data) {
+		m = latest
+	}
+	if !m.IsValid() {
+		b.fail(r, newError(http.StatusNotImplemented, "NotImplemented", "%s.%s is not implemented by fakeaws", r.ClientInfo.ServiceName, r.Operation.Name))
+		return
+	}
+	if err := verifyContentMD5(r); err != nil {
+		b.fail(r, err)
+		return
+	}
+
+	results := m.Call([]reflect.Value{reflect.ValueOf(r.Params)})
+	if err, ok := results[1].Interface().(error); ok && err != nil {
+		b.fail(r, err)
+		return
+	}
+	out := results[0]
+	if !out.IsNil() {
+		if err := setOutput(r.Data, out); err != nil {
+			b.fail(r, err)
+			return
+		}
+	}
+	r.HTTPResponse = &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: ioutil.NopCloser(&bytes.Buffer{})}
+}
+
+func setOutput(data interface{}, out reflect.Value) error {
+	if out.Type() == reflect.TypeOf(data) {
+		reflect.ValueOf(data).Elem().Set(out.Elem())
+		return nil
+	}
+	// Fields are matched by their names as the aws-sdk-go unmarshals responses
+	body, err := json.Marshal(out.Interface())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, data)
+}
+
+func (b *Backend) fail(r *request.Request, err error) {
+	status := http.StatusBadRequest
+	if f, ok := err.(awserr.RequestFailure); ok {
+		status = f.StatusCode()
+	}
+	r.Error = err
+	r.Retryable = newFalse()
+	r.HTTPResponse = &http.Response{StatusCode: status, Header: http.Header{}, Body: ioutil.NopCloser(&bytes.Buffer{})}
+}
+
+// verifyContentMD5 rejects a request whose body doesn't match the Content-MD5 header as S3 does
+func verifyContentMD5(r *request.Request) error {
+	expected := r.HTTPRequest.Header.Get("Content-MD5")
+	if expected == "" || r.Params == nil {
+		return nil
+	}
+	body := reflect.ValueOf(r.Params).Elem().FieldByName("Body")
+	if !body.IsValid() || body.IsNil() {
+		return nil
+	}
+	seeker, ok := body.Interface().(io.ReadSeeker)
+	if !ok {
+		return nil
+	}
+	data, err := ioutil.ReadAll(seeker)
+	if err != nil {
+		return err
+	}
+	if _, err := seeker.Seek(0, 0); err != nil {
+		return err
+	}
+	sum := md5.Sum(data)
+	if base64.StdEncoding.EncodeToString(sum[:]) != expected {
+		return newError(http.StatusBadRequest, "BadDigest", "The Content-MD5 you specified did not match what we received.")
+	}
+	return nil
+}
+
+// now returns the current time, which is strictly increasing among calls so that events are ordered even when they are
+// recorded in the same clock tick
+func (b *Backend) now() time.Time {
+	t := time.Now()
+	if !t.After(b.lastTime) {
+		t = b.lastTime.Add(time.Millisecond)
+	}
+	b.lastTime = t
+	return t
+}
+
+// nextID returns a string unique in the backend which is used to build IDs and names of resources
+func (b *Backend) nextID() string {
+	b.lastID++
+	return fmt.Sprintf("%012x", b.lastID)
+}
+
+func newError(status int, code string, format string, args ...interface{}) error {
+	return awserr.NewRequestFailure(awserr.New(code, fmt.Sprintf(format, args...), nil), status, "fakeaws")
+}
+
+func newFalse() *bool {
+	f := false
+	return &f
+}
+
+func reflectStruct(v interface{}) reflect.Value {
+	return reflect.Indirect(reflect.ValueOf(v))
+}
@@ -0,0 +1,1454 @@
+package fakeaws
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+)
+
+const (
+	templateBodySizeLimit = 51200
+	templateURLSizeLimit  = 460800
+)
+
+var stackNamePattern = regexp.MustCompile(`^[a-zA-Z][-a-zA-Z0-9]{0,127}$`)
+
+// CloudFormation simulates stacks including nested ones. Every resource other than a nested stack is created, updated
+// and deleted successfully unless a failure is injected with FailResource. Operations are applied immediately but
+// reported to be in progress for PollsToComplete calls to DescribeStacks on the stack, so that waiters can be tested
+type CloudFormation struct {
+	// PollsToComplete is the number of times DescribeStacks reports an operation in progress before it completes.
+	// Operations complete immediately when it is zero
+	PollsToComplete int
+
+	b          *Backend
+	stacks     []*stack
+	exports    map[string]export
+	failures   map[string]string
+	changeSets []*changeSet
+	// touched records the index of the first event of each stack recorded during the current operation
+	touched map[*stack]int
+
+	terminationProtectionUpdates int
+}
+
+type stack struct {
+	id     string
+	name   string
+	parent *stack
+
+	status string
+	reason string
+
+	stackState
+
+	tags                  []*cloudformation.Tag
+	capabilities          []*string
+	onFailure             string
+	policy                string
+	terminationProtection bool
+
+	events  []*cloudformation.StackEvent
+	created time.Time
+	updated *time.Time
+
+	// pending is set while the stack is reported to be in progress of an operation which has already been applied
+	pending *pendingOperation
+}
+
+// stackState is the part of a stack which is restored when an update is rolled back
+type stackState struct {
+	body           string
+	tmpl           *template
+	params         map[string]string
+	outputs        map[string]string
+	outputDescs    map[string]string
+	resources      map[string]*stackResource
+	resourceOrders []string
+}
+
+type stackResource struct {
+	logicalID  string
+	physicalID string
+	typ        string
+	status     string
+	reason     string
+	// properties are the properties with intrinsic functions resolved
+	properties map[string]interface{}
+	nested     *stack
+	updated    time.Time
+}
+
+type pendingOperation struct {
+	root          *stack
+	polls         int
+	status        map[*stack]string
+	visibleEvents map[*stack]int
+}
+
+type export struct {
+	stackID string
+	value   string
+}
+
+type changeSet struct {
+	id        string
+	name      string
+	stack     *stack
+	body      string
+	tmpl      *template
+	params    map[string]string
+	status    string
+	reason    string
+	changes   []*cloudformation.Change
+	execution string
+	created   time.Time
+}
+
+func newCloudFormation(b *Backend) *CloudFormation {
+	return &CloudFormation{
+		b:        b,
+		exports:  map[string]export{},
+		failures: map[string]string{},
+	}
+}
+
+// FailResource makes creations, updates and deletions of resources with the logical ID fail with the reason in any stack
+func (c *CloudFormation) FailResource(logicalID string, reason string) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	c.failures[logicalID] = reason
+}
+
+// ClearFailures removes all the failures injected with FailResource
+func (c *CloudFormation) ClearFailures() {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	c.failures = map[string]string{}
+}
+
+// LiveStackNames returns names of the stacks, including nested ones, which are not deleted yet
+func (c *CloudFormation) LiveStackNames() []string {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+	names := []string{}
+	for _, s := range c.stacks {
+		if s.status != cloudformation.StackStatusDeleteComplete {
+			names = append(names, s.name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *CloudFormation) CreateStack(input *cloudformation.CreateStackInput) (*cloudformation.CreateStackOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	name := aws.StringValue(input.StackName)
+	if !stackNamePattern.MatchString(name) {
+		return nil, validationError("1 validation error detected: Value '%s' at 'stackName' failed to satisfy constraint: Member must satisfy regular expression pattern: [a-zA-Z][-a-zA-Z0-9]*", name)
+	}
+	if s := c.findByName(name); s != nil {
+		return nil, newError(http.StatusBadRequest, "AlreadyExistsException", "Stack [%s] already exists", name)
+	}
+	body, tmpl, err := c.templateFrom(input.TemplateBody, input.TemplateURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireCapabilities(tmpl, input.Capabilities); err != nil {
+		return nil, err
+	}
+	params, err := resolveParameters(tmpl, input.Parameters, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	onFailure := aws.StringValue(input.OnFailure)
+	if onFailure == "" {
+		onFailure = cloudformation.OnFailureRollback
+		if aws.BoolValue(input.DisableRollback) {
+			onFailure = cloudformation.OnFailureDoNothing
+		}
+	}
+
+	s := c.newStack(name, nil)
+	s.tags = input.Tags
+	s.capabilities = input.Capabilities
+	s.onFailure = onFailure
+	s.policy = aws.StringValue(input.StackPolicyBody)
+	c.run(s, func() {
+		c.create(s, body, tmpl, params)
+	})
+
+	return &cloudformation.CreateStackOutput{StackId: aws.String(s.id)}, nil
+}
+
+func (c *CloudFormation) UpdateStack(input *cloudformation.UpdateStackInput) (*cloudformation.UpdateStackOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	if err := c.updatable(s); err != nil {
+		return nil, err
+	}
+
+	body, tmpl := s.body, s.tmpl
+	if !aws.BoolValue(input.UsePreviousTemplate) {
+		body, tmpl, err = c.templateFrom(input.TemplateBody, input.TemplateURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := requireCapabilities(tmpl, input.Capabilities); err != nil {
+		return nil, err
+	}
+	params, err := resolveParameters(tmpl, input.Parameters, s.params)
+	if err != nil {
+		return nil, err
+	}
+	if !c.changed(s, body, tmpl, params) {
+		return nil, validationError("No updates are to be performed.")
+	}
+
+	if input.StackPolicyBody != nil {
+		s.policy = aws.StringValue(input.StackPolicyBody)
+	}
+	c.run(s, func() {
+		c.update(s, body, tmpl, params)
+	})
+
+	return &cloudformation.UpdateStackOutput{StackId: aws.String(s.id)}, nil
+}
+
+func (c *CloudFormation) DeleteStack(input *cloudformation.DeleteStackInput) (*cloudformation.DeleteStackOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		// Deleting a nonexistent stack succeeds as CloudFormation does
+		return &cloudformation.DeleteStackOutput{}, nil
+	}
+	if s.status == cloudformation.StackStatusDeleteComplete {
+		return &cloudformation.DeleteStackOutput{}, nil
+	}
+	if s.terminationProtection {
+		return nil, validationError("Stack [%s] cannot be deleted while TerminationProtection is enabled", s.name)
+	}
+	if s.pending != nil {
+		return nil, validationError("Stack [%s] is in %s state and can not be deleted", s.name, s.reportedStatus())
+	}
+	retain := aws.StringValueSlice(input.RetainResources)
+	if len(retain) > 0 && s.status != cloudformation.StackStatusDeleteFailed {
+		return nil, validationError("Invalid operation on stack [%s]. RetainResources can only be specified when the stack is in the DELETE_FAILED state", s.id)
+	}
+
+	c.run(s, func() {
+		c.delete(s, retain)
+	})
+
+	return &cloudformation.DeleteStackOutput{}, nil
+}
+
+func (c *CloudFormation) DescribeStacks(input *cloudformation.DescribeStacksInput) (*cloudformation.DescribeStacksOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	stacks := []*stack{}
+	if input.StackName == nil {
+		for _, s := range c.stacks {
+			if s.status != cloudformation.StackStatusDeleteComplete || s.pending != nil {
+				stacks = append(stacks, s)
+			}
+		}
+	} else {
+		s, err := c.find(aws.StringValue(input.StackName))
+		if err != nil {
+			return nil, err
+		}
+		stacks = append(stacks, s)
+	}
+
+	out := &cloudformation.DescribeStacksOutput{Stacks: []*cloudformation.Stack{}}
+	for _, s := range stacks {
+		out.Stacks = append(out.Stacks, s.describe())
+		if p := s.pending; p != nil && p.root == s {
+			p.polls--
+			if p.polls <= 0 {
+				for t := range p.status {
+					t.pending = nil
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// DescribeStacksLatest serves DescribeStacks requests expecting EnableTerminationProtection, which the vendored
+// aws-sdk-go predates. Unlike DescribeStacks, it doesn't count as a poll of pending operations
+func (c *CloudFormation) DescribeStacksLatest(input *cloudformation.DescribeStacksInput) (*describeStacksLatestOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &describeStacksLatestOutput{
+		Stacks: []*stackLatest{{Stack: *s.describe(), EnableTerminationProtection: aws.Bool(s.terminationProtection)}},
+	}, nil
+}
+
+type describeStacksLatestOutput struct {
+	Stacks []*stackLatest
+}
+
+type stackLatest struct {
+	cloudformation.Stack
+	EnableTerminationProtection *bool
+}
+
+func (c *CloudFormation) DescribeStackEvents(input *cloudformation.DescribeStackEventsInput) (*cloudformation.DescribeStackEventsOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	events := s.visibleEvents()
+	out := &cloudformation.DescribeStackEventsOutput{StackEvents: []*cloudformation.StackEvent{}}
+	// Newest first as CloudFormation returns
+	for i := len(events) - 1; i >= 0; i-- {
+		out.StackEvents = append(out.StackEvents, events[i])
+	}
+	return out, nil
+}
+
+func (c *CloudFormation) ListStackResources(input *cloudformation.ListStackResourcesInput) (*cloudformation.ListStackResourcesOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	out := &cloudformation.ListStackResourcesOutput{StackResourceSummaries: []*cloudformation.StackResourceSummary{}}
+	for _, r := range s.sortedResources() {
+		out.StackResourceSummaries = append(out.StackResourceSummaries, &cloudformation.StackResourceSummary{
+			LogicalResourceId:    aws.String(r.logicalID),
+			PhysicalResourceId:   stringOrNil(r.physicalID),
+			ResourceType:         aws.String(r.typ),
+			ResourceStatus:       aws.String(r.status),
+			ResourceStatusReason: stringOrNil(r.reason),
+			LastUpdatedTimestamp: aws.Time(r.updated),
+		})
+	}
+	return out, nil
+}
+
+func (c *CloudFormation) DescribeStackResources(input *cloudformation.DescribeStackResourcesInput) (*cloudformation.DescribeStackResourcesOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	out := &cloudformation.DescribeStackResourcesOutput{StackResources: []*cloudformation.StackResource{}}
+	for _, r := range s.sortedResources() {
+		if input.LogicalResourceId != nil && aws.StringValue(input.LogicalResourceId) != r.logicalID {
+			continue
+		}
+		if input.PhysicalResourceId != nil && aws.StringValue(input.PhysicalResourceId) != r.physicalID {
+			continue
+		}
+		out.StackResources = append(out.StackResources, &cloudformation.StackResource{
+			StackId:              aws.String(s.id),
+			StackName:            aws.String(s.name),
+			LogicalResourceId:    aws.String(r.logicalID),
+			PhysicalResourceId:   stringOrNil(r.physicalID),
+			ResourceType:         aws.String(r.typ),
+			ResourceStatus:       aws.String(r.status),
+			ResourceStatusReason: stringOrNil(r.reason),
+			Timestamp:            aws.Time(r.updated),
+		})
+	}
+	return out, nil
+}
+
+func (c *CloudFormation) DescribeStackResource(input *cloudformation.DescribeStackResourceInput) (*cloudformation.DescribeStackResourceOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	id := aws.StringValue(input.LogicalResourceId)
+	r, ok := s.resources[id]
+	if !ok {
+		return nil, validationError("Resource %s does not exist for stack %s", id, s.name)
+	}
+	return &cloudformation.DescribeStackResourceOutput{
+		StackResourceDetail: &cloudformation.StackResourceDetail{
+			StackId:              aws.String(s.id),
+			StackName:            aws.String(s.name),
+			LogicalResourceId:    aws.String(r.logicalID),
+			PhysicalResourceId:   stringOrNil(r.physicalID),
+			ResourceType:         aws.String(r.typ),
+			ResourceStatus:       aws.String(r.status),
+			ResourceStatusReason: stringOrNil(r.reason),
+			LastUpdatedTimestamp: aws.Time(r.updated),
+		},
+	}, nil
+}
+
+func (c *CloudFormation) GetTemplate(input *cloudformation.GetTemplateInput) (*cloudformation.GetTemplateOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.GetTemplateOutput{TemplateBody: aws.String(s.body)}, nil
+}
+
+func (c *CloudFormation) ValidateTemplate(input *cloudformation.ValidateTemplateInput) (*cloudformation.ValidateTemplateOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	_, tmpl, err := c.templateFrom(input.TemplateBody, input.TemplateURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tmpl.creationOrder(); err != nil {
+		return nil, err
+	}
+
+	out := &cloudformation.ValidateTemplateOutput{Parameters: []*cloudformation.TemplateParameter{}}
+	names := []string{}
+	for name := range tmpl.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := &cloudformation.TemplateParameter{ParameterKey: aws.String(name), NoEcho: aws.Bool(false)}
+		if d := tmpl.Parameters[name].Default; d != nil {
+			p.DefaultValue = aws.String(fmt.Sprint(d))
+		}
+		out.Parameters = append(out.Parameters, p)
+	}
+	if requiresIAM(tmpl) {
+		out.Capabilities = []*string{aws.String(cloudformation.CapabilityCapabilityIam)}
+		out.CapabilitiesReason = aws.String("The following resource(s) require capabilities: [AWS::IAM::Role]")
+	}
+	return out, nil
+}
+
+func (c *CloudFormation) EstimateTemplateCost(input *cloudformation.EstimateTemplateCostInput) (*cloudformation.EstimateTemplateCostOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if _, _, err := c.templateFrom(input.TemplateBody, input.TemplateURL); err != nil {
+		return nil, err
+	}
+	return &cloudformation.EstimateTemplateCostOutput{
+		Url: aws.String("http://calculator.s3.amazonaws.com/calc5.html?key=fakeaws"),
+	}, nil
+}
+
+func (c *CloudFormation) SetStackPolicy(input *cloudformation.SetStackPolicyInput) (*cloudformation.SetStackPolicyOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	s.policy = aws.StringValue(input.StackPolicyBody)
+	return &cloudformation.SetStackPolicyOutput{}, nil
+}
+
+func (c *CloudFormation) GetStackPolicy(input *cloudformation.GetStackPolicyInput) (*cloudformation.GetStackPolicyOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.GetStackPolicyOutput{StackPolicyBody: stringOrNil(s.policy)}, nil
+}
+
+// UpdateTerminationProtection accepts any input having StackName and EnableTerminationProtection because the vendored
+// aws-sdk-go predates the API
+func (c *CloudFormation) UpdateTerminationProtection(input interface{}) (*struct{}, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	name, enabled := terminationProtectionInput(input)
+	s, err := c.find(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.parent != nil {
+		return nil, validationError("Termination protection cannot be updated on nested stack %s", s.name)
+	}
+	s.terminationProtection = enabled
+	c.terminationProtectionUpdates++
+	return nil, nil
+}
+
+// TerminationProtectionUpdates returns how many times termination protection has been updated
+func (c *CloudFormation) TerminationProtectionUpdates() int {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	return c.terminationProtectionUpdates
+}
+
+// TerminationProtectionEnabled returns true when termination protection of the stack is enabled
+func (c *CloudFormation) TerminationProtectionEnabled(name string) bool {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	s, err := c.find(name)
+	return err == nil && s.terminationProtection
+}
+
+func (c *CloudFormation) CreateChangeSet(input *cloudformation.CreateChangeSetInput) (*cloudformation.CreateChangeSetOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	if t := aws.StringValue(input.ChangeSetType); t != "" && t != cloudformation.ChangeSetTypeUpdate {
+		return nil, newError(http.StatusNotImplemented, "NotImplemented", "change sets of type %s are not implemented by fakeaws", t)
+	}
+	s, err := c.find(aws.StringValue(input.StackName))
+	if err != nil {
+		return nil, err
+	}
+	body, tmpl := s.body, s.tmpl
+	if !aws.BoolValue(input.UsePreviousTemplate) {
+		body, tmpl, err = c.templateFrom(input.TemplateBody, input.TemplateURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+	params, err := resolveParameters(tmpl, input.Parameters, s.params)
+	if err != nil {
+		return nil, err
+	}
+
+	name := aws.StringValue(input.ChangeSetName)
+	cs := &changeSet{
+		id:        fmt.Sprintf("arn:%s:cloudformation:%s:%s:changeSet/%s/%s", partitionOf(c.b.Region), c.b.Region, c.b.AccountID, name, c.b.nextID()),
+		name:      name,
+		stack:     s,
+		body:      body,
+		tmpl:      tmpl,
+		params:    params,
+		status:    cloudformation.ChangeSetStatusCreateComplete,
+		execution: cloudformation.ExecutionStatusAvailable,
+		created:   c.b.now(),
+	}
+	cs.changes = c.changes(s, tmpl)
+	if len(cs.changes) == 0 && !c.changed(s, body, tmpl, params) {
+		cs.status = cloudformation.ChangeSetStatusFailed
+		cs.execution = cloudformation.ExecutionStatusUnavailable
+		cs.reason = "The submitted information didn't contain changes. Submit different information to create a change set."
+	}
+	c.changeSets = append(c.changeSets, cs)
+
+	return &cloudformation.CreateChangeSetOutput{Id: aws.String(cs.id), StackId: aws.String(s.id)}, nil
+}
+
+func (c *CloudFormation) DescribeChangeSet(input *cloudformation.DescribeChangeSetInput) (*cloudformation.DescribeChangeSetOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	cs, err := c.findChangeSet(input.StackName, input.ChangeSetName)
+	if err != nil {
+		return nil, err
+	}
+	return &cloudformation.DescribeChangeSetOutput{
+		ChangeSetId:     aws.String(cs.id),
+		ChangeSetName:   aws.String(cs.name),
+		StackId:         aws.String(cs.stack.id),
+		StackName:       aws.String(cs.stack.name),
+		Status:          aws.String(cs.status),
+		StatusReason:    stringOrNil(cs.reason),
+		ExecutionStatus: aws.String(cs.execution),
+		Changes:         cs.changes,
+		CreationTime:    aws.Time(cs.created),
+	}, nil
+}
+
+func (c *CloudFormation) ExecuteChangeSet(input *cloudformation.ExecuteChangeSetInput) (*cloudformation.ExecuteChangeSetOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	cs, err := c.findChangeSet(input.StackName, input.ChangeSetName)
+	if err != nil {
+		return nil, err
+	}
+	if cs.execution != cloudformation.ExecutionStatusAvailable {
+		return nil, newError(http.StatusBadRequest, "InvalidChangeSetStatus", "ChangeSet [%s] cannot be executed in its current execution status of [%s]", cs.id, cs.execution)
+	}
+	if err := c.updatable(cs.stack); err != nil {
+		return nil, err
+	}
+	cs.execution = cloudformation.ExecutionStatusExecuteComplete
+	s := cs.stack
+	c.run(s, func() {
+		c.update(s, cs.body, cs.tmpl, cs.params)
+	})
+	// Other change sets of the stack are obsolete once one is executed
+	remaining := []*changeSet{}
+	for _, other := range c.changeSets {
+		if other.stack != s {
+			remaining = append(remaining, other)
+		}
+	}
+	c.changeSets = remaining
+
+	return &cloudformation.ExecuteChangeSetOutput{}, nil
+}
+
+func (c *CloudFormation) DeleteChangeSet(input *cloudformation.DeleteChangeSetInput) (*cloudformation.DeleteChangeSetOutput, error) {
+	c.b.mu.Lock()
+	defer c.b.mu.Unlock()
+
+	cs, err := c.findChangeSet(input.StackName, input.ChangeSetName)
+	if err != nil {
+		return nil, err
+	}
+	remaining := []*changeSet{}
+	for _, other := range c.changeSets {
+		if other != cs {
+			remaining = append(remaining, other)
+		}
+	}
+	c.changeSets = remaining
+	return &cloudformation.DeleteChangeSetOutput{}, nil
+}
+
+func (c *CloudFormation) findChangeSet(stackName *string, nameOrID *string) (*changeSet, error) {
+	for _, cs := range c.changeSets {
+		if cs.id == aws.StringValue(nameOrID) {
+			return cs, nil
+		}
+		if cs.name == aws.StringValue(nameOrID) && (cs.stack.name == aws.StringValue(stackName) || cs.stack.id == aws.StringValue(stackName)) {
+			return cs, nil
+		}
+	}
+	return nil, newError(http.StatusNotFound, "ChangeSetNotFound", "ChangeSet [%s] does not exist", aws.StringValue(nameOrID))
+}
+
+// run applies the operation to the stack and its nested stacks. When PollsToComplete is positive, the stacks are
+// reported to be in progress of the operation until the stack is described that many times
+func (c *CloudFormation) run(s *stack, op func()) {
+	c.touched = map[*stack]int{}
+	op()
+	touched := c.touched
+	c.touched = nil
+
+	if c.PollsToComplete <= 0 {
+		return
+	}
+	p := &pendingOperation{
+		root:          s,
+		polls:         c.PollsToComplete,
+		status:        map[*stack]string{},
+		visibleEvents: map[*stack]int{},
+	}
+	for t, first := range touched {
+		p.status[t] = aws.StringValue(t.events[first].ResourceStatus)
+		p.visibleEvents[t] = first + 1
+		t.pending = p
+	}
+}
+
+func (c *CloudFormation) newStack(name string, parent *stack) *stack {
+	s := &stack{
+		id:      fmt.Sprintf("arn:%s:cloudformation:%s:%s:stack/%s/%s", partitionOf(c.b.Region), c.b.Region, c.b.AccountID, name, c.b.nextID()),
+		name:    name,
+		parent:  parent,
+		created: c.b.now(),
+	}
+	s.resources = map[string]*stackResource{}
+	s.params = map[string]string{}
+	s.outputs = map[string]string{}
+	c.stacks = append(c.stacks, s)
+	return s
+}
+
+func (c *CloudFormation) findByName(name string) *stack {
+	for i := len(c.stacks) - 1; i >= 0; i-- {
+		s := c.stacks[i]
+		if s.name == name && (s.status != cloudformation.StackStatusDeleteComplete || s.pending != nil) {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *CloudFormation) find(nameOrID string) (*stack, error) {
+	for _, s := range c.stacks {
+		if s.id == nameOrID {
+			return s, nil
+		}
+	}
+	if s := c.findByName(nameOrID); s != nil {
+		return s, nil
+	}
+	return nil, validationError("Stack with id %s does not exist", nameOrID)
+}
+
+func (c *CloudFormation) updatable(s *stack) error {
+	if s.pending != nil {
+		return validationError("Stack:%s is in %s state and can not be updated.", s.id, s.reportedStatus())
+	}
+	switch s.status {
+	case cloudformation.StackStatusCreateComplete, cloudformation.StackStatusUpdateComplete, cloudformation.StackStatusUpdateRollbackComplete:
+		return nil
+	}
+	return validationError("Stack:%s is in %s state and can not be updated.", s.id, s.status)
+}
+
+// changed returns true when the template or the parameters of the stack or any of its nested stacks differ. Templates
+// of nested stacks are fetched again as CloudFormation does, so that a nested stack is updated even if its URL is the same
+func (c *CloudFormation) changed(s *stack, body string, tmpl *template, params map[string]string) bool {
+	if s.body != body || !stringMapsEqual(s.params, params) {
+		return true
+	}
+	e := c.evaluator(s, tmpl, params, s.resources)
+	for id, r := range s.resources {
+		if r.nested == nil {
+			continue
+		}
+		props, err := e.eval(tmpl.Resources[id].Properties)
+		if err != nil {
+			return true
+		}
+		m, _ := props.(map[string]interface{})
+		nestedBody, nestedTmpl, nestedParams, err := c.nestedTemplate(m)
+		if err != nil || c.changed(r.nested, nestedBody, nestedTmpl, nestedParams) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFrom returns the template body given directly or uploaded to the fake S3
+func (c *CloudFormation) templateFrom(body *string, url *string) (string, *template, error) {
+	var b string
+	switch {
+	case body != nil && url != nil:
+		return "", nil, validationError("Specify either TemplateBody or TemplateURL, but not both")
+	case body != nil:
+		b = aws.StringValue(body)
+		if len(b) > templateBodySizeLimit {
+			return "", nil, validationError("1 validation error detected: Value '%s...' at 'templateBody' failed to satisfy constraint: Member must have length less than or equal to %d", b[:16], templateBodySizeLimit)
+		}
+	case url != nil:
+		o, ok := c.b.S3.objectAtURL(aws.StringValue(url))
+		if !ok {
+			return "", nil, validationError("TemplateURL must reference a valid S3 object to which you have access.")
+		}
+		b = string(o.Body)
+		if len(b) > templateURLSizeLimit {
+			return "", nil, validationError("Template may not exceed %d bytes in size.", templateURLSizeLimit)
+		}
+	default:
+		return "", nil, validationError("Either Template URL or Template Body must be specified.")
+	}
+	tmpl, err := parseTemplate(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return b, tmpl, nil
+}
+
+func (c *CloudFormation) addEvent(s *stack, logicalID string, physicalID string, typ string, status string, reason string) {
+	if c.touched != nil {
+		if _, ok := c.touched[s]; !ok {
+			c.touched[s] = len(s.events)
+		}
+	}
+	s.events = append(s.events, &cloudformation.StackEvent{
+		EventId:              aws.String(c.b.nextID()),
+		StackId:              aws.String(s.id),
+		StackName:            aws.String(s.name),
+		LogicalResourceId:    aws.String(logicalID),
+		PhysicalResourceId:   stringOrNil(physicalID),
+		ResourceType:         aws.String(typ),
+		ResourceStatus:       aws.String(status),
+		ResourceStatusReason: stringOrNil(reason),
+		Timestamp:            aws.Time(c.b.now()),
+	})
+}
+
+func (c *CloudFormation) setStatus(s *stack, status string, reason string) {
+	s.status = status
+	s.reason = reason
+	c.addEvent(s, s.name, s.id, nestedStackType, status, reason)
+}
+
+func (c *CloudFormation) setResourceStatus(s *stack, r *stackResource, status string, reason string) {
+	r.status = status
+	r.reason = reason
+	r.updated = c.b.now()
+	c.addEvent(s, r.logicalID, r.physicalID, r.typ, status, reason)
+}
+
+func (c *CloudFormation) evaluator(s *stack, tmpl *template, params map[string]string, resources map[string]*stackResource) evaluator {
+	return evaluator{b: c.b, s: s, tmpl: tmpl, params: params, resources: resources}
+}
+
+// create creates the resources of the stack in the order of dependencies and returns true when all of them are created
+func (c *CloudFormation) create(s *stack, body string, tmpl *template, params map[string]string) bool {
+	s.body = body
+	s.tmpl = tmpl
+	s.params = params
+	reason := ""
+	if s.parent == nil {
+		reason = "User Initiated"
+	}
+	c.setStatus(s, cloudformation.StackStatusCreateInProgress, reason)
+
+	failed := c.createResources(s, tmpl, params, s.resources)
+	if len(failed) == 0 {
+		failed = c.evaluateOutputs(s)
+	}
+	if len(failed) > 0 {
+		reason := fmt.Sprintf("The following resource(s) failed to create: [%s]. ", strings.Join(failed, ", "))
+		switch s.onFailure {
+		case cloudformation.OnFailureDoNothing:
+			c.setStatus(s, cloudformation.StackStatusCreateFailed, reason)
+		case cloudformation.OnFailureDelete:
+			c.setStatus(s, cloudformation.StackStatusDeleteInProgress, reason+"Delete requested by user.")
+			c.deleteResources(s, nil)
+			c.setStatus(s, cloudformation.StackStatusDeleteComplete, "")
+		default:
+			c.setStatus(s, cloudformation.StackStatusRollbackInProgress, reason+"Rollback requested by user.")
+			if len(c.deleteResources(s, nil)) > 0 {
+				c.setStatus(s, cloudformation.StackStatusRollbackFailed, "")
+			} else {
+				c.setStatus(s, cloudformation.StackStatusRollbackComplete, "")
+			}
+		}
+		return false
+	}
+
+	c.setStatus(s, cloudformation.StackStatusCreateComplete, "")
+	return true
+}
+
+// createResources creates resources in the template which don't exist in resources yet, and returns logical IDs of ones
+// failed to be created
+func (c *CloudFormation) createResources(s *stack, tmpl *template, params map[string]string, resources map[string]*stackResource) []string {
+	order, err := tmpl.creationOrder()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	e := c.evaluator(s, tmpl, params, resources)
+	for _, id := range order {
+		if _, ok := resources[id]; ok {
+			continue
+		}
+		def := tmpl.Resources[id]
+		if ok, err := e.condition(def.Condition); err != nil || !ok {
+			continue
+		}
+		r := &stackResource{logicalID: id, typ: def.Type}
+		resources[id] = r
+		s.resourceOrders = append(s.resourceOrders, id)
+		if !c.createResource(s, e, r, def) {
+			return []string{id}
+		}
+	}
+	return nil
+}
+
+func (c *CloudFormation) createResource(s *stack, e evaluator, r *stackResource, def templateResource) bool {
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateInProgress, "")
+
+	props, err := e.eval(def.Properties)
+	if err != nil {
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateFailed, err.Error())
+		return false
+	}
+	r.properties, _ = props.(map[string]interface{})
+
+	if r.typ == nestedStackType {
+		body, tmpl, params, err := c.nestedTemplate(r.properties)
+		if err != nil {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateFailed, err.Error())
+			return false
+		}
+		child := c.newStack(fmt.Sprintf("%s-%s-%s", s.name, r.logicalID, strings.ToUpper(c.b.nextID())), s)
+		child.onFailure = s.onFailure
+		child.tags = s.tags
+		r.physicalID = child.id
+		r.nested = child
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateInProgress, "Resource creation Initiated")
+		if !c.create(child, body, tmpl, params) {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateFailed, fmt.Sprintf("Embedded stack %s was not successfully created: %s", child.id, child.reason))
+			return false
+		}
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateComplete, "")
+		return true
+	}
+
+	if reason, ok := c.failures[r.logicalID]; ok {
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateFailed, reason)
+		return false
+	}
+	r.physicalID = c.physicalIDFor(s, r)
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusCreateComplete, "")
+	return true
+}
+
+// physicalIDFor returns the name given in the properties of the resource, or one generated from the stack name and the
+// logical ID as CloudFormation does
+func (c *CloudFormation) physicalIDFor(s *stack, r *stackResource) string {
+	for _, key := range []string{"LoadBalancerName", "AutoScalingGroupName", "LaunchConfigurationName", "RoleName", "BucketName", "Name"} {
+		if name, ok := r.properties[key].(string); ok && name != "" {
+			return name
+		}
+	}
+	return fmt.Sprintf("%s-%s-%s", s.name, r.logicalID, strings.ToUpper(c.b.nextID()))
+}
+
+func (c *CloudFormation) nestedTemplate(props map[string]interface{}) (string, *template, map[string]string, error) {
+	url, _ := props["TemplateURL"].(string)
+	body, tmpl, err := c.templateFrom(nil, aws.String(url))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	provided := []*cloudformation.Parameter{}
+	if ps, ok := props["Parameters"].(map[string]interface{}); ok {
+		for k, v := range ps {
+			provided = append(provided, &cloudformation.Parameter{ParameterKey: aws.String(k), ParameterValue: aws.String(fmt.Sprint(v))})
+		}
+	}
+	params, err := resolveParameters(tmpl, provided, nil)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return body, tmpl, params, nil
+}
+
+func (c *CloudFormation) evaluateOutputs(s *stack) []string {
+	e := c.evaluator(s, s.tmpl, s.params, s.resources)
+	outputs := map[string]string{}
+	descs := map[string]string{}
+	for key, o := range s.tmpl.Outputs {
+		if ok, err := e.condition(o.Condition); err != nil || !ok {
+			continue
+		}
+		v, err := e.evalString(o.Value)
+		if err != nil {
+			return []string{key}
+		}
+		outputs[key] = v
+		descs[key] = o.Description
+		if o.Export != nil {
+			name, err := e.evalString(o.Export.Name)
+			if err != nil {
+				return []string{key}
+			}
+			if existing, ok := c.exports[name]; ok && existing.stackID != s.id {
+				return []string{key}
+			}
+			c.exports[name] = export{stackID: s.id, value: v}
+		}
+	}
+	s.outputs = outputs
+	s.outputDescs = descs
+	return nil
+}
+
+// update updates the stack to the template and parameters, and rolls the stack back to the previous state on failure
+func (c *CloudFormation) update(s *stack, body string, tmpl *template, params map[string]string) bool {
+	snapshot := c.snapshot(s)
+	reason := ""
+	if s.parent == nil {
+		reason = "User Initiated"
+	}
+	c.setStatus(s, cloudformation.StackStatusUpdateInProgress, reason)
+
+	old := s.stackState
+	s.body = body
+	s.tmpl = tmpl
+	s.params = params
+	s.resources = map[string]*stackResource{}
+	for id, r := range old.resources {
+		s.resources[id] = r
+	}
+
+	failed := c.updateResources(s, old.tmpl, tmpl, params)
+	if len(failed) == 0 {
+		failed = c.evaluateOutputs(s)
+	}
+	if len(failed) > 0 {
+		c.setStatus(s, cloudformation.StackStatusUpdateRollbackInProgress, fmt.Sprintf("The following resource(s) failed to update: [%s]. ", strings.Join(failed, ", ")))
+		c.restore(s, snapshot)
+		c.setStatus(s, cloudformation.StackStatusUpdateRollbackComplete, "")
+		return false
+	}
+
+	c.setStatus(s, cloudformation.StackStatusUpdateCompleteCleanupInProgress, "")
+	removed := []string{}
+	for i := len(old.resourceOrders) - 1; i >= 0; i-- {
+		id := old.resourceOrders[i]
+		if _, ok := tmpl.Resources[id]; !ok || !c.conditionHolds(s, id) {
+			removed = append(removed, id)
+		}
+	}
+	for _, id := range removed {
+		r := s.resources[id]
+		if r.status != cloudformation.ResourceStatusDeleteComplete {
+			c.deleteResource(s, r)
+		}
+		delete(s.resources, id)
+	}
+	s.resourceOrders = orderedIDs(tmpl, s.resources)
+	now := c.b.now()
+	s.updated = &now
+	c.setStatus(s, cloudformation.StackStatusUpdateComplete, "")
+	return true
+}
+
+func (c *CloudFormation) conditionHolds(s *stack, id string) bool {
+	e := c.evaluator(s, s.tmpl, s.params, s.resources)
+	ok, err := e.condition(s.tmpl.Resources[id].Condition)
+	return err == nil && ok
+}
+
+func (c *CloudFormation) updateResources(s *stack, oldTmpl *template, tmpl *template, params map[string]string) []string {
+	order, err := tmpl.creationOrder()
+	if err != nil {
+		return []string{err.Error()}
+	}
+	e := c.evaluator(s, tmpl, params, s.resources)
+	for _, id := range order {
+		def := tmpl.Resources[id]
+		if ok, err := e.condition(def.Condition); err != nil || !ok {
+			continue
+		}
+		r, exists := s.resources[id]
+		if !exists {
+			r = &stackResource{logicalID: id, typ: def.Type}
+			s.resources[id] = r
+			if !c.createResource(s, e, r, def) {
+				return []string{id}
+			}
+			continue
+		}
+		if !c.updateResource(s, e, r, def) {
+			return []string{id}
+		}
+	}
+	return nil
+}
+
+func (c *CloudFormation) updateResource(s *stack, e evaluator, r *stackResource, def templateResource) bool {
+	props, err := e.eval(def.Properties)
+	if err != nil {
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateFailed, err.Error())
+		return false
+	}
+	newProps, _ := props.(map[string]interface{})
+
+	if r.nested != nil {
+		body, tmpl, params, err := c.nestedTemplate(newProps)
+		if err != nil {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateFailed, err.Error())
+			return false
+		}
+		r.properties = newProps
+		if !c.changed(r.nested, body, tmpl, params) {
+			return true
+		}
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateInProgress, "")
+		if !c.update(r.nested, body, tmpl, params) {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateFailed, fmt.Sprintf("Embedded stack %s was not successfully updated. Currently in %s.", r.nested.id, r.nested.status))
+			return false
+		}
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateComplete, "")
+		return true
+	}
+
+	if r.typ == def.Type && propertiesEqual(r.properties, newProps) {
+		return true
+	}
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateInProgress, "")
+	if reason, ok := c.failures[r.logicalID]; ok {
+		c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateFailed, reason)
+		return false
+	}
+	r.typ = def.Type
+	r.properties = newProps
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusUpdateComplete, "")
+	return true
+}
+
+// delete deletes the resources of the stack in the reverse order of creation except the retained ones, and returns true
+// when the stack is deleted
+func (c *CloudFormation) delete(s *stack, retain []string) bool {
+	reason := ""
+	if s.parent == nil {
+		reason = "User Initiated"
+	}
+	c.setStatus(s, cloudformation.StackStatusDeleteInProgress, reason)
+	if failed := c.deleteResources(s, retain); len(failed) > 0 {
+		c.setStatus(s, cloudformation.StackStatusDeleteFailed, fmt.Sprintf("The following resource(s) failed to delete: [%s]. ", strings.Join(failed, ", ")))
+		return false
+	}
+	for name, e := range c.exports {
+		if e.stackID == s.id {
+			delete(c.exports, name)
+		}
+	}
+	c.setStatus(s, cloudformation.StackStatusDeleteComplete, "")
+	return true
+}
+
+func (c *CloudFormation) deleteResources(s *stack, retain []string) []string {
+	retained := map[string]bool{}
+	for _, id := range retain {
+		retained[id] = true
+	}
+	failed := []string{}
+	for i := len(s.resourceOrders) - 1; i >= 0; i-- {
+		r, ok := s.resources[s.resourceOrders[i]]
+		if !ok || r.status == cloudformation.ResourceStatusDeleteComplete || r.status == cloudformation.ResourceStatusDeleteSkipped {
+			continue
+		}
+		if retained[r.logicalID] {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteSkipped, "")
+			continue
+		}
+		if !c.deleteResource(s, r) {
+			failed = append(failed, r.logicalID)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+func (c *CloudFormation) deleteResource(s *stack, r *stackResource) bool {
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteInProgress, "")
+	if r.nested != nil {
+		if r.nested.status != cloudformation.StackStatusDeleteComplete && !c.delete(r.nested, nil) {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteFailed, fmt.Sprintf("Embedded stack %s was not successfully deleted: %s", r.nested.id, r.nested.reason))
+			return false
+		}
+	} else if r.physicalID != "" {
+		if reason, ok := c.failures[r.logicalID]; ok {
+			c.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteFailed, reason)
+			return false
+		}
+	}
+	c.setResourceStatus(s, r, cloudformation.ResourceStatusDeleteComplete, "")
+	return true
+}
+
+// snapshot returns states of the stack and its nested stacks to be restored when an update fails
+func (c *CloudFormation) snapshot(s *stack) map[*stack]stackState {
+	states := map[*stack]stackState{}
+	var walk func(s *stack)
+	walk = func(s *stack) {
+		state := s.stackState
+		state.resources = map[string]*stackResource{}
+		for id, r := range s.resources {
+			copied := *r
+			state.resources[id] = &copied
+			if r.nested != nil {
+				walk(r.nested)
+			}
+		}
+		state.resourceOrders = append([]string{}, s.resourceOrders...)
+		states[s] = state
+	}
+	walk(s)
+	return states
+}
+
+// restore rolls the stack and its nested stacks back to the snapshot. Nested stacks created during the failed update
+// are deleted
+func (c *CloudFormation) restore(s *stack, states map[*stack]stackState) {
+	for _, r := range s.resources {
+		if r.nested == nil {
+			continue
+		}
+		if _, existed := states[r.nested]; !existed {
+			if r.nested.status != cloudformation.StackStatusDeleteComplete {
+				c.delete(r.nested, nil)
+			}
+		} else if r.nested.body != states[r.nested].body || !stringMapsEqual(r.nested.params, states[r.nested].params) {
+			c.setStatus(r.nested, cloudformation.StackStatusUpdateRollbackInProgress, "")
+			c.restore(r.nested, states)
+			c.setStatus(r.nested, cloudformation.StackStatusUpdateRollbackComplete, "")
+		}
+	}
+	s.stackState = states[s]
+}
+
+// changes returns changes to resources made by updating the stack to the template
+func (c *CloudFormation) changes(s *stack, tmpl *template) []*cloudformation.Change {
+	changes := []*cloudformation.Change{}
+	ids := []string{}
+	for id := range tmpl.Resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		def := tmpl.Resources[id]
+		r, ok := s.resources[id]
+		change := &cloudformation.ResourceChange{
+			LogicalResourceId: aws.String(id),
+			ResourceType:      aws.String(def.Type),
+		}
+		switch {
+		case !ok:
+			change.Action = aws.String(cloudformation.ChangeActionAdd)
+		case r.typ != def.Type:
+			change.Action = aws.String(cloudformation.ChangeActionModify)
+			change.PhysicalResourceId = aws.String(r.physicalID)
+			change.Replacement = aws.String(cloudformation.ReplacementTrue)
+		case def.Type == nestedStackType || !propertiesEqual(s.tmpl.Resources[id].Properties, def.Properties):
+			if def.Type == nestedStackType && propertiesEqual(s.tmpl.Resources[id].Properties, def.Properties) {
+				// Nested stacks are always reported as modified when the template of the parent is different as
+				// CloudFormation can't tell whether the nested template changes until it is fetched
+				continue
+			}
+			change.Action = aws.String(cloudformation.ChangeActionModify)
+			change.PhysicalResourceId = aws.String(r.physicalID)
+			change.Replacement = aws.String(cloudformation.ReplacementFalse)
+		default:
+			continue
+		}
+		changes = append(changes, &cloudformation.Change{Type: aws.String(cloudformation.ChangeTypeResource), ResourceChange: change})
+	}
+	for _, id := range sortedKeys(s.resources) {
+		if _, ok := tmpl.Resources[id]; !ok {
+			r := s.resources[id]
+			changes = append(changes, &cloudformation.Change{
+				Type: aws.String(cloudformation.ChangeTypeResource),
+				ResourceChange: &cloudformation.ResourceChange{
+					Action:             aws.String(cloudformation.ChangeActionRemove),
+					LogicalResourceId:  aws.String(id),
+					PhysicalResourceId: aws.String(r.physicalID),
+					ResourceType:       aws.String(r.typ),
+				},
+			})
+		}
+	}
+	return changes
+}
+
+// liveResources returns resources of the type in the stacks which are not deleted, in the order of creation
+func (c *CloudFormation) liveResources(typ string) []*stackResource {
+	resources := []*stackResource{}
+	for _, s := range c.stacks {
+		for _, id := range s.resourceOrders {
+			r, ok := s.resources[id]
+			if !ok || r.typ != typ || r.physicalID == "" {
+				continue
+			}
+			switch r.status {
+			case cloudformation.ResourceStatusCreateFailed, cloudformation.ResourceStatusDeleteComplete:
+				continue
+			}
+			resources = append(resources, r)
+		}
+	}
+	return resources
+}
+
+func (s *stack) reportedStatus() string {
+	if s.pending != nil {
+		return s.pending.status[s]
+	}
+	return s.status
+}
+
+func (s *stack) visibleEvents() []*cloudformation.StackEvent {
+	if s.pending != nil {
+		return s.events[:s.pending.visibleEvents[s]]
+	}
+	return s.events
+}
+
+func (s *stack) sortedResources() []*stackResource {
+	resources := []*stackResource{}
+	for _, id := range sortedKeys(s.resources) {
+		resources = append(resources, s.resources[id])
+	}
+	return resources
+}
+
+func (s *stack) describe() *cloudformation.Stack {
+	status := s.reportedStatus()
+	reason := s.reason
+	if s.pending != nil {
+		reason = ""
+	}
+	out := &cloudformation.Stack{
+		StackId:           aws.String(s.id),
+		StackName:         aws.String(s.name),
+		StackStatus:       aws.String(status),
+		StackStatusReason: stringOrNil(reason),
+		CreationTime:      aws.Time(s.created),
+		LastUpdatedTime:   s.updated,
+		Capabilities:      s.capabilities,
+		Tags:              s.tags,
+		DisableRollback:   aws.Bool(s.onFailure == cloudformation.OnFailureDoNothing),
+		Parameters:        []*cloudformation.Parameter{},
+		Outputs:           []*cloudformation.Output{},
+	}
+	for _, k := range sortedStringKeys(s.params) {
+		out.Parameters = append(out.Parameters, &cloudformation.Parameter{ParameterKey: aws.String(k), ParameterValue: aws.String(s.params[k])})
+	}
+	if s.pending == nil {
+		for _, k := range sortedStringKeys(s.outputs) {
+			out.Outputs = append(out.Outputs, &cloudformation.Output{
+				OutputKey:   aws.String(k),
+				OutputValue: aws.String(s.outputs[k]),
+				Description: stringOrNil(s.outputDescs[k]),
+			})
+		}
+	}
+	return out
+}
+
+// resolveParameters returns values of all the parameters in the template from the provided ones, previous values and
+// defaults in this order
+func resolveParameters(tmpl *template, provided []*cloudformation.Parameter, previous map[string]string) (map[string]string, error) {
+	params := map[string]string{}
+	for _, p := range provided {
+		key := aws.StringValue(p.ParameterKey)
+		if _, ok := tmpl.Parameters[key]; !ok {
+			return nil, validationError("Parameters: [%s] do not exist in the template", key)
+		}
+		if aws.BoolValue(p.UsePreviousValue) {
+			v, ok := previous[key]
+			if !ok {
+				return nil, validationError("Invalid input for parameter key %s. Cannot specify usePreviousValue as true for a parameter key not in the previous template", key)
+			}
+			params[key] = v
+			continue
+		}
+		params[key] = aws.StringValue(p.ParameterValue)
+	}
+	missing := []string{}
+	for key, p := range tmpl.Parameters {
+		if _, ok := params[key]; ok {
+			continue
+		}
+		if p.Default == nil {
+			missing = append(missing, key)
+			continue
+		}
+		params[key] = fmt.Sprint(p.Default)
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, validationError("Parameters: [%s] must have values", strings.Join(missing, ", "))
+	}
+	return params, nil
+}
+
+func requiresIAM(tmpl *template) bool {
+	for _, r := range tmpl.Resources {
+		if strings.HasPrefix(r.Type, "AWS::IAM::") {
+			return true
+		}
+	}
+	return false
+}
+
+func requireCapabilities(tmpl *template, capabilities []*string) error {
+	if !requiresIAM(tmpl) {
+		return nil
+	}
+	for _, c := range aws.StringValueSlice(capabilities) {
+		if c == cloudformation.CapabilityCapabilityIam || c == cloudformation.CapabilityCapabilityNamedIam {
+			return nil
+		}
+	}
+	return newError(http.StatusBadRequest, "InsufficientCapabilitiesException", "Requires capabilities : [%s]", cloudformation.CapabilityCapabilityIam)
+}
+
+func terminationProtectionInput(input interface{}) (string, bool) {
+	v := reflectStruct(input)
+	name, _ := v.FieldByName("StackName").Interface().(*string)
+	enabled, _ := v.FieldByName("EnableTerminationProtection").Interface().(*bool)
+	return aws.StringValue(name), aws.BoolValue(enabled)
+}
+
+func orderedIDs(tmpl *template, resources map[string]*stackResource) []string {
+	order, _ := tmpl.creationOrder()
+	ids := []string{}
+	for _, id := range order {
+		if _, ok := resources[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func validationError(format string, args ...interface{}) error {
+	return newError(http.StatusBadRequest, "ValidationError", format, args...)
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return aws.String(s)
+}
+
+func stringMapsEqual(a map[string]string, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if w, ok := b[k]; !ok || v != w {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(m map[string]*stackResource) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,329 @@
+package fakeaws
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+const rootTemplate = `{
+  "Parameters": {"Size": {"Type": "String", "Default": "1"}},
+  "Resources": {
+    "Role": {"Type": "AWS::IAM::Role", "Properties": {}},
+    "Nested": {
+      "Type": "AWS::CloudFormation::Stack",
+      "Properties": {
+        "TemplateURL": "https://s3.amazonaws.com/mybucket/nested.json",
+        "Parameters": {"Size": {"Ref": "Size"}, "RoleName": {"Ref": "Role"}}
+      }
+    }
+  },
+  "Outputs": {
+    "Group": {"Value": {"Fn::GetAtt": ["Nested", "Outputs.Group"]}}
+  }
+}`
+
+const nestedTemplate = `{
+  "Parameters": {"Size": {"Type": "String"}, "RoleName": {"Type": "String"}},
+  "Resources": {
+    "LaunchConfig": {"Type": "AWS::AutoScaling::LaunchConfiguration", "Properties": {"InstanceType": "t2.medium"}},
+    "Group": {
+      "Type": "AWS::AutoScaling::AutoScalingGroup",
+      "Properties": {"MinSize": {"Ref": "Size"}, "MaxSize": {"Ref": "Size"}, "LaunchConfigurationName": {"Ref": "LaunchConfig"}}
+    }
+  },
+  "Outputs": {
+    "Group": {"Value": {"Ref": "Group"}}
+  }
+}`
+
+func newTestBackend(t *testing.T) *Backend {
+	b := New("us-west-1")
+	b.S3.AddBucket("mybucket")
+	if _, err := b.S3.PutObject(&s3.PutObjectInput{Bucket: aws.String("mybucket"), Key: aws.String("nested.json"), Body: strings.NewReader(nestedTemplate)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+func createTestStack(t *testing.T, b *Backend) string {
+	out, err := b.CloudFormation.CreateStack(&cloudformation.CreateStackInput{
+		StackName:    aws.String("mycluster"),
+		TemplateBody: aws.String(rootTemplate),
+		Capabilities: aws.StringSlice([]string{cloudformation.CapabilityCapabilityIam}),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return aws.StringValue(out.StackId)
+}
+
+func describeStack(t *testing.T, b *Backend, name string) *cloudformation.Stack {
+	out, err := b.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(name)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out.Stacks[0]
+}
+
+// resourceStatuses returns statuses of resources in events of the stack, oldest first
+func resourceStatuses(t *testing.T, b *Backend, name string, logicalID string) []string {
+	out, err := b.CloudFormation.DescribeStackEvents(&cloudformation.DescribeStackEventsInput{StackName: aws.String(name)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	statuses := []string{}
+	for i := len(out.StackEvents) - 1; i >= 0; i-- {
+		e := out.StackEvents[i]
+		if aws.StringValue(e.LogicalResourceId) == logicalID {
+			statuses = append(statuses, aws.StringValue(e.ResourceStatus))
+		}
+	}
+	return statuses
+}
+
+func errorCode(err error) string {
+	if e, ok := err.(awserr.Error); ok {
+		return e.Code()
+	}
+	return ""
+}
+
+func TestCreateStackWithNestedStack(t *testing.T) {
+	b := newTestBackend(t)
+	id := createTestStack(t, b)
+
+	s := describeStack(t, b, "mycluster")
+	if aws.StringValue(s.StackId) != id || aws.StringValue(s.StackStatus) != cloudformation.StackStatusCreateComplete {
+		t.Fatalf("unexpected stack: %+v", s)
+	}
+	if len(s.Outputs) != 1 || !strings.HasPrefix(aws.StringValue(s.Outputs[0].OutputValue), "mycluster-Nested-") {
+		t.Errorf("the output of the nested stack was not propagated: %+v", s.Outputs)
+	}
+
+	names := b.CloudFormation.LiveStackNames()
+	if len(names) != 2 || names[0] != "mycluster" || !strings.HasPrefix(names[1], "mycluster-Nested-") {
+		t.Errorf("unexpected stacks: %v", names)
+	}
+
+	groups, err := b.AutoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups.AutoScalingGroups) != 1 || len(groups.AutoScalingGroups[0].Instances) != 1 {
+		t.Errorf("unexpected autoscaling groups: %+v", groups)
+	}
+
+	expected := []string{"CREATE_IN_PROGRESS", "CREATE_IN_PROGRESS", "CREATE_COMPLETE"}
+	if actual := resourceStatuses(t, b, "mycluster", "Nested"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected events of the nested stack: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestCreateStackRequiresCapabilities(t *testing.T) {
+	b := newTestBackend(t)
+	_, err := b.CloudFormation.CreateStack(&cloudformation.CreateStackInput{
+		StackName:    aws.String("mycluster"),
+		TemplateBody: aws.String(rootTemplate),
+	})
+	if errorCode(err) != "InsufficientCapabilitiesException" {
+		t.Errorf("expected InsufficientCapabilitiesException, got %v", err)
+	}
+}
+
+func TestCreateStackRollsBackOnFailure(t *testing.T) {
+	b := newTestBackend(t)
+	b.CloudFormation.FailResource("Group", "Resource limit exceeded")
+	createTestStack(t, b)
+
+	s := describeStack(t, b, "mycluster")
+	if status := aws.StringValue(s.StackStatus); status != cloudformation.StackStatusRollbackComplete {
+		t.Errorf("expected %s, got %s", cloudformation.StackStatusRollbackComplete, status)
+	}
+	expected := []string{"CREATE_IN_PROGRESS", "CREATE_IN_PROGRESS", "CREATE_FAILED", "DELETE_IN_PROGRESS", "DELETE_COMPLETE"}
+	if actual := resourceStatuses(t, b, "mycluster", "Nested"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected events of the nested stack: expected %v, got %v", expected, actual)
+	}
+	if names := b.CloudFormation.LiveStackNames(); len(names) != 1 {
+		t.Errorf("the nested stack was not deleted: %v", names)
+	}
+}
+
+func TestUpdateStack(t *testing.T) {
+	b := newTestBackend(t)
+	createTestStack(t, b)
+
+	update := func(size string) error {
+		_, err := b.CloudFormation.UpdateStack(&cloudformation.UpdateStackInput{
+			StackName:           aws.String("mycluster"),
+			UsePreviousTemplate: aws.Bool(true),
+			Capabilities:        aws.StringSlice([]string{cloudformation.CapabilityCapabilityIam}),
+			Parameters:          []*cloudformation.Parameter{{ParameterKey: aws.String("Size"), ParameterValue: aws.String(size)}},
+		})
+		return err
+	}
+
+	if err := update("1"); err == nil || !strings.Contains(err.Error(), "No updates are to be performed.") {
+		t.Errorf("expected no updates, got %v", err)
+	}
+
+	if err := update("3"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := aws.StringValue(describeStack(t, b, "mycluster").StackStatus); status != cloudformation.StackStatusUpdateComplete {
+		t.Errorf("expected %s, got %s", cloudformation.StackStatusUpdateComplete, status)
+	}
+	groups, _ := b.AutoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	if size := aws.Int64Value(groups.AutoScalingGroups[0].MaxSize); size != 3 {
+		t.Errorf("the group was not updated: max size is %d", size)
+	}
+
+	b.CloudFormation.FailResource("Group", "Invalid size")
+	if err := update("5"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := aws.StringValue(describeStack(t, b, "mycluster").StackStatus); status != cloudformation.StackStatusUpdateRollbackComplete {
+		t.Errorf("expected %s, got %s", cloudformation.StackStatusUpdateRollbackComplete, status)
+	}
+	groups, _ = b.AutoScaling.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{})
+	if size := aws.Int64Value(groups.AutoScalingGroups[0].MaxSize); size != 3 {
+		t.Errorf("the group was not rolled back: max size is %d", size)
+	}
+}
+
+func TestDeleteStack(t *testing.T) {
+	b := newTestBackend(t)
+	id := createTestStack(t, b)
+
+	b.CloudFormation.FailResource("Role", "Role is in use")
+	if _, err := b.CloudFormation.DeleteStack(&cloudformation.DeleteStackInput{StackName: aws.String("mycluster")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status := aws.StringValue(describeStack(t, b, "mycluster").StackStatus); status != cloudformation.StackStatusDeleteFailed {
+		t.Errorf("expected %s, got %s", cloudformation.StackStatusDeleteFailed, status)
+	}
+
+	if _, err := b.CloudFormation.DeleteStack(&cloudformation.DeleteStackInput{
+		StackName:       aws.String("mycluster"),
+		RetainResources: aws.StringSlice([]string{"Role"}),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := b.CloudFormation.LiveStackNames(); len(names) != 0 {
+		t.Errorf("stacks left: %v", names)
+	}
+	if _, err := b.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String("mycluster")}); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected the stack not to exist, got %v", err)
+	}
+	expected := []string{"CREATE_IN_PROGRESS", "CREATE_COMPLETE", "DELETE_IN_PROGRESS", "DELETE_FAILED", "DELETE_SKIPPED"}
+	if actual := resourceStatuses(t, b, id, "Role"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected events of the role: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestTerminationProtection(t *testing.T) {
+	b := newTestBackend(t)
+	createTestStack(t, b)
+
+	input := &struct {
+		EnableTerminationProtection *bool
+		StackName                   *string
+	}{aws.Bool(true), aws.String("mycluster")}
+	if _, err := b.CloudFormation.UpdateTerminationProtection(input); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err := b.CloudFormation.DeleteStack(&cloudformation.DeleteStackInput{StackName: aws.String("mycluster")})
+	if err == nil || !strings.Contains(err.Error(), "TerminationProtection is enabled") {
+		t.Errorf("expected the deletion to be prevented, got %v", err)
+	}
+}
+
+func TestPollsToComplete(t *testing.T) {
+	b := newTestBackend(t)
+	b.CloudFormation.PollsToComplete = 2
+	createTestStack(t, b)
+
+	for i, expected := range []string{"CREATE_IN_PROGRESS", "CREATE_IN_PROGRESS", "CREATE_COMPLETE"} {
+		if status := aws.StringValue(describeStack(t, b, "mycluster").StackStatus); status != expected {
+			t.Errorf("poll %d: expected %s, got %s", i, expected, status)
+		}
+		if i == 0 {
+			if events := resourceStatuses(t, b, "mycluster", "Nested"); len(events) != 0 {
+				t.Errorf("events of an in-progress operation must not be visible yet: %v", events)
+			}
+		}
+	}
+}
+
+func TestChangeSet(t *testing.T) {
+	b := newTestBackend(t)
+	createTestStack(t, b)
+
+	body := strings.Replace(rootTemplate, `"Properties": {}`, `"Properties": {"Path": "/kube-aws/"}`, 1)
+	out, err := b.CloudFormation.CreateChangeSet(&cloudformation.CreateChangeSetInput{
+		StackName:     aws.String("mycluster"),
+		ChangeSetName: aws.String("kube-aws-1"),
+		TemplateBody:  aws.String(body),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	desc, err := b.CloudFormation.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{ChangeSetName: out.Id})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(desc.Changes) != 1 || aws.StringValue(desc.Changes[0].ResourceChange.LogicalResourceId) != "Role" {
+		t.Errorf("unexpected changes: %+v", desc.Changes)
+	}
+	if _, err := b.CloudFormation.ExecuteChangeSet(&cloudformation.ExecuteChangeSetInput{ChangeSetName: out.Id}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"CREATE_IN_PROGRESS", "CREATE_COMPLETE", "UPDATE_IN_PROGRESS", "UPDATE_COMPLETE"}
+	if actual := resourceStatuses(t, b, "mycluster", "Role"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected events of the role: expected %v, got %v", expected, actual)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	b := newTestBackend(t)
+	sess := session.Must(session.NewSession(aws.NewConfig().WithRegion("us-west-1").WithCredentials(credentials.AnonymousCredentials)))
+	b.Install(sess)
+
+	s3Svc := s3.New(sess)
+	req, _ := s3Svc.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String("mybucket"),
+		Key:    aws.String("userdata/controller"),
+		Body:   bytes.NewReader([]byte("#cloud-config")),
+	})
+	req.HTTPRequest.Header.Set("Content-MD5", "invalid")
+	if err := req.Send(); errorCode(err) != "BadDigest" {
+		t.Errorf("expected BadDigest, got %v", err)
+	}
+	if _, err := s3Svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String("mybucket"),
+		Key:    aws.String("userdata/controller"),
+		Body:   bytes.NewReader([]byte("#cloud-config")),
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if o, ok := b.S3.Object("mybucket", "userdata/controller"); !ok || string(o.Body) != "#cloud-config" {
+		t.Errorf("unexpected object: %+v", o)
+	}
+
+	cfSvc := cloudformation.New(sess)
+	if _, err := cfSvc.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String("nonexistent")}); errorCode(err) != "ValidationError" {
+		t.Errorf("expected ValidationError, got %v", err)
+	}
+	if _, err := cfSvc.ListExports(&cloudformation.ListExportsInput{}); errorCode(err) != "NotImplemented" {
+		t.Errorf("expected NotImplemented, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package fakeaws
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+// EC2 holds VPCs, subnets and key pairs existing before a cluster is created. Spot fleets are the ones created by
+// CloudFormation
+type EC2 struct {
+	b        *Backend
+	vpcs     []*ec2.Vpc
+	subnets  []*ec2.Subnet
+	keyPairs []*ec2.KeyPairInfo
+	volumes  []*ec2.Volume
+}
+
+func newEC2(b *Backend) *EC2 {
+	return &EC2{b: b}
+}
+
+// AddVPC adds an existing VPC and returns its ID
+func (e *EC2) AddVPC(cidr string) string {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	id := "vpc-" + e.b.nextID()[4:]
+	e.vpcs = append(e.vpcs, &ec2.Vpc{VpcId: aws.String(id), CidrBlock: aws.String(cidr), State: aws.String(ec2.VpcStateAvailable)})
+	return id
+}
+
+// AddSubnet adds an existing subnet to the VPC and returns its ID
+func (e *EC2) AddSubnet(vpcID string, az string, cidr string) string {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	id := "subnet-" + e.b.nextID()[4:]
+	e.subnets = append(e.subnets, &ec2.Subnet{
+		SubnetId:         aws.String(id),
+		VpcId:            aws.String(vpcID),
+		AvailabilityZone: aws.String(az),
+		CidrBlock:        aws.String(cidr),
+		State:            aws.String(ec2.SubnetStateAvailable),
+	})
+	return id
+}
+
+// AddKeyPair adds an existing key pair
+func (e *EC2) AddKeyPair(name string) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	e.keyPairs = append(e.keyPairs, &ec2.KeyPairInfo{KeyName: aws.String(name), KeyFingerprint: aws.String("1f:51:ae:28:bf:89:e9:d8:1f:25:5d:37:2d:7d:b8:ca:9f:f5:f1:6f")})
+}
+
+func (e *EC2) DescribeVpcs(input *ec2.DescribeVpcsInput) (*ec2.DescribeVpcsOutput, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	out := &ec2.DescribeVpcsOutput{Vpcs: []*ec2.Vpc{}}
+	for _, id := range aws.StringValueSlice(input.VpcIds) {
+		found := false
+		for _, v := range e.vpcs {
+			if aws.StringValue(v.VpcId) == id {
+				out.Vpcs = append(out.Vpcs, v)
+				found = true
+			}
+		}
+		if !found {
+			return nil, newError(http.StatusBadRequest, "InvalidVpcID.NotFound", "The vpc ID '%s' does not exist", id)
+		}
+	}
+	if len(input.VpcIds) == 0 {
+		out.Vpcs = append(out.Vpcs, e.vpcs...)
+	}
+	return out, nil
+}
+
+func (e *EC2) DescribeSubnets(input *ec2.DescribeSubnetsInput) (*ec2.DescribeSubnetsOutput, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	ids := aws.StringValueSlice(input.SubnetIds)
+	out := &ec2.DescribeSubnetsOutput{Subnets: []*ec2.Subnet{}}
+	for _, s := range e.subnets {
+		if len(ids) > 0 && !contains(ids, aws.StringValue(s.SubnetId)) {
+			continue
+		}
+		if !matchesFilters(input.Filters, map[string]string{
+			"vpc-id":            aws.StringValue(s.VpcId),
+			"availability-zone": aws.StringValue(s.AvailabilityZone),
+			"cidr-block":        aws.StringValue(s.CidrBlock),
+		}) {
+			continue
+		}
+		out.Subnets = append(out.Subnets, s)
+	}
+	return out, nil
+}
+
+func (e *EC2) DescribeKeyPairs(input *ec2.DescribeKeyPairsInput) (*ec2.DescribeKeyPairsOutput, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	out := &ec2.DescribeKeyPairsOutput{KeyPairs: []*ec2.KeyPairInfo{}}
+	for _, name := range aws.StringValueSlice(input.KeyNames) {
+		found := false
+		for _, k := range e.keyPairs {
+			if aws.StringValue(k.KeyName) == name {
+				out.KeyPairs = append(out.KeyPairs, k)
+				found = true
+			}
+		}
+		if !found {
+			return nil, newError(http.StatusBadRequest, "InvalidKeyPair.NotFound", "The key pair '%s' does not exist", name)
+		}
+	}
+	if len(input.KeyNames) == 0 {
+		out.KeyPairs = append(out.KeyPairs, e.keyPairs...)
+	}
+	return out, nil
+}
+
+func (e *EC2) DescribeAvailabilityZones(input *ec2.DescribeAvailabilityZonesInput) (*ec2.DescribeAvailabilityZonesOutput, error) {
+	out := &ec2.DescribeAvailabilityZonesOutput{AvailabilityZones: []*ec2.AvailabilityZone{}}
+	for _, suffix := range []string{"a", "b", "c"} {
+		out.AvailabilityZones = append(out.AvailabilityZones, &ec2.AvailabilityZone{
+			ZoneName:   aws.String(e.b.Region + suffix),
+			RegionName: aws.String(e.b.Region),
+			State:      aws.String(ec2.AvailabilityZoneStateAvailable),
+		})
+	}
+	return out, nil
+}
+
+// CreateVolume validates the volume as EC2 does, so that a dry run fails with DryRunOperation only for a valid volume
+func (e *EC2) CreateVolume(input *ec2.CreateVolumeInput) (*ec2.Volume, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	if !strings.HasPrefix(aws.StringValue(input.AvailabilityZone), e.b.Region) {
+		return nil, newError(http.StatusBadRequest, "InvalidParameterValue", "Invalid availability zone: [%s]", aws.StringValue(input.AvailabilityZone))
+	}
+	typ := aws.StringValue(input.VolumeType)
+	if typ == "" {
+		typ = ec2.VolumeTypeStandard
+	}
+	size := aws.Int64Value(input.Size)
+	iops := aws.Int64Value(input.Iops)
+	switch typ {
+	case ec2.VolumeTypeIo1:
+		if iops < 100 || iops > 20000 {
+			return nil, newError(http.StatusBadRequest, "InvalidParameterValue", "Volume iops of %d is too low; minimum is 100.", iops)
+		}
+		if size < 4 {
+			return nil, newError(http.StatusBadRequest, "InvalidParameterValue", "Volume of %dGiB is too small; minimum is 4GiB.", size)
+		}
+	case ec2.VolumeTypeGp2, ec2.VolumeTypeStandard:
+		if iops > 0 {
+			return nil, newError(http.StatusBadRequest, "InvalidParameterCombination", "The parameter iops is not supported for %s volumes.", typ)
+		}
+		if size < 1 {
+			return nil, newError(http.StatusBadRequest, "InvalidParameterValue", "Volume of %dGiB is too small; minimum is 1GiB.", size)
+		}
+	default:
+		return nil, newError(http.StatusBadRequest, "InvalidParameterValue", "Value (%s) for parameter volumeType is invalid.", typ)
+	}
+	if aws.BoolValue(input.DryRun) {
+		return nil, newError(http.StatusPreconditionFailed, "DryRunOperation", "Request would have succeeded, but DryRun flag is set.")
+	}
+
+	v := &ec2.Volume{
+		VolumeId:         aws.String("vol-" + e.b.nextID()),
+		AvailabilityZone: input.AvailabilityZone,
+		Size:             aws.Int64(size),
+		VolumeType:       aws.String(typ),
+		State:            aws.String(ec2.VolumeStateAvailable),
+		CreateTime:       aws.Time(e.b.now()),
+	}
+	if iops > 0 {
+		v.Iops = aws.Int64(iops)
+	}
+	e.volumes = append(e.volumes, v)
+	return v, nil
+}
+
+// DescribeSpotFleetRequests describes spot fleets created by CloudFormation, whose target capacities are fulfilled
+func (e *EC2) DescribeSpotFleetRequests(input *ec2.DescribeSpotFleetRequestsInput) (*ec2.DescribeSpotFleetRequestsOutput, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	ids := aws.StringValueSlice(input.SpotFleetRequestIds)
+	out := &ec2.DescribeSpotFleetRequestsOutput{SpotFleetRequestConfigs: []*ec2.SpotFleetRequestConfig{}}
+	for _, r := range e.b.CloudFormation.liveResources("AWS::EC2::SpotFleet") {
+		if len(ids) > 0 && !contains(ids, r.physicalID) {
+			continue
+		}
+		data, _ := r.properties["SpotFleetRequestConfigData"].(map[string]interface{})
+		capacity := intProperty(data, "TargetCapacity")
+		out.SpotFleetRequestConfigs = append(out.SpotFleetRequestConfigs, &ec2.SpotFleetRequestConfig{
+			SpotFleetRequestId:    aws.String(r.physicalID),
+			SpotFleetRequestState: aws.String(ec2.BatchStateActive),
+			CreateTime:            aws.Time(r.updated),
+			SpotFleetRequestConfig: &ec2.SpotFleetRequestConfigData{
+				TargetCapacity:    aws.Int64(capacity),
+				FulfilledCapacity: aws.Float64(float64(capacity)),
+			},
+		})
+	}
+	if len(ids) > 0 && len(out.SpotFleetRequestConfigs) == 0 {
+		return nil, newError(http.StatusBadRequest, "InvalidSpotFleetRequestId.NotFound", "The spot fleet request ID '%s' does not exist", ids[0])
+	}
+	return out, nil
+}
+
+func matchesFilters(filters []*ec2.Filter, values map[string]string) bool {
+	for _, f := range filters {
+		v, ok := values[aws.StringValue(f.Name)]
+		if !ok || !contains(aws.StringValueSlice(f.Values), v) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
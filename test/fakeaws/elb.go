@@ -0,0 +1,49 @@
+package fakeaws
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elb"
+)
+
+// ELB describes classic load balancers created by CloudFormation
+type ELB struct {
+	b *Backend
+}
+
+func (e *ELB) DescribeLoadBalancers(input *elb.DescribeLoadBalancersInput) (*elb.DescribeLoadBalancersOutput, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+
+	names := aws.StringValueSlice(input.LoadBalancerNames)
+	out := &elb.DescribeLoadBalancersOutput{LoadBalancerDescriptions: []*elb.LoadBalancerDescription{}}
+	for _, r := range e.b.CloudFormation.liveResources("AWS::ElasticLoadBalancing::LoadBalancer") {
+		if len(names) > 0 && !contains(names, r.physicalID) {
+			continue
+		}
+		scheme, _ := r.properties["Scheme"].(string)
+		if scheme == "" {
+			scheme = "internet-facing"
+		}
+		out.LoadBalancerDescriptions = append(out.LoadBalancerDescriptions, &elb.LoadBalancerDescription{
+			LoadBalancerName: aws.String(r.physicalID),
+			DNSName:          aws.String(fmt.Sprintf("%s-123.%s.elb.amazonaws.com", r.physicalID, e.b.Region)),
+			Scheme:           aws.String(scheme),
+			Subnets:          aws.StringSlice(stringsProperty(r.properties, "Subnets")),
+			SecurityGroups:   aws.StringSlice(stringsProperty(r.properties, "SecurityGroups")),
+			CreatedTime:      aws.Time(r.updated),
+		})
+	}
+	for _, name := range names {
+		found := false
+		for _, d := range out.LoadBalancerDescriptions {
+			found = found || aws.StringValue(d.LoadBalancerName) == name
+		}
+		if !found {
+			return nil, newError(http.StatusBadRequest, elb.ErrCodeAccessPointNotFoundException, "There is no ACTIVE Load Balancer named '%s'", name)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package fakeaws
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+)
+
+// ciphertextPrefix marks data encrypted by the fake KMS, which is the plaintext prefixed with the key ARN
+const ciphertextPrefix = "fakeaws-kms:"
+
+// KMS encrypts data with any key ARN. Ciphertexts contain the plaintext as is so that tests can inspect them
+type KMS struct {
+	b *Backend
+}
+
+func (k *KMS) Encrypt(input *kms.EncryptInput) (*kms.EncryptOutput, error) {
+	keyID := aws.StringValue(input.KeyId)
+	if keyID == "" {
+		return nil, newError(http.StatusBadRequest, kms.ErrCodeNotFoundException, "Invalid keyId")
+	}
+	blob := append([]byte(ciphertextPrefix+keyID+"\n"), input.Plaintext...)
+	return &kms.EncryptOutput{CiphertextBlob: blob, KeyId: aws.String(keyID)}, nil
+}
+
+func (k *KMS) Decrypt(input *kms.DecryptInput) (*kms.DecryptOutput, error) {
+	blob := input.CiphertextBlob
+	i := bytes.IndexByte(blob, '\n')
+	if !bytes.HasPrefix(blob, []byte(ciphertextPrefix)) || i < 0 {
+		return nil, newError(http.StatusBadRequest, kms.ErrCodeInvalidCiphertextException, "")
+	}
+	return &kms.DecryptOutput{
+		KeyId:     aws.String(string(blob[len(ciphertextPrefix):i])),
+		Plaintext: blob[i+1:],
+	}, nil
+}
@@ -0,0 +1,138 @@
+package fakeaws
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Route53 holds hosted zones existing before a cluster is created. Record sets are the ones added with AddRecordSet and
+// the ones created by CloudFormation
+type Route53 struct {
+	b          *Backend
+	zones      []*route53.HostedZone
+	recordSets map[string][]*route53.ResourceRecordSet
+}
+
+func newRoute53(b *Backend) *Route53 {
+	return &Route53{b: b, recordSets: map[string][]*route53.ResourceRecordSet{}}
+}
+
+// AddHostedZone adds a public hosted zone for the domain and returns its ID
+func (r *Route53) AddHostedZone(name string) string {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	id := "Z" + strings.ToUpper(r.b.nextID())
+	r.zones = append(r.zones, &route53.HostedZone{
+		Id:              aws.String("/hostedzone/" + id),
+		Name:            aws.String(withTrailingDot(name)),
+		CallerReference: aws.String(id),
+		Config:          &route53.HostedZoneConfig{PrivateZone: aws.Bool(false)},
+	})
+	return id
+}
+
+// AddRecordSet adds an A record to the hosted zone
+func (r *Route53) AddRecordSet(zoneID string, name string, value string) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+	id := trimZoneID(zoneID)
+	r.recordSets[id] = append(r.recordSets[id], &route53.ResourceRecordSet{
+		Name:            aws.String(withTrailingDot(name)),
+		Type:            aws.String(route53.RRTypeA),
+		TTL:             aws.Int64(300),
+		ResourceRecords: []*route53.ResourceRecord{{Value: aws.String(value)}},
+	})
+}
+
+func (r *Route53) GetHostedZone(input *route53.GetHostedZoneInput) (*route53.GetHostedZoneOutput, error) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+
+	z, err := r.zone(aws.StringValue(input.Id))
+	if err != nil {
+		return nil, err
+	}
+	return &route53.GetHostedZoneOutput{HostedZone: z}, nil
+}
+
+func (r *Route53) ListHostedZonesByName(input *route53.ListHostedZonesByNameInput) (*route53.ListHostedZonesByNameOutput, error) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+
+	zones := []*route53.HostedZone{}
+	start := withTrailingDot(aws.StringValue(input.DNSName))
+	for _, z := range r.zones {
+		if input.DNSName == nil || aws.StringValue(z.Name) >= start {
+			zones = append(zones, z)
+		}
+	}
+	sort.Sort(zonesByName(zones))
+	return &route53.ListHostedZonesByNameOutput{
+		DNSName:     input.DNSName,
+		HostedZones: zones,
+		IsTruncated: aws.Bool(false),
+		MaxItems:    aws.String("100"),
+	}, nil
+}
+
+func (r *Route53) ListResourceRecordSets(input *route53.ListResourceRecordSetsInput) (*route53.ListResourceRecordSetsOutput, error) {
+	r.b.mu.Lock()
+	defer r.b.mu.Unlock()
+
+	z, err := r.zone(aws.StringValue(input.HostedZoneId))
+	if err != nil {
+		return nil, err
+	}
+	id := trimZoneID(aws.StringValue(z.Id))
+	recordSets := append([]*route53.ResourceRecordSet{}, r.recordSets[id]...)
+	for _, res := range r.b.CloudFormation.liveResources("AWS::Route53::RecordSet") {
+		zoneID, _ := res.properties["HostedZoneId"].(string)
+		zoneName, _ := res.properties["HostedZoneName"].(string)
+		if trimZoneID(zoneID) != id && withTrailingDot(zoneName) != aws.StringValue(z.Name) {
+			continue
+		}
+		name, _ := res.properties["Name"].(string)
+		typ, _ := res.properties["Type"].(string)
+		recordSets = append(recordSets, &route53.ResourceRecordSet{
+			Name: aws.String(withTrailingDot(name)),
+			Type: aws.String(typ),
+		})
+	}
+	return &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: recordSets,
+		IsTruncated:        aws.Bool(false),
+		MaxItems:           aws.String("100"),
+	}, nil
+}
+
+func (r *Route53) zone(id string) (*route53.HostedZone, error) {
+	for _, z := range r.zones {
+		if trimZoneID(aws.StringValue(z.Id)) == trimZoneID(id) {
+			return z, nil
+		}
+	}
+	return nil, newError(http.StatusNotFound, route53.ErrCodeNoSuchHostedZone, "No hosted zone found with ID: %s", trimZoneID(id))
+}
+
+type zonesByName []*route53.HostedZone
+
+func (z zonesByName) Len() int      { return len(z) }
+func (z zonesByName) Swap(i, j int) { z[i], z[j] = z[j], z[i] }
+func (z zonesByName) Less(i, j int) bool {
+	return aws.StringValue(z[i].Name) < aws.StringValue(z[j].Name)
+}
+
+func trimZoneID(id string) string {
+	return strings.TrimPrefix(id, "/hostedzone/")
+}
+
+func withTrailingDot(name string) string {
+	if name == "" || strings.HasSuffix(name, ".") {
+		return name
+	}
+	return name + "."
+}
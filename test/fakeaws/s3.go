@@ -0,0 +1,308 @@
+package fakeaws
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// S3 stores objects in buckets which must be created beforehand with AddBucket or CreateBucket
+type S3 struct {
+	b       *Backend
+	buckets map[string]map[string]*Object
+}
+
+// Object is an object stored in the fake S3
+type Object struct {
+	Body                 []byte
+	ContentType          string
+	ETag                 string
+	ServerSideEncryption string
+	SSEKMSKeyID          string
+	Tagging              string
+	LastModified         time.Time
+}
+
+func newS3(b *Backend) *S3 {
+	return &S3{b: b, buckets: map[string]map[string]*Object{}}
+}
+
+// AddBucket creates an empty bucket unless it exists
+func (s *S3) AddBucket(name string) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if _, ok := s.buckets[name]; !ok {
+		s.buckets[name] = map[string]*Object{}
+	}
+}
+
+// Object returns the object stored at the key in the bucket
+func (s *S3) Object(bucket string, key string) (*Object, bool) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	o, ok := s.buckets[bucket][key]
+	return o, ok
+}
+
+// Keys returns keys of all the objects in the bucket in the lexical order
+func (s *S3) Keys(bucket string) []string {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	keys := []string{}
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *S3) CreateBucket(input *s3.CreateBucketInput) (*s3.CreateBucketOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	name := aws.StringValue(input.Bucket)
+	if _, ok := s.buckets[name]; ok {
+		return nil, newError(http.StatusConflict, s3.ErrCodeBucketAlreadyOwnedByYou, "Your previous request to create the named bucket succeeded and you already own it.")
+	}
+	s.buckets[name] = map[string]*Object{}
+	return &s3.CreateBucketOutput{Location: aws.String("/" + name)}, nil
+}
+
+func (s *S3) HeadBucket(input *s3.HeadBucketInput) (*s3.HeadBucketOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	if _, err := s.bucket(input.Bucket); err != nil {
+		return nil, newError(http.StatusNotFound, "NotFound", "Not Found")
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (s *S3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	var body []byte
+	if input.Body != nil {
+		if body, err = ioutil.ReadAll(input.Body); err != nil {
+			return nil, err
+		}
+	}
+	sum := md5.Sum(body)
+	o := &Object{
+		Body:                 body,
+		ContentType:          aws.StringValue(input.ContentType),
+		ETag:                 fmt.Sprintf("%q", hex.EncodeToString(sum[:])),
+		ServerSideEncryption: aws.StringValue(input.ServerSideEncryption),
+		SSEKMSKeyID:          aws.StringValue(input.SSEKMSKeyId),
+		Tagging:              aws.StringValue(input.Tagging),
+		LastModified:         s.b.now(),
+	}
+	objects[aws.StringValue(input.Key)] = o
+
+	out := &s3.PutObjectOutput{ETag: aws.String(o.ETag)}
+	if o.ServerSideEncryption != "" {
+		out.ServerSideEncryption = aws.String(o.ServerSideEncryption)
+	}
+	if o.SSEKMSKeyID != "" {
+		out.SSEKMSKeyId = aws.String(o.SSEKMSKeyID)
+	}
+	return out, nil
+}
+
+func (s *S3) HeadObject(input *s3.HeadObjectInput) (*s3.HeadObjectOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := objects[aws.StringValue(input.Key)]
+	if !ok {
+		// HEAD responses have no body so that the error code is derived from the status
+		return nil, newError(http.StatusNotFound, "NotFound", "Not Found")
+	}
+	return &s3.HeadObjectOutput{
+		ContentLength: aws.Int64(int64(len(o.Body))),
+		ContentType:   stringOrNil(o.ContentType),
+		ETag:          aws.String(o.ETag),
+		LastModified:  aws.Time(o.LastModified),
+	}, nil
+}
+
+func (s *S3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	o, ok := objects[aws.StringValue(input.Key)]
+	if !ok {
+		return nil, newError(http.StatusNotFound, s3.ErrCodeNoSuchKey, "The specified key does not exist.")
+	}
+	return &s3.GetObjectOutput{
+		Body:          ioutil.NopCloser(bytes.NewReader(o.Body)),
+		ContentLength: aws.Int64(int64(len(o.Body))),
+		ContentType:   stringOrNil(o.ContentType),
+		ETag:          aws.String(o.ETag),
+		LastModified:  aws.Time(o.LastModified),
+	}, nil
+}
+
+func (s *S3) DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	delete(objects, aws.StringValue(input.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (s *S3) DeleteObjects(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if input.Delete == nil || len(input.Delete.Objects) > 1000 {
+		return nil, newError(http.StatusBadRequest, "MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema")
+	}
+	out := &s3.DeleteObjectsOutput{}
+	for _, o := range input.Delete.Objects {
+		delete(objects, aws.StringValue(o.Key))
+		if !aws.BoolValue(input.Delete.Quiet) {
+			out.Deleted = append(out.Deleted, &s3.DeletedObject{Key: o.Key})
+		}
+	}
+	return out, nil
+}
+
+func (s *S3) ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	start := aws.StringValue(input.StartAfter)
+	if token := aws.StringValue(input.ContinuationToken); token != "" {
+		start = token
+	}
+	contents, truncated := s.list(objects, aws.StringValue(input.Prefix), start, input.MaxKeys)
+
+	out := &s3.ListObjectsV2Output{
+		Name:        input.Bucket,
+		Prefix:      input.Prefix,
+		Contents:    contents,
+		KeyCount:    aws.Int64(int64(len(contents))),
+		IsTruncated: aws.Bool(truncated),
+	}
+	if truncated {
+		out.NextContinuationToken = contents[len(contents)-1].Key
+	}
+	return out, nil
+}
+
+func (s *S3) ListObjects(input *s3.ListObjectsInput) (*s3.ListObjectsOutput, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	objects, err := s.bucket(input.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	contents, truncated := s.list(objects, aws.StringValue(input.Prefix), aws.StringValue(input.Marker), input.MaxKeys)
+
+	out := &s3.ListObjectsOutput{
+		Name:        input.Bucket,
+		Prefix:      input.Prefix,
+		Contents:    contents,
+		IsTruncated: aws.Bool(truncated),
+	}
+	if truncated {
+		out.NextMarker = contents[len(contents)-1].Key
+	}
+	return out, nil
+}
+
+// list returns objects having the prefix whose keys are after the start key in the lexical order, up to maxKeys
+func (s *S3) list(objects map[string]*Object, prefix string, start string, maxKeys *int64) ([]*s3.Object, bool) {
+	limit := 1000
+	if maxKeys != nil && *maxKeys > 0 && *maxKeys < 1000 {
+		limit = int(*maxKeys)
+	}
+	keys := []string{}
+	for k := range objects {
+		if strings.HasPrefix(k, prefix) && k > start {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	truncated := len(keys) > limit
+	if truncated {
+		keys = keys[:limit]
+	}
+	contents := []*s3.Object{}
+	for _, k := range keys {
+		o := objects[k]
+		contents = append(contents, &s3.Object{
+			Key:          aws.String(k),
+			ETag:         aws.String(o.ETag),
+			Size:         aws.Int64(int64(len(o.Body))),
+			LastModified: aws.Time(o.LastModified),
+			StorageClass: aws.String(s3.ObjectStorageClassStandard),
+		})
+	}
+	return contents, truncated
+}
+
+func (s *S3) bucket(name *string) (map[string]*Object, error) {
+	objects, ok := s.buckets[aws.StringValue(name)]
+	if !ok {
+		return nil, newError(http.StatusNotFound, s3.ErrCodeNoSuchBucket, "The specified bucket does not exist")
+	}
+	return objects, nil
+}
+
+// objectAtURL returns the object referenced by a path-style or virtual hosted-style URL e.g. a template URL
+func (s *S3) objectAtURL(rawurl string) (*Object, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, false
+	}
+	path := strings.TrimPrefix(u.Path, "/")
+	var bucket, key string
+	if i := strings.Index(u.Host, ".s3"); i > 0 && !strings.HasPrefix(u.Host, "s3") {
+		bucket, key = u.Host[:i], path
+	} else {
+		parts := strings.SplitN(path, "/", 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		bucket, key = parts[0], parts[1]
+	}
+	o, ok := s.buckets[bucket][key]
+	return o, ok
+}
@@ -0,0 +1,506 @@
+package fakeaws
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/coreos/kube-aws/model"
+)
+
+const nestedStackType = "AWS::CloudFormation::Stack"
+
+// template is a parsed JSON CloudFormation template. Only what is needed to simulate stack operations is interpreted
+type template struct {
+	Parameters map[string]templateParameter `json:"Parameters"`
+	Mappings   map[string]map[string]map[string]interface{}
+	Conditions map[string]interface{}
+	Resources  map[string]templateResource
+	Outputs    map[string]templateOutput
+}
+
+type templateParameter struct {
+	Type    string
+	Default interface{}
+}
+
+type templateResource struct {
+	Type       string
+	Condition  string
+	Properties map[string]interface{}
+	DependsOn  interface{}
+}
+
+type templateOutput struct {
+	Description string
+	Condition   string
+	Value       interface{}
+	Export      *struct {
+		Name interface{}
+	}
+}
+
+func parseTemplate(body string) (*template, error) {
+	var t template
+	if err := json.Unmarshal([]byte(body), &t); err != nil {
+		return nil, newError(http.StatusBadRequest, "ValidationError", "Template format error: JSON not well-formed. (%v)", err)
+	}
+	if len(t.Resources) == 0 {
+		return nil, newError(http.StatusBadRequest, "ValidationError", "Template format error: At least one Resources member must be defined.")
+	}
+	for id, r := range t.Resources {
+		if r.Type == "" {
+			return nil, newError(http.StatusBadRequest, "ValidationError", "Template format error: [/Resources/%s] Every Resources object must contain a Type member.", id)
+		}
+	}
+	return &t, nil
+}
+
+// dependencies returns logical IDs of resources the resource depends on explicitly via DependsOn or implicitly via Ref
+// and Fn::GetAtt
+func (t *template) dependencies(id string) []string {
+	r := t.Resources[id]
+	deps := map[string]bool{}
+	switch d := r.DependsOn.(type) {
+	case string:
+		deps[d] = true
+	case []interface{}:
+		for _, v := range d {
+			if s, ok := v.(string); ok {
+				deps[s] = true
+			}
+		}
+	}
+	collectReferences(r.Properties, deps)
+
+	result := []string{}
+	for d := range deps {
+		if _, ok := t.Resources[d]; ok && d != id {
+			result = append(result, d)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func collectReferences(v interface{}, refs map[string]bool) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["Ref"].(string); ok && len(v) == 1 {
+			refs[ref] = true
+			return
+		}
+		if args, ok := v["Fn::GetAtt"].([]interface{}); ok && len(v) == 1 && len(args) > 0 {
+			if id, ok := args[0].(string); ok {
+				refs[id] = true
+			}
+			return
+		}
+		for _, e := range v {
+			collectReferences(e, refs)
+		}
+	case []interface{}:
+		for _, e := range v {
+			collectReferences(e, refs)
+		}
+	}
+}
+
+// creationOrder returns logical IDs of the resources sorted so that every resource comes after its dependencies
+func (t *template) creationOrder() ([]string, error) {
+	ids := []string{}
+	for id := range t.Resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	order := []string{}
+	state := map[string]int{}
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case 1:
+			return newError(http.StatusBadRequest, "ValidationError", "Circular dependency between resources: [%s]", id)
+		case 2:
+			return nil
+		}
+		state[id] = 1
+		for _, d := range t.dependencies(id) {
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[id] = 2
+		order = append(order, id)
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// evaluator resolves intrinsic functions in a template against the state of a stack
+type evaluator struct {
+	b         *Backend
+	s         *stack
+	tmpl      *template
+	params    map[string]string
+	resources map[string]*stackResource
+}
+
+func (e evaluator) condition(name string) (bool, error) {
+	if name == "" {
+		return true, nil
+	}
+	c, ok := e.tmpl.Conditions[name]
+	if !ok {
+		return false, newError(http.StatusBadRequest, "ValidationError", "Template format error: Unresolved condition dependency %s", name)
+	}
+	return e.truthy(c)
+}
+
+func (e evaluator) truthy(v interface{}) (bool, error) {
+	switch v := v.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(v)
+	case map[string]interface{}:
+		if len(v) != 1 {
+			break
+		}
+		for fn, args := range v {
+			list, _ := args.([]interface{})
+			switch fn {
+			case "Condition":
+				name, _ := args.(string)
+				return e.condition(name)
+			case "Fn::Equals":
+				if len(list) != 2 {
+					break
+				}
+				a, err := e.eval(list[0])
+				if err != nil {
+					return false, err
+				}
+				b, err := e.eval(list[1])
+				if err != nil {
+					return false, err
+				}
+				return fmt.Sprint(a) == fmt.Sprint(b), nil
+			case "Fn::Not":
+				if len(list) != 1 {
+					break
+				}
+				t, err := e.truthy(list[0])
+				return !t, err
+			case "Fn::And", "Fn::Or":
+				result := fn == "Fn::And"
+				for _, c := range list {
+					t, err := e.truthy(c)
+					if err != nil {
+						return false, err
+					}
+					if fn == "Fn::And" {
+						result = result && t
+					} else {
+						result = result || t
+					}
+				}
+				return result, nil
+			}
+		}
+	}
+	return false, newError(http.StatusBadRequest, "ValidationError", "Template format error: unsupported condition %v", v)
+}
+
+// eval returns the value with intrinsic functions resolved. Attributes of resources other than nested stacks are
+// resolved to placeholders derived from their physical IDs
+func (e evaluator) eval(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for fn, args := range v {
+				if fn == "Ref" || fn == "Condition" || strings.HasPrefix(fn, "Fn::") {
+					return e.call(fn, args)
+				}
+			}
+		}
+		m := map[string]interface{}{}
+		for k, val := range v {
+			r, err := e.eval(val)
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				m[k] = r
+			}
+		}
+		return m, nil
+	case []interface{}:
+		l := []interface{}{}
+		for _, val := range v {
+			r, err := e.eval(val)
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				l = append(l, r)
+			}
+		}
+		return l, nil
+	default:
+		return v, nil
+	}
+}
+
+func (e evaluator) evalString(v interface{}) (string, error) {
+	r, err := e.eval(v)
+	if err != nil {
+		return "", err
+	}
+	switch r := r.(type) {
+	case string:
+		return r, nil
+	case nil:
+		return "", nil
+	case map[string]interface{}, []interface{}:
+		return "", newError(http.StatusBadRequest, "ValidationError", "Template error: expected a string but got %v", r)
+	default:
+		return fmt.Sprint(r), nil
+	}
+}
+
+func (e evaluator) call(fn string, args interface{}) (interface{}, error) {
+	list, _ := args.([]interface{})
+	switch fn {
+	case "Ref":
+		name, _ := args.(string)
+		return e.ref(name)
+	case "Condition":
+		name, _ := args.(string)
+		return e.condition(name)
+	case "Fn::GetAtt":
+		if len(list) != 2 {
+			break
+		}
+		id, _ := list[0].(string)
+		attr, _ := list[1].(string)
+		return e.getAtt(id, attr)
+	case "Fn::Join":
+		if len(list) != 2 {
+			break
+		}
+		delim, _ := list[0].(string)
+		items, err := e.eval(list[1])
+		if err != nil {
+			return nil, err
+		}
+		strs := []string{}
+		itemList, _ := items.([]interface{})
+		for _, i := range itemList {
+			strs = append(strs, fmt.Sprint(i))
+		}
+		return strings.Join(strs, delim), nil
+	case "Fn::Split":
+		if len(list) != 2 {
+			break
+		}
+		delim, _ := list[0].(string)
+		source, err := e.evalString(list[1])
+		if err != nil {
+			return nil, err
+		}
+		items := []interface{}{}
+		for _, i := range strings.Split(source, delim) {
+			items = append(items, i)
+		}
+		return items, nil
+	case "Fn::Sub":
+		var format string
+		vars := map[string]interface{}{}
+		switch a := args.(type) {
+		case string:
+			format = a
+		case []interface{}:
+			if len(a) != 2 {
+				break
+			}
+			format, _ = a[0].(string)
+			vars, _ = a[1].(map[string]interface{})
+		}
+		return e.sub(format, vars)
+	case "Fn::Select":
+		if len(list) != 2 {
+			break
+		}
+		index, err := e.evalString(list[0])
+		if err != nil {
+			return nil, err
+		}
+		items, err := e.eval(list[1])
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(index)
+		itemList, _ := items.([]interface{})
+		if err != nil || i < 0 || i >= len(itemList) {
+			return nil, newError(http.StatusBadRequest, "ValidationError", "Template error: Fn::Select cannot select nonexistent value at index %s", index)
+		}
+		return itemList[i], nil
+	case "Fn::If":
+		if len(list) != 3 {
+			break
+		}
+		name, _ := list[0].(string)
+		t, err := e.condition(name)
+		if err != nil {
+			return nil, err
+		}
+		if t {
+			return e.eval(list[1])
+		}
+		return e.eval(list[2])
+	case "Fn::FindInMap":
+		if len(list) != 3 {
+			break
+		}
+		keys := []string{}
+		for _, k := range list {
+			s, err := e.evalString(k)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, s)
+		}
+		v, ok := e.tmpl.Mappings[keys[0]][keys[1]][keys[2]]
+		if !ok {
+			return nil, newError(http.StatusBadRequest, "ValidationError", "Template error: Unable to get mapping for %s::%s::%s", keys[0], keys[1], keys[2])
+		}
+		return v, nil
+	case "Fn::GetAZs":
+		return []interface{}{e.b.Region + "a", e.b.Region + "b", e.b.Region + "c"}, nil
+	case "Fn::Base64":
+		return e.evalString(args)
+	case "Fn::ImportValue":
+		name, err := e.evalString(args)
+		if err != nil {
+			return nil, err
+		}
+		v, ok := e.b.CloudFormation.exports[name]
+		if !ok {
+			return nil, newError(http.StatusBadRequest, "ValidationError", "No export named %s found.", name)
+		}
+		return v.value, nil
+	}
+	return nil, newError(http.StatusBadRequest, "ValidationError", "Template error: unsupported or malformed intrinsic function %s: %v", fn, args)
+}
+
+func (e evaluator) ref(name string) (interface{}, error) {
+	switch name {
+	case "AWS::StackName":
+		return e.s.name, nil
+	case "AWS::StackId":
+		return e.s.id, nil
+	case "AWS::Region":
+		return e.b.Region, nil
+	case "AWS::AccountId":
+		return e.b.AccountID, nil
+	case "AWS::Partition":
+		return partitionOf(e.b.Region), nil
+	case "AWS::URLSuffix":
+		return model.PartitionForRegion(e.b.Region).DNSSuffix, nil
+	case "AWS::NoValue":
+		return nil, nil
+	}
+	if v, ok := e.params[name]; ok {
+		return v, nil
+	}
+	if r, ok := e.resources[name]; ok {
+		return r.physicalID, nil
+	}
+	return nil, newError(http.StatusBadRequest, "ValidationError", "Template format error: Unresolved resource dependencies [%s] in the Resources block of the template", name)
+}
+
+func (e evaluator) getAtt(id string, attr string) (interface{}, error) {
+	r, ok := e.resources[id]
+	if !ok {
+		return nil, newError(http.StatusBadRequest, "ValidationError", "Template error: instance of Fn::GetAtt references undefined resource %s", id)
+	}
+	if r.nested != nil && strings.HasPrefix(attr, "Outputs.") {
+		key := strings.TrimPrefix(attr, "Outputs.")
+		v, ok := r.nested.outputs[key]
+		if !ok {
+			return nil, newError(http.StatusBadRequest, "ValidationError", "Template error: resource %s does not support attribute type %s in Fn::GetAtt", id, attr)
+		}
+		return v, nil
+	}
+	return fmt.Sprintf("%s.%s", r.physicalID, attr), nil
+}
+
+func (e evaluator) sub(format string, vars map[string]interface{}) (string, error) {
+	result := ""
+	for {
+		start := strings.Index(format, "${")
+		if start < 0 {
+			return result + format, nil
+		}
+		end := strings.Index(format[start:], "}")
+		if end < 0 {
+			return result + format, nil
+		}
+		name := format[start+2 : start+end]
+		var v interface{}
+		var err error
+		if val, ok := vars[name]; ok {
+			v, err = e.eval(val)
+		} else if i := strings.Index(name, "."); i > 0 {
+			v, err = e.getAtt(name[:i], name[i+1:])
+		} else {
+			v, err = e.ref(name)
+		}
+		if err != nil {
+			return "", err
+		}
+		result += format[:start] + fmt.Sprint(v)
+		format = format[start+end+1:]
+	}
+}
+
+// propertiesEqual returns true when the resource definitions are the same ignoring formatting
+func propertiesEqual(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func partitionOf(region string) string {
+	return model.PartitionForRegion(region).ID
+}
+
+// intProperty returns the property as an integer, which is either a JSON number or a string in templates
+func intProperty(props map[string]interface{}, key string) int64 {
+	switch v := props[key].(type) {
+	case float64:
+		return int64(v)
+	case string:
+		i, _ := strconv.ParseInt(v, 10, 64)
+		return i
+	}
+	return 0
+}
+
+// stringsProperty returns the property which is a list of strings
+func stringsProperty(props map[string]interface{}, key string) []string {
+	result := []string{}
+	vs, _ := props[key].([]interface{})
+	for _, v := range vs {
+		result = append(result, fmt.Sprint(v))
+	}
+	return result
+}
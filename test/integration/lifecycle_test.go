@@ -0,0 +1,188 @@
+package integration
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudformation"
+	"github.com/coreos/kube-aws/cfnstack"
+	"github.com/coreos/kube-aws/core/root"
+	"github.com/coreos/kube-aws/core/root/config"
+	"github.com/coreos/kube-aws/test/fakeaws"
+	"github.com/coreos/kube-aws/test/helper"
+)
+
+// Creates, updates and destroys a cluster against the in-memory fake AWS backend
+func TestClusterLifecycleWithFakeAWS(t *testing.T) {
+	backend := fakeaws.New("us-west-1")
+	deactivate := backend.Activate()
+	defer deactivate()
+
+	// Stacks are reported in progress for a while so that waiters poll them
+	backend.CloudFormation.PollsToComplete = 2
+	initial, max := cfnstack.InitialPollInterval, cfnstack.MaxPollInterval
+	cfnstack.InitialPollInterval, cfnstack.MaxPollInterval = time.Millisecond, time.Millisecond
+	defer func() {
+		cfnstack.InitialPollInterval, cfnstack.MaxPollInterval = initial, max
+	}()
+
+	settings := kubeAwsSettings{
+		clusterName:     "kubeaws-fake",
+		externalDNSName: "test.staging.core-os.net",
+		keyName:         "test-key-name",
+		kmsKeyArn:       "arn:aws:kms:us-west-1:123456789012:key/00000000-0000-0000-0000-000000000000",
+		region:          "us-west-1",
+	}
+	backend.S3.AddBucket("examplebucket")
+	backend.EC2.AddKeyPair(settings.keyName)
+	s3URI := "s3://examplebucket/exampledir"
+
+	clusterYaml := func(terminationProtection bool, workerCount int) string {
+		return settings.minimumValidClusterYaml() + fmt.Sprintf(`
+amiId: ami-12345678
+enableTerminationProtection: %v
+worker:
+  nodePools:
+  - name: pool1
+    count: %d
+`, terminationProtection, workerCount)
+	}
+	configYaml := clusterYaml(true, 1)
+
+	helper.WithDummyCredentials(func(dummyAssetsDir string) {
+		configPath := filepath.Join(dummyAssetsDir, "cluster.yaml")
+		if err := ioutil.WriteFile(configPath, []byte(configYaml), 0644); err != nil {
+			t.Fatalf("failed to write cluster.yaml: %v", err)
+		}
+
+		newCluster := func(yaml string) root.Cluster {
+			cfg, err := config.ConfigFromBytesWithEncryptService([]byte(yaml), helper.DummyEncryptService{})
+			if err != nil {
+				t.Fatalf("failed to parse config: %v", err)
+			}
+			opts := root.NewOptions(s3URI, false, false)
+			opts.AssetsDir = dummyAssetsDir
+			opts.ControllerTmplFile = "../../core/controlplane/config/templates/cloud-config-controller"
+			opts.WorkerTmplFile = "../../core/controlplane/config/templates/cloud-config-worker"
+			opts.EtcdTmplFile = "../../core/controlplane/config/templates/cloud-config-etcd"
+			opts.RootStackTemplateTmplFile = "../../core/root/config/templates/stack-template.json"
+			opts.NodePoolStackTemplateTmplFile = "../../core/nodepool/config/templates/stack-template.json"
+			opts.ControlPlaneStackTemplateTmplFile = "../../core/controlplane/config/templates/stack-template.json"
+			cluster, err := root.ClusterFromConfig(cfg, opts, false)
+			if err != nil {
+				t.Fatalf("failed to create cluster driver: %v", err)
+			}
+			return cluster
+		}
+
+		cluster := newCluster(configYaml)
+
+		problems, err := cluster.LintTemplates()
+		if err != nil {
+			t.Fatalf("failed to lint templates: %v", err)
+		}
+		for _, p := range problems {
+			t.Errorf("unexpected problem in rendered templates: %s", p)
+		}
+
+		if report, err := cluster.ValidateStack(); err != nil {
+			t.Fatalf("failed to validate stack: %s %v", report, err)
+		}
+
+		if err := cluster.Create(); err != nil {
+			t.Fatalf("failed to create cluster: %v", err)
+		}
+		stacks := backend.CloudFormation.LiveStackNames()
+		if len(stacks) != 3 {
+			t.Errorf("expected the root stack and nested stacks for the control plane and pool1, got %v", stacks)
+		}
+		if !backend.CloudFormation.TerminationProtectionEnabled(settings.clusterName) {
+			t.Errorf("termination protection was not enabled on the root stack")
+		}
+		if keys := backend.S3.Keys("examplebucket"); len(keys) == 0 {
+			t.Errorf("no assets were uploaded")
+		}
+
+		plan, err := cluster.Plan("kube-aws-plan-unchanged")
+		if err != nil {
+			t.Fatalf("failed to plan: %v", err)
+		}
+		if plan.ChangeSetName != "" || plan.HasReplacements() {
+			t.Errorf("expected nothing to be applied to the unchanged cluster, got %s", plan)
+		}
+		if _, err := backend.CloudFormation.DescribeChangeSet(&cloudformation.DescribeChangeSetInput{
+			StackName:     aws.String(settings.clusterName),
+			ChangeSetName: aws.String("kube-aws-plan-unchanged"),
+		}); err == nil {
+			t.Errorf("expected the empty change set to be deleted")
+		}
+
+		info, err := cluster.Info()
+		if err != nil {
+			t.Fatalf("failed to describe cluster: %v", err)
+		}
+		if !strings.HasSuffix(info.ControlPlane.ControllerHost, ".us-west-1.elb.amazonaws.com") {
+			t.Errorf("unexpected controller host: %s", info.ControlPlane.ControllerHost)
+		}
+
+		destroyer, err := root.ClusterDestroyerFromFile(configPath, root.DestroyOptions{Wait: true, DeleteAssets: true, S3URI: s3URI})
+		if err != nil {
+			t.Fatalf("failed to create destroyer: %v", err)
+		}
+		if err := destroyer.Destroy(); !cfnstack.IsTerminationProtectionError(err) {
+			t.Errorf("expected destroy to be prevented by termination protection, got %v", err)
+		}
+
+		// Disables termination protection while scaling out the node pool
+		updated := newCluster(clusterYaml(false, 2))
+		if _, err := updated.Update(); err != nil {
+			t.Fatalf("failed to update cluster: %v", err)
+		}
+		if status := stackStatus(t, backend, settings.clusterName); status != cloudformation.StackStatusUpdateComplete {
+			t.Errorf("expected %s, got %s", cloudformation.StackStatusUpdateComplete, status)
+		}
+
+		if backend.CloudFormation.TerminationProtectionEnabled(settings.clusterName) {
+			t.Errorf("termination protection was not disabled on the root stack")
+		}
+		if updates := backend.CloudFormation.TerminationProtectionUpdates(); updates != 2 {
+			t.Errorf("expected termination protection to be updated once on create and once on update, but was updated %d times", updates)
+		}
+
+		// Leaves termination protection as is while it is unchanged
+		if _, err := newCluster(clusterYaml(false, 3)).Update(); err != nil {
+			t.Fatalf("failed to update cluster: %v", err)
+		}
+		if updates := backend.CloudFormation.TerminationProtectionUpdates(); updates != 2 {
+			t.Errorf("expected termination protection not to be updated while it is unchanged, but was updated %d times", updates-2)
+		}
+
+		if err := destroyer.Destroy(); err != nil {
+			t.Fatalf("failed to destroy cluster: %v", err)
+		}
+		if stacks := backend.CloudFormation.LiveStackNames(); len(stacks) != 0 {
+			t.Errorf("stacks left after destroy: %v", stacks)
+		}
+		if keys := backend.S3.Keys("examplebucket"); len(keys) != 0 {
+			t.Errorf("assets left after destroy: %v", keys)
+		}
+	})
+}
+
+func stackStatus(t *testing.T, backend *fakeaws.Backend, name string) string {
+	var status string
+	// Polls until the operation is reported complete
+	for i := 0; i <= backend.CloudFormation.PollsToComplete; i++ {
+		resp, err := backend.CloudFormation.DescribeStacks(&cloudformation.DescribeStacksInput{StackName: aws.String(name)})
+		if err != nil {
+			t.Fatalf("failed to describe stack %s: %v", name, err)
+		}
+		status = aws.StringValue(resp.Stacks[0].StackStatus)
+	}
+	return status
+}
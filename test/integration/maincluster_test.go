@@ -2,6 +2,7 @@ package integration
 
 import (
 	"fmt"
+	"github.com/coreos/kube-aws/cfnlint"
 	"github.com/coreos/kube-aws/cfnstack"
 	controlplane_config "github.com/coreos/kube-aws/core/controlplane/config"
 	"github.com/coreos/kube-aws/core/root"
@@ -1233,6 +1234,31 @@ worker:
 				},
 			},
 		},
+		{
+			context: "WithIgnitionUserData",
+			configYaml: minimalValidConfigYaml + `
+userDataFormat: ignition
+worker:
+  nodePools:
+  - name: pool1
+  - name: pool2
+    userDataFormat: cloud-config
+`,
+			assertConfig: []ConfigTester{
+				hasDefaultEtcdSettings,
+				func(c *config.Config, t *testing.T) {
+					if c.UserDataFormat != "ignition" {
+						t.Errorf("unexpected userDataFormat: %s", c.UserDataFormat)
+					}
+					if c.NodePools[0].UserDataFormat != "ignition" {
+						t.Errorf("expected node pool at index 0 to inherit userDataFormat, got %s", c.NodePools[0].UserDataFormat)
+					}
+					if c.NodePools[1].UserDataFormat != "cloud-config" {
+						t.Errorf("unexpected userDataFormat of node pool at index 1: %s", c.NodePools[1].UserDataFormat)
+					}
+				},
+			},
+		},
 		{
 			context:    "WithMinimalValidConfig",
 			configYaml: minimalValidConfigYaml,
@@ -2584,6 +2610,36 @@ etcdDataVolumeIOPS: 104
 					}
 				})
 
+				t.Run("LintTemplates", func(t *testing.T) {
+					problems, err := cluster.LintTemplates()
+					if err != nil {
+						t.Fatalf("failed to lint stack templates: %v", err)
+					}
+					for _, p := range problems {
+						// Warnings e.g. imports of values exported by stacks outside the cluster are expected
+						if p.Severity == cfnlint.SeverityError {
+							t.Errorf("unexpected problem in stack templates: %s", p)
+						}
+					}
+				})
+
+				t.Run("UserDataSizes", func(t *testing.T) {
+					sizes, err := cluster.UserDataSizes()
+					if err != nil {
+						t.Fatalf("failed to size userdata: %v", err)
+					}
+					roles := map[string]int{}
+					for _, s := range sizes {
+						roles[s.Role]++
+						if s.Headroom() < 0 {
+							t.Errorf("userdata exceeds the limit: %+v", s)
+						}
+					}
+					if roles["controller"] != 1 || roles["etcd"] != 1 || roles["worker"] != len(providedConfig.NodePools) {
+						t.Errorf("expected userdata of every role to be sized, got %+v", sizes)
+					}
+				})
+
 				if os.Getenv("KUBE_AWS_INTEGRATION_TEST") == "" {
 					t.Skipf("`export KUBE_AWS_INTEGRATION_TEST=1` is required to run integration tests. Skipping.")
 					t.SkipNow()
@@ -2628,6 +2684,13 @@ controller:
 			configYaml:           kubeAwsSettings.withClusterName("my.cluster").minimumValidClusterYaml(),
 			expectedErrorMessage: "clusterName(=my.cluster) is malformed. It must consist only of alphanumeric characters, colons, or hyphens",
 		},
+		{
+			context: "WithInvalidUserDataFormat",
+			configYaml: minimalValidConfigYaml + `
+userDataFormat: yaml
+`,
+			expectedErrorMessage: "userDataFormat must be either cloud-config or ignition but was yaml",
+		},
 		{
 			context: "WithInvalidTaint",
 			configYaml: minimalValidConfigYaml + `
@@ -2818,6 +2881,89 @@ worker:
 `,
 			expectedErrorMessage: "unknown keys found in worker.nodePools[0].clusterAutoscaler: baz",
 		},
+		{
+			context: "WithUnknownKeyInControllerCustomSystemdUnitDropIn",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  customSystemdUnits:
+  - name: docker.service
+    dropIns:
+    - name: 90-custom.conf
+      contents: foo
+`,
+			expectedErrorMessage: "unknown keys found in controller.customSystemdUnits[0].dropIns[0]: contents",
+		},
+		{
+			context: "WithInvalidEtcdCustomSystemdUnitName",
+			configYaml: minimalValidConfigYaml + `
+etcd:
+  customSystemdUnits:
+  - name: etcd-backup
+    command: start
+`,
+			expectedErrorMessage: "invalid name \"etcd-backup\" of custom systemd unit: it must end with a unit type like .service or .timer",
+		},
+		{
+			context: "WithRelativeWorkerNodePoolCustomFilePath",
+			configYaml: minimalValidConfigYaml + `
+worker:
+  nodePools:
+  - name: pool1
+    customFiles:
+    - path: etc/motd
+      content: foo
+`,
+			expectedErrorMessage: "invalid path \"etc/motd\" of custom file: it must be absolute and clean",
+		},
+		{
+			context: "WithNonOctalControllerCustomFilePermissions",
+			configYaml: minimalValidConfigYaml + `
+controller:
+  customFiles:
+  - path: /etc/motd
+    permissions: 0689
+    content: foo
+`,
+			expectedErrorMessage: "invalid file mode \"0689\": it must be in the octal notation like 0644",
+		},
+		{
+			context: "WithUnsupportedTLSKeyAlgorithm",
+			configYaml: minimalValidConfigYaml + `
+tls:
+  keyAlgorithm: dsa-1024
+`,
+			expectedErrorMessage: "`tls.keyAlgorithm` must be one of [rsa-2048 rsa-4096 ecdsa-p256 ecdsa-p384] but was \"dsa-1024\"",
+		},
+		{
+			context: "WithTLSBootstrapOnKubernetes15",
+			configYaml: minimalValidConfigYaml + `
+kubernetesVersion: v1.5.5_coreos.0
+experimental:
+  plugins:
+    rbac:
+      enabled: true
+  tlsBootstrap:
+    enabled: true
+`,
+			expectedErrorMessage: "`experimental.tlsBootstrap.enabled` requires `kubernetesVersion` to be v1.6.0 or later",
+		},
+		{
+			context: "WithTLSBootstrapWithoutRBAC",
+			configYaml: minimalValidConfigYaml + `
+experimental:
+  tlsBootstrap:
+    enabled: true
+`,
+			expectedErrorMessage: "`experimental.tlsBootstrap.enabled` requires `experimental.plugins.rbac.enabled` to be true",
+		},
+		{
+			context: "WithUnknownKeyInTLS",
+			configYaml: minimalValidConfigYaml + `
+tls:
+  keySize: 4096
+`,
+			expectedErrorMessage: "unknown keys found in tls: keySize",
+		},
 		{
 			context: "WithTooLongControllerIAMRoleName",
 			configYaml: kubeAwsSettings.withClusterName("kubeaws-it-main").withRegion("ap-northeast-1").minimumValidClusterYaml() + `
@@ -0,0 +1,48 @@
+package tlsutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+)
+
+const (
+	KeyAlgorithmRSA2048   = "rsa-2048"
+	KeyAlgorithmRSA4096   = "rsa-4096"
+	KeyAlgorithmECDSAP256 = "ecdsa-p256"
+	KeyAlgorithmECDSAP384 = "ecdsa-p384"
+
+	DefaultKeyAlgorithm = KeyAlgorithmRSA2048
+)
+
+// KeyAlgorithms are the supported algorithms of private keys
+var KeyAlgorithms = []string{KeyAlgorithmRSA2048, KeyAlgorithmRSA4096, KeyAlgorithmECDSAP256, KeyAlgorithmECDSAP384}
+
+// NewPrivateKeyWithAlgorithm generates a private key with one of KeyAlgorithms
+func NewPrivateKeyWithAlgorithm(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case KeyAlgorithmRSA2048:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case KeyAlgorithmRSA4096:
+		return rsa.GenerateKey(rand.Reader, 4096)
+	case KeyAlgorithmECDSAP256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case KeyAlgorithmECDSAP384:
+		return ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	default:
+		return nil, fmt.Errorf("unsupported key algorithm %q: must be one of %v", algorithm, KeyAlgorithms)
+	}
+}
+
+// keyUsage adds key encipherment to the usages of a certificate for the key if it is an RSA key.
+// ECDSA keys can only sign
+func keyUsage(key crypto.Signer, usage x509.KeyUsage) x509.KeyUsage {
+	if _, ok := key.Public().(*rsa.PublicKey); ok {
+		return usage | x509.KeyUsageKeyEncipherment
+	}
+	return usage
+}
@@ -1,22 +1,63 @@
 package tlsutil
 
 import (
+	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
+	"fmt"
 )
 
-func EncodePrivateKeyPEM(key *rsa.PrivateKey) []byte {
-	block := pem.Block{
-		Type:  "RSA PRIVATE KEY",
-		Bytes: x509.MarshalPKCS1PrivateKey(key),
+// EncodePrivateKeyPEM encodes an RSA key in PKCS#1 or an ECDSA key in SEC 1
+func EncodePrivateKeyPEM(key crypto.Signer) ([]byte, error) {
+	var block pem.Block
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		block = pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(k),
+		}
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		block = pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: der,
+		}
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
 	}
-	return pem.EncodeToMemory(&block)
+	return pem.EncodeToMemory(&block), nil
 }
 
-func DecodePrivateKeyPEM(data []byte) (*rsa.PrivateKey, error) {
+// DecodePrivateKeyPEM decodes an RSA key in PKCS#1, an ECDSA key in SEC 1, or either of them in PKCS#8
+func DecodePrivateKeyPEM(data []byte) (crypto.Signer, error) {
 	block, _ := pem.Decode(data)
-	return x509.ParsePKCS1PrivateKey(block.Bytes)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	default:
+		return nil, fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
 }
 
 func EncodeCertificatePEM(cert *x509.Certificate) []byte {
@@ -29,5 +70,32 @@ func EncodeCertificatePEM(cert *x509.Certificate) []byte {
 
 func DecodeCertificatePEM(data []byte) (*x509.Certificate, error) {
 	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
 	return x509.ParseCertificate(block.Bytes)
 }
+
+// DecodeCertificatesPEM parses every certificate in a PEM bundle such as a CA bundle. Blocks other than certificates are skipped
+func DecodeCertificatesPEM(data []byte) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no certificate found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,90 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// CertSigner issues the server and client certificates of the cluster for private keys generated by kube-aws
+type CertSigner interface {
+	SignServerCertificate(cfg ServerCertConfig, key crypto.Signer) (*x509.Certificate, error)
+	SignClientCertificate(cfg ClientCertConfig, key crypto.Signer) (*x509.Certificate, error)
+	// CACertificates returns the issuing CA followed by the CAs up to the root, which are written to ca.pem
+	CACertificates() ([]*x509.Certificate, error)
+}
+
+// caSigner signs certificates with a CA key available locally
+type caSigner struct {
+	chain []*x509.Certificate
+	key   crypto.Signer
+}
+
+// NewLocalCASigner returns a signer backed by a root CA, either generated by kube-aws or read from --ca-key-path
+func NewLocalCASigner(caCert *x509.Certificate, caKey crypto.Signer) CertSigner {
+	return &caSigner{chain: []*x509.Certificate{caCert}, key: caKey}
+}
+
+// NewIntermediateCASigner returns a signer backed by an intermediate CA. The chain starts with the certificate of the
+// intermediate CA whose key is given, followed by the certificates of its issuers up to the root
+func NewIntermediateCASigner(chain []*x509.Certificate, key crypto.Signer) (CertSigner, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("CA chain must contain at least the intermediate CA")
+	}
+	if !chain[0].IsCA {
+		return nil, fmt.Errorf("%s is not a CA certificate", chain[0].Subject.CommonName)
+	}
+	if err := VerifyKeyPair(chain[0], key); err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(chain)-1; i++ {
+		if err := chain[i].CheckSignatureFrom(chain[i+1]); err != nil {
+			return nil, fmt.Errorf("%s in the CA chain is not signed by the next certificate %s: %v", chain[i].Subject.CommonName, chain[i+1].Subject.CommonName, err)
+		}
+	}
+	return &caSigner{chain: chain, key: key}, nil
+}
+
+func (s *caSigner) SignServerCertificate(cfg ServerCertConfig, key crypto.Signer) (*x509.Certificate, error) {
+	return NewSignedServerCertificate(cfg, key, s.chain[0], s.key)
+}
+
+func (s *caSigner) SignClientCertificate(cfg ClientCertConfig, key crypto.Signer) (*x509.Certificate, error) {
+	return NewSignedClientCertificate(cfg, key, s.chain[0], s.key)
+}
+
+func (s *caSigner) CACertificates() ([]*x509.Certificate, error) {
+	return s.chain, nil
+}
+
+// IsCertificateChain reports whether each certificate is signed by the next one as in a chain from an intermediate CA to
+// the root, rather than a bundle of independent CAs
+func IsCertificateChain(certs []*x509.Certificate) bool {
+	if len(certs) < 2 {
+		return false
+	}
+	for i := 0; i < len(certs)-1; i++ {
+		if certs[i].CheckSignatureFrom(certs[i+1]) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyKeyPair returns an error if the certificate is not of the key
+func VerifyKeyPair(cert *x509.Certificate, key crypto.Signer) error {
+	certPub, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return err
+	}
+	keyPub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(certPub, keyPub) {
+		return fmt.Errorf("the key doesn't match the certificate %s", cert.Subject.CommonName)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultVaultPKIMount is the path the PKI secret backend is mounted at by default
+const DefaultVaultPKIMount = "pki"
+
+type VaultSignerConfig struct {
+	// Addr is the address of the Vault server e.g. https://vault.example.com:8200
+	Addr  string
+	Token string
+	// Mount is the path the PKI secret backend is mounted at
+	Mount string
+	// Role is the role to sign certificates with
+	Role string
+	// OrganizationRoles are the roles to sign client certificates with the organization instead of Role.
+	// The PKI backend sets the organization of certificates from the role rather than CSRs
+	OrganizationRoles map[string]string
+	HTTPClient        *http.Client
+}
+
+// vaultSigner signs certificates with the /v1/<mount>/sign/<role> endpoint of the PKI secret backend of Vault so that
+// the CA key never leaves Vault
+type vaultSigner struct {
+	cfg   VaultSignerConfig
+	chain []*x509.Certificate
+}
+
+type vaultSignRequest struct {
+	CSR        string `json:"csr"`
+	CommonName string `json:"common_name"`
+	AltNames   string `json:"alt_names,omitempty"`
+	IPSANs     string `json:"ip_sans,omitempty"`
+	TTL        string `json:"ttl"`
+	Format     string `json:"format"`
+}
+
+type vaultSignResponse struct {
+	Data struct {
+		Certificate string   `json:"certificate"`
+		IssuingCA   string   `json:"issuing_ca"`
+		CAChain     []string `json:"ca_chain"`
+	} `json:"data"`
+}
+
+type vaultErrorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+func NewVaultSigner(cfg VaultSignerConfig) (CertSigner, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("address of Vault must be specified")
+	}
+	if cfg.Token == "" {
+		return nil, errors.New("token of Vault must be specified")
+	}
+	if cfg.Role == "" {
+		return nil, errors.New("role of the PKI secret backend of Vault must be specified")
+	}
+	if cfg.Mount == "" {
+		cfg.Mount = DefaultVaultPKIMount
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	cfg.Addr = strings.TrimRight(cfg.Addr, "/")
+	return &vaultSigner{cfg: cfg}, nil
+}
+
+func (s *vaultSigner) SignServerCertificate(cfg ServerCertConfig, key crypto.Signer) (*x509.Certificate, error) {
+	return s.sign(s.cfg.Role, cfg.CommonName, nil, cfg.DNSNames, cfg.IPAddresses, cfg.Duration, key)
+}
+
+func (s *vaultSigner) SignClientCertificate(cfg ClientCertConfig, key crypto.Signer) (*x509.Certificate, error) {
+	role := s.cfg.Role
+	for _, o := range cfg.Organization {
+		if r, ok := s.cfg.OrganizationRoles[o]; ok {
+			role = r
+		}
+	}
+	return s.sign(role, cfg.CommonName, cfg.Organization, cfg.DNSNames, cfg.IPAddresses, cfg.Duration, key)
+}
+
+// CACertificates returns the CA chain returned with the last certificate signed, or the issuing CA of the backend if
+// nothing has been signed yet
+func (s *vaultSigner) CACertificates() ([]*x509.Certificate, error) {
+	if len(s.chain) > 0 {
+		return s.chain, nil
+	}
+	body, err := s.do("GET", fmt.Sprintf("/v1/%s/ca/pem", s.cfg.Mount), nil)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeCertificatesPEM(body)
+}
+
+func (s *vaultSigner) sign(role string, commonName string, organization []string, dnsNames []string, ipAddresses []string, duration time.Duration, key crypto.Signer) (*x509.Certificate, error) {
+	ips := make([]net.IP, len(ipAddresses))
+	for i, ip := range ipAddresses {
+		ips[i] = net.ParseIP(ip)
+	}
+	csrTmpl := x509.CertificateRequest{
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: organization,
+		},
+		DNSNames:    dnsNames,
+		IPAddresses: ips,
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &csrTmpl, key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := json.Marshal(vaultSignRequest{
+		CSR:        string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr})),
+		CommonName: commonName,
+		AltNames:   strings.Join(dnsNames, ","),
+		IPSANs:     strings.Join(ipAddresses, ","),
+		TTL:        fmt.Sprintf("%dh", int64(duration/time.Hour)),
+		Format:     "pem",
+	})
+	if err != nil {
+		return nil, err
+	}
+	body, err := s.do("POST", fmt.Sprintf("/v1/%s/sign/%s", s.cfg.Mount, role), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign the certificate of %s: %v", commonName, err)
+	}
+
+	var resp vaultSignResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse the response from Vault: %v", err)
+	}
+	cert, err := DecodeCertificatePEM([]byte(resp.Data.Certificate))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the certificate of %s signed by Vault: %v", commonName, err)
+	}
+	if err := VerifyKeyPair(cert, key); err != nil {
+		return nil, err
+	}
+	for _, o := range organization {
+		if !contains(cert.Subject.Organization, o) {
+			return nil, fmt.Errorf("the certificate of %s signed by Vault with the role %s lacks the organization %s. Sign it with a role configured with the organization", commonName, role, o)
+		}
+	}
+
+	chainPEM := strings.Join(resp.Data.CAChain, "\n")
+	if chainPEM == "" {
+		chainPEM = resp.Data.IssuingCA
+	}
+	chain, err := DecodeCertificatesPEM([]byte(chainPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the CA chain returned by Vault: %v", err)
+	}
+	if err := cert.CheckSignatureFrom(chain[0]); err != nil {
+		return nil, fmt.Errorf("the certificate of %s is not signed by the issuing CA returned by Vault: %v", commonName, err)
+	}
+	s.chain = chain
+
+	return cert, nil
+}
+
+func (s *vaultSigner) do(method string, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, s.cfg.Addr+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", s.cfg.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		var errResp vaultErrorResponse
+		if err := json.Unmarshal(respBody, &errResp); err == nil && len(errResp.Errors) > 0 {
+			return nil, fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, strings.Join(errResp.Errors, ", "))
+		}
+		return nil, fmt.Errorf("%s %s returned %d", method, path, resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}